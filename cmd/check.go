@@ -25,9 +25,13 @@ func init() {
 func doCheck() {
 	whoAmI()
 
-	gh := pkg.NewGitHubinator(pkg.NewLogger()).WithToken(cfg.PAT)
+	gh := cfg.GetGitHubinator(getGitHubinator())
 
 	for _, w := range cfg.Watches {
+		if !w.IsEnabled() {
+			continue
+		}
+
 		for _, r := range w.Repositories {
 			if err := gh.CheckRepository(ctx, r); err != nil {
 				fmt.Println(err)
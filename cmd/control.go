@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/learnitall/watchinator/pkg"
+	"github.com/spf13/cobra"
+)
+
+var (
+	controlAddr string
+
+	reprocessWatch string
+	reprocessForce bool
+
+	pauseCmd = &cobra.Command{
+		Use:   "pause",
+		Short: "Pause all polls of a running watchinator",
+		Run: func(cmd *cobra.Command, args []string) {
+			doControl(pkg.ControlPausePath)
+		},
+	}
+
+	resumeCmd = &cobra.Command{
+		Use:   "resume",
+		Short: "Resume all polls of a running watchinator",
+		Run: func(cmd *cobra.Command, args []string) {
+			doControl(pkg.ControlResumePath)
+		},
+	}
+
+	reprocessCmd = &cobra.Command{
+		Use:   "reprocess owner/repo#number",
+		Short: "Have a running watchinator perform a watch's actions on an item again, if it matches the watch",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := cobra.ExactArgs(1)(cmd, args); err != nil {
+				fmt.Println(err.Error())
+
+				os.Exit(1)
+			}
+
+			doReprocess(args[0])
+		},
+	}
+)
+
+func init() {
+	for _, c := range []*cobra.Command{pauseCmd, resumeCmd, reprocessCmd} {
+		c.Flags().StringVar(
+			&controlAddr, "addr", "http://localhost:2112", "Address of the running watchinator's control endpoint",
+		)
+		rootCmd.AddCommand(c)
+	}
+
+	reprocessCmd.Flags().StringVar(&reprocessWatch, "watch", "", "Name of the watch to reprocess the item through")
+	reprocessCmd.Flags().BoolVar(&reprocessForce, "force", false, "Ignore the watch's notifyCooldown")
+	_ = reprocessCmd.MarkFlagRequired("watch")
+}
+
+// postControl sends a POST request with the given body to the given control endpoint path and returns the response.
+// If an error occurs or the response is not OK, print it and exit with rc 1.
+func postControl(path string, body io.Reader) *http.Response {
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	resp, err := client.Post(strings.TrimSuffix(controlAddr, "/")+path, "application/json", body)
+	if err != nil {
+		fmt.Printf("unable to reach watchinator at %s: %s\n", controlAddr, err)
+		os.Exit(1)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		fmt.Printf("unexpected response from watchinator: %s: %s\n", resp.Status, strings.TrimSpace(string(msg)))
+		os.Exit(1)
+	}
+
+	return resp
+}
+
+// doControl sends a request to the given control endpoint path and prints the returned status.
+// If an error occurs, print it and exit with rc 1.
+func doControl(path string) {
+	resp := postControl(path, nil)
+	defer resp.Body.Close()
+
+	status := pkg.ControlStatus{}
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		fmt.Printf("unable to decode response from watchinator: %s\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("paused: %t\n", status.Paused)
+	fmt.Printf("polls: %s\n", strings.Join(status.Polls, ", "))
+}
+
+// doReprocess asks the running watchinator to reprocess the given item through reprocessWatch and prints the result.
+// If the item doesn't match the watch, exit with rc 2. If an error occurs, print it and exit with rc 1.
+func doReprocess(item string) {
+	if _, _, err := pkg.ParseGitHubItemRef(item); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	body, err := json.Marshal(pkg.ReprocessRequest{Watch: reprocessWatch, Item: item, Force: reprocessForce})
+	if err != nil {
+		fmt.Printf("unable to marshal request: %s\n", err)
+		os.Exit(1)
+	}
+
+	resp := postControl(pkg.ControlReprocessPath, bytes.NewReader(body))
+	defer resp.Body.Close()
+
+	result := pkg.ReprocessResult{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		fmt.Printf("unable to decode response from watchinator: %s\n", err)
+		os.Exit(1)
+	}
+
+	if !result.Matched {
+		fmt.Printf("%s does not match watch '%s': %s\n", result.Item, result.Watch, result.Reason)
+		os.Exit(2)
+	}
+
+	fmt.Printf("reprocessed %s through watch '%s'\n", result.Item, result.Watch)
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/learnitall/watchinator/pkg"
+	"github.com/spf13/cobra"
+)
+
+var (
+	costMaxFraction float64
+
+	costCmd = &cobra.Command{
+		Use:   "cost watch_name",
+		Short: "Estimate the GitHub API cost of a single tick of a watch. No actions are performed.",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := cobra.MinimumNArgs(1)(cmd, args); err != nil {
+				fmt.Println(err.Error())
+
+				os.Exit(1)
+			}
+
+			doCost(args[0])
+		},
+	}
+)
+
+func init() {
+	costCmd.Flags().Float64Var(
+		&costMaxFraction, "max-fraction", 0.1,
+		"Exit with rc 2 if a single tick uses more than this fraction of the hourly rate limit",
+	)
+	rootCmd.AddCommand(costCmd)
+}
+
+func doCost(watchName string) {
+	initConfigOrDie()
+
+	validateConfigOrDie()
+
+	gh := cfg.GetGitHubinator(getGitHubinator())
+
+	watch := cfg.GetWatch(watchName)
+	if watch == nil {
+		fmt.Printf("unknown watch with name '%s'\n", watchName)
+		os.Exit(1)
+	}
+
+	before, err := gh.RateLimit(ctx)
+	if err != nil {
+		fmt.Printf("unable to get rate limit: %s\n", err)
+		os.Exit(1)
+	}
+
+	pagesBefore := pkg.CounterValue(pkg.MetricIssueQueryTotal)
+	labelsBefore := pkg.CounterValue(pkg.MetricIssueLabelQueryTotal)
+	bodiesBefore := pkg.CounterValue(pkg.MetricIssueBodyQueryTotal)
+
+	issues, err := watch.ListItems(ctx, gh, pkg.NewSystemClock())
+	if err != nil {
+		fmt.Printf("unable to list issues: %s\n", err)
+		os.Exit(1)
+	}
+
+	numItems := len(issues)
+
+	after, err := gh.RateLimit(ctx)
+	if err != nil {
+		fmt.Printf("unable to get rate limit: %s\n", err)
+		os.Exit(1)
+	}
+
+	// If the rate limit window reset during the run, the remaining points can't be compared, so fall back to
+	// the number of points used in the new window.
+	points := before.Remaining - after.Remaining
+	if !after.ResetAt.Equal(before.ResetAt) {
+		points = after.Used
+	}
+
+	interval := cfg.GetInterval(watch)
+	ticksPerHour := float64(time.Hour) / float64(interval)
+
+	fmt.Printf("watch: %s\n", watch.Name)
+	fmt.Printf("matched items: %d\n", numItems)
+	fmt.Printf("issue pages fetched: %.0f\n", pkg.CounterValue(pkg.MetricIssueQueryTotal)-pagesBefore)
+	fmt.Printf("label sub-queries: %.0f\n", pkg.CounterValue(pkg.MetricIssueLabelQueryTotal)-labelsBefore)
+	fmt.Printf("body sub-queries: %.0f\n", pkg.CounterValue(pkg.MetricIssueBodyQueryTotal)-bodiesBefore)
+	fmt.Printf("rate limit points per tick: %d\n", points)
+	fmt.Printf("ticks per hour (interval %s): %.2f\n", interval, ticksPerHour)
+	fmt.Printf("estimated rate limit points per hour: %.0f of %d\n", float64(points)*ticksPerHour, after.Limit)
+
+	if after.Limit > 0 && float64(points) > costMaxFraction*float64(after.Limit) {
+		fmt.Printf(
+			"a single tick uses more than %.0f%% of the hourly rate limit, consider narrowing the watch's filters\n",
+			costMaxFraction*100,
+		)
+		os.Exit(2)
+	}
+}
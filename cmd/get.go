@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/goccy/go-json"
+	"github.com/learnitall/watchinator/pkg"
+	"github.com/spf13/cobra"
+)
+
+var (
+	getCmd = &cobra.Command{
+		Use:   "get owner/repo#number",
+		Short: "Get a single issue from GitHub and print it as JSON.",
+		Long: "Get a single issue from GitHub and print it as JSON, in the format output by 'list'. The issue's " +
+			"labels and body are always included. The issue isn't matched against any watch.",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := cobra.ExactArgs(1)(cmd, args); err != nil {
+				fmt.Println(err.Error())
+
+				os.Exit(1)
+			}
+
+			doGet(args[0])
+		},
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(getCmd)
+}
+
+func doGet(ref string) {
+	repo, number, err := pkg.ParseGitHubItemRef(ref)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
+	initConfigOrDie()
+
+	if err := cfg.LoadCredentials(ctx); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	gh := cfg.GetGitHubinator(getGitHubinator())
+
+	item, err := gh.GetIssue(ctx, repo, number, pkg.NewMatchinator())
+	if err != nil {
+		fmt.Printf("unable to get issue %s: %s\n", ref, err)
+		os.Exit(1)
+	}
+
+	marshalled, err := json.Marshal(item)
+	if err != nil {
+		fmt.Printf("unable to marshal issue to json: %s\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println(string(marshalled))
+}
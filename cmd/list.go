@@ -1,18 +1,23 @@
 package cmd
 
 import (
-	"bytes"
 	"fmt"
 	"os"
 
 	"github.com/goccy/go-json"
+	"github.com/learnitall/watchinator/pkg"
 	"github.com/spf13/cobra"
 )
 
 var (
+	listTrace bool
+
 	listCmd = &cobra.Command{
 		Use:   "list watch_name",
 		Short: "List things on GitHub using the provided config.",
+		Long: "List things on GitHub using the provided config. With --trace, every item scanned by the watch is " +
+			"printed as a line of JSON instead, with whether it matched the watch's filters, why not, and which " +
+			"actions would be performed on it. No actions are performed.",
 		Run: func(cmd *cobra.Command, args []string) {
 			if err := cobra.MinimumNArgs(1)(cmd, args); err != nil {
 				fmt.Println(err.Error())
@@ -26,6 +31,9 @@ var (
 )
 
 func init() {
+	listCmd.Flags().BoolVar(
+		&listTrace, "trace", false, "Print the match decision and planned actions of every scanned item as JSONL",
+	)
 	rootCmd.AddCommand(listCmd)
 }
 
@@ -34,7 +42,7 @@ func doList(watchName string) {
 
 	validateConfigOrDie()
 
-	gh := getGitHubinator().WithToken(cfg.PAT)
+	gh := cfg.GetGitHubinator(getGitHubinator())
 
 	watch := cfg.GetWatch(watchName)
 	if watch == nil {
@@ -42,38 +50,43 @@ func doList(watchName string) {
 		os.Exit(1)
 	}
 
-	matcher := watch.GetMatchinator()
-	issueFilter := watch.GetIssueFilter()
-	numRepos := len(watch.Repositories)
-
-	buf := bytes.Buffer{}
-	buf.WriteString("[")
+	if listTrace {
+		doListTrace(watch, gh)
 
-	for repoIndex, r := range watch.Repositories {
-		issues, err := gh.ListIssues(ctx, r, issueFilter, matcher)
-		if err != nil {
-			fmt.Printf("unable to list issues: %s\n", err)
-			os.Exit(1)
-		}
+		return
+	}
 
-		numIssues := len(issues)
+	issues, err := watch.ListItems(ctx, gh, pkg.NewSystemClock())
+	if err != nil {
+		fmt.Printf("unable to list issues: %s\n", err)
+		os.Exit(1)
+	}
 
-		for issueIndex, issue := range issues {
-			marshalled, err := json.Marshal(issue)
-			if err != nil {
-				fmt.Printf("unable to marshal issue to json '%+v': %s\n", issue, err)
+	marshalled, err := json.Marshal(issues)
+	if err != nil {
+		fmt.Printf("unable to marshal issues to json: %s\n", err)
+		os.Exit(1)
+	}
 
-				return
-			}
+	fmt.Println(string(marshalled))
+}
 
-			buf.Write(marshalled)
+// doListTrace prints a MatchTrace for each item scanned by the watch, one JSON object per line.
+func doListTrace(watch *pkg.Watch, gh pkg.GitHubinator) {
+	// Actions are only planned, so no emails are sent and an Emailinator isn't needed.
+	traces, err := watch.TraceItems(ctx, gh, nil)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
 
-			if repoIndex != numRepos-1 || issueIndex != numIssues-1 {
-				buf.WriteString(",")
-			}
+	for _, t := range traces {
+		marshalled, err := json.Marshal(t)
+		if err != nil {
+			fmt.Printf("unable to marshal trace to json: %s\n", err)
+			os.Exit(1)
 		}
-	}
 
-	buf.WriteString("]")
-	fmt.Println(buf.String())
+		fmt.Println(string(marshalled))
+	}
 }
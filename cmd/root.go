@@ -12,9 +12,17 @@ import (
 
 var (
 	configFilePath string
+	configProfile  string
+
+	skipEmailValidation bool
+	validateOffline     bool
+
+	dryRun bool
 
 	gitHubRetries    int
 	gitHubTimeoutSec int
+	gitHubBaseURL    string
+	gitHubPageSize   int
 
 	ctx = context.Background()
 	cfg *pkg.Config
@@ -48,27 +56,51 @@ func init() {
 	rootCmd.PersistentFlags().IntVar(
 		&gitHubTimeoutSec, "gh-timeout", 5*60, "Maximum number of seconds a request to GitHub can take",
 	)
+	rootCmd.PersistentFlags().IntVar(
+		&gitHubPageSize, "gh-page-size", pkg.GitHubMaxPageSize,
+		"Number of issues and labels to request per page from GitHub, at most 100. Overridden by the config's pageSize",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&gitHubBaseURL, "gh-base-url", "",
+		"URL of the GitHub Enterprise Server instance to use, such as https://github.example.com. "+
+			"Overridden by the config's baseURL",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&configFilePath, "config", "/opt/watchinator/config.yaml",
+		"Path to config file, or to a directory of config files",
+	)
 	rootCmd.PersistentFlags().StringVar(
-		&configFilePath, "config", "/opt/watchinator/config.yaml", "Path to config file",
+		&configProfile, "profile", "", "Name of the profile in the config file to use, if any",
+	)
+	rootCmd.PersistentFlags().BoolVar(
+		&dryRun, "dry-run", false,
+		"Log the actions watches would perform on items, such as subscribing and emailing, instead of performing them",
 	)
 }
 
 func getGitHubinator() pkg.GitHubinator {
-	return pkg.NewGitHubinator(pkg.NewLogger()).
+	gh := pkg.NewGitHubinator(pkg.NewLogger())
+	if gitHubBaseURL != "" {
+		gh = gh.WithBaseURL(gitHubBaseURL)
+	}
+
+	return gh.
 		WithRetries(gitHubRetries).
-		WithTimeout(time.Duration(gitHubTimeoutSec) * time.Second)
+		WithTimeout(time.Duration(gitHubTimeoutSec) * time.Second).
+		WithPageSize(gitHubPageSize)
 }
 
 func getEmailinator() pkg.Emailinator {
 	return pkg.NewEmailinator(pkg.NewLogger())
 }
 
-// initConfigOrDie reads the cmd's configFilePath variable and loads it into the cmd's cfg variable.
+// initConfigOrDie reads the cmd's configFilePath variable and loads it into the cmd's cfg variable, applying the
+// configProfile if set.
 // If an error occurs, print it exit with rc 1.
 func initConfigOrDie() {
 	var err error
 
-	cfg, err = pkg.NewConfigFromFile(configFilePath)
+	cfg, err = pkg.NewConfigFromFile(configFilePath, configProfile)
 	if err != nil {
 		fmt.Printf("unable to load config from %s: %s\n", configFilePath, err)
 		os.Exit(1)
@@ -77,13 +109,19 @@ func initConfigOrDie() {
 	pkg.NewLogger().Debug("loaded config", "path", configFilePath, "config", cfg)
 }
 
-// validateConfigOrDie calls cfg.Validate.
+// validateConfigOrDie calls cfg.Validate. If skipEmailValidation is set, the test connection to the SMTP service is
+// skipped. If validateOffline is set, every check which needs GitHub or the SMTP service is skipped.
 // If an error occurs, print it and exit with rc 1.
 func validateConfigOrDie() {
 	gh := getGitHubinator()
 	e := getEmailinator()
 
-	if err := cfg.Validate(context.Background(), gh, e); err != nil {
+	vo := &pkg.ValidateOptions{
+		SkipEmailConnection: skipEmailValidation,
+		Offline:             validateOffline,
+	}
+
+	if err := cfg.Validate(context.Background(), gh, e, vo); err != nil {
 		fmt.Printf("unable to validate config: %s\n", err.Error())
 		os.Exit(1)
 	}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/goccy/go-json"
+	"github.com/learnitall/watchinator/pkg"
+	"github.com/spf13/cobra"
+)
+
+var (
+	testMatchWatch  string
+	testMatchInput  string
+	testMatchExpect int
+
+	testMatchCmd = &cobra.Command{
+		Use:   "test-match",
+		Short: "Match a watch's filters against items from a JSON file without querying GitHub.",
+		Long: "Match a watch's selectors, required labels and regexes against items from a JSON file, in the format " +
+			"output by 'list', without querying GitHub. Filters which are applied by GitHub, such as searchLabels, " +
+			"states and search, are not applied. If --expect is given and a different number of items match, exits " +
+			"with rc 2.",
+		Run: func(cmd *cobra.Command, args []string) {
+			doTestMatch(cmd.Flags().Changed("expect"))
+		},
+	}
+)
+
+func init() {
+	testMatchCmd.Flags().StringVar(&testMatchWatch, "watch", "", "Name of the watch whose filters are used")
+	testMatchCmd.Flags().StringVar(&testMatchInput, "input", "", "Path to a JSON file containing a list of items")
+	testMatchCmd.Flags().IntVar(&testMatchExpect, "expect", 0, "Number of items which are expected to match")
+	_ = testMatchCmd.MarkFlagRequired("watch")
+	_ = testMatchCmd.MarkFlagRequired("input")
+	rootCmd.AddCommand(testMatchCmd)
+}
+
+// doTestMatch prints whether each item in the input file matches the watch. If checkExpect is set and the number of
+// matched items isn't testMatchExpect, exit with rc 2. If an error occurs, print it and exit with rc 1.
+func doTestMatch(checkExpect bool) {
+	initConfigOrDie()
+
+	if err := cfg.ExpandMatchers(); err != nil {
+		fmt.Printf("unable to expand matcher sets: %s\n", err)
+		os.Exit(1)
+	}
+
+	if err := cfg.PopulateSelectorAliases(); err != nil {
+		fmt.Printf("unable to load selector aliases: %s\n", err)
+		os.Exit(1)
+	}
+
+	if err := cfg.PopulateAgeBuckets(); err != nil {
+		fmt.Printf("unable to load age buckets: %s\n", err)
+		os.Exit(1)
+	}
+
+	watch := cfg.GetWatch(testMatchWatch)
+	if watch == nil {
+		fmt.Printf("unknown watch with name '%s'\n", testMatchWatch)
+		os.Exit(1)
+	}
+
+	if err := watch.PopulateMatchers(); err != nil {
+		fmt.Printf("unable to parse filters of watch '%s': %s\n", testMatchWatch, err)
+		os.Exit(1)
+	}
+
+	input, err := os.ReadFile(testMatchInput)
+	if err != nil {
+		fmt.Printf("unable to read items from %s: %s\n", testMatchInput, err)
+		os.Exit(1)
+	}
+
+	items := []*pkg.GitHubItem{}
+	if err := json.Unmarshal(input, &items); err != nil {
+		fmt.Printf("unable to unmarshal items from %s: %s\n", testMatchInput, err)
+		os.Exit(1)
+	}
+
+	matched := 0
+
+	for _, r := range pkg.MatchItems(watch.GetMatchinator(), items) {
+		name := fmt.Sprintf("%s/%s#%d", r.Item.Repo.Owner, r.Item.Repo.Name, r.Item.Number)
+
+		if r.Matches {
+			matched++
+
+			fmt.Printf("PASS %s: %s\n", name, r.Item.Title)
+		} else {
+			fmt.Printf("FAIL %s: %s (%s)\n", name, r.Item.Title, r.Reason)
+		}
+	}
+
+	fmt.Printf("%d of %d items matched\n", matched, len(items))
+
+	if checkExpect && matched != testMatchExpect {
+		fmt.Printf("expected %d items to match\n", testMatchExpect)
+		os.Exit(2)
+	}
+}
@@ -1,20 +1,80 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
+
+	"github.com/learnitall/watchinator/pkg"
 	"github.com/spf13/cobra"
 )
 
 var (
+	requireMatches bool
+
 	validateConfigCmd = &cobra.Command{
 		Use:   "validate-config",
 		Short: "Validate config file",
 		Run: func(cmd *cobra.Command, args []string) {
+			if validateOffline && requireMatches {
+				fmt.Println("--offline and --require-matches cannot be used together")
+				os.Exit(1)
+			}
+
 			initConfigOrDie()
 			validateConfigOrDie()
+
+			if requireMatches {
+				checkWatchesMatchOrDie()
+			}
 		},
 	}
 )
 
 func init() {
+	validateConfigCmd.Flags().BoolVar(
+		&requireMatches, "require-matches", false,
+		"Run each watch once without performing actions and fail if any watch matches zero items",
+	)
+	validateConfigCmd.Flags().BoolVar(
+		&skipEmailValidation, "skip-email-validation", false,
+		"Skip the test connection to the SMTP service. The email config is still checked for missing fields",
+	)
+	validateConfigCmd.Flags().BoolVar(
+		&validateOffline, "offline", false,
+		"Skip every check which needs GitHub or the SMTP service, such as checking the PAT and repositories, so the "+
+			"config can be linted without network access",
+	)
 	rootCmd.AddCommand(validateConfigCmd)
 }
+
+// checkWatchesMatchOrDie runs each enabled watch in the config once and reports the number of items it matched.
+// If a watch matches zero items or an error occurs, exit with rc 1.
+func checkWatchesMatchOrDie() {
+	gh := cfg.GetGitHubinator(getGitHubinator())
+	failed := false
+
+	for _, w := range cfg.Watches {
+		if !w.IsEnabled() {
+			fmt.Printf("watch '%s': disabled, skipping\n", w.Name)
+
+			continue
+		}
+
+		issues, err := w.ListItems(ctx, gh, pkg.NewSystemClock())
+		if err != nil {
+			fmt.Printf("unable to run watch '%s': %s\n", w.Name, err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("watch '%s': %d matching items\n", w.Name, len(issues))
+
+		if len(issues) == 0 {
+			failed = true
+		}
+	}
+
+	if failed {
+		fmt.Println("at least one watch matched zero items")
+		os.Exit(1)
+	}
+}
@@ -27,11 +27,14 @@ func init() {
 func doWatch() {
 	ctx := context.Background()
 	logger := pkg.NewLogger()
-	configinator := pkg.NewConfiginator(logger)
+	configinator := pkg.NewConfiginator(logger).WithProfile(configProfile)
 	pollinator := pkg.NewPollinator(ctx, logger)
 	emailinator := pkg.NewEmailinator(logger)
 	gitHubinator := pkg.NewGitHubinator(logger)
-	watchinator := pkg.NewWatchinator(logger, gitHubinator, pollinator, configinator, emailinator)
+	watchinator := pkg.NewWatchinator(logger, gitHubinator, pollinator, configinator, emailinator).WithDryRun(dryRun)
+
+	pkg.HandleControlEndpoints(pollinator)
+	pkg.HandleReprocessEndpoint(watchinator)
 
 	go pkg.ServePromEndpoint(ctx)
 
@@ -25,7 +25,7 @@ func init() {
 func whoAmI() {
 	initConfigOrDie()
 
-	if err := cfg.LoadPATFile(ctx); err != nil {
+	if err := cfg.LoadCredentials(ctx); err != nil {
 		fmt.Println(err)
 
 		os.Exit(1)
@@ -33,17 +33,17 @@ func whoAmI() {
 
 	logger := pkg.NewLogger()
 
-	user, pat := cfg.User, cfg.PAT
+	user := cfg.User
 
-	if len(user) == 0 || len(pat) == 0 {
-		fmt.Println("need both user and pat")
+	if len(user) == 0 || (len(cfg.PAT) == 0 && cfg.AppAuth == nil) {
+		fmt.Println("need both user and pat or appAuth")
 
 		os.Exit(1)
 	}
 
-	logger.Debug("Checking PAT", "user", user)
+	logger.Debug("Checking credentials", "user", user)
 
-	gh := getGitHubinator().WithToken(pat)
+	gh := cfg.GetGitHubinator(getGitHubinator())
 
 	_, err := gh.WhoAmI(ctx)
 	if err != nil {
@@ -1,29 +1,196 @@
 package pkg
 
 import (
+	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"text/template"
+	"time"
 
+	"github.com/hashicorp/go-retryablehttp"
 	"github.com/shurcooL/githubv4"
 	"github.com/wneessen/go-mail"
 	"golang.org/x/exp/slog"
 	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
 )
 
+const (
+	// ActionSkipReasonAlreadySubscribed is used when an action is skipped because the user is already subscribed to
+	// the item.
+	ActionSkipReasonAlreadySubscribed = "already-subscribed"
+	// ActionSkipReasonNotSubscribed is used when an action is skipped because the user is not subscribed to the item.
+	ActionSkipReasonNotSubscribed = "not-subscribed"
+	// ActionSkipReasonAlreadyIgnored is used when an action is skipped because the user is already ignoring the item.
+	ActionSkipReasonAlreadyIgnored = "already-ignored"
+	// ActionSkipReasonAlreadySeen is used when an action is skipped because it was already performed on the item and
+	// the item has not been updated since.
+	ActionSkipReasonAlreadySeen = "already-seen"
+	// ActionSkipReasonCooldown is used when an action is skipped because the item was updated within the action's
+	// notify cooldown.
+	ActionSkipReasonCooldown = "cooldown"
+	// ActionSkipReasonAlreadyLabeled is used when an action is skipped because the item already has each of the
+	// action's labels.
+	ActionSkipReasonAlreadyLabeled = "already-labeled"
+	// ActionSkipReasonAlreadyCommented is used when an action is skipped because the viewer already left the action's
+	// comment on the item.
+	ActionSkipReasonAlreadyCommented = "already-commented"
+	// ActionSkipReasonDiscussion is used when an action is skipped because it doesn't support discussions.
+	ActionSkipReasonDiscussion = "discussion"
+	// ActionSkipReasonRepo is used when an action is skipped because the item is not from one of the repositories
+	// the action is limited to.
+	ActionSkipReasonRepo = "repo"
+)
+
+// skipAction records that the given action was not performed on the given item for the given reason.
+func skipAction(logger *slog.Logger, action string, reason string, i GitHubItem) {
+	logger.Debug("skipping action for item", "number", i.Number, "reason", reason)
+	MetricActionSkippedTotal.WithLabelValues(action, reason).Inc()
+}
+
 type GitHubItemAction struct {
 	Handle func(ctx context.Context, i GitHubItem, logger *slog.Logger) error
 	Name   string
+	// DependsOn is a list of names of actions which must complete successfully before this action is handled.
+	// Names of actions which aren't registered with the Actioninator are ignored.
+	DependsOn []string
+	// Repos limits the action to items from the given repositories. If empty, items from any repository are handled.
+	Repos []GitHubRepository
+	// SkipReason returns the reason Handle skips the given item without performing the action, or an empty string if
+	// the action would be performed. It allows actions to be planned without side effects, see Actioninator.Plan.
+	// If nil, the action is never skipped by Handle.
+	SkipReason func(i GitHubItem) string
+	// Flush is called once every item of a poll has been handled, for actions which batch items rather than acting on
+	// each one as it's handled, see NewDigestEmailAction. It returns the items the action was performed on, which are
+	// only then recorded as seen. If nil, there is nothing to flush.
+	Flush func(ctx context.Context, logger *slog.Logger) ([]GitHubItem, error)
+	// Enqueue is called by the Actioninator instead of calling Handle directly, for actions which are performed in the
+	// background, see NewBufferedAction. It queues perform, which performs the action on the item and records it as
+	// seen, and returns without waiting for it. If nil, the Actioninator performs the action as the item is handled.
+	Enqueue func(ctx context.Context, i GitHubItem, logger *slog.Logger, perform func(ctx context.Context) error) error
+}
+
+// ActionDecision records whether an action would be performed on an item, and if not, why.
+type ActionDecision struct {
+	Action string `json:"action"`
+	Fire   bool   `json:"fire"`
+	// Reason is one of the ActionSkipReason constants if Fire is false.
+	Reason string `json:"reason,omitempty"`
+}
+
+// subscribedSkipReason returns ActionSkipReasonAlreadySubscribed if the viewer is subscribed to the given item.
+func subscribedSkipReason(i GitHubItem) string {
+	if i.Subscription == githubv4.SubscriptionStateSubscribed {
+		return ActionSkipReasonAlreadySubscribed
+	}
+
+	return ""
+}
+
+// unsubscribedSkipReason returns ActionSkipReasonNotSubscribed if the viewer is not subscribed to the given item.
+func unsubscribedSkipReason(i GitHubItem) string {
+	if i.Subscription != githubv4.SubscriptionStateSubscribed {
+		return ActionSkipReasonNotSubscribed
+	}
+
+	return ""
+}
+
+// ignoredSkipReason returns ActionSkipReasonAlreadyIgnored if the viewer is ignoring the given item.
+func ignoredSkipReason(i GitHubItem) string {
+	if i.Subscription == githubv4.SubscriptionStateIgnored {
+		return ActionSkipReasonAlreadyIgnored
+	}
+
+	return ""
+}
+
+// findDependencyCycle returns the names of actions forming a dependency cycle, with the first action repeated at the
+// end, or nil if there is no cycle. The given map holds the dependencies of each action, keyed by action name.
+func findDependencyCycle(deps map[string][]string) []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := map[string]int{}
+	path := []string{}
+
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		state[name] = visiting
+		path = append(path, name)
+
+		for _, d := range deps[name] {
+			if _, ok := deps[d]; !ok {
+				continue
+			}
+
+			switch state[d] {
+			case visiting:
+				for i, p := range path {
+					if p == d {
+						return append(append([]string{}, path[i:]...), d)
+					}
+				}
+			case unvisited:
+				if cycle := visit(d); cycle != nil {
+					return cycle
+				}
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[name] = visited
+
+		return nil
+	}
+
+	names := make([]string, 0, len(deps))
+	for name := range deps {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		if state[name] == unvisited {
+			if cycle := visit(name); cycle != nil {
+				return cycle
+			}
+		}
+	}
+
+	return nil
+}
+
+// actionResult holds the outcome of an action during a call to Handle. The done channel is closed once err is set.
+type actionResult struct {
+	done chan struct{}
+	err  error
 }
 
 func NewSubscribeAction(gh GitHubinator) GitHubItemAction {
 	return GitHubItemAction{
 		Handle: func(ctx context.Context, i GitHubItem, logger *slog.Logger) error {
-			if i.Subscription == githubv4.SubscriptionStateSubscribed {
-				logger.Debug("not subscribing to issue, user is already subscribed")
+			if reason := subscribedSkipReason(i); reason != "" {
+				skipAction(logger, "subscribe", reason, i)
 
 				return nil
 			}
@@ -39,27 +206,362 @@ func NewSubscribeAction(gh GitHubinator) GitHubItemAction {
 
 			return nil
 		},
-		Name: "subscribe",
+		Name:       "subscribe",
+		SkipReason: subscribedSkipReason,
 	}
 }
 
-func NewEmailAction(emailinator Emailinator, to string) GitHubItemAction {
+// NewUnsubscribeAction returns an action which unsubscribes the viewer from items they are subscribed to.
+func NewUnsubscribeAction(gh GitHubinator) GitHubItemAction {
 	return GitHubItemAction{
 		Handle: func(ctx context.Context, i GitHubItem, logger *slog.Logger) error {
-			if i.Subscription == githubv4.SubscriptionStateSubscribed {
-				logger.Debug("not emailing issue, user is already subscribed")
+			if reason := unsubscribedSkipReason(i); reason != "" {
+				skipAction(logger, "unsubscribe", reason, i)
 
 				return nil
 			}
 
-			logger.Info("Emailing item", "to", to)
-			MetricActionHandleTotal.WithLabelValues("email").Inc()
+			logger.Info("unsubscribing from issue")
+			MetricActionHandleTotal.WithLabelValues("unsubscribe").Inc()
 
-			asJson, err := json.MarshalIndent(i, "", "\t")
+			if err := gh.SetSubscription(ctx, i.ID, githubv4.SubscriptionStateUnsubscribed); err != nil {
+				logger.Error("unable to update subscription for issue", LogKeyError, err)
+
+				return err
+			}
+
+			return nil
+		},
+		Name:       "unsubscribe",
+		SkipReason: unsubscribedSkipReason,
+	}
+}
+
+// NewIgnoreAction returns an action which ignores items, so the viewer isn't notified of any activity on them.
+func NewIgnoreAction(gh GitHubinator) GitHubItemAction {
+	return GitHubItemAction{
+		Handle: func(ctx context.Context, i GitHubItem, logger *slog.Logger) error {
+			if reason := ignoredSkipReason(i); reason != "" {
+				skipAction(logger, "ignore", reason, i)
+
+				return nil
+			}
+
+			logger.Info("ignoring issue")
+			MetricActionHandleTotal.WithLabelValues("ignore").Inc()
+
+			if err := gh.SetSubscription(ctx, i.ID, githubv4.SubscriptionStateIgnored); err != nil {
+				logger.Error("unable to update subscription for issue", LogKeyError, err)
+
+				return err
+			}
+
+			return nil
+		},
+		Name:       "ignore",
+		SkipReason: ignoredSkipReason,
+	}
+}
+
+// missingLabels returns the given labels which the given item doesn't have.
+func missingLabels(i GitHubItem, labels []string) []string {
+	missing := []string{}
+
+	for _, l := range labels {
+		if !slices.Contains(i.Labels, l) {
+			missing = append(missing, l)
+		}
+	}
+
+	return missing
+}
+
+// NewLabelAction returns an action which adds the given labels to items. Labels the item already has are skipped, and
+// the IDs of the others are resolved in the item's repository.
+func NewLabelAction(gh GitHubinator, labels []string) GitHubItemAction {
+	skipReason := func(i GitHubItem) string {
+		if len(missingLabels(i, labels)) == 0 {
+			return ActionSkipReasonAlreadyLabeled
+		}
+
+		return ""
+	}
+
+	return GitHubItemAction{
+		Handle: func(ctx context.Context, i GitHubItem, logger *slog.Logger) error {
+			if reason := skipReason(i); reason != "" {
+				skipAction(logger, "label", reason, i)
+
+				return nil
+			}
+
+			missing := missingLabels(i, labels)
+
+			logger.Info("labeling issue", "labels", missing)
+			MetricActionHandleTotal.WithLabelValues("label").Inc()
+
+			ids, err := gh.GetLabelIDs(ctx, i.Repo, missing)
 			if err != nil {
-				return fmt.Errorf("unable to marshal item to json: %w", err)
+				return fmt.Errorf("unable to get ids of labels: %w", err)
+			}
+
+			if err := gh.SetLabels(ctx, i.ID, ids); err != nil {
+				logger.Error("unable to add labels to issue", LogKeyError, err)
+
+				return err
 			}
 
+			return nil
+		},
+		Name:       "label",
+		SkipReason: skipReason,
+	}
+}
+
+// ParseItemTemplate parses the given text/template, which is executed against a GitHubItem. The template is also
+// executed against a test item, so references to fields which don't exist are caught when it is parsed.
+func ParseItemTemplate(name string, tmpl string) (*template.Template, error) {
+	parsed, err := template.New(name).Parse(tmpl)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := parsed.Execute(io.Discard, NewTestGitHubItem()); err != nil {
+		return nil, err
+	}
+
+	return parsed, nil
+}
+
+// renderItemTemplate executes the given template against the given item. The item's URL is always set, see
+// gitHubItemURL.
+func renderItemTemplate(tmpl *template.Template, i GitHubItem) (string, error) {
+	i.URL = gitHubItemURL(i)
+
+	b := strings.Builder{}
+	if err := tmpl.Execute(&b, i); err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+// commentMarker returns the hidden marker appended to comments rendered from the given template, which is used to
+// find out if the comment was already left on an item. Different templates have different markers.
+func commentMarker(tmpl string) string {
+	sum := sha256.Sum256([]byte(tmpl))
+
+	return fmt.Sprintf("<!-- watchinator-comment:%s -->", hex.EncodeToString(sum[:6]))
+}
+
+// commentSkipReason skips discussions, which can't be commented on with the addComment mutation.
+func commentSkipReason(i GitHubItem) string {
+	if i.Type == GitHubItemDiscussion {
+		return ActionSkipReasonDiscussion
+	}
+
+	return ""
+}
+
+// NewCommentAction returns an action which comments on items, with a body rendered from the given text/template. A
+// hidden marker is added to each comment, so an item is only commented on once, even if it is matched again.
+func NewCommentAction(gh GitHubinator, tmpl string) GitHubItemAction {
+	parsed, parseErr := ParseItemTemplate("comment", tmpl)
+	marker := commentMarker(tmpl)
+
+	return GitHubItemAction{
+		Handle: func(ctx context.Context, i GitHubItem, logger *slog.Logger) error {
+			if reason := commentSkipReason(i); reason != "" {
+				skipAction(logger, "comment", reason, i)
+
+				return nil
+			}
+
+			if parseErr != nil {
+				return fmt.Errorf("invalid comment template: %w", parseErr)
+			}
+
+			body, err := renderItemTemplate(parsed, i)
+			if err != nil {
+				return fmt.Errorf("unable to render comment: %w", err)
+			}
+
+			commented, err := gh.HasComment(ctx, i.ID, marker)
+			if err != nil {
+				return fmt.Errorf("unable to check for existing comment: %w", err)
+			}
+
+			if commented {
+				skipAction(logger, "comment", ActionSkipReasonAlreadyCommented, i)
+
+				return nil
+			}
+
+			logger.Info("commenting on issue")
+			MetricActionHandleTotal.WithLabelValues("comment").Inc()
+
+			if err := gh.AddComment(ctx, i.ID, body+"\n\n"+marker); err != nil {
+				logger.Error("unable to comment on issue", LogKeyError, err)
+
+				return err
+			}
+
+			return nil
+		},
+		Name:       "comment",
+		SkipReason: commentSkipReason,
+	}
+}
+
+// emailLabelChipStyle is the inline style of the labels in HTML emails. Email clients ignore most stylesheets, so the
+// style is set on each label.
+const emailLabelChipStyle = "display: inline-block; padding: 0 7px; border: 1px solid #d0d7de; border-radius: 2em; " +
+	"background-color: #f6f8fa; font-size: 12px; line-height: 18px;"
+
+// renderEmailHTML renders an HTML email body for the given item, containing its title, a link to it, its author, its
+// labels and its Markdown body rendered as sanitized HTML.
+func renderEmailHTML(i GitHubItem) string {
+	itemURL := gitHubItemURL(i)
+
+	b := strings.Builder{}
+	b.WriteString("<html><body>\n")
+	b.WriteString(fmt.Sprintf(
+		"<h2><a href=\"%s\">%s</a></h2>\n", html.EscapeString(itemURL), html.EscapeString(i.Title),
+	))
+	b.WriteString(fmt.Sprintf(
+		"<p><a href=\"%s\">%s#%d</a> by %s</p>\n",
+		html.EscapeString(itemURL), html.EscapeString(i.Repo.fullName()), i.Number, html.EscapeString(i.Author.Login),
+	))
+
+	if len(i.Labels) > 0 {
+		b.WriteString("<p>")
+
+		for _, l := range i.Labels {
+			b.WriteString(fmt.Sprintf("<span style=\"%s\">%s</span> ", emailLabelChipStyle, html.EscapeString(l)))
+		}
+
+		b.WriteString("</p>\n")
+	}
+
+	b.WriteString(RenderMarkdownHTML(i.BodyMarkdown))
+	b.WriteString("</body></html>\n")
+
+	return b.String()
+}
+
+// gitHubItemURL returns the URL of the given item on GitHub. If the item's URL wasn't fetched, such as for items
+// loaded from the output of 'list' before it was added, the URL is assumed to be on github.com.
+func gitHubItemURL(i GitHubItem) string {
+	if i.URL != "" {
+		return i.URL
+	}
+
+	kind := "issues"
+
+	switch i.Type {
+	case GitHubItemPullRequest:
+		kind = "pull"
+	case GitHubItemDiscussion:
+		kind = "discussions"
+	}
+
+	return fmt.Sprintf("https://github.com/%s/%s/%s/%d", i.Repo.Owner, i.Repo.Name, kind, i.Number)
+}
+
+// setEmailBody sets the given plain text body on the given message, unless it's attached instead, see
+// attachEmailBody. It returns if the body was attached.
+func setEmailBody(
+	m *mail.Msg, body string, maxBodySize int, name string, contentType mail.ContentType, summary string,
+) (bool, error) {
+	attached, err := attachEmailBody(m, body, maxBodySize, name, contentType, summary)
+	if err != nil || attached {
+		return attached, err
+	}
+
+	m.SetBodyString(mail.TypeTextPlain, body)
+
+	return false, nil
+}
+
+// attachEmailBody attaches the given body to the given message as a file with the given name and content type, if
+// maxBodySize is greater than zero and the body is larger, and uses the given summary as the message's body. It returns
+// if the body was attached, leaving the message unchanged otherwise.
+func attachEmailBody(
+	m *mail.Msg, body string, maxBodySize int, name string, contentType mail.ContentType, summary string,
+) (bool, error) {
+	if maxBodySize <= 0 || len(body) <= maxBodySize {
+		return false, nil
+	}
+
+	if err := m.AttachReader(name, strings.NewReader(body), mail.WithFileContentType(contentType)); err != nil {
+		return false, fmt.Errorf("unable to attach %s: %w", name, err)
+	}
+
+	m.SetBodyString(mail.TypeTextPlain, fmt.Sprintf(
+		"%s\n\nThe full content is %d bytes, which exceeds the maximum body size of %d bytes, so it is "+
+			"attached as %s.\n",
+		summary, len(body), maxBodySize, name,
+	))
+
+	return true, nil
+}
+
+// EmailTagsHeader is the email header holding the tags of the watch which sent the email, see FormatTags.
+const EmailTagsHeader = "X-Watchinator-Tags"
+
+// EmailTemplates holds the templates used to render emails about items, see ParseItemTemplate. Nil templates fall back
+// to the default subject or body.
+type EmailTemplates struct {
+	Subject *template.Template
+	Body    *template.Template
+}
+
+// defaultEmailSubject returns the default subject of emails about the given item, which holds its repository, number,
+// title and URL.
+func defaultEmailSubject(i GitHubItem) string {
+	subjectLine := strings.Builder{}
+	subjectLine.WriteString("watchinator: ")
+	subjectLine.WriteString(i.Repo.Owner)
+	subjectLine.WriteString("/")
+	subjectLine.WriteString(i.Repo.Name)
+
+	switch i.Type {
+	case GitHubItemIssue, GitHubItemPullRequest, GitHubItemDiscussion:
+		subjectLine.WriteString("#")
+		subjectLine.WriteString(strconv.Itoa(i.Number))
+		subjectLine.WriteString(": ")
+		subjectLine.WriteString(i.Title)
+	default:
+		subjectLine.WriteString(": unknown")
+	}
+
+	subjectLine.WriteString(" (")
+	subjectLine.WriteString(gitHubItemURL(i))
+	subjectLine.WriteString(")")
+
+	return subjectLine.String()
+}
+
+// NewEmailAction creates a new action which emails items to the given address. The email body is the item as JSON,
+// unless a body template is given. If renderBodyHTML is set, an HTML alternative body is added with the item's
+// Markdown body rendered as sanitized HTML. If maxBodySize is greater than zero and the body is larger, it is attached
+// as 'item.json', or 'item.txt' if templated, instead, with a short summary of the item holding its URL as the body
+// and no HTML alternative. The given watch tags are set in the EmailTagsHeader.
+func NewEmailAction(
+	emailinator Emailinator, to string, renderBodyHTML bool, maxBodySize int, tags map[string]string,
+	templates EmailTemplates,
+) GitHubItemAction {
+	return GitHubItemAction{
+		Handle: func(ctx context.Context, i GitHubItem, logger *slog.Logger) error {
+			if reason := subscribedSkipReason(i); reason != "" {
+				skipAction(logger, "email", reason, i)
+
+				return nil
+			}
+
+			logger.Info("emailing item", "to", to)
+			MetricActionHandleTotal.WithLabelValues("email").Inc()
+
 			m, err := emailinator.NewMsg()
 			if err != nil {
 				return fmt.Errorf("unable to create new message: %w", err)
@@ -69,29 +571,68 @@ func NewEmailAction(emailinator Emailinator, to string) GitHubItemAction {
 				return fmt.Errorf("unable to set To address: %w", err)
 			}
 
-			subjectLine := strings.Builder{}
-			subjectLine.WriteString("watchinator: ")
-			subjectLine.WriteString(i.Repo.Owner)
-			subjectLine.WriteString("/")
-			subjectLine.WriteString(i.Repo.Name)
+			subjectLineString := defaultEmailSubject(i)
 
-			switch i.Type {
-			case GitHubItemIssue:
-				subjectLine.WriteString("#")
-				subjectLine.WriteString(strconv.Itoa(i.Number))
-				subjectLine.WriteString(": ")
-				subjectLine.WriteString(i.Title)
-			default:
-				subjectLine.WriteString(": unknown")
+			if templates.Subject != nil {
+				rendered, err := renderItemTemplate(templates.Subject, i)
+				if err != nil {
+					return fmt.Errorf("unable to render subject: %w", err)
+				}
+
+				// Subjects are a single line, so any line breaks from the template are collapsed.
+				subjectLineString = strings.Join(strings.Fields(rendered), " ")
 			}
 
-			subjectLineString := subjectLine.String()
 			logger.Debug("using the following subject line", "subject", subjectLineString)
 			m.Subject(subjectLineString)
 
-			body := string(asJson)
+			attachmentName, attachmentType := "item.json", mail.ContentType("application/json")
+
+			var body string
+
+			if templates.Body != nil {
+				attachmentName, attachmentType = "item.txt", mail.TypeTextPlain
+
+				body, err = renderItemTemplate(templates.Body, i)
+				if err != nil {
+					return fmt.Errorf("unable to render body: %w", err)
+				}
+			} else {
+				asJson, err := json.MarshalIndent(i, "", "\t")
+				if err != nil {
+					return fmt.Errorf("unable to marshal item to json: %w", err)
+				}
+
+				body = string(asJson)
+			}
+
 			logger.Debug("using the following body line", "body", body)
-			m.SetBodyString(mail.TypeTextPlain, body)
+
+			// The summary always identifies the item and links to it, even if the subject is templated.
+			summary := defaultEmailSubject(i)
+
+			attached, err := attachEmailBody(m, body, maxBodySize, attachmentName, attachmentType, summary)
+			if err != nil {
+				return err
+			}
+
+			switch {
+			case attached:
+				logger.Info("email body exceeds maximum size, attaching it", "size", len(body), "maxSize", maxBodySize)
+			case templates.Body == nil:
+				// Lead with the item's URL, so it's clickable without digging through the JSON.
+				m.SetBodyString(mail.TypeTextPlain, fmt.Sprintf("%s\n\n%s", gitHubItemURL(i), body))
+			default:
+				m.SetBodyString(mail.TypeTextPlain, body)
+			}
+
+			if renderBodyHTML && !attached {
+				m.AddAlternativeString(mail.TypeTextHTML, renderEmailHTML(i))
+			}
+
+			if len(tags) > 0 {
+				m.SetGenHeader(EmailTagsHeader, FormatTags(tags))
+			}
 
 			if err := m.SetAddrHeader("To", to); err != nil {
 				return fmt.Errorf("unable to set to address: %w", err)
@@ -103,18 +644,441 @@ func NewEmailAction(emailinator Emailinator, to string) GitHubItemAction {
 
 			return nil
 		},
-		Name: "email",
+		Name:       "email",
+		SkipReason: subscribedSkipReason,
+	}
+}
+
+// FormatDigest formats a plain text digest of the given items, which newly matched the Watch with the given name.
+// Items are listed in the order given.
+func FormatDigest(watch string, items []*GitHubItem) string {
+	b := strings.Builder{}
+
+	b.WriteString(fmt.Sprintf("%d new item(s) matched watch '%s'.\n", len(items), watch))
+	writeItemList(&b, items)
+
+	return b.String()
+}
+
+// NewDigestEmailAction creates a new action which batches items and emails them to the given address in a single
+// digest when flushed, rather than sending an email per item, see FormatDigest. Items the viewer is subscribed to are
+// skipped, like NewEmailAction. If maxBodySize is greater than zero and the digest is larger, it is attached as
+// 'digest.txt' instead. If the digest fails to be sent, its items are kept and sent by the next flush. The given watch
+// tags are set in the EmailTagsHeader.
+func NewDigestEmailAction(
+	emailinator Emailinator, to string, watch string, maxBodySize int, tags map[string]string,
+) GitHubItemAction {
+	lock := &sync.Mutex{}
+	pending := []*GitHubItem{}
+
+	send := func(ctx context.Context, items []*GitHubItem) error {
+		m, err := emailinator.NewMsg()
+		if err != nil {
+			return fmt.Errorf("unable to create new message: %w", err)
+		}
+
+		if err := m.To(to); err != nil {
+			return fmt.Errorf("unable to set To address: %w", err)
+		}
+
+		m.Subject(fmt.Sprintf("watchinator digest: %s: %d new item(s)", watch, len(items)))
+
+		digest := FormatDigest(watch, items)
+		summary, _, _ := strings.Cut(digest, "\n")
+
+		if _, err := setEmailBody(m, digest, maxBodySize, "digest.txt", mail.TypeTextPlain, summary); err != nil {
+			return err
+		}
+
+		if len(tags) > 0 {
+			m.SetGenHeader(EmailTagsHeader, FormatTags(tags))
+		}
+
+		if err := emailinator.Send(ctx, m); err != nil {
+			return fmt.Errorf("unable to send message: %w", err)
+		}
+
+		return nil
+	}
+
+	return GitHubItemAction{
+		Handle: func(ctx context.Context, i GitHubItem, logger *slog.Logger) error {
+			if reason := subscribedSkipReason(i); reason != "" {
+				skipAction(logger, "email", reason, i)
+
+				return nil
+			}
+
+			logger.Debug("adding item to digest", "to", to)
+
+			lock.Lock()
+			defer lock.Unlock()
+
+			// Items kept from a failed flush are replaced, so they're only listed once.
+			pending = slices.DeleteFunc(pending, func(p *GitHubItem) bool {
+				return p.Repo.Owner == i.Repo.Owner && p.Repo.Name == i.Repo.Name &&
+					p.Type == i.Type && p.Number == i.Number
+			})
+			pending = append(pending, &i)
+
+			return nil
+		},
+		Flush: func(ctx context.Context, logger *slog.Logger) ([]GitHubItem, error) {
+			lock.Lock()
+			items := pending
+			pending = []*GitHubItem{}
+			lock.Unlock()
+
+			if len(items) == 0 {
+				return nil, nil
+			}
+
+			SortGitHubItems(items)
+
+			logger.Info("emailing digest", "to", to, "items", len(items))
+			MetricActionHandleTotal.WithLabelValues("email").Inc()
+
+			if err := send(ctx, items); err != nil {
+				// The items are kept, so they're sent by the next flush.
+				lock.Lock()
+				pending = append(items, pending...)
+				lock.Unlock()
+
+				return nil, err
+			}
+
+			sent := make([]GitHubItem, 0, len(items))
+			for _, i := range items {
+				sent = append(sent, *i)
+			}
+
+			return sent, nil
+		},
+		Name:       "email",
+		SkipReason: subscribedSkipReason,
+	}
+}
+
+const (
+	// WebhookSignatureHeader is the header holding the signature of a webhook's body, if the webhook has a secret. It
+	// follows the format of GitHub's own webhooks: 'sha256=' followed by the hex-encoded HMAC-SHA256 of the body.
+	WebhookSignatureHeader = "X-Watchinator-Signature-256"
+	// WebhookTimeout is how long each webhook request may take, including reading the response.
+	WebhookTimeout = 10 * time.Second
+	// WebhookRetries is the number of times a webhook request is retried, if its HTTPStatusPolicy allows it.
+	WebhookRetries = 3
+)
+
+// postJSON POSTs the given JSON body to the given URL with the given headers. Responses without a 2xx status are
+// errors.
+func postJSON(ctx context.Context, client *http.Client, url string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("unable to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return unexpectedStatusError(resp)
+	}
+
+	return nil
+}
+
+// unexpectedStatusError returns an error holding the status and the start of the body of the given response. Only
+// the start of the body is kept, in case the endpoint returns an entire error page.
+func unexpectedStatusError(resp *http.Response) error {
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	return fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(respBody)))
+}
+
+// newWebhookClient returns a client which retries requests up to WebhookRetries times according to the given policy.
+// Responses are passed through once retries are exhausted, rather than replaced with an error holding the URL, as
+// webhook URLs often embed a token.
+func newWebhookClient(policy HTTPStatusPolicy) *retryablehttp.Client {
+	client := retryablehttp.NewClient()
+	client.HTTPClient = &http.Client{Timeout: WebhookTimeout}
+	client.RetryMax = WebhookRetries
+	client.CheckRetry = policy.CheckRetry
+	client.ErrorHandler = retryablehttp.PassthroughErrorHandler
+	client.Logger = nil
+
+	return client
+}
+
+// postWebhook POSTs the given JSON body to the given URL with the given headers, using a client from
+// newWebhookClient. Responses whose status isn't a success according to the given policy are errors.
+func postWebhook(
+	ctx context.Context, client *retryablehttp.Client, policy HTTPStatusPolicy, url string, body []byte,
+	headers map[string]string,
+) error {
+	req, err := retryablehttp.NewRequestWithContext(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return fmt.Errorf("unable to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if resp == nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if !policy.IsSuccess(resp.StatusCode) {
+		return unexpectedStatusError(resp)
+	}
+
+	return nil
+}
+
+// signWebhookBody returns the value of the WebhookSignatureHeader for the given body signed with the given secret.
+func signWebhookBody(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewWebhookAction creates a new action which POSTs items as JSON, in the same format as emails, to the given URL with
+// the given headers. If secret is set, the body is signed with it in the WebhookSignatureHeader. Responses are
+// retried and checked according to the given policy, and those which aren't a success are errors. The given watch
+// tags are set in the EmailTagsHeader.
+func NewWebhookAction(
+	url string, headers map[string]string, secret string, policy HTTPStatusPolicy, tags map[string]string,
+) GitHubItemAction {
+	client := newWebhookClient(policy)
+
+	return GitHubItemAction{
+		Handle: func(ctx context.Context, i GitHubItem, logger *slog.Logger) error {
+			if reason := subscribedSkipReason(i); reason != "" {
+				skipAction(logger, "webhook", reason, i)
+
+				return nil
+			}
+
+			// The URL isn't logged, as webhook URLs often embed a token.
+			logger.Info("posting item to webhook")
+			MetricActionHandleTotal.WithLabelValues("webhook").Inc()
+
+			body, err := json.MarshalIndent(i, "", "\t")
+			if err != nil {
+				return fmt.Errorf("unable to marshal item to json: %w", err)
+			}
+
+			requestHeaders := map[string]string{}
+
+			if len(tags) > 0 {
+				requestHeaders[EmailTagsHeader] = FormatTags(tags)
+			}
+
+			for k, v := range headers {
+				requestHeaders[k] = v
+			}
+
+			if secret != "" {
+				requestHeaders[WebhookSignatureHeader] = signWebhookBody(body, secret)
+			}
+
+			if err := postWebhook(ctx, client, policy, url, body, requestHeaders); err != nil {
+				return fmt.Errorf("unable to post item to webhook: %w", err)
+			}
+
+			return nil
+		},
+		Name:       "webhook",
+		SkipReason: subscribedSkipReason,
+	}
+}
+
+// slackEscaper escapes the characters Slack treats as control characters in message text.
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// formatSlackMessage returns a compact Slack message for the given item, linking its reference to it, such as
+// '<https://github.com/owner/repo/issues/1|owner/repo#1>: title'.
+func formatSlackMessage(i GitHubItem) string {
+	ref := fmt.Sprintf("%s/%s#%d", i.Repo.Owner, i.Repo.Name, i.Number)
+
+	return fmt.Sprintf("<%s|%s>: %s", gitHubItemURL(i), slackEscaper.Replace(ref), slackEscaper.Replace(i.Title))
+}
+
+// NewSlackAction creates a new action which posts a message for each item to the given Slack incoming webhook URL. If
+// channel is set, it overrides the webhook's default channel. Unlike emails, messages are sent for items the user is
+// subscribed to as well, unless skipSubscribed is set.
+func NewSlackAction(webhookURL string, channel string, skipSubscribed bool) GitHubItemAction {
+	client := &http.Client{Timeout: WebhookTimeout}
+
+	var skipReason func(i GitHubItem) string
+	if skipSubscribed {
+		skipReason = subscribedSkipReason
+	}
+
+	return GitHubItemAction{
+		Handle: func(ctx context.Context, i GitHubItem, logger *slog.Logger) error {
+			if reason := subscribedSkipReason(i); skipSubscribed && reason != "" {
+				skipAction(logger, "slack", reason, i)
+
+				return nil
+			}
+
+			logger.Info("posting item to slack", "channel", channel)
+			MetricActionHandleTotal.WithLabelValues("slack").Inc()
+
+			payload := struct {
+				Text    string `json:"text"`
+				Channel string `json:"channel,omitempty"`
+			}{
+				Text:    formatSlackMessage(i),
+				Channel: channel,
+			}
+
+			body, err := json.Marshal(payload)
+			if err != nil {
+				return fmt.Errorf("unable to marshal slack message to json: %w", err)
+			}
+
+			if err := postJSON(ctx, client, webhookURL, body, nil); err != nil {
+				return fmt.Errorf("unable to post item to slack: %w", err)
+			}
+
+			return nil
+		},
+		Name:       "slack",
+		SkipReason: skipReason,
+	}
+}
+
+// DefaultExecTimeout is how long the command of an exec action may run for if no timeout is configured.
+const DefaultExecTimeout = 30 * time.Second
+
+// execEnv returns the environment variables describing the given item which are passed to the commands of exec
+// actions, on top of watchinator's own environment.
+func execEnv(i GitHubItem) []string {
+	return []string{
+		"WATCHINATOR_REPO=" + i.Repo.Owner + "/" + i.Repo.Name,
+		"WATCHINATOR_NUMBER=" + strconv.Itoa(i.Number),
+		"WATCHINATOR_TITLE=" + i.Title,
+		"WATCHINATOR_URL=" + gitHubItemURL(i),
+	}
+}
+
+// NewExecAction creates a new action which runs the given command for each item. The command is given the item as
+// JSON, in the same format as emails, on stdin, and its key fields in environment variables, see execEnv. The command
+// is killed if it runs for longer than the given timeout, or DefaultExecTimeout if zero, and exiting with a non-zero
+// status is an error.
+func NewExecAction(command []string, timeout time.Duration) GitHubItemAction {
+	if timeout <= 0 {
+		timeout = DefaultExecTimeout
+	}
+
+	return GitHubItemAction{
+		Handle: func(ctx context.Context, i GitHubItem, logger *slog.Logger) error {
+			if reason := subscribedSkipReason(i); reason != "" {
+				skipAction(logger, "exec", reason, i)
+
+				return nil
+			}
+
+			logger.Info("running command for item", "command", command[0])
+			MetricActionHandleTotal.WithLabelValues("exec").Inc()
+
+			asJson, err := json.MarshalIndent(i, "", "\t")
+			if err != nil {
+				return fmt.Errorf("unable to marshal item to json: %w", err)
+			}
+
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			output := bytes.Buffer{}
+
+			cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+			cmd.Stdin = bytes.NewReader(asJson)
+			cmd.Stdout = &output
+			cmd.Stderr = &output
+			cmd.Env = append(os.Environ(), execEnv(i)...)
+			// Don't wait forever on output from children of the command which outlive it.
+			cmd.WaitDelay = time.Second
+
+			err = cmd.Run()
+			logger.Debug("command finished", "output", output.String())
+
+			// Only the end of the output is kept, as it most likely holds the cause of the error.
+			out := strings.TrimSpace(output.String())
+			if len(out) > 512 {
+				out = "..." + out[len(out)-512:]
+			}
+
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("command %s timed out after %s: %s", command[0], timeout, out)
+			}
+
+			if err != nil {
+				return fmt.Errorf("command %s failed: %w: %s", command[0], err, out)
+			}
+
+			return nil
+		},
+		Name:       "exec",
+		SkipReason: subscribedSkipReason,
 	}
 }
 
 type Actioninator interface {
 	WithAction(action GitHubItemAction) Actioninator
+	// WithCooldown suppresses an action on an item for the given duration after it was last performed. The given
+	// store is used to record when actions are performed for the given watch. A zero cooldown disables this.
+	WithCooldown(store SeenStore, watch string, cooldown time.Duration) Actioninator
+	// WithDedup skips an action on an item which hasn't been updated since the action was last performed on it, as
+	// recorded in the given store for the given watch. Unlike the cooldown, this doesn't expire. It should be given the
+	// same store as WithCooldown.
+	WithDedup(store SeenStore, watch string) Actioninator
+	// WithClock sets the Clock used to determine when actions are performed.
+	WithClock(clock Clock) Actioninator
+	// WithPolicy sets how each action is retried and timed out when handling and flushing items.
+	WithPolicy(policy ActionPolicy) Actioninator
+	// WithSemaphore limits the number of actions performed at once to the weight of the given semaphore. It can be
+	// shared between Actioninators, to limit actions across every watch. If nil, actions aren't limited.
+	WithSemaphore(sem *semaphore.Weighted) Actioninator
+	// WithDryRun logs the actions which would be performed on items instead of performing them, if dryRun is set.
+	// Nothing is recorded for the cooldown, so the actions are performed once dry-run is disabled.
+	WithDryRun(dryRun bool) Actioninator
 	Handle(ctx context.Context, item GitHubItem, logger *slog.Logger) error
+	// Flush flushes each action which batches items, and should be called once every item of a poll has been handled.
+	// Every action is flushed even if some fail, and their errors are joined.
+	Flush(ctx context.Context, logger *slog.Logger) error
+	// Plan returns whether each action would be performed on the given item by Handle, without performing any of
+	// them. Failures of dependencies can't be known ahead of time, so are not taken into account.
+	Plan(item GitHubItem) []ActionDecision
 }
 
 type actioninator struct {
 	actions    []GitHubItemAction
 	actionLock *sync.Mutex
+	seenStore  SeenStore
+	watch      string
+	cooldown   time.Duration
+	dedup      bool
+	dryRun     bool
+	policy     ActionPolicy
+	sem        *semaphore.Weighted
+	clock      Clock
 }
 
 func (a *actioninator) WithAction(action GitHubItemAction) Actioninator {
@@ -123,25 +1087,250 @@ func (a *actioninator) WithAction(action GitHubItemAction) Actioninator {
 	return a
 }
 
+func (a *actioninator) WithCooldown(store SeenStore, watch string, cooldown time.Duration) Actioninator {
+	a.seenStore = store
+	a.watch = watch
+	a.cooldown = cooldown
+
+	return a
+}
+
+func (a *actioninator) WithDedup(store SeenStore, watch string) Actioninator {
+	a.seenStore = store
+	a.watch = watch
+	a.dedup = true
+
+	return a
+}
+
+func (a *actioninator) WithClock(clock Clock) Actioninator {
+	a.clock = clock
+
+	return a
+}
+
+func (a *actioninator) WithPolicy(policy ActionPolicy) Actioninator {
+	a.policy = policy
+
+	return a
+}
+
+func (a *actioninator) WithSemaphore(sem *semaphore.Weighted) Actioninator {
+	a.sem = sem
+
+	return a
+}
+
+// ErrActionTimedOut is returned when an action doesn't complete within the Timeout of its ActionPolicy.
+var ErrActionTimedOut = errors.New("action timed out")
+
+// callWithTimeout calls fn, failing if it doesn't return within the given timeout. The context given to fn is
+// cancelled once the timeout passes, but fn isn't waited on, so an action which ignores its context can't hold up the
+// others. A zero timeout disables this.
+func callWithTimeout(ctx context.Context, timeout time.Duration, fn func(ctx context.Context) error) error {
+	if timeout <= 0 {
+		return fn(ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- fn(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("%w after %s: %w", ErrActionTimedOut, timeout, ctx.Err())
+	}
+}
+
+// call calls fn once a slot in the actioninator's semaphore is free, timing it out according to the ActionPolicy. The
+// slot is released once fn returns or times out, so an action which is abandoned doesn't hold on to it.
+func (a *actioninator) call(ctx context.Context, fn func(ctx context.Context) error) error {
+	if a.sem != nil {
+		if err := a.sem.Acquire(ctx, 1); err != nil {
+			return err
+		}
+		defer a.sem.Release(1)
+	}
+
+	return callWithTimeout(ctx, a.policy.Timeout, fn)
+}
+
+// withPolicy calls fn for the action with the given name, retrying it with an exponential backoff and timing out
+// each attempt according to the actioninator's ActionPolicy. The error from the final attempt is returned. An attempt
+// which times out isn't retried, as it's abandoned rather than stopped, and a retry could perform the action twice.
+func (a *actioninator) withPolicy(
+	ctx context.Context, name string, logger *slog.Logger, fn func(ctx context.Context) error,
+) error {
+	backoff := a.policy.getBackoff()
+
+	for attempt := 0; ; attempt++ {
+		err := a.call(ctx, fn)
+		if err == nil || attempt >= a.policy.MaxRetries || errors.Is(err, ErrActionTimedOut) {
+			return err
+		}
+
+		logger.Warn("action failed, retrying", "attempt", attempt+1, "backoff", backoff, LogKeyError, err)
+		MetricActionRetryTotal.WithLabelValues(name).Inc()
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+	}
+}
+
+func (a *actioninator) WithDryRun(dryRun bool) Actioninator {
+	a.dryRun = dryRun
+
+	return a
+}
+
+// recordsSeen returns if actions performed on items are recorded in the seen store, which is the case if a cooldown is
+// configured or dedup is enabled.
+func (a *actioninator) recordsSeen() bool {
+	return a.seenStore != nil && (a.cooldown > 0 || a.dedup)
+}
+
+// cooldownSkipReason returns the reason the given action should be skipped for the given item if the action was
+// performed on the item within the configured cooldown, or if dedup is enabled and the item wasn't updated since the
+// action was performed. If the action should not be skipped, an empty string is returned.
+func (a *actioninator) cooldownSkipReason(item GitHubItem, action GitHubItemAction) string {
+	if !a.recordsSeen() {
+		return ""
+	}
+
+	last, ok := a.seenStore.LastSeen(a.watch, item.ID, action.Name)
+	if !ok {
+		return ""
+	}
+
+	inCooldown := a.cooldown > 0 && a.clock.Now().Sub(last) < a.cooldown
+
+	switch {
+	case !item.UpdatedAt.After(last) && (a.dedup || inCooldown):
+		return ActionSkipReasonAlreadySeen
+	case inCooldown:
+		return ActionSkipReasonCooldown
+	default:
+		return ""
+	}
+}
+
+// skipReason returns the reason the given action should be skipped for the given item, because the action is limited
+// to other repositories or because of the cooldown. If the action should not be skipped, an empty string is returned.
+func (a *actioninator) skipReason(item GitHubItem, action GitHubItemAction) string {
+	if len(action.Repos) > 0 && !containsRepository(action.Repos, item.Repo) {
+		return ActionSkipReasonRepo
+	}
+
+	return a.cooldownSkipReason(item, action)
+}
+
+func (a *actioninator) Flush(ctx context.Context, logger *slog.Logger) error {
+	a.actionLock.Lock()
+	defer a.actionLock.Unlock()
+
+	errs := []error{}
+
+	for _, action := range a.actions {
+		if action.Flush == nil {
+			continue
+		}
+
+		actionLogger := logger.With("action", action.Name)
+		flushed := []GitHubItem{}
+
+		err := a.withPolicy(ctx, action.Name, actionLogger, func(ctx context.Context) error {
+			items, err := action.Flush(ctx, actionLogger)
+			flushed = items
+
+			return err
+		})
+		if err != nil {
+			MetricActionHandleErrorTotal.WithLabelValues(action.Name).Inc()
+
+			errs = append(errs, fmt.Errorf("unable to flush action '%s': %w", action.Name, err))
+
+			continue
+		}
+
+		for _, item := range flushed {
+			if err := a.markSeen(item, action); err != nil {
+				errs = append(errs, fmt.Errorf("unable to record action '%s' in seen store: %w", action.Name, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (a *actioninator) Plan(item GitHubItem) []ActionDecision {
+	decisions := []ActionDecision{}
+
+	for _, action := range a.actions {
+		reason := a.skipReason(item, action)
+		if reason == "" && action.SkipReason != nil {
+			reason = action.SkipReason(item)
+		}
+
+		decisions = append(decisions, ActionDecision{Action: action.Name, Fire: reason == "", Reason: reason})
+	}
+
+	return decisions
+}
+
+// markSeen records that the given action was performed on the given item, if a cooldown is configured or dedup is
+// enabled.
+func (a *actioninator) markSeen(item GitHubItem, action GitHubItemAction) error {
+	if !a.recordsSeen() {
+		return nil
+	}
+
+	return a.seenStore.MarkSeen(a.watch, item.ID, action.Name, a.clock.Now())
+}
+
+// Handle performs each action on the given item. Actions are performed concurrently, except that an action waits
+// for each of the actions it depends on to complete, and is not performed if any of them fail.
 func (a *actioninator) Handle(ctx context.Context, item GitHubItem, logger *slog.Logger) error {
 	a.actionLock.Lock()
 	defer a.actionLock.Unlock()
 
+	deps := map[string][]string{}
+	results := map[string]*actionResult{}
+
+	for _, action := range a.actions {
+		deps[action.Name] = action.DependsOn
+		results[action.Name] = &actionResult{done: make(chan struct{})}
+	}
+
+	if cycle := findDependencyCycle(deps); cycle != nil {
+		return fmt.Errorf("actions have a dependency cycle: %s", strings.Join(cycle, " -> "))
+	}
+
 	g, gctx := errgroup.WithContext(ctx)
 	g.SetLimit(len(a.actions))
 
 	for _, _action := range a.actions {
 		action := _action
+		result := results[action.Name]
+
 		g.Go(
 			func(action GitHubItemAction) func() error {
 				return func() error {
-					if err := action.Handle(gctx, item, logger); err != nil {
-						MetricActionHandleErrorTotal.WithLabelValues(action.Name).Inc()
+					result.err = a.handleAction(gctx, item, action, results, logger)
+					close(result.done)
 
-						return err
-					}
-
-					return nil
+					return result.err
 				}
 			}(action),
 		)
@@ -154,9 +1343,93 @@ func (a *actioninator) Handle(ctx context.Context, item GitHubItem, logger *slog
 	return nil
 }
 
+// handleAction waits for the dependencies of the given action to complete and then performs the action.
+func (a *actioninator) handleAction(
+	ctx context.Context, item GitHubItem, action GitHubItemAction, results map[string]*actionResult,
+	logger *slog.Logger,
+) error {
+	actionLogger := logger.With("action", action.Name)
+
+	for _, d := range action.DependsOn {
+		dep, ok := results[d]
+		if !ok {
+			continue
+		}
+
+		select {
+		case <-dep.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		if dep.err != nil {
+			actionLogger.Debug("not handling item, dependency failed", "dependency", d)
+
+			return fmt.Errorf("action '%s' not handled, dependency '%s' failed: %w", action.Name, d, dep.err)
+		}
+	}
+
+	if reason := a.skipReason(item, action); reason != "" {
+		skipAction(actionLogger.With("cooldown", a.cooldown), action.Name, reason, item)
+
+		return nil
+	}
+
+	if a.dryRun {
+		if action.SkipReason != nil {
+			if reason := action.SkipReason(item); reason != "" {
+				skipAction(actionLogger, action.Name, reason, item)
+
+				return nil
+			}
+		}
+
+		actionLogger.Info("dry-run, would perform action on item", "item", item.Number)
+		MetricActionDryRunTotal.WithLabelValues(action.Name).Inc()
+
+		return nil
+	}
+
+	perform := func(ctx context.Context) error {
+		return a.performAction(ctx, item, action, actionLogger)
+	}
+
+	if action.Enqueue != nil {
+		return action.Enqueue(ctx, item, actionLogger, perform)
+	}
+
+	return perform(ctx)
+}
+
+// performAction performs the given action on the given item according to the ActionPolicy, and records it as seen.
+func (a *actioninator) performAction(
+	ctx context.Context, item GitHubItem, action GitHubItemAction, logger *slog.Logger,
+) error {
+	err := a.withPolicy(ctx, action.Name, logger, func(ctx context.Context) error {
+		return action.Handle(ctx, item, logger)
+	})
+	if err != nil {
+		MetricActionHandleErrorTotal.WithLabelValues(action.Name).Inc()
+
+		return err
+	}
+
+	// Batched actions are only performed once flushed, so the item is recorded as seen then.
+	if action.Flush != nil {
+		return nil
+	}
+
+	if err := a.markSeen(item, action); err != nil {
+		return fmt.Errorf("unable to record action in seen store: %w", err)
+	}
+
+	return nil
+}
+
 func NewActioninator() Actioninator {
 	return &actioninator{
 		actions:    []GitHubItemAction{},
 		actionLock: &sync.Mutex{},
+		clock:      NewSystemClock(),
 	}
 }
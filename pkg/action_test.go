@@ -1,12 +1,31 @@
 package pkg
 
 import (
+	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/shurcooL/githubv4"
+	"github.com/wneessen/go-mail"
+	"golang.org/x/exp/slog"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
 	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
 )
 
 func TestSubscribeActionSubscribesToIssueIfNeeded(t *testing.T) {
@@ -17,12 +36,16 @@ func TestSubscribeActionSubscribesToIssueIfNeeded(t *testing.T) {
 	logger := NewLogger()
 
 	// Subscribed issue, no error = no call to SetSubscription, no error
+	skipped := MetricActionSkippedTotal.WithLabelValues("subscribe", ActionSkipReasonAlreadySubscribed)
+	skippedBefore := CounterValue(skipped)
+
 	item.Subscription = githubv4.SubscriptionStateSubscribed
 	assert.NilError(t, a.Handle(ctx, item, logger), "unexpected error handling already subscribed issue")
 	assert.Assert(
 		t, len(gh.SetSubscriptionRequests) == 0,
 		"expected no call to SetSubscription on already subscribed issue with no returned error",
 	)
+	assert.Equal(t, CounterValue(skipped)-skippedBefore, float64(1))
 
 	// Subscribed issue, error = no call to SetSubscription, no error
 	gh.SetSubscriptionError = errors.New("my test error")
@@ -49,10 +72,148 @@ func TestSubscribeActionSubscribesToIssueIfNeeded(t *testing.T) {
 	)
 }
 
+func TestUnsubscribeActionUnsubscribesIfSubscribed(t *testing.T) {
+	gh := NewMockGitHubinator()
+	a := NewUnsubscribeAction(gh)
+	item := *NewTestGitHubItem()
+	ctx := context.Background()
+	logger := NewLogger()
+
+	skipped := MetricActionSkippedTotal.WithLabelValues("unsubscribe", ActionSkipReasonNotSubscribed)
+	skippedBefore := CounterValue(skipped)
+	handled := MetricActionHandleTotal.WithLabelValues("unsubscribe")
+	handledBefore := CounterValue(handled)
+
+	for _, state := range []githubv4.SubscriptionState{
+		githubv4.SubscriptionStateUnsubscribed, githubv4.SubscriptionStateIgnored,
+	} {
+		item.Subscription = state
+		assert.NilError(t, a.Handle(ctx, item, logger))
+		assert.Equal(t, a.SkipReason(item), ActionSkipReasonNotSubscribed)
+	}
+
+	assert.Equal(t, len(gh.SetSubscriptionRequests), 0)
+	assert.Equal(t, CounterValue(skipped)-skippedBefore, float64(2))
+
+	item.Subscription = githubv4.SubscriptionStateSubscribed
+	assert.Equal(t, a.SkipReason(item), "")
+	assert.NilError(t, a.Handle(ctx, item, logger))
+	assert.Equal(t, len(gh.SetSubscriptionRequests), 1)
+	assert.Equal(t, CounterValue(handled)-handledBefore, float64(1))
+
+	gh.SetSubscriptionError = errors.New("my test error")
+	assert.ErrorContains(t, a.Handle(ctx, item, logger), "my test error")
+}
+
+func TestLabelActionAddsMissingLabels(t *testing.T) {
+	gh := NewMockGitHubinator()
+	a := NewLabelAction(gh, []string{"another/label", "triage"})
+	item := *NewTestGitHubItem()
+	ctx := context.Background()
+	logger := NewLogger()
+
+	// Only the label the item doesn't have yet is added.
+	assert.NilError(t, a.Handle(ctx, item, logger))
+	assert.DeepEqual(t, gh.GetLabelIDsRequests, []string{item.Repo.fullName() + ":triage"})
+	assert.DeepEqual(t, gh.SetLabelsRequests, []githubv4.ID{item.ID})
+	assert.DeepEqual(t, gh.SetLabelsLabelIDs, [][]githubv4.ID{{githubv4.ID("triage")}})
+
+	// The item has every label, so the action is skipped.
+	skipped := MetricActionSkippedTotal.WithLabelValues("label", ActionSkipReasonAlreadyLabeled)
+	skippedBefore := CounterValue(skipped)
+
+	item.Labels = append(item.Labels, "triage")
+	assert.Equal(t, a.SkipReason(item), ActionSkipReasonAlreadyLabeled)
+	assert.NilError(t, a.Handle(ctx, item, logger))
+	assert.Equal(t, len(gh.SetLabelsRequests), 1)
+	assert.Equal(t, CounterValue(skipped)-skippedBefore, float64(1))
+
+	// Errors resolving labels or adding them are returned.
+	item.Labels = []string{}
+	gh.GetLabelIDsError = errors.New("label 'triage' does not exist")
+	assert.ErrorContains(t, a.Handle(ctx, item, logger), "label 'triage' does not exist")
+	assert.Equal(t, len(gh.SetLabelsRequests), 1)
+
+	gh.GetLabelIDsError = nil
+	gh.SetLabelsError = errors.New("my test error")
+	assert.ErrorContains(t, a.Handle(ctx, item, logger), "my test error")
+}
+
+func TestCommentActionCommentsOnce(t *testing.T) {
+	gh := NewMockGitHubinator()
+	a := NewCommentAction(gh, "See CONTRIBUTING.md to get started on {{ .Repo.Owner }}/{{ .Repo.Name }}#{{ .Number }}.")
+	item := *NewTestGitHubItem()
+	ctx := context.Background()
+	logger := NewLogger()
+
+	assert.NilError(t, a.Handle(ctx, item, logger))
+	assert.DeepEqual(t, gh.AddCommentRequests, []githubv4.ID{item.ID})
+	expected := fmt.Sprintf("See CONTRIBUTING.md to get started on %s#%d.", item.Repo.fullName(), item.Number)
+	assert.Assert(t, cmp.Contains(gh.AddCommentBodies[0], expected))
+
+	// The item was already commented on, so it isn't commented on again.
+	skipped := MetricActionSkippedTotal.WithLabelValues("comment", ActionSkipReasonAlreadyCommented)
+	skippedBefore := CounterValue(skipped)
+
+	assert.NilError(t, a.Handle(ctx, item, logger))
+	assert.Equal(t, len(gh.AddCommentRequests), 1)
+	assert.Equal(t, CounterValue(skipped)-skippedBefore, float64(1))
+
+	// A comment from another template is left on the item.
+	assert.NilError(t, NewCommentAction(gh, "Thanks!").Handle(ctx, item, logger))
+	assert.Equal(t, len(gh.AddCommentRequests), 2)
+
+	// Discussions are skipped.
+	discussion := *NewTestGitHubItem()
+	discussion.ID = "discussion"
+	discussion.Type = GitHubItemDiscussion
+	assert.Equal(t, a.SkipReason(discussion), ActionSkipReasonDiscussion)
+	assert.NilError(t, a.Handle(ctx, discussion, logger))
+	assert.Equal(t, len(gh.AddCommentRequests), 2)
+
+	gh.HasCommentError = errors.New("my test error")
+	assert.ErrorContains(t, a.Handle(ctx, item, logger), "unable to check for existing comment: my test error")
+
+	gh.HasCommentError = nil
+	gh.AddCommentError = errors.New("my other test error")
+	item.ID = "another"
+	assert.ErrorContains(t, a.Handle(ctx, item, logger), "my other test error")
+}
+
+func TestIgnoreActionIgnoresIfNotIgnored(t *testing.T) {
+	gh := NewMockGitHubinator()
+	a := NewIgnoreAction(gh)
+	item := *NewTestGitHubItem()
+	ctx := context.Background()
+	logger := NewLogger()
+
+	skipped := MetricActionSkippedTotal.WithLabelValues("ignore", ActionSkipReasonAlreadyIgnored)
+	skippedBefore := CounterValue(skipped)
+
+	item.Subscription = githubv4.SubscriptionStateIgnored
+	assert.NilError(t, a.Handle(ctx, item, logger))
+	assert.Equal(t, a.SkipReason(item), ActionSkipReasonAlreadyIgnored)
+	assert.Equal(t, len(gh.SetSubscriptionRequests), 0)
+	assert.Equal(t, CounterValue(skipped)-skippedBefore, float64(1))
+
+	for _, state := range []githubv4.SubscriptionState{
+		githubv4.SubscriptionStateSubscribed, githubv4.SubscriptionStateUnsubscribed,
+	} {
+		item.Subscription = state
+		assert.Equal(t, a.SkipReason(item), "")
+		assert.NilError(t, a.Handle(ctx, item, logger))
+	}
+
+	assert.Equal(t, len(gh.SetSubscriptionRequests), 2)
+
+	gh.SetSubscriptionError = errors.New("my test error")
+	assert.ErrorContains(t, a.Handle(ctx, item, logger), "my test error")
+}
+
 func TestEmailActionSendsEmail(t *testing.T) {
 	e := NewMockEmailinator()
 	toAddress := "test@example.com"
-	a := NewEmailAction(e, toAddress)
+	a := NewEmailAction(e, toAddress, false, 0, nil, EmailTemplates{})
 	item := *NewTestGitHubItem()
 	ctx := context.Background()
 	logger := NewLogger()
@@ -65,3 +226,899 @@ func TestEmailActionSendsEmail(t *testing.T) {
 
 	assert.ErrorContains(t, a.Handle(ctx, item, logger), "my test error")
 }
+
+// capturingEmailinator is an Emailinator which records the messages it sends.
+type capturingEmailinator struct {
+	MockEmailinator
+	sent []*mail.Msg
+}
+
+func (c *capturingEmailinator) Send(_ context.Context, msg *mail.Msg) error {
+	c.sent = append(c.sent, msg)
+
+	return nil
+}
+
+func TestEmailActionSetsTagsHeader(t *testing.T) {
+	e := &capturingEmailinator{MockEmailinator: *NewMockEmailinator()}
+	item := *NewTestGitHubItem()
+	ctx := context.Background()
+	logger := NewLogger()
+
+	tags := map[string]string{"team": "platform", "env": "prod"}
+	a := NewEmailAction(e, "test@example.com", false, 0, tags, EmailTemplates{})
+	assert.NilError(t, a.Handle(ctx, item, logger))
+	assert.DeepEqual(t, e.sent[0].GetGenHeader(EmailTagsHeader), []string{"env=prod, team=platform"})
+
+	a = NewEmailAction(e, "test@example.com", false, 0, nil, EmailTemplates{})
+	assert.NilError(t, a.Handle(ctx, item, logger))
+	assert.Equal(t, len(e.sent[1].GetGenHeader(EmailTagsHeader)), 0)
+}
+
+func TestEmailActionIncludesURL(t *testing.T) {
+	e := &capturingEmailinator{MockEmailinator: *NewMockEmailinator()}
+	item := *NewTestGitHubItem()
+	item.URL = "https://github.example.com/owner/repo/issues/1"
+
+	a := NewEmailAction(e, "test@example.com", false, 0, nil, EmailTemplates{})
+	assert.NilError(t, a.Handle(context.Background(), item, NewLogger()))
+
+	assert.DeepEqual(
+		t, e.sent[0].GetGenHeader(mail.HeaderSubject),
+		[]string{"watchinator: owner/repo#1: a test issue (https://github.example.com/owner/repo/issues/1)"},
+	)
+
+	body, err := e.sent[0].GetParts()[0].GetContent()
+	assert.NilError(t, err)
+	assert.Assert(t, strings.HasPrefix(string(body), item.URL+"\n\n{"), string(body))
+}
+
+func TestEmailActionRendersTemplates(t *testing.T) {
+	e := &capturingEmailinator{MockEmailinator: *NewMockEmailinator()}
+	item := *NewTestGitHubItem()
+	item.URL = ""
+
+	subject, err := ParseItemTemplate("subject", "[{{ .Repo.Name }}]\n{{ .Title }}")
+	assert.NilError(t, err)
+
+	body, err := ParseItemTemplate("body", "{{ .Title }}\nLabels:{{ range .Labels }} {{ . }}{{ end }}\n{{ .URL }}\n")
+	assert.NilError(t, err)
+
+	a := NewEmailAction(e, "test@example.com", false, 0, nil, EmailTemplates{Subject: subject, Body: body})
+	assert.NilError(t, a.Handle(context.Background(), item, NewLogger()))
+	assert.DeepEqual(t, e.sent[0].GetGenHeader(mail.HeaderSubject), []string{"[repo] a test issue"})
+
+	content, err := e.sent[0].GetParts()[0].GetContent()
+	assert.NilError(t, err)
+	assert.Equal(
+		t, string(content),
+		"a test issue\nLabels: a/test/label another/label\nhttps://github.com/owner/repo/issues/1\n",
+	)
+
+	// Templated bodies which are too large are attached as text.
+	a = NewEmailAction(e, "test@example.com", false, 10, nil, EmailTemplates{Body: body})
+	assert.NilError(t, a.Handle(context.Background(), item, NewLogger()))
+	assert.Equal(t, len(e.sent[1].GetAttachments()), 1)
+	assert.Equal(t, e.sent[1].GetAttachments()[0].Name, "item.txt")
+
+	// The summary links to the item, even if the subject doesn't.
+	a = NewEmailAction(e, "test@example.com", false, 10, nil, EmailTemplates{Subject: subject, Body: body})
+	assert.NilError(t, a.Handle(context.Background(), item, NewLogger()))
+
+	content, err = e.sent[2].GetParts()[0].GetContent()
+	assert.NilError(t, err)
+	assert.Assert(t, cmp.Contains(string(content), "https://github.com/owner/repo/issues/1"))
+}
+
+func TestWebhookActionPostsItem(t *testing.T) {
+	requests := []*http.Request{}
+	bodies := [][]byte{}
+	status := http.StatusOK
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NilError(t, err)
+
+		requests = append(requests, r)
+		bodies = append(bodies, body)
+
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("my test response"))
+	}))
+	t.Cleanup(server.Close)
+
+	item := *NewTestGitHubItem()
+	ctx := context.Background()
+	logger := NewLogger()
+
+	a := NewWebhookAction(
+		server.URL+"/hook", map[string]string{"Authorization": "Bearer token"}, "my secret", HTTPStatusPolicy{},
+		map[string]string{"team": "platform"},
+	)
+
+	handled := MetricActionHandleTotal.WithLabelValues("webhook")
+	handledBefore := CounterValue(handled)
+
+	assert.NilError(t, a.Handle(ctx, item, logger))
+	assert.Equal(t, CounterValue(handled)-handledBefore, float64(1))
+	assert.Equal(t, len(requests), 1)
+	assert.Equal(t, requests[0].Method, http.MethodPost)
+	assert.Equal(t, requests[0].URL.Path, "/hook")
+	assert.Equal(t, requests[0].Header.Get("Content-Type"), "application/json")
+	assert.Equal(t, requests[0].Header.Get("Authorization"), "Bearer token")
+	assert.Equal(t, requests[0].Header.Get(EmailTagsHeader), "team=platform")
+
+	posted := GitHubItem{}
+	assert.NilError(t, json.Unmarshal(bodies[0], &posted))
+	assert.Equal(t, posted.Title, item.Title)
+	assert.Equal(t, posted.Number, item.Number)
+
+	// The signature can be checked with the secret, like GitHub's webhooks.
+	mac := hmac.New(sha256.New, []byte("my secret"))
+	mac.Write(bodies[0])
+	assert.Equal(t, requests[0].Header.Get(WebhookSignatureHeader), "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	// Without a secret, requests aren't signed.
+	a = NewWebhookAction(server.URL, nil, "", HTTPStatusPolicy{}, nil)
+	assert.NilError(t, a.Handle(ctx, item, logger))
+	assert.Equal(t, requests[1].Header.Get(WebhookSignatureHeader), "")
+
+	// Responses without a 2xx status are errors, and client errors aren't retried.
+	status = http.StatusBadRequest
+	assert.ErrorContains(
+		t, a.Handle(ctx, item, logger),
+		"unable to post item to webhook: unexpected status 400 Bad Request: my test response",
+	)
+
+	// Items the user is subscribed to are skipped.
+	item.Subscription = githubv4.SubscriptionStateSubscribed
+	assert.NilError(t, a.Handle(ctx, item, logger))
+	assert.Equal(t, len(requests), 3)
+}
+
+func TestWebhookActionHonorsContextCancellation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	t.Cleanup(server.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	a := NewWebhookAction(server.URL, nil, "", HTTPStatusPolicy{}, nil)
+	assert.ErrorIs(t, a.Handle(ctx, *NewTestGitHubItem(), NewLogger()), context.Canceled)
+}
+
+func TestWebhookActionRetriesAccordingToStatusPolicy(t *testing.T) {
+	statuses := []int{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := statuses[0]
+		statuses = statuses[1:]
+
+		// Retry-After is honored for 503s, so retries don't slow down the test.
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	ctx := context.Background()
+	logger := NewLogger()
+	item := *NewTestGitHubItem()
+	retries := MetricHTTPRetryTotal.WithLabelValues("5xx")
+	retriesBefore := CounterValue(retries)
+
+	// Server errors are retried until a success.
+	a := NewWebhookAction(server.URL, nil, "", HTTPStatusPolicy{}, nil)
+	statuses = []int{http.StatusServiceUnavailable, http.StatusOK}
+	assert.NilError(t, a.Handle(ctx, item, logger))
+	assert.Equal(t, CounterValue(retries)-retriesBefore, float64(1))
+
+	// Once retries are exhausted, the last response is an error.
+	statuses = []int{}
+	for n := 0; n <= WebhookRetries; n++ {
+		statuses = append(statuses, http.StatusServiceUnavailable)
+	}
+
+	assert.ErrorContains(t, a.Handle(ctx, item, logger), "unexpected status 503 Service Unavailable")
+	assert.Equal(t, len(statuses), 0)
+
+	// Statuses can be treated as a success, such as a conflict from an endpoint which already has the item.
+	a = NewWebhookAction(server.URL, nil, "", HTTPStatusPolicy{SuccessStatuses: []string{"2xx", "409"}}, nil)
+	statuses = []int{http.StatusConflict}
+	assert.NilError(t, a.Handle(ctx, item, logger))
+}
+
+func TestFormatSlackMessage(t *testing.T) {
+	item := *NewTestGitHubItem()
+	item.Number = 7
+	item.Title = "Crash when <input> & output differ"
+	item.URL = "https://github.com/owner/repo/issues/7"
+
+	assert.Equal(
+		t, formatSlackMessage(item),
+		"<https://github.com/owner/repo/issues/7|owner/repo#7>: Crash when &lt;input&gt; &amp; output differ",
+	)
+}
+
+func TestSlackActionPostsMessage(t *testing.T) {
+	payloads := []map[string]string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload := map[string]string{}
+		assert.NilError(t, json.NewDecoder(r.Body).Decode(&payload))
+
+		payloads = append(payloads, payload)
+	}))
+	t.Cleanup(server.Close)
+
+	item := *NewTestGitHubItem()
+	item.Subscription = githubv4.SubscriptionStateSubscribed
+	ctx := context.Background()
+	logger := NewLogger()
+
+	// Subscribed items are posted by default.
+	a := NewSlackAction(server.URL, "#alerts", false)
+	assert.NilError(t, a.Handle(ctx, item, logger))
+	assert.Equal(t, len(payloads), 1)
+	assert.DeepEqual(t, payloads[0], map[string]string{"text": formatSlackMessage(item), "channel": "#alerts"})
+	assert.Assert(t, a.SkipReason == nil)
+
+	// Unless they're configured to be skipped.
+	a = NewSlackAction(server.URL, "", true)
+	assert.NilError(t, a.Handle(ctx, item, logger))
+	assert.Equal(t, len(payloads), 1)
+	assert.Equal(t, a.SkipReason(item), ActionSkipReasonAlreadySubscribed)
+
+	item.Subscription = githubv4.SubscriptionStateUnsubscribed
+	assert.NilError(t, a.Handle(ctx, item, logger))
+	assert.DeepEqual(t, payloads[1], map[string]string{"text": formatSlackMessage(item)})
+}
+
+func TestExecActionRunsCommand(t *testing.T) {
+	dir := t.TempDir()
+	item := *NewTestGitHubItem()
+	item.Number = 7
+	item.Title = "my title"
+	item.URL = "https://github.com/owner/repo/issues/7"
+	ctx := context.Background()
+	logger := NewLogger()
+
+	a := NewExecAction(
+		[]string{"sh", "-c", `cat > "$0/item.json" && env | grep ^WATCHINATOR_ | sort > "$0/env"`, dir}, 0,
+	)
+	assert.NilError(t, a.Handle(ctx, item, logger))
+
+	asJson, err := os.ReadFile(dir + "/item.json")
+	assert.NilError(t, err)
+
+	stdin := GitHubItem{}
+	assert.NilError(t, json.Unmarshal(asJson, &stdin))
+	assert.Equal(t, stdin.Title, "my title")
+
+	env, err := os.ReadFile(dir + "/env")
+	assert.NilError(t, err)
+	assert.Equal(
+		t, string(env),
+		"WATCHINATOR_NUMBER=7\nWATCHINATOR_REPO=owner/repo\nWATCHINATOR_TITLE=my title\n"+
+			"WATCHINATOR_URL=https://github.com/owner/repo/issues/7\n",
+	)
+
+	// Items the user is subscribed to are skipped.
+	assert.NilError(t, os.Remove(dir+"/item.json"))
+
+	item.Subscription = githubv4.SubscriptionStateSubscribed
+	assert.NilError(t, a.Handle(ctx, item, logger))
+
+	_, err = os.Stat(dir + "/item.json")
+	assert.Assert(t, os.IsNotExist(err))
+}
+
+func TestExecActionReturnsErrors(t *testing.T) {
+	item := *NewTestGitHubItem()
+	logger := NewLogger()
+
+	a := NewExecAction([]string{"sh", "-c", "echo my test error >&2; exit 3"}, 0)
+	assert.ErrorContains(t, a.Handle(context.Background(), item, logger), "exit status 3: my test error")
+
+	a = NewExecAction([]string{"sh", "-c", "echo still working; exec sleep 5"}, 50*time.Millisecond)
+	assert.ErrorContains(
+		t, a.Handle(context.Background(), item, logger), "command sh timed out after 50ms: still working",
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.ErrorIs(t, a.Handle(ctx, item, logger), context.Canceled)
+}
+
+func TestActioninatorScopesLoggerWithActionName(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(buf, nil))
+	item := *NewTestGitHubItem()
+
+	a := NewActioninator().WithAction(
+		GitHubItemAction{
+			Handle: func(ctx context.Context, i GitHubItem, logger *slog.Logger) error {
+				logger.Info("handling item")
+
+				return nil
+			},
+			Name: "test-action",
+		},
+	)
+
+	assert.NilError(t, a.Handle(context.Background(), item, logger.With("watch", "my-watch")))
+	assert.Assert(t, cmp.Contains(buf.String(), "watch=my-watch"))
+	assert.Assert(t, cmp.Contains(buf.String(), "action=test-action"))
+}
+
+func TestActioninatorSuppressesActionsWithinCooldown(t *testing.T) {
+	ctx := context.Background()
+	logger := NewLogger()
+	item := *NewTestGitHubItem()
+	item.ID = "an-id"
+
+	numHandled := 0
+	clock := NewFakeClock(time.Now())
+
+	a := NewActioninator().
+		WithAction(
+			GitHubItemAction{
+				Handle: func(ctx context.Context, i GitHubItem, logger *slog.Logger) error {
+					numHandled += 1
+
+					return nil
+				},
+				Name: "test-action",
+			},
+		).
+		WithCooldown(NewMemorySeenStore(), "watch", 4*time.Hour).
+		WithClock(clock)
+
+	assert.NilError(t, a.Handle(ctx, item, logger))
+	assert.Equal(t, numHandled, 1)
+
+	// Handling the same version of the item again is reported as already seen.
+	alreadySeen := MetricActionSkippedTotal.WithLabelValues("test-action", ActionSkipReasonAlreadySeen)
+	alreadySeenBefore := CounterValue(alreadySeen)
+
+	assert.NilError(t, a.Handle(ctx, item, logger))
+	assert.Equal(t, numHandled, 1)
+	assert.Equal(t, CounterValue(alreadySeen)-alreadySeenBefore, float64(1))
+
+	// Rapid updates within the cooldown are suppressed.
+	cooldown := MetricActionSkippedTotal.WithLabelValues("test-action", ActionSkipReasonCooldown)
+	cooldownBefore := CounterValue(cooldown)
+
+	for _, d := range []time.Duration{time.Minute, time.Hour, 2 * time.Hour} {
+		item.UpdatedAt = item.UpdatedAt.Add(d)
+		clock.Advance(d)
+
+		assert.NilError(t, a.Handle(ctx, item, logger))
+		assert.Equal(t, numHandled, 1)
+	}
+
+	assert.Equal(t, CounterValue(cooldown)-cooldownBefore, float64(3))
+
+	// Once the cooldown passes, the action is performed again.
+	clock.Advance(time.Hour)
+
+	assert.NilError(t, a.Handle(ctx, item, logger))
+	assert.Equal(t, numHandled, 2)
+
+	// Other items are not affected by the cooldown.
+	otherItem := *NewTestGitHubItem()
+	otherItem.ID = "another-id"
+
+	assert.NilError(t, a.Handle(ctx, otherItem, logger))
+	assert.Equal(t, numHandled, 3)
+}
+
+func TestActioninatorDedupSkipsItemsNotUpdatedSinceAction(t *testing.T) {
+	ctx := context.Background()
+	logger := NewLogger()
+	item := *NewTestGitHubItem()
+	item.ID = "an-id"
+
+	numHandled := 0
+	clock := NewFakeClock(time.Now())
+	store := NewMemorySeenStore()
+
+	newActioninator := func() Actioninator {
+		return NewActioninator().
+			WithAction(
+				GitHubItemAction{
+					Handle: func(ctx context.Context, i GitHubItem, logger *slog.Logger) error {
+						numHandled += 1
+
+						return nil
+					},
+					Name: "test-action",
+				},
+			).
+			WithDedup(store, "watch").
+			WithClock(clock)
+	}
+
+	a := newActioninator()
+	assert.NilError(t, a.Handle(ctx, item, logger))
+	assert.Equal(t, numHandled, 1)
+
+	// Without a cooldown, the record doesn't expire, including for new Actioninators sharing the store.
+	clock.Advance(30 * 24 * time.Hour)
+	assert.NilError(t, a.Handle(ctx, item, logger))
+	assert.NilError(t, newActioninator().Handle(ctx, item, logger))
+	assert.Equal(t, numHandled, 1)
+	assert.DeepEqual(
+		t, a.Plan(item), []ActionDecision{{Action: "test-action", Fire: false, Reason: ActionSkipReasonAlreadySeen}},
+	)
+
+	// Once the item is updated, the action is performed again.
+	item.UpdatedAt = clock.Now()
+	assert.NilError(t, a.Handle(ctx, item, logger))
+	assert.Equal(t, numHandled, 2)
+}
+
+func TestActioninatorDryRunDoesNotPerformActions(t *testing.T) {
+	ctx := context.Background()
+	logger := NewLogger()
+	gh := NewMockGitHubinator()
+	e := &capturingEmailinator{MockEmailinator: *NewMockEmailinator()}
+	store := NewMemorySeenStore()
+	item := *NewTestGitHubItem()
+
+	email := NewEmailAction(e, "test@example.com", false, 0, nil, EmailTemplates{})
+	email.DependsOn = []string{"subscribe"}
+
+	a := NewActioninator().
+		WithAction(NewSubscribeAction(gh)).
+		WithAction(email).
+		WithAction(NewUnsubscribeAction(gh)).
+		WithCooldown(store, "watch", time.Hour).
+		WithDryRun(true)
+
+	subscribeBefore := CounterValue(MetricActionDryRunTotal.WithLabelValues("subscribe"))
+	emailBefore := CounterValue(MetricActionDryRunTotal.WithLabelValues("email"))
+	unsubscribeBefore := CounterValue(MetricActionDryRunTotal.WithLabelValues("unsubscribe"))
+
+	assert.NilError(t, a.Handle(ctx, item, logger))
+	assert.NilError(t, a.Flush(ctx, logger))
+
+	assert.Equal(t, len(gh.SetSubscriptionRequests), 0)
+	assert.Equal(t, len(e.sent), 0)
+	assert.Equal(t, CounterValue(MetricActionDryRunTotal.WithLabelValues("subscribe"))-subscribeBefore, float64(1))
+	assert.Equal(t, CounterValue(MetricActionDryRunTotal.WithLabelValues("email"))-emailBefore, float64(1))
+
+	// Actions which would skip the item aren't counted.
+	assert.Equal(
+		t, CounterValue(MetricActionDryRunTotal.WithLabelValues("unsubscribe"))-unsubscribeBefore, float64(0),
+	)
+
+	// Nothing is recorded, so the actions are performed once dry-run is disabled.
+	_, ok := store.LastSeen("watch", item.ID, "subscribe")
+	assert.Assert(t, !ok)
+
+	assert.NilError(t, a.WithDryRun(false).Handle(ctx, item, logger))
+	assert.Equal(t, len(gh.SetSubscriptionRequests), 1)
+	assert.Equal(t, len(e.sent), 1)
+}
+
+func TestActioninatorRetriesFailedActions(t *testing.T) {
+	ctx := context.Background()
+	logger := NewLogger()
+	item := *NewTestGitHubItem()
+
+	attempts := 0
+	action := GitHubItemAction{
+		Handle: func(ctx context.Context, i GitHubItem, logger *slog.Logger) error {
+			attempts++
+
+			if attempts < 3 {
+				return errors.New("my test error")
+			}
+
+			return nil
+		},
+		Name: "flaky-action",
+	}
+
+	retries := MetricActionRetryTotal.WithLabelValues("flaky-action")
+	retriesBefore := CounterValue(retries)
+
+	a := NewActioninator().WithAction(action).WithPolicy(ActionPolicy{MaxRetries: 2, Backoff: time.Millisecond})
+	assert.NilError(t, a.Handle(ctx, item, logger))
+	assert.Equal(t, attempts, 3)
+	assert.Equal(t, CounterValue(retries)-retriesBefore, float64(2))
+
+	// The error from the final attempt is returned once the retries run out.
+	attempts = 0
+	a = NewActioninator().WithAction(action).WithPolicy(ActionPolicy{MaxRetries: 1, Backoff: time.Millisecond})
+	assert.ErrorContains(t, a.Handle(ctx, item, logger), "my test error")
+	assert.Equal(t, attempts, 2)
+}
+
+func TestActioninatorTimesOutHangingActions(t *testing.T) {
+	ctx := context.Background()
+	logger := NewLogger()
+	item := *NewTestGitHubItem()
+
+	// The hanging action ignores its context, and is only released once the test is done.
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+
+	handled := false
+	a := NewActioninator().
+		WithAction(GitHubItemAction{
+			Handle: func(ctx context.Context, i GitHubItem, logger *slog.Logger) error {
+				<-release
+
+				return nil
+			},
+			Name: "hanging-action",
+		}).
+		WithAction(GitHubItemAction{
+			Handle: func(ctx context.Context, i GitHubItem, logger *slog.Logger) error {
+				handled = true
+
+				return nil
+			},
+			Name: "quick-action",
+		}).
+		WithPolicy(ActionPolicy{Timeout: 20 * time.Millisecond})
+
+	start := time.Now()
+	assert.ErrorContains(t, a.Handle(ctx, item, logger), "action timed out after 20ms")
+	assert.Assert(t, time.Since(start) < time.Second)
+	assert.Assert(t, handled)
+}
+
+func TestActioninatorDoesNotRetryTimedOutActions(t *testing.T) {
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+
+	var attempts atomic.Int32
+	a := NewActioninator().
+		WithAction(GitHubItemAction{
+			Handle: func(ctx context.Context, i GitHubItem, logger *slog.Logger) error {
+				attempts.Add(1)
+				<-release
+
+				return nil
+			},
+			Name: "hanging-action",
+		}).
+		WithPolicy(ActionPolicy{MaxRetries: 2, Timeout: 20 * time.Millisecond, Backoff: time.Millisecond})
+
+	// The abandoned attempt may still complete, so retrying it could perform the action twice.
+	err := a.Handle(context.Background(), *NewTestGitHubItem(), NewLogger())
+	assert.ErrorIs(t, err, ErrActionTimedOut)
+	assert.Equal(t, attempts.Load(), int32(1))
+}
+
+func TestActioninatorSemaphoreLimitsConcurrentActions(t *testing.T) {
+	ctx := context.Background()
+	logger := NewLogger()
+	item := *NewTestGitHubItem()
+	sem := semaphore.NewWeighted(2)
+
+	lock := &sync.Mutex{}
+	running, maxRunning, total := 0, 0, 0
+
+	action := func(name string) GitHubItemAction {
+		return GitHubItemAction{
+			Handle: func(ctx context.Context, i GitHubItem, logger *slog.Logger) error {
+				lock.Lock()
+				running++
+				total++
+				maxRunning = max(maxRunning, running)
+				lock.Unlock()
+
+				time.Sleep(10 * time.Millisecond)
+
+				lock.Lock()
+				running--
+				lock.Unlock()
+
+				return nil
+			},
+			Name: name,
+		}
+	}
+
+	// Each watch has its own actioninator, which share the semaphore.
+	g := errgroup.Group{}
+
+	for w := 0; w < 3; w++ {
+		a := NewActioninator().WithSemaphore(sem)
+		for n := 0; n < 3; n++ {
+			a = a.WithAction(action(fmt.Sprintf("action-%d", n)))
+		}
+
+		g.Go(func() error {
+			return a.Handle(ctx, item, logger)
+		})
+	}
+
+	assert.NilError(t, g.Wait())
+	assert.Equal(t, total, 9)
+	assert.Assert(t, maxRunning <= 2, "%d actions ran at once", maxRunning)
+}
+
+func TestActioninatorRunsDependentActionsInOrder(t *testing.T) {
+	ctx := context.Background()
+	logger := NewLogger()
+	item := *NewTestGitHubItem()
+
+	orderLock := &sync.Mutex{}
+	order := []string{}
+
+	newAction := func(name string, err error, dependsOn ...string) GitHubItemAction {
+		return GitHubItemAction{
+			Handle: func(ctx context.Context, i GitHubItem, logger *slog.Logger) error {
+				orderLock.Lock()
+				defer orderLock.Unlock()
+
+				order = append(order, name)
+
+				return err
+			},
+			Name:      name,
+			DependsOn: dependsOn,
+		}
+	}
+
+	a := NewActioninator().
+		WithAction(newAction("third", nil, "second")).
+		WithAction(newAction("second", nil, "first")).
+		WithAction(newAction("first", nil))
+
+	assert.NilError(t, a.Handle(ctx, item, logger))
+	assert.DeepEqual(t, order, []string{"first", "second", "third"})
+
+	// Dependents of a failed action are not handled.
+	order = []string{}
+	a = NewActioninator().
+		WithAction(newAction("second", nil, "first")).
+		WithAction(newAction("first", errors.New("my test error")))
+
+	assert.ErrorContains(t, a.Handle(ctx, item, logger), "my test error")
+	assert.DeepEqual(t, order, []string{"first"})
+
+	// Cycles are rejected.
+	a = NewActioninator().
+		WithAction(newAction("first", nil, "second")).
+		WithAction(newAction("second", nil, "first"))
+
+	assert.ErrorContains(t, a.Handle(ctx, item, logger), "dependency cycle")
+}
+
+func TestActioninatorOnlyHandlesItemsFromActionRepos(t *testing.T) {
+	ctx := context.Background()
+	logger := NewLogger()
+	item := *NewTestGitHubItem()
+
+	handled := []string{}
+	handledLock := &sync.Mutex{}
+
+	newAction := func(name string, repos ...GitHubRepository) GitHubItemAction {
+		return GitHubItemAction{
+			Handle: func(ctx context.Context, i GitHubItem, logger *slog.Logger) error {
+				handledLock.Lock()
+				defer handledLock.Unlock()
+
+				handled = append(handled, name)
+
+				return nil
+			},
+			Name:  name,
+			Repos: repos,
+		}
+	}
+
+	a := NewActioninator().
+		WithAction(newAction("team-a", GitHubRepository{Owner: "owner", Name: "repo-a"})).
+		WithAction(newAction("team-b", GitHubRepository{Owner: "owner", Name: "repo-b"}))
+
+	item.Repo = GitHubRepository{Owner: "owner", Name: "repo-b"}
+	assert.NilError(t, a.Handle(ctx, item, logger))
+	assert.DeepEqual(t, handled, []string{"team-b"})
+
+	handled = []string{}
+	a = a.WithAction(newAction("everyone"))
+	item.Repo = GitHubRepository{Owner: "owner", Name: "repo-c"}
+	assert.NilError(t, a.Handle(ctx, item, logger))
+	assert.DeepEqual(t, handled, []string{"everyone"})
+}
+
+func TestEmailActionAddsHTMLAlternative(t *testing.T) {
+	e := &capturingEmailinator{MockEmailinator: *NewMockEmailinator()}
+	item := *NewTestGitHubItem()
+	ctx := context.Background()
+	logger := NewLogger()
+
+	a := NewEmailAction(e, "test@example.com", true, 0, nil, EmailTemplates{})
+	assert.NilError(t, a.Handle(ctx, item, logger))
+
+	parts := e.sent[0].GetParts()
+	assert.Equal(t, len(parts), 2)
+	assert.Equal(t, parts[0].GetContentType(), mail.TypeTextPlain)
+	assert.Equal(t, parts[1].GetContentType(), mail.TypeTextHTML)
+
+	content, err := parts[1].GetContent()
+	assert.NilError(t, err)
+	assert.Assert(t, cmp.Contains(string(content), "<p>issue <strong>body</strong></p>"))
+
+	// Without renderBodyHTML, emails are plain text only.
+	a = NewEmailAction(e, "test@example.com", false, 0, nil, EmailTemplates{})
+	assert.NilError(t, a.Handle(ctx, item, logger))
+	assert.Equal(t, len(e.sent[1].GetParts()), 1)
+}
+
+func TestDigestEmailActionBatchesItems(t *testing.T) {
+	e := &capturingEmailinator{MockEmailinator: *NewMockEmailinator()}
+	ctx := context.Background()
+	logger := NewLogger()
+	a := NewDigestEmailAction(e, "test@example.com", "name", 0, map[string]string{"team": "platform"})
+
+	first := *NewTestGitHubItem()
+	first.Number = 2
+	first.Title = "second issue"
+	second := *NewTestGitHubItem()
+	subscribed := *NewTestGitHubItem()
+	subscribed.Number = 3
+	subscribed.Subscription = githubv4.SubscriptionStateSubscribed
+
+	for _, i := range []GitHubItem{first, second, subscribed} {
+		assert.NilError(t, a.Handle(ctx, i, logger))
+	}
+
+	assert.Equal(t, len(e.sent), 0)
+
+	// Items are sent in a single email once flushed, in order.
+	flushed, err := a.Flush(ctx, logger)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, flushed, []GitHubItem{second, first})
+	assert.Equal(t, len(e.sent), 1)
+	assert.DeepEqual(
+		t, e.sent[0].GetGenHeader(mail.HeaderSubject), []string{"watchinator digest: name: 2 new item(s)"},
+	)
+	assert.DeepEqual(t, e.sent[0].GetGenHeader(EmailTagsHeader), []string{"team=platform"})
+
+	body, err := e.sent[0].GetParts()[0].GetContent()
+	assert.NilError(t, err)
+	assert.Equal(t, string(body), FormatDigest("name", []*GitHubItem{&second, &first}))
+	assert.Assert(t, strings.HasPrefix(string(body), "2 new item(s) matched watch 'name'.\n"))
+
+	// There's nothing left to send.
+	flushed, err = a.Flush(ctx, logger)
+	assert.NilError(t, err)
+	assert.Equal(t, len(flushed), 0)
+	assert.Equal(t, len(e.sent), 1)
+}
+
+// flakyEmailinator is a capturingEmailinator which fails to send the given number of messages before succeeding.
+type flakyEmailinator struct {
+	capturingEmailinator
+	failures int
+}
+
+func (f *flakyEmailinator) Send(ctx context.Context, msg *mail.Msg) error {
+	if f.failures > 0 {
+		f.failures--
+
+		return errors.New("my test error")
+	}
+
+	return f.capturingEmailinator.Send(ctx, msg)
+}
+
+func TestDigestEmailActionKeepsItemsWhenSendFails(t *testing.T) {
+	e := &flakyEmailinator{capturingEmailinator: capturingEmailinator{MockEmailinator: *NewMockEmailinator()}}
+	e.failures = 1
+	ctx := context.Background()
+	logger := NewLogger()
+	item := *NewTestGitHubItem()
+	a := NewDigestEmailAction(e, "test@example.com", "name", 0, nil)
+
+	assert.NilError(t, a.Handle(ctx, item, logger))
+	_, err := a.Flush(ctx, logger)
+	assert.ErrorContains(t, err, "my test error")
+
+	// The item is handled again on the next poll, but is only listed once.
+	assert.NilError(t, a.Handle(ctx, item, logger))
+	_, err = a.Flush(ctx, logger)
+	assert.NilError(t, err)
+	assert.Equal(t, len(e.sent), 1)
+	assert.DeepEqual(
+		t, e.sent[0].GetGenHeader(mail.HeaderSubject), []string{"watchinator digest: name: 1 new item(s)"},
+	)
+}
+
+func TestActioninatorRecordsDigestItemsAsSeenOnceFlushed(t *testing.T) {
+	e := &flakyEmailinator{capturingEmailinator: capturingEmailinator{MockEmailinator: *NewMockEmailinator()}}
+	e.failures = 1
+	ctx := context.Background()
+	logger := NewLogger()
+	item := *NewTestGitHubItem()
+	store := NewMemorySeenStore()
+
+	a := NewActioninator().
+		WithAction(NewDigestEmailAction(e, "test@example.com", "watch", 0, nil)).
+		WithDedup(store, "watch")
+
+	// The item isn't seen until the digest is sent, so a failed send doesn't lose it.
+	assert.NilError(t, a.Handle(ctx, item, logger))
+	assert.ErrorContains(t, a.Flush(ctx, logger), "my test error")
+
+	_, seen := store.LastSeen("watch", item.ID, "email")
+	assert.Assert(t, !seen)
+
+	assert.NilError(t, a.Handle(ctx, item, logger))
+	assert.NilError(t, a.Flush(ctx, logger))
+
+	_, seen = store.LastSeen("watch", item.ID, "email")
+	assert.Assert(t, seen)
+	assert.Equal(t, len(e.sent), 1)
+}
+
+func TestActioninatorFlushJoinsErrors(t *testing.T) {
+	flushed := []string{}
+	newAction := func(name string, err error) GitHubItemAction {
+		return GitHubItemAction{
+			Name:   name,
+			Handle: func(context.Context, GitHubItem, *slog.Logger) error { return nil },
+			Flush: func(context.Context, *slog.Logger) ([]GitHubItem, error) {
+				flushed = append(flushed, name)
+
+				return nil, err
+			},
+		}
+	}
+
+	a := NewActioninator().
+		WithAction(newAction("first", errors.New("my test error"))).
+		WithAction(GitHubItemAction{Name: "unbatched"}).
+		WithAction(newAction("second", nil))
+
+	assert.ErrorContains(t, a.Flush(context.Background(), NewLogger()), "unable to flush action 'first': my test error")
+	assert.DeepEqual(t, flushed, []string{"first", "second"})
+}
+
+func TestEmailActionAttachesLargeBodies(t *testing.T) {
+	e := &capturingEmailinator{MockEmailinator: *NewMockEmailinator()}
+	item := *NewTestGitHubItem()
+	ctx := context.Background()
+	logger := NewLogger()
+
+	// Small items are inline.
+	a := NewEmailAction(e, "test@example.com", true, 1<<20, nil, EmailTemplates{})
+	assert.NilError(t, a.Handle(ctx, item, logger))
+	assert.Equal(t, len(e.sent[0].GetAttachments()), 0)
+	assert.Equal(t, len(e.sent[0].GetParts()), 2)
+
+	// Large items are attached, without the HTML alternative.
+	item.Body = strings.Repeat("a large body ", 1000)
+
+	a = NewEmailAction(e, "test@example.com", true, 1024, nil, EmailTemplates{})
+	assert.NilError(t, a.Handle(ctx, item, logger))
+
+	attachments := e.sent[1].GetAttachments()
+	assert.Equal(t, len(attachments), 1)
+	assert.Equal(t, attachments[0].Name, "item.json")
+	assert.Equal(t, attachments[0].ContentType, mail.ContentType("application/json"))
+
+	attached := &bytes.Buffer{}
+	_, err := attachments[0].Writer(attached)
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(attached.String(), item.Body))
+
+	parts := e.sent[1].GetParts()
+	assert.Equal(t, len(parts), 1)
+
+	body, err := parts[0].GetContent()
+	assert.NilError(t, err)
+	assert.Assert(t, len(body) < 1024, "expected body to be a short summary, got %d bytes", len(body))
+	assert.Assert(t, cmp.Contains(string(body), "https://github.com/owner/repo/issues/1"))
+	assert.Assert(t, cmp.Contains(string(body), "attached as item.json"))
+}
@@ -0,0 +1,151 @@
+package pkg
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Duration is a time.Duration which can also be given in days in configs, such as '30d' or '1d12h', as ages of
+// items are usually thought of in days.
+type Duration time.Duration
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// UnmarshalYAML parses the node's value with ParseDuration.
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	var s string
+	if err := value.Decode(&s); err != nil {
+		return err
+	}
+
+	parsed, err := ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(parsed)
+
+	return nil
+}
+
+// ParseDuration parses the given duration like time.ParseDuration, but also accepts a leading number of days, such as
+// '30d' or '1d12h'.
+func ParseDuration(s string) (time.Duration, error) {
+	days, rest, ok := strings.Cut(s, "d")
+	if !ok {
+		return time.ParseDuration(s)
+	}
+
+	n, err := strconv.Atoi(days)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid duration '%s', expected a whole number of days followed by 'd'", s)
+	}
+
+	d := time.Duration(n) * 24 * time.Hour
+
+	if rest != "" {
+		remainder, err := time.ParseDuration(rest)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration '%s': %w", s, err)
+		}
+
+		d += remainder
+	}
+
+	return d, nil
+}
+
+// AgeBucket groups items by how long ago they were created, so they can be matched with selectors such as
+// 'ageBucket==stale'. An item falls in the first bucket whose MaxAge is greater than the item's age.
+type AgeBucket struct {
+	// Name is the value of the 'ageBucket' selector key for items in the bucket.
+	Name string `yaml:"name"`
+	// MaxAge is the exclusive upper bound on the age of items in the bucket. Zero means the bucket has no upper
+	// bound, which is only allowed for the last bucket.
+	MaxAge time.Duration `yaml:"maxAge"`
+}
+
+// DefaultAgeBuckets are the age buckets used when a config doesn't set any.
+var DefaultAgeBuckets = []AgeBucket{
+	{Name: "new", MaxAge: 24 * time.Hour},
+	{Name: "week", MaxAge: 7 * 24 * time.Hour},
+	{Name: "month", MaxAge: 30 * 24 * time.Hour},
+	{Name: "stale"},
+}
+
+// GetAgeBucket returns the name of the bucket an item of the given age falls in. An empty string is returned if the
+// age exceeds every bucket.
+func GetAgeBucket(buckets []AgeBucket, age time.Duration) string {
+	for _, b := range buckets {
+		if b.MaxAge == 0 || age < b.MaxAge {
+			return b.Name
+		}
+	}
+
+	return ""
+}
+
+// ValidateAgeBuckets ensures each bucket has a unique name which is a valid selector value, and that the buckets are
+// in order of increasing MaxAge, ending with a bucket without a MaxAge so every item falls in a bucket.
+func ValidateAgeBuckets(buckets []AgeBucket) error {
+	if len(buckets) == 0 {
+		return fmt.Errorf("expected at least one age bucket")
+	}
+
+	names := map[string]bool{}
+
+	var previous time.Duration
+
+	for i, b := range buckets {
+		if errs := validation.IsValidLabelValue(b.Name); len(errs) > 0 || b.Name == "" {
+			return fmt.Errorf("invalid age bucket name '%s', expected a valid selector value", b.Name)
+		}
+
+		if names[b.Name] {
+			return fmt.Errorf("duplicate age bucket '%s'", b.Name)
+		}
+
+		names[b.Name] = true
+
+		last := i == len(buckets)-1
+
+		switch {
+		case last && b.MaxAge != 0:
+			return fmt.Errorf("last age bucket '%s' cannot set maxAge, so it holds every older item", b.Name)
+		case !last && b.MaxAge <= previous:
+			return fmt.Errorf(
+				"maxAge of age bucket '%s' must be greater than %s, as buckets are in order of increasing age",
+				b.Name, previous,
+			)
+		}
+
+		previous = b.MaxAge
+	}
+
+	return nil
+}
+
+// checkAgeBucketSelectorValues ensures the given values of the 'ageBucket' selector key are names of the given
+// buckets.
+func checkAgeBucketSelectorValues(buckets []AgeBucket, values []string) error {
+	names := []string{}
+	for _, b := range buckets {
+		names = append(names, b.Name)
+	}
+
+	for _, v := range values {
+		if !slices.Contains(names, v) {
+			return fmt.Errorf("unknown age bucket '%s', expected one of '%s'", v, strings.Join(names, "', '"))
+		}
+	}
+
+	return nil
+}
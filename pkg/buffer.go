@@ -0,0 +1,158 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"golang.org/x/exp/slog"
+)
+
+// NotificationDropPolicy determines what a buffered action does with a new item when its buffer is full.
+type NotificationDropPolicy string
+
+const (
+	// NotificationDropPolicyBlock waits for room in the buffer, so no items are dropped.
+	NotificationDropPolicyBlock NotificationDropPolicy = "block"
+	// NotificationDropPolicyDropOldest drops the oldest buffered item to make room for the new item.
+	NotificationDropPolicyDropOldest NotificationDropPolicy = "drop-oldest"
+	// NotificationDropPolicyDropNewest drops the new item.
+	NotificationDropPolicyDropNewest NotificationDropPolicy = "drop-newest"
+)
+
+// bufferedNotification is an item waiting in a notificationBuffer.
+type bufferedNotification struct {
+	// ctx is detached from the context the item was added with, which is canceled once the item is handled.
+	ctx     context.Context
+	item    GitHubItem
+	logger  *slog.Logger
+	perform func(ctx context.Context) error
+}
+
+// notificationBuffer is a bounded buffer in front of an action. Items are handed to the action by a worker, which is
+// started when an item is added and exits once the buffer is empty.
+type notificationBuffer struct {
+	action  GitHubItemAction
+	size    int
+	policy  NotificationDropPolicy
+	lock    *sync.Mutex
+	cond    *sync.Cond
+	queue   []bufferedNotification
+	running bool
+}
+
+// drop records that the given item was dropped from the buffer.
+func (b *notificationBuffer) drop(n bufferedNotification) {
+	n.logger.Warn(
+		"notification buffer full, dropping item",
+		"policy", b.policy, "size", b.size, "droppedItem", n.item.Number,
+	)
+	MetricNotificationsDropped.WithLabelValues(b.action.Name, string(b.policy)).Inc()
+}
+
+// add adds the given item to the buffer, applying the drop policy if the buffer is full. Once the item leaves the
+// buffer, perform is called to perform the action on it. If the policy is NotificationDropPolicyBlock, add waits for
+// room until the given context is canceled.
+func (b *notificationBuffer) add(
+	ctx context.Context, item GitHubItem, logger *slog.Logger, perform func(ctx context.Context) error,
+) error {
+	n := bufferedNotification{ctx: context.WithoutCancel(ctx), item: item, logger: logger, perform: perform}
+
+	// Wake up add if it's waiting for room, so it can see the context was canceled.
+	stop := context.AfterFunc(ctx, func() {
+		b.lock.Lock()
+		defer b.lock.Unlock()
+
+		b.cond.Broadcast()
+	})
+	defer stop()
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	warned := false
+
+	for len(b.queue) >= b.size {
+		switch b.policy {
+		case NotificationDropPolicyDropOldest:
+			b.drop(b.queue[0])
+			b.queue = b.queue[1:]
+		case NotificationDropPolicyDropNewest:
+			b.drop(n)
+
+			return nil
+		default:
+			if err := ctx.Err(); err != nil {
+				return fmt.Errorf("unable to add item to full notification buffer: %w", err)
+			}
+
+			if !warned {
+				logger.Warn("notification buffer full, waiting for room", "size", b.size)
+
+				warned = true
+			}
+
+			b.cond.Wait()
+		}
+	}
+
+	b.queue = append(b.queue, n)
+
+	if !b.running {
+		b.running = true
+
+		go b.work()
+	}
+
+	return nil
+}
+
+// work hands each buffered item to the action, until the buffer is empty.
+func (b *notificationBuffer) work() {
+	for {
+		b.lock.Lock()
+
+		if len(b.queue) == 0 {
+			b.running = false
+			b.lock.Unlock()
+
+			return
+		}
+
+		n := b.queue[0]
+		b.queue = b.queue[1:]
+		b.cond.Broadcast()
+		b.lock.Unlock()
+
+		if err := n.perform(n.ctx); err != nil {
+			n.logger.Error("unable to handle buffered item", LogKeyError, err)
+		}
+	}
+}
+
+// NewBufferedAction wraps the given action with a buffer holding up to size items, so slow or unavailable
+// notification sinks don't hold up polls. The returned action's Enqueue adds items to the buffer and returns
+// immediately, unless the buffer is full and the policy is NotificationDropPolicyBlock. The Actioninator only records
+// items as seen once they've left the buffer and the action was performed, so items which fail to be sent aren't
+// recorded. Errors from the wrapped action are logged, as they happen after the item was handled. Handle is left as
+// is, so it performs the action without the buffer.
+func NewBufferedAction(action GitHubItemAction, size int, policy NotificationDropPolicy) (GitHubItemAction, error) {
+	if size <= 0 {
+		return GitHubItemAction{}, fmt.Errorf("buffer size must be greater than zero, got %d", size)
+	}
+
+	lock := &sync.Mutex{}
+	b := &notificationBuffer{
+		action: action,
+		size:   size,
+		policy: policy,
+		lock:   lock,
+		cond:   sync.NewCond(lock),
+		queue:  []bufferedNotification{},
+	}
+
+	buffered := action
+	buffered.Enqueue = b.add
+
+	return buffered, nil
+}
@@ -0,0 +1,224 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/exp/slog"
+	"gotest.tools/v3/assert"
+	gotestpoll "gotest.tools/v3/poll"
+)
+
+// newBlockingTestAction returns an action which sends the number of each item it handles to started, then waits
+// for release before returning.
+func newBlockingTestAction(name string) (GitHubItemAction, chan int, chan struct{}) {
+	started := make(chan int, 10)
+	release := make(chan struct{})
+
+	return GitHubItemAction{
+		Name: name,
+		Handle: func(_ context.Context, i GitHubItem, _ *slog.Logger) error {
+			started <- i.Number
+			<-release
+
+			return nil
+		},
+	}, started, release
+}
+
+// handleBufferedTestItems enqueues an item with each of the given numbers, failing the test if any of them error.
+func handleBufferedTestItems(t *testing.T, action GitHubItemAction, numbers ...int) {
+	t.Helper()
+
+	for _, n := range numbers {
+		item := *NewTestGitHubItem()
+		item.Number = n
+		logger := NewLogger()
+		perform := func(ctx context.Context) error {
+			return action.Handle(ctx, item, logger)
+		}
+
+		assert.NilError(t, action.Enqueue(context.Background(), item, logger, perform))
+	}
+}
+
+// receiveBufferedTestItems returns the numbers of the next count items handled by the action.
+func receiveBufferedTestItems(t *testing.T, started chan int, release chan struct{}, count int) []int {
+	t.Helper()
+
+	numbers := []int{}
+
+	for i := 0; i < count; i++ {
+		select {
+		case n := <-started:
+			numbers = append(numbers, n)
+			release <- struct{}{}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for buffered item %d", i)
+		}
+	}
+
+	return numbers
+}
+
+func TestNewBufferedActionChecksSize(t *testing.T) {
+	action, _, _ := newBlockingTestAction("buffer-size")
+
+	_, err := NewBufferedAction(action, 0, NotificationDropPolicyBlock)
+	assert.ErrorContains(t, err, "buffer size must be greater than zero, got 0")
+}
+
+func TestBufferedActionDropsNewest(t *testing.T) {
+	action, started, release := newBlockingTestAction("buffer-drop-newest")
+	dropped := MetricNotificationsDropped.WithLabelValues(action.Name, string(NotificationDropPolicyDropNewest))
+	droppedBefore := CounterValue(dropped)
+
+	buffered, err := NewBufferedAction(action, 2, NotificationDropPolicyDropNewest)
+	assert.NilError(t, err)
+	assert.Equal(t, buffered.Name, action.Name)
+
+	// Item 1 is taken by the worker, items 2 and 3 fill the buffer and item 4 is dropped.
+	handleBufferedTestItems(t, buffered, 1)
+	<-started
+	handleBufferedTestItems(t, buffered, 2, 3, 4)
+	release <- struct{}{}
+
+	assert.DeepEqual(t, receiveBufferedTestItems(t, started, release, 2), []int{2, 3})
+	assert.Equal(t, CounterValue(dropped)-droppedBefore, float64(1))
+}
+
+func TestBufferedActionDropsOldest(t *testing.T) {
+	action, started, release := newBlockingTestAction("buffer-drop-oldest")
+	dropped := MetricNotificationsDropped.WithLabelValues(action.Name, string(NotificationDropPolicyDropOldest))
+	droppedBefore := CounterValue(dropped)
+
+	buffered, err := NewBufferedAction(action, 2, NotificationDropPolicyDropOldest)
+	assert.NilError(t, err)
+
+	// Item 1 is taken by the worker, items 2 and 3 fill the buffer and item 2 is dropped to make room for item 4.
+	handleBufferedTestItems(t, buffered, 1)
+	<-started
+	handleBufferedTestItems(t, buffered, 2, 3, 4)
+	release <- struct{}{}
+
+	assert.DeepEqual(t, receiveBufferedTestItems(t, started, release, 2), []int{3, 4})
+	assert.Equal(t, CounterValue(dropped)-droppedBefore, float64(1))
+}
+
+func TestBufferedActionBlocksUntilThereIsRoom(t *testing.T) {
+	action, started, release := newBlockingTestAction("buffer-block")
+
+	buffered, err := NewBufferedAction(action, 1, NotificationDropPolicyBlock)
+	assert.NilError(t, err)
+
+	// Item 1 is taken by the worker and item 2 fills the buffer, so item 3 waits.
+	handleBufferedTestItems(t, buffered, 1)
+	<-started
+	handleBufferedTestItems(t, buffered, 2)
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		handleBufferedTestItems(t, buffered, 3)
+	}()
+
+	release <- struct{}{}
+
+	assert.DeepEqual(t, receiveBufferedTestItems(t, started, release, 2), []int{2, 3})
+	wg.Wait()
+}
+
+func TestBufferedActionStopsBlockingOnceCanceled(t *testing.T) {
+	action, started, release := newBlockingTestAction("buffer-block-canceled")
+
+	buffered, err := NewBufferedAction(action, 1, NotificationDropPolicyBlock)
+	assert.NilError(t, err)
+
+	// Item 1 is taken by the worker and item 2 fills the buffer, so item 3 waits until it times out.
+	handleBufferedTestItems(t, buffered, 1)
+	<-started
+	handleBufferedTestItems(t, buffered, 2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	item := *NewTestGitHubItem()
+	err = buffered.Enqueue(ctx, item, NewLogger(), func(_ context.Context) error { return nil })
+	assert.ErrorContains(t, err, "unable to add item to full notification buffer: context deadline exceeded")
+
+	release <- struct{}{}
+	assert.DeepEqual(t, receiveBufferedTestItems(t, started, release, 1), []int{2})
+}
+
+func TestActioninatorHandlesBufferedItemsAfterReturning(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemorySeenStore()
+	release := make(chan error)
+	sent := make(chan error)
+
+	action := GitHubItemAction{
+		Name: "buffer-actioninator",
+		Handle: func(ctx context.Context, _ GitHubItem, _ *slog.Logger) error {
+			// The item is only sent once Handle returned, which cancels the context the item was handled with.
+			err := <-release
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				err = ctxErr
+			}
+
+			sent <- err
+
+			return err
+		},
+	}
+
+	buffered, err := NewBufferedAction(action, 10, NotificationDropPolicyBlock)
+	assert.NilError(t, err)
+
+	a := NewActioninator().
+		WithAction(buffered).
+		WithDedup(store, "my-watch").
+		WithPolicy(ActionPolicy{Timeout: time.Minute})
+	item := *NewTestGitHubItem()
+
+	// Items which fail to be sent aren't recorded as seen.
+	assert.NilError(t, a.Handle(ctx, item, NewLogger()))
+	release <- errors.New("my test error")
+	assert.ErrorContains(t, <-sent, "my test error")
+
+	_, seen := store.LastSeen("my-watch", item.ID, action.Name)
+	assert.Assert(t, !seen)
+
+	assert.NilError(t, a.Handle(ctx, item, NewLogger()))
+	release <- nil
+	assert.NilError(t, <-sent)
+
+	// The item is recorded once the action returns, so wait for the worker to catch up.
+	gotestpoll.WaitOn(t, func(_ gotestpoll.LogT) gotestpoll.Result {
+		if _, seen := store.LastSeen("my-watch", item.ID, action.Name); !seen {
+			return gotestpoll.Continue("item not recorded as seen")
+		}
+
+		return gotestpoll.Success()
+	}, gotestpoll.WithTimeout(5*time.Second))
+}
+
+func TestWatchValidateChecksNotificationBuffer(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	w := NewTestWatch()
+
+	w.Actions.Email.Buffer = NotificationBufferConfig{Size: 10}
+	assert.NilError(t, w.ValidateAndPopulate(ctx, gh))
+
+	w.Actions.Email.Buffer = NotificationBufferConfig{Size: -1}
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "size cannot be negative, got -1")
+
+	w.Actions.Email.Buffer = NotificationBufferConfig{Size: 10, DropPolicy: "drop-all"}
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "unknown dropPolicy 'drop-all'")
+}
@@ -0,0 +1,142 @@
+package pkg
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock provides the current time and tickers. It allows time-based logic to be tested without waiting on the
+// system clock.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// NewTicker returns a new Ticker which ticks on the given interval.
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker delivers ticks on an interval, see time.Ticker.
+type Ticker interface {
+	// C returns the channel on which ticks are delivered.
+	C() <-chan time.Time
+
+	// Stop turns off the ticker. No more ticks will be sent after Stop returns.
+	Stop()
+}
+
+// systemClock is an implementation of the Clock interface which uses the system clock.
+type systemClock struct{}
+
+func (s systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (s systemClock) NewTicker(d time.Duration) Ticker {
+	return systemTicker{time.NewTicker(d)}
+}
+
+// systemTicker wraps a time.Ticker to implement the Ticker interface.
+type systemTicker struct {
+	*time.Ticker
+}
+
+func (s systemTicker) C() <-chan time.Time {
+	return s.Ticker.C
+}
+
+// NewSystemClock creates a new Clock which uses the system clock.
+func NewSystemClock() Clock {
+	return systemClock{}
+}
+
+// FakeClock is an implementation of the Clock interface whose time only changes when Advance is called.
+type FakeClock struct {
+	lock    *sync.Mutex
+	now     time.Time
+	tickers []*fakeTicker
+}
+
+// fakeTicker is a Ticker created by a FakeClock.
+type fakeTicker struct {
+	c        chan time.Time
+	stopped  chan struct{}
+	stopOnce *sync.Once
+	interval time.Duration
+	next     time.Time
+}
+
+func (f *fakeTicker) C() <-chan time.Time {
+	return f.c
+}
+
+func (f *fakeTicker) Stop() {
+	f.stopOnce.Do(func() { close(f.stopped) })
+}
+
+func (f *FakeClock) Now() time.Time {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.now
+}
+
+func (f *FakeClock) NewTicker(d time.Duration) Ticker {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	t := &fakeTicker{
+		c:        make(chan time.Time),
+		stopped:  make(chan struct{}),
+		stopOnce: &sync.Once{},
+		interval: d,
+		next:     f.now.Add(d),
+	}
+
+	f.tickers = append(f.tickers, t)
+
+	return t
+}
+
+// Advance moves the clock forward by the given duration. Each ticker fires once for every interval that elapses.
+// Ticks are sent in order and Advance blocks until each tick is received or the ticker is stopped.
+func (f *FakeClock) Advance(d time.Duration) {
+	type tick struct {
+		ticker *fakeTicker
+		at     time.Time
+	}
+
+	f.lock.Lock()
+
+	f.now = f.now.Add(d)
+	ticks := []tick{}
+
+	for _, t := range f.tickers {
+		for !t.next.After(f.now) {
+			ticks = append(ticks, tick{ticker: t, at: t.next})
+			t.next = t.next.Add(t.interval)
+		}
+	}
+
+	f.lock.Unlock()
+
+	sort.SliceStable(ticks, func(i, j int) bool {
+		return ticks[i].at.Before(ticks[j].at)
+	})
+
+	for _, t := range ticks {
+		select {
+		case t.ticker.c <- t.at:
+		case <-t.ticker.stopped:
+		}
+	}
+}
+
+// NewFakeClock creates a new FakeClock whose current time is the given time.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{
+		lock:    &sync.Mutex{},
+		now:     now,
+		tickers: []*fakeTicker{},
+	}
+}
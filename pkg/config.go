@@ -5,10 +5,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"os/user"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"sort"
 	"strings"
 	"time"
 
@@ -72,26 +75,103 @@ func ReadFirstLineFromFile(path string) (string, error) {
 	return strings.TrimSpace(line), nil
 }
 
+const (
+	// DefaultRetryAttempts is the number of attempts used when RetryConfig.Attempts is unset.
+	DefaultRetryAttempts = 3
+	// DefaultRetryBackoff is the initial backoff used when RetryConfig.Backoff is unset.
+	DefaultRetryBackoff = time.Second
+)
+
+// RetryConfig configures how a network check performed during validation is retried, so a transient failure
+// doesn't fail the whole validation.
+type RetryConfig struct {
+	// Attempts is the maximum number of times the check is attempted.
+	Attempts int `yaml:"attempts"`
+	// Backoff is the amount of time waited after the first failed attempt. It doubles after each failure.
+	Backoff time.Duration `yaml:"backoff"`
+}
+
+func (r *RetryConfig) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("attempts", r.Attempts),
+		slog.Duration("backoff", r.Backoff),
+	)
+}
+
+// Do calls fn until it succeeds or the configured number of attempts is exhausted, backing off exponentially
+// in-between attempts. The error from the final attempt is returned.
+func (r *RetryConfig) Do(ctx context.Context, fn func() error) error {
+	attempts := r.Attempts
+	if attempts <= 0 {
+		attempts = DefaultRetryAttempts
+	}
+
+	backoff := r.Backoff
+	if backoff <= 0 {
+		backoff = DefaultRetryBackoff
+	}
+
+	var err error
+
+	for i := 0; i < attempts; i++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+
+		if i == attempts-1 {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+	}
+
+	return err
+}
+
 type EmailConfig struct {
 	// Username to login to SMTP service with. Should be the same as the
 	// sender email address.
 	Username string `yaml:"username"`
 	// PasswordFile containing the password used to login to the SMTP service.
 	PasswordFile string `yaml:"passwordFile"`
-	// Password contained within the PasswordFile
+	// PasswordEnv is the name of an environment variable containing the password, which is used instead of
+	// PasswordFile. If the variable is empty, PasswordFile is read instead.
+	PasswordEnv string `yaml:"passwordEnv"`
+	// Password contained within the PasswordEnv or PasswordFile
 	Password string `yaml:"-"`
 	// Host address of the SMTP service (ie smtp.gmail.com).
 	Host string `yaml:"host"`
 	// Port of the SMTP service to connect to.
 	Port int `yaml:"port"`
+	// TLSPolicy is how the connection to the SMTP service is encrypted, one of EmailTLSPolicies. If empty, it is
+	// inferred from the port: 'tls' for 587 and 'ssl' for 465. Other ports must set it.
+	TLSPolicy string `yaml:"tlsPolicy"`
+	// AuthMechanism used to login to the SMTP service, one of EmailAuthMechanisms. For XOAUTH2, the password is an
+	// OAuth2 access token, which is read again for each connection. For NONE, no password is needed. If empty, PLAIN
+	// is used.
+	AuthMechanism string `yaml:"authMechanism"`
+	// retry is used to retry the test connection to the SMTP service. It is set from Config.ValidationRetry.
+	retry RetryConfig `yaml:"-"`
+	// skipConnection skips the test connection to the SMTP service. It is set from
+	// ValidateOptions.SkipEmailConnection.
+	skipConnection bool `yaml:"-"`
 }
 
 func (e *EmailConfig) LogValue() slog.Value {
 	return slog.GroupValue(
 		slog.String("username", e.Username),
 		slog.String("passwordFile", e.PasswordFile),
+		slog.String("passwordEnv", e.PasswordEnv),
 		slog.String("host", e.Host),
 		slog.Int("port", e.Port),
+		slog.String("tlsPolicy", e.getTLSPolicy()),
+		slog.String("authMechanism", string(e.getAuthType())),
 	)
 }
 
@@ -100,29 +180,43 @@ func (e *EmailConfig) Validate(ctx context.Context, emailinator Emailinator) err
 		return errors.New("username cannot be empty")
 	}
 
-	if len(e.PasswordFile) == 0 {
-		return errors.New("password file cannot be empty")
+	if !slices.Contains(EmailAuthMechanisms, e.getAuthType()) {
+		return fmt.Errorf(
+			"authMechanism must be one of PLAIN, LOGIN, CRAM-MD5, XOAUTH2 or NONE, got '%s'", e.AuthMechanism,
+		)
 	}
 
-	password, err := ReadFirstLineFromFile(e.PasswordFile)
-	if err != nil {
-		return fmt.Errorf("unable to read password from file %s: %w", e.PasswordFile, err)
-	}
+	if e.getAuthType() != EmailAuthMechanismNone {
+		password, err := readSecret("password", e.PasswordEnv, e.PasswordFile)
+		if err != nil {
+			return err
+		}
 
-	e.Password = password
+		e.Password = password
+	}
 
 	if len(e.Host) == 0 {
 		return errors.New("host cannot be empty")
 	}
 
-	if !(e.Port == 587 || e.Port == 465) {
-		return errors.New("port must be 587 (TLS) or 465 (SSL)")
+	if e.Port < 1 || e.Port > 65535 {
+		return fmt.Errorf("port must be between 1 and 65535, got %d", e.Port)
+	}
+
+	if policy := e.getTLSPolicy(); policy == "" {
+		return fmt.Errorf("tlsPolicy must be set for port %d, only ports 587 and 465 have a default", e.Port)
+	} else if !slices.Contains(EmailTLSPolicies, policy) {
+		return fmt.Errorf("tlsPolicy must be one of none, opportunistic, starttls, tls or ssl, got '%s'", e.TLSPolicy)
 	}
 
 	testEmailinator := emailinator.WithConfig(e)
 
-	if err := testEmailinator.TestConnection(ctx); err != nil {
-		return fmt.Errorf("unable to validate email config with dial: %w", err)
+	if !e.skipConnection {
+		if err := e.retry.Do(ctx, func() error {
+			return testEmailinator.TestConnection(ctx)
+		}); err != nil {
+			return fmt.Errorf("unable to validate email config with dial: %w", err)
+		}
 	}
 
 	if _, err := testEmailinator.NewMsg(); err != nil {
@@ -132,19 +226,84 @@ func (e *EmailConfig) Validate(ctx context.Context, emailinator Emailinator) err
 	return nil
 }
 
+// readSecret returns the value of the environment variable with the given name, falling back to the first line of the
+// given file if the variable is unset or empty. An error is returned if neither is available. The kind of secret, such
+// as 'password', is used in errors.
+func readSecret(kind string, env string, file string) (string, error) {
+	if len(env) != 0 {
+		if value := strings.TrimSpace(os.Getenv(env)); len(value) != 0 {
+			return value, nil
+		}
+
+		if len(file) == 0 {
+			return "", fmt.Errorf(
+				"environment variable %s holding the %s is empty and no %s file is set", env, kind, kind,
+			)
+		}
+	}
+
+	if len(file) == 0 {
+		return "", fmt.Errorf("%s file cannot be empty", kind)
+	}
+
+	secret, err := ReadFirstLineFromFile(file)
+	if err != nil {
+		return "", fmt.Errorf("unable to read %s from file %s: %w", kind, file, err)
+	}
+
+	return secret, nil
+}
+
+// ActionOptions holds options which are shared by every action.
+type ActionOptions struct {
+	// DependsOn is a list of names of actions which must complete successfully before this action is performed.
+	// Actions without dependencies between them are performed concurrently.
+	DependsOn []string `yaml:"dependsOn"`
+	// Repos limits the action to items from the given repositories, which must be part of the watch's repos. If
+	// empty, the action is performed on items from every repository in the watch.
+	Repos []GitHubRepository `yaml:"repos"`
+}
+
 type EmailActionConfig struct {
 	Enabled bool   `yaml:"enabled"`
 	SendTo  string `yaml:"sendTo"`
+	// RenderBodyHTML adds an HTML alternative to each email, with the item's Markdown body rendered as sanitized
+	// HTML. By default, emails are plain text only.
+	RenderBodyHTML bool `yaml:"renderBodyHTML"`
+	// MaxBodySize is the maximum size of each email's body in bytes. Larger items are attached as 'item.json', with a
+	// short summary as the body. Zero disables the limit, so items are always inline.
+	MaxBodySize int `yaml:"maxBodySize"`
+	// SubjectTemplate is a text/template rendering the subject of each email, executed against the item. By default,
+	// the subject holds the item's repository, number, title and URL.
+	SubjectTemplate string `yaml:"subjectTemplate"`
+	// BodyTemplate is a text/template rendering the plain text body of each email, executed against the item. By
+	// default, the body is the item's URL followed by the item as JSON.
+	BodyTemplate string `yaml:"bodyTemplate"`
+	// Digest batches the items handled during each poll into a single email, rather than sending an email per item.
+	// It can't be combined with templates or a buffer.
+	Digest bool `yaml:"digest"`
+	// Buffer configures a bounded buffer in front of the email sender, so a slow or unavailable SMTP service
+	// doesn't hold up polls.
+	Buffer        NotificationBufferConfig `yaml:"buffer"`
+	ActionOptions `yaml:",inline"`
 }
 
 func (e *EmailActionConfig) LogValue() slog.Value {
 	return slog.GroupValue(
 		slog.Bool("enabled", e.Enabled),
 		slog.String("sendTo", e.SendTo),
+		slog.Bool("renderBodyHTML", e.RenderBodyHTML),
+		slog.Int("maxBodySize", e.MaxBodySize),
+		slog.String("subjectTemplate", e.SubjectTemplate),
+		slog.String("bodyTemplate", e.BodyTemplate),
+		slog.Bool("digest", e.Digest),
+		slog.Any("buffer", e.Buffer.LogValue()),
+		slog.Any("dependsOn", e.DependsOn),
+		slog.Any("repos", e.Repos),
 	)
 }
 
-func (e *EmailActionConfig) Validate(_ context.Context) error {
+func (e *EmailActionConfig) Validate(ctx context.Context) error {
 	if !e.Enabled {
 		return nil
 	}
@@ -153,16 +312,108 @@ func (e *EmailActionConfig) Validate(_ context.Context) error {
 		return fmt.Errorf("sendTo cannot be empty if email action is enabled")
 	}
 
+	if e.MaxBodySize < 0 {
+		return fmt.Errorf("maxBodySize cannot be negative, got %d", e.MaxBodySize)
+	}
+
+	if _, err := e.templates(); err != nil {
+		return err
+	}
+
+	if err := e.Buffer.Validate(ctx); err != nil {
+		return fmt.Errorf("invalid buffer: %w", err)
+	}
+
+	if e.Digest && (e.SubjectTemplate != "" || e.BodyTemplate != "") {
+		return errors.New("subjectTemplate and bodyTemplate cannot be used with digest")
+	}
+
+	if e.Digest && e.Buffer.Size > 0 {
+		return errors.New("buffer cannot be used with digest")
+	}
+
 	return nil
 }
 
+// templates parses the SubjectTemplate and BodyTemplate. Unset templates are left nil.
+func (e *EmailActionConfig) templates() (EmailTemplates, error) {
+	templates := EmailTemplates{}
+
+	if e.SubjectTemplate != "" {
+		subject, err := ParseItemTemplate("subject", e.SubjectTemplate)
+		if err != nil {
+			return templates, fmt.Errorf("invalid subjectTemplate: %w", err)
+		}
+
+		templates.Subject = subject
+	}
+
+	if e.BodyTemplate != "" {
+		body, err := ParseItemTemplate("body", e.BodyTemplate)
+		if err != nil {
+			return templates, fmt.Errorf("invalid bodyTemplate: %w", err)
+		}
+
+		templates.Body = body
+	}
+
+	return templates, nil
+}
+
+// NotificationBufferConfig configures a bounded buffer in front of a notification action. Items are added to the
+// buffer and sent in the background, in order.
+type NotificationBufferConfig struct {
+	// Size is the maximum number of items held in the buffer. Zero disables the buffer, so items are sent as they are
+	// handled.
+	Size int `yaml:"size"`
+	// DropPolicy is what happens to a new item when the buffer is full: "block" waits for room, "drop-oldest" drops
+	// the oldest buffered item and "drop-newest" drops the new item. Defaults to "block".
+	DropPolicy NotificationDropPolicy `yaml:"dropPolicy"`
+}
+
+func (n *NotificationBufferConfig) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("size", n.Size),
+		slog.String("dropPolicy", string(n.getDropPolicy())),
+	)
+}
+
+// getDropPolicy returns the DropPolicy, or NotificationDropPolicyBlock if none is set.
+func (n *NotificationBufferConfig) getDropPolicy() NotificationDropPolicy {
+	if n.DropPolicy == "" {
+		return NotificationDropPolicyBlock
+	}
+
+	return n.DropPolicy
+}
+
+// Validate ensures the size isn't negative and the drop policy is known.
+func (n *NotificationBufferConfig) Validate(_ context.Context) error {
+	if n.Size < 0 {
+		return fmt.Errorf("size cannot be negative, got %d", n.Size)
+	}
+
+	switch n.getDropPolicy() {
+	case NotificationDropPolicyBlock, NotificationDropPolicyDropOldest, NotificationDropPolicyDropNewest:
+		return nil
+	default:
+		return fmt.Errorf(
+			"unknown dropPolicy '%s', expected one of '%s', '%s' or '%s'", n.DropPolicy,
+			NotificationDropPolicyBlock, NotificationDropPolicyDropOldest, NotificationDropPolicyDropNewest,
+		)
+	}
+}
+
 type SubscribeActionConfig struct {
-	Enabled bool `yaml:"enabled"`
+	Enabled       bool `yaml:"enabled"`
+	ActionOptions `yaml:",inline"`
 }
 
 func (s *SubscribeActionConfig) LogValue() slog.Value {
 	return slog.GroupValue(
 		slog.Bool("enabled", s.Enabled),
+		slog.Any("dependsOn", s.DependsOn),
+		slog.Any("repos", s.Repos),
 	)
 }
 
@@ -170,343 +421,2554 @@ func (s *SubscribeActionConfig) Validate(_ context.Context) error {
 	return nil
 }
 
-type ActionConfig struct {
-	Subscribe SubscribeActionConfig `yaml:"subscribe"`
-	Email     EmailActionConfig     `yaml:"email"`
+// UnsubscribeActionConfig configures the unsubscribe action, which unsubscribes the user from matched items they are
+// subscribed to.
+type UnsubscribeActionConfig struct {
+	Enabled       bool `yaml:"enabled"`
+	ActionOptions `yaml:",inline"`
 }
 
-func (a *ActionConfig) LogValue() slog.Value {
+func (u *UnsubscribeActionConfig) LogValue() slog.Value {
 	return slog.GroupValue(
-		slog.Any("subscribe", a.Subscribe.LogValue()),
-		slog.Any("email", a.Subscribe.LogValue()),
+		slog.Bool("enabled", u.Enabled),
+		slog.Any("dependsOn", u.DependsOn),
+		slog.Any("repos", u.Repos),
 	)
 }
 
-func (a *ActionConfig) Validate(ctx context.Context) error {
-	if err := a.Subscribe.Validate(ctx); err != nil {
-		return err
+func (u *UnsubscribeActionConfig) Validate(_ context.Context) error {
+	return nil
+}
+
+// IgnoreActionConfig configures the ignore action, which ignores matched items so the user isn't notified of them.
+type IgnoreActionConfig struct {
+	Enabled       bool `yaml:"enabled"`
+	ActionOptions `yaml:",inline"`
+}
+
+func (i *IgnoreActionConfig) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Bool("enabled", i.Enabled),
+		slog.Any("dependsOn", i.DependsOn),
+		slog.Any("repos", i.Repos),
+	)
+}
+
+func (i *IgnoreActionConfig) Validate(_ context.Context) error {
+	return nil
+}
+
+// WebhookActionConfig configures the webhook action, which POSTs matched items the user isn't subscribed to as JSON.
+type WebhookActionConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// URL is the http or https URL items are posted to.
+	URL string `yaml:"url"`
+	// Headers are set on each request, such as to authenticate to the endpoint.
+	Headers map[string]string `yaml:"headers"`
+	// SecretFile is the file holding the secret used to sign each request's body. If neither it nor SecretEnv is
+	// set, requests aren't signed.
+	SecretFile string `yaml:"secretFile"`
+	// SecretEnv is the name of an environment variable holding the secret. It is used instead of SecretFile, which
+	// is only read if the variable is empty.
+	SecretEnv string `yaml:"secretEnv"`
+	// Secret is the secret loaded from SecretEnv or SecretFile.
+	Secret string `yaml:"-"`
+	// HTTPStatusPolicy decides which response statuses are retried, up to WebhookRetries times, and which are a
+	// success.
+	HTTPStatusPolicy `yaml:",inline"`
+	ActionOptions    `yaml:",inline"`
+}
+
+func (w *WebhookActionConfig) LogValue() slog.Value {
+	// The URL and header values are left out, as they often hold tokens.
+	headers := make([]string, 0, len(w.Headers))
+	for k := range w.Headers {
+		headers = append(headers, k)
 	}
 
-	if err := a.Email.Validate(ctx); err != nil {
+	sort.Strings(headers)
+
+	return slog.GroupValue(
+		slog.Bool("enabled", w.Enabled),
+		slog.Any("headers", headers),
+		slog.String("secretFile", w.SecretFile),
+		slog.String("secretEnv", w.SecretEnv),
+		slog.Any("retryStatuses", w.getRetryStatuses()),
+		slog.Any("successStatuses", w.getSuccessStatuses()),
+		slog.Any("dependsOn", w.DependsOn),
+		slog.Any("repos", w.Repos),
+	)
+}
+
+// Validate ensures the URL is an absolute http or https URL and loads the secret, if one is set.
+func (w *WebhookActionConfig) Validate(ctx context.Context) error {
+	if !w.Enabled {
+		return nil
+	}
+
+	if w.URL == "" {
+		return errors.New("url cannot be empty if webhook action is enabled")
+	}
+
+	// The URL isn't included in errors, as webhook URLs often embed a token.
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("invalid webhook url, expected an absolute http or https URL")
+	}
+
+	if err := w.HTTPStatusPolicy.Validate(ctx); err != nil {
+		return fmt.Errorf("invalid webhook statuses: %w", err)
+	}
+
+	if w.SecretEnv == "" && w.SecretFile == "" {
+		return nil
+	}
+
+	secret, err := readSecret("webhook secret", w.SecretEnv, w.SecretFile)
+	if err != nil {
 		return err
 	}
 
+	w.Secret = secret
+
 	return nil
 }
 
-// Watch specifies which items in GitHub a user will be subscribed to.
-// This struct uses private fields of some exported fields to perform further parsing and setup.
-// After unmarshalling, you must calll ValidateAndPopulate.
-type Watch struct {
-	// Name is a human-readable description of the watch.
-	Name string `yaml:"name"`
-	// Repositories to watch issues from.
-	Repositories []GitHubRepository `yaml:"repos"`
-	// Selectors are used to specify which items to watch, follows the k8s label selector syntax.
-	// See the GitHubItem struct for valid keys and fields and
-	// https://pkg.go.dev/k8s.io/apimachinery@v0.27.1/pkg/labels#Parse for the syntax.
-	Selectors []string          `yaml:"selectors"`
-	selectors []labels.Selector `yaml:"-"`
-	// RequiredLabels are a list of labels that must be present for an item to be watched. An item must have all of
-	// these labels to be watched.
-	RequiredLabels []string `yaml:"requiredLabels"`
-	// SearchLabels are a set of labels that will be used to find new items. They will not be used as criteria for if
-	// an item is watched, but if an item is discovered from GitHub.
-	SearchLabels []string `yaml:"searchLabels"`
-	// BodyRegex is a list of regex expressions which must match the item's body.
-	BodyRegex []string         `yaml:"bodyRegex"`
-	bodyRegex []*regexp.Regexp `yaml:"-"`
-	// TitleRegex is a list of regex expressions which must match the item's title.
-	TitleRegex []string         `yaml:"titleRegex"`
-	titleRegex []*regexp.Regexp `yaml:"-"`
-	// States are a list of issues states to filter by.
-	States []string `yaml:"states"`
-	// Actions are a list of actions to perform when an item matches the set of filters.
-	Actions ActionConfig `yaml:"actions"`
+// SlackActionConfig configures the slack action, which posts a message for each matched item to a Slack channel
+// through an incoming webhook.
+type SlackActionConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// WebhookURL is the URL of the Slack incoming webhook, such as 'https://hooks.slack.com/services/...'.
+	WebhookURL string `yaml:"webhookURL"`
+	// Channel overrides the webhook's default channel, if set.
+	Channel string `yaml:"channel"`
+	// SkipSubscribed skips items the user is already subscribed to, like the email action. By default, every matched
+	// item is posted.
+	SkipSubscribed bool `yaml:"skipSubscribed"`
+	ActionOptions  `yaml:",inline"`
 }
 
-func (w *Watch) LogValue() slog.Value {
+func (s *SlackActionConfig) LogValue() slog.Value {
+	// The webhook URL is left out, as it holds a token.
 	return slog.GroupValue(
-		slog.String("name", w.Name),
-		slog.Any("repos", w.Repositories),
-		slog.Any("selectors", w.Selectors),
-		slog.Any("requiredLabels", w.RequiredLabels),
-		slog.Any("searchLabels", w.SearchLabels),
-		slog.Any("bodyRegex", w.BodyRegex),
-		slog.Any("titleRegex", w.TitleRegex),
-		slog.Any("states", w.States),
+		slog.Bool("enabled", s.Enabled),
+		slog.String("channel", s.Channel),
+		slog.Bool("skipSubscribed", s.SkipSubscribed),
+		slog.Any("dependsOn", s.DependsOn),
+		slog.Any("repos", s.Repos),
 	)
 }
 
-// ValidateAndPopulate ensures that the Watch struct has its fields properly set and populates fields as necessary
-// when the struct was unmarshalled from a YAML config. For instance, the field BodyRegex has an associated
-// unexported field bodyRegex of the type []string, which is populated during unmarshalling. After calling
-// ValidateAndPopulate, the field BodyRegex of the type []*regex.Regexp will be populated with the parsed regex strings
-// found in the bodyRegex field.
-func (w *Watch) ValidateAndPopulate(ctx context.Context, gh GitHubinator) error {
-	if len(w.Name) == 0 {
-		return fmt.Errorf("name cannot be empty")
+// SlackWebhookHost is the host of Slack incoming webhook URLs.
+const SlackWebhookHost = "hooks.slack.com"
+
+// Validate ensures the webhook URL looks like a Slack incoming webhook URL.
+func (s *SlackActionConfig) Validate(_ context.Context) error {
+	if !s.Enabled {
+		return nil
 	}
 
-	if len(w.Repositories) == 0 {
-		return fmt.Errorf("expected at least one repository")
+	if s.WebhookURL == "" {
+		return errors.New("webhookURL cannot be empty if slack action is enabled")
+	}
+
+	// The URL isn't included in errors, as it holds a token.
+	u, err := url.Parse(s.WebhookURL)
+	if err != nil || u.Scheme != "https" || u.Host != SlackWebhookHost || !strings.HasPrefix(u.Path, "/services/") {
+		return fmt.Errorf("invalid slack webhookURL, expected a URL like 'https://%s/services/...'", SlackWebhookHost)
+	}
+
+	return nil
+}
+
+// ExecActionConfig configures the exec action, which runs a command for each matched item the user isn't subscribed
+// to.
+type ExecActionConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Command is the command to run and its arguments. It is run directly, not through a shell.
+	Command []string `yaml:"command"`
+	// Timeout is how long the command may run for before it is killed. Defaults to DefaultExecTimeout.
+	Timeout       time.Duration `yaml:"timeout"`
+	ActionOptions `yaml:",inline"`
+}
+
+func (e *ExecActionConfig) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Bool("enabled", e.Enabled),
+		slog.Any("command", e.Command),
+		slog.Duration("timeout", e.Timeout),
+		slog.Any("dependsOn", e.DependsOn),
+		slog.Any("repos", e.Repos),
+	)
+}
+
+// Validate ensures a command is set and the timeout isn't negative.
+func (e *ExecActionConfig) Validate(_ context.Context) error {
+	if !e.Enabled {
+		return nil
+	}
+
+	if len(e.Command) == 0 || e.Command[0] == "" {
+		return errors.New("command cannot be empty if exec action is enabled")
+	}
+
+	if e.Timeout < 0 {
+		return fmt.Errorf("timeout cannot be negative, got %s", e.Timeout)
+	}
+
+	return nil
+}
+
+// LabelActionConfig configures the label action, which adds labels to matched items.
+type LabelActionConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Labels are the names of the labels to add. Each must exist in the repository of every matched item.
+	Labels        []string `yaml:"labels"`
+	ActionOptions `yaml:",inline"`
+}
+
+func (l *LabelActionConfig) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Bool("enabled", l.Enabled),
+		slog.Any("labels", l.Labels),
+		slog.Any("dependsOn", l.DependsOn),
+		slog.Any("repos", l.Repos),
+	)
+}
+
+// Validate ensures at least one label is set, and that none are empty.
+func (l *LabelActionConfig) Validate(_ context.Context) error {
+	if !l.Enabled {
+		return nil
+	}
+
+	if len(l.Labels) == 0 {
+		return errors.New("labels cannot be empty if label action is enabled")
+	}
+
+	if slices.Contains(l.Labels, "") {
+		return errors.New("labels cannot contain an empty label")
+	}
+
+	return nil
+}
+
+// CommentActionConfig configures the comment action, which comments on matched items.
+type CommentActionConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Template is the text/template rendering the body of the comment, executed against each matched item.
+	Template      string `yaml:"template"`
+	ActionOptions `yaml:",inline"`
+}
+
+func (c *CommentActionConfig) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Bool("enabled", c.Enabled),
+		slog.String("template", c.Template),
+		slog.Any("dependsOn", c.DependsOn),
+		slog.Any("repos", c.Repos),
+	)
+}
+
+// Validate ensures the template is set, and that it compiles and renders against an item.
+func (c *CommentActionConfig) Validate(_ context.Context) error {
+	if !c.Enabled {
+		return nil
+	}
+
+	if c.Template == "" {
+		return errors.New("template cannot be empty if comment action is enabled")
+	}
+
+	if _, err := ParseItemTemplate("comment", c.Template); err != nil {
+		return fmt.Errorf("invalid comment template: %w", err)
+	}
+
+	return nil
+}
+
+// DefaultActionRetryBackoff is the initial backoff used when ActionPolicy.Backoff is unset.
+const DefaultActionRetryBackoff = time.Second
+
+// ActionPolicy configures how each action of a Watch is retried and timed out, so a transient failure, such as an
+// SMTP or network blip, doesn't fail the action for a whole poll, and a hanging action doesn't hold up the others.
+type ActionPolicy struct {
+	// MaxRetries is the number of times a failed action is retried. Zero disables retries.
+	MaxRetries int `yaml:"maxRetries"`
+	// Timeout is the maximum amount of time each attempt of an action may take. Zero disables the timeout.
+	Timeout time.Duration `yaml:"timeout"`
+	// Backoff is the amount of time waited after the first failed attempt. It doubles after each failure. If zero,
+	// DefaultActionRetryBackoff is used.
+	Backoff time.Duration `yaml:"backoff"`
+}
+
+func (p *ActionPolicy) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("maxRetries", p.MaxRetries),
+		slog.Duration("timeout", p.Timeout),
+		slog.Duration("backoff", p.getBackoff()),
+	)
+}
+
+func (p *ActionPolicy) Validate(_ context.Context) error {
+	if p.MaxRetries < 0 {
+		return fmt.Errorf("maxRetries cannot be negative, got %d", p.MaxRetries)
+	}
+
+	if p.Timeout < 0 {
+		return fmt.Errorf("timeout cannot be negative, got %s", p.Timeout)
+	}
+
+	if p.Backoff < 0 {
+		return fmt.Errorf("backoff cannot be negative, got %s", p.Backoff)
+	}
+
+	return nil
+}
+
+// getBackoff returns the Backoff, or DefaultActionRetryBackoff if it is unset.
+func (p *ActionPolicy) getBackoff() time.Duration {
+	if p.Backoff <= 0 {
+		return DefaultActionRetryBackoff
+	}
+
+	return p.Backoff
+}
+
+type ActionConfig struct {
+	Subscribe   SubscribeActionConfig   `yaml:"subscribe"`
+	Unsubscribe UnsubscribeActionConfig `yaml:"unsubscribe"`
+	Ignore      IgnoreActionConfig      `yaml:"ignore"`
+	Email       EmailActionConfig       `yaml:"email"`
+	Webhook     WebhookActionConfig     `yaml:"webhook"`
+	Slack       SlackActionConfig       `yaml:"slack"`
+	Exec        ExecActionConfig        `yaml:"exec"`
+	Label       LabelActionConfig       `yaml:"label"`
+	Comment     CommentActionConfig     `yaml:"comment"`
+	// NotifyCooldown is the amount of time after an action is performed on an item during which the action will
+	// not be performed on the item again, even if the item is updated. Zero disables the cooldown.
+	NotifyCooldown time.Duration `yaml:"notifyCooldown"`
+	// Policy configures how each action is retried and timed out.
+	Policy ActionPolicy `yaml:"policy"`
+}
+
+func (a *ActionConfig) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Any("subscribe", a.Subscribe.LogValue()),
+		slog.Any("unsubscribe", a.Unsubscribe.LogValue()),
+		slog.Any("ignore", a.Ignore.LogValue()),
+		slog.Any("email", a.Subscribe.LogValue()),
+		slog.Any("webhook", a.Webhook.LogValue()),
+		slog.Any("slack", a.Slack.LogValue()),
+		slog.Any("exec", a.Exec.LogValue()),
+		slog.Any("label", a.Label.LogValue()),
+		slog.Any("comment", a.Comment.LogValue()),
+		slog.Duration("notifyCooldown", a.NotifyCooldown),
+		slog.Any("policy", a.Policy.LogValue()),
+	)
+}
+
+func (a *ActionConfig) Validate(ctx context.Context) error {
+	if a.NotifyCooldown < 0 {
+		return fmt.Errorf("notifyCooldown cannot be negative '%s'", a.NotifyCooldown)
+	}
+
+	if err := a.Policy.Validate(ctx); err != nil {
+		return fmt.Errorf("invalid policy: %w", err)
+	}
+
+	if err := a.Subscribe.Validate(ctx); err != nil {
+		return err
+	}
+
+	if err := a.Unsubscribe.Validate(ctx); err != nil {
+		return err
+	}
+
+	if err := a.Ignore.Validate(ctx); err != nil {
+		return err
+	}
+
+	// Each of these actions sets the subscription of items, so they would undo each other.
+	enabled := 0
+
+	for _, e := range []bool{a.Subscribe.Enabled, a.Unsubscribe.Enabled, a.Ignore.Enabled} {
+		if e {
+			enabled++
+		}
+	}
+
+	if enabled > 1 {
+		return fmt.Errorf("only one of the subscribe, unsubscribe and ignore actions can be enabled")
+	}
+
+	if err := a.Email.Validate(ctx); err != nil {
+		return err
+	}
+
+	if err := a.Webhook.Validate(ctx); err != nil {
+		return err
+	}
+
+	if err := a.Slack.Validate(ctx); err != nil {
+		return err
+	}
+
+	if err := a.Exec.Validate(ctx); err != nil {
+		return err
+	}
+
+	if err := a.Label.Validate(ctx); err != nil {
+		return err
+	}
+
+	if err := a.Comment.Validate(ctx); err != nil {
+		return err
+	}
+
+	if err := a.validateDependencies(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// options returns the ActionOptions of each enabled action, keyed by action name.
+func (a *ActionConfig) options() map[string]ActionOptions {
+	opts := map[string]ActionOptions{}
+
+	if a.Subscribe.Enabled {
+		opts["subscribe"] = a.Subscribe.ActionOptions
+	}
+
+	if a.Unsubscribe.Enabled {
+		opts["unsubscribe"] = a.Unsubscribe.ActionOptions
+	}
+
+	if a.Ignore.Enabled {
+		opts["ignore"] = a.Ignore.ActionOptions
+	}
+
+	if a.Email.Enabled {
+		opts["email"] = a.Email.ActionOptions
+	}
+
+	if a.Webhook.Enabled {
+		opts["webhook"] = a.Webhook.ActionOptions
+	}
+
+	if a.Slack.Enabled {
+		opts["slack"] = a.Slack.ActionOptions
+	}
+
+	if a.Exec.Enabled {
+		opts["exec"] = a.Exec.ActionOptions
+	}
+
+	if a.Label.Enabled {
+		opts["label"] = a.Label.ActionOptions
+	}
+
+	if a.Comment.Enabled {
+		opts["comment"] = a.Comment.ActionOptions
+	}
+
+	return opts
+}
+
+// dependencies returns the dependencies of each enabled action, keyed by action name.
+func (a *ActionConfig) dependencies() map[string][]string {
+	deps := map[string][]string{}
+
+	for name, opts := range a.options() {
+		deps[name] = opts.DependsOn
+	}
+
+	return deps
+}
+
+// validateDependencies ensures that each action only depends on other enabled actions and that there are no cycles.
+func (a *ActionConfig) validateDependencies() error {
+	deps := a.dependencies()
+
+	for name, dependsOn := range deps {
+		for _, d := range dependsOn {
+			if _, ok := deps[d]; !ok {
+				return fmt.Errorf("action '%s' depends on unknown or disabled action '%s'", name, d)
+			}
+
+			// A digest is only sent once the poll is done, after the dependent action would have been performed.
+			if d == "email" && a.Email.Digest {
+				return fmt.Errorf("action '%s' cannot depend on action 'email', which sends a digest", name)
+			}
+
+			// Buffered emails are sent in the background, after the dependent action would have been performed.
+			if d == "email" && a.Email.Buffer.Size > 0 {
+				return fmt.Errorf("action '%s' cannot depend on action 'email', which is buffered", name)
+			}
+		}
+	}
+
+	if cycle := findDependencyCycle(deps); cycle != nil {
+		return fmt.Errorf("actions have a dependency cycle: %s", strings.Join(cycle, " -> "))
+	}
+
+	return nil
+}
+
+// SelectorGroup is a named group of selectors and labels, which matches an item if any of its selectors match it or
+// it has any of the labels.
+type SelectorGroup struct {
+	// Name identifies the group in the reason an item didn't match.
+	Name string `yaml:"name"`
+	// Selectors use the same syntax and keys as Watch.Selectors.
+	Selectors []string `yaml:"selectors"`
+	// Labels are matched like Watch.RequiredLabels, but an item only needs one of them.
+	Labels []string `yaml:"labels"`
+}
+
+// NumberRangeConfig configures an inclusive range of item numbers. Either end may be left unset.
+type NumberRangeConfig struct {
+	// Min is the lowest number matched. If zero, there is no lower bound.
+	Min int `yaml:"min"`
+	// Max is the highest number matched. If zero, there is no upper bound.
+	Max int `yaml:"max"`
+}
+
+// Validate ensures the bounds aren't negative, at least one is set and min isn't greater than max.
+func (n *NumberRangeConfig) Validate(_ context.Context) error {
+	if n.Min < 0 || n.Max < 0 {
+		return fmt.Errorf("numberRange bounds cannot be negative, got min %d and max %d", n.Min, n.Max)
+	}
+
+	if n.Min == 0 && n.Max == 0 {
+		return fmt.Errorf("numberRange must set at least one of min and max")
+	}
+
+	if n.Max > 0 && n.Min > n.Max {
+		return fmt.Errorf("numberRange min %d cannot be greater than max %d", n.Min, n.Max)
+	}
+
+	return nil
+}
+
+// MatcherSet is a named set of matchers, defined in Config.Matchers, which watches can share with Watch.Use instead of
+// repeating them. Each field has the same meaning as the Watch field of the same name.
+type MatcherSet struct {
+	Selectors      []string        `yaml:"selectors"`
+	SelectorGroups []SelectorGroup `yaml:"selectorGroups"`
+	RequiredLabels []string        `yaml:"requiredLabels"`
+	ExcludedLabels []string        `yaml:"excludedLabels"`
+	BodyRegex      []string        `yaml:"bodyRegex"`
+	CommentRegex   []string        `yaml:"commentRegex"`
+	TitleRegex     []string        `yaml:"titleRegex"`
+}
+
+// LastActivityByConfig configures matching on the actor of an item's most recent timeline activity.
+type LastActivityByConfig struct {
+	// Logins are the logins of the actors to match.
+	Logins []string `yaml:"logins"`
+}
+
+// Watch specifies which items in GitHub a user will be subscribed to.
+// This struct uses private fields of some exported fields to perform further parsing and setup.
+// After unmarshalling, you must calll ValidateAndPopulate.
+type Watch struct {
+	// Name is a human-readable description of the watch. It must be unique within the config.
+	Name string `yaml:"name"`
+	// Enabled can be set to false to stop polling the watch without removing it from the config. Disabled watches are
+	// still validated, but aren't checked with GitHub. Defaults to true.
+	Enabled *bool `yaml:"enabled"`
+	// Repositories to watch issues from.
+	Repositories []GitHubRepository `yaml:"repos"`
+	// ReposFile is a file listing the repositories to watch, one 'owner/name' per line, instead of listing
+	// Repositories. Blank lines and lines starting with '#' are ignored. The file is re-read when the config is
+	// reloaded.
+	ReposFile string `yaml:"reposFile"`
+	// ReposCommand is a command, given as a list of arguments and run without a shell, which prints the repositories
+	// to watch in the same format as ReposFile. It is re-run when the config is reloaded.
+	ReposCommand []string `yaml:"reposCommand"`
+	// repositoriesResolved is set once Repositories has been populated from ReposFile or ReposCommand.
+	repositoriesResolved bool `yaml:"-"`
+	// Self watches issues from every repository the user owns or collaborates on, instead of listing Repositories.
+	// The list of repositories is expanded once per tick.
+	Self bool `yaml:"self"`
+	// Search finds issues using a GitHub search, instead of listing Repositories. It can be a GitHub search URL
+	// copied from the browser, the URL's 'q=' query string, or a search query. See ParseGitHubSearch.
+	Search string `yaml:"search"`
+	search string `yaml:"-"`
+	// Use is a list of names of the Config.Matchers whose matchers are added to the Watch's own, in order. They are
+	// expanded by Config.ExpandMatchers.
+	Use []string `yaml:"use"`
+	// matchersExpanded is set once the matchers referenced by Use have been added to the Watch.
+	matchersExpanded bool `yaml:"-"`
+	// Selectors are used to specify which items to watch, follows the k8s label selector syntax.
+	// See the GitHubItem struct for valid keys and fields and
+	// https://pkg.go.dev/k8s.io/apimachinery@v0.27.1/pkg/labels#Parse for the syntax.
+	// Keys may use the aliases in Config.SelectorAliases.
+	Selectors []string          `yaml:"selectors"`
+	selectors []labels.Selector `yaml:"-"`
+	// SelectorGroups are groups of selectors and labels, each of which matches if any of its selectors match or the
+	// item has any of its labels. Every group must match, in addition to Selectors, so they allow expressing 'a OR b'.
+	SelectorGroups []SelectorGroup `yaml:"selectorGroups"`
+	// selectorGroups holds the parsed selectors of each of the SelectorGroups, in the same order.
+	selectorGroups [][]labels.Selector `yaml:"-"`
+	// selectorAliases maps selector key aliases to their canonical key. It is set from Config.SelectorAliases.
+	selectorAliases map[string]string `yaml:"-"`
+	// ageBuckets are the buckets of the 'ageBucket' selector key. They are set from Config.AgeBuckets.
+	ageBuckets []AgeBucket `yaml:"-"`
+	// offline skips the checks which need GitHub, such as that the Repositories exist. It is set from
+	// ValidateOptions.Offline, and for disabled watches.
+	offline bool `yaml:"-"`
+	// repoConcurrency is the number of repositories items are listed from at once. It is set from
+	// Config.RepoConcurrency.
+	repoConcurrency int `yaml:"-"`
+	// RequiredLabels are a list of labels that must be present for an item to be watched. An item must have all of
+	// these labels to be watched.
+	RequiredLabels []string `yaml:"requiredLabels"`
+	// ExcludedLabels are a list of labels that must not be present for an item to be watched. An item with any of
+	// these labels is not watched.
+	ExcludedLabels []string `yaml:"excludedLabels"`
+	// Authors are a list of logins, one of which must have opened an item for it to be watched. If a single author is
+	// given, GitHub filters issues by it, so issues by other authors aren't fetched at all. Otherwise, authors are
+	// only matched after listing items. Authors are checked in addition to Selectors, so a selector on
+	// 'author.login' further narrows the items matched by Authors rather than replacing them.
+	Authors []string `yaml:"authors"`
+	// Assignees are a list of logins that an item must be assigned to in order to be watched. An item must be
+	// assigned to all of them. For issues, this requires an extra query to GitHub for each item.
+	Assignees []string `yaml:"assignees"`
+	// SearchLabels are a set of labels that will be used to find new items. They will not be used as criteria for if
+	// an item is watched, but if an item is discovered from GitHub.
+	SearchLabels []string `yaml:"searchLabels"`
+	// BodyRegex is a list of regex expressions which must match the item's body.
+	BodyRegex []string         `yaml:"bodyRegex"`
+	bodyRegex []*regexp.Regexp `yaml:"-"`
+	// CommentRegex is a list of regex expressions which must match the item's comments, joined together by newlines.
+	// This requires an extra query to GitHub for each item, and is only supported for issues.
+	CommentRegex []string         `yaml:"commentRegex"`
+	commentRegex []*regexp.Regexp `yaml:"-"`
+	// AllowFullBodyScan allows BodyRegex, CommentRegex and ReferencesIssue to be used without SearchLabels or States.
+	// Without these server-side filters, the body or comments of every issue in the watched repositories need to be
+	// fetched on every tick, which is very expensive for large repositories.
+	AllowFullBodyScan bool `yaml:"allowFullBodyScan"`
+	// TitleRegex is a list of regex expressions which must match the item's title.
+	TitleRegex []string         `yaml:"titleRegex"`
+	titleRegex []*regexp.Regexp `yaml:"-"`
+	// TitlePrefix, TitleSuffix and TitleContains match items whose title starts with, ends with or contains the given
+	// string, such as '[RFC]' or '(WIP)'. Unlike TitleRegex, they are matched literally, so no escaping is needed.
+	TitlePrefix   string `yaml:"titlePrefix"`
+	TitleSuffix   string `yaml:"titleSuffix"`
+	TitleContains string `yaml:"titleContains"`
+	// TitleCaseSensitive makes TitlePrefix, TitleSuffix and TitleContains case-sensitive.
+	TitleCaseSensitive bool `yaml:"titleCaseSensitive"`
+	// CaseSensitive makes BodyRegex, CommentRegex and TitleRegex case-sensitive. Otherwise, they are matched
+	// case-insensitively, as if they started with '(?i)'.
+	CaseSensitive bool `yaml:"caseSensitive"`
+	// States are a list of issues states to filter by. An item is returned if it is in any of the given states, so
+	// setting both OPEN and CLOSED will return open and closed items in a single scan. MERGED may be used if
+	// ItemTypes only contains pull requests.
+	States []string `yaml:"states"`
+	// UpdatedSince only lists items updated within the given duration of each poll, such as '24h', so items which
+	// haven't changed recently aren't processed again. It cannot be used with Search, whose query can use the
+	// 'updated:' qualifier instead. If zero, items are listed regardless of when they were updated.
+	UpdatedSince time.Duration `yaml:"updatedSince"`
+	// UpdatedWithin and CreatedWithin only match items updated or created within the given duration, such as '72h' or
+	// '7d'. Unlike UpdatedSince, they're matched after items are listed, so they can be used with Search and
+	// 'test-match'.
+	UpdatedWithin Duration `yaml:"updatedWithin"`
+	CreatedWithin Duration `yaml:"createdWithin"`
+	// NotUpdatedWithin only matches stale items, which haven't been updated within the given duration, such as '30d'.
+	NotUpdatedWithin Duration `yaml:"notUpdatedWithin"`
+	// Milestone only lists items in the milestone with the given title, such as 'v1.2.0', or in any milestone if
+	// '*'. It cannot be used with Search, whose query can use the 'milestone:' qualifier instead. Discussions can't be
+	// in a milestone, so they are never listed if it is set.
+	Milestone string `yaml:"milestone"`
+	// ItemTypes are the types of items to watch in the Watch's repositories: 'issue', 'pullRequest' and
+	// 'discussion'. Defaults to only issues. It cannot be used with Search, whose query selects the types of items
+	// instead. GitHub can't filter discussions by label, so SearchLabels are applied to discussions after listing
+	// them, which means every discussion in the repository is listed.
+	ItemTypes []GitHubItemType `yaml:"itemTypes"`
+	// LastActivityBy matches items whose most recent timeline activity, such as a comment or label change, was by
+	// one of the given logins. This requires an extra query to GitHub for each item.
+	LastActivityBy *LastActivityByConfig `yaml:"lastActivityBy"`
+	// ExcludeDrafts skips draft pull requests. Issues are never drafts, so they are unaffected.
+	ExcludeDrafts bool `yaml:"excludeDrafts"`
+	// NumberRange only matches items whose number is within the given range, such as to skip an old backlog.
+	NumberRange *NumberRangeConfig `yaml:"numberRange"`
+	// ReferencesIssue matches items whose body references the issue or pull request with the given number in the
+	// same repository, such as 'part of #100'. This requires fetching the body of each item.
+	ReferencesIssue int `yaml:"referencesIssue"`
+	// ReferencesIssueTimeline also matches items which appear as cross-references in the timeline of the issue given
+	// by ReferencesIssue, catching references made outside the body, such as in comments. This requires an extra
+	// query to GitHub, which is cached briefly.
+	ReferencesIssueTimeline bool `yaml:"referencesIssueTimeline"`
+	// Actions are a list of actions to perform when an item matches the set of filters.
+	Actions ActionConfig `yaml:"actions"`
+	// Report emails a summary of every matching item on a schedule.
+	Report ReportConfig `yaml:"report"`
+	// InitialScan overrides Config.InitialScan for the Watch.
+	InitialScan *bool `yaml:"initialScan"`
+	// Interval overrides Config.Interval for the Watch, so busy repositories can be polled more often than quiet
+	// ones. If zero, Config.Interval is used.
+	Interval time.Duration `yaml:"interval"`
+	// Tags are attached to the Watch's logs, notifications and metrics, allowing watches to be grouped, such as by
+	// team. Each tag is exported as a series of the watchinator_watch_tag metric, so the number of distinct tags
+	// should be kept small.
+	Tags map[string]string `yaml:"tags"`
+}
+
+func (w *Watch) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", w.Name),
+		slog.Bool("enabled", w.IsEnabled()),
+		slog.Any("repos", w.Repositories),
+		slog.String("reposFile", w.ReposFile),
+		slog.Any("reposCommand", w.ReposCommand),
+		slog.Bool("self", w.Self),
+		slog.String("search", w.Search),
+		slog.Any("use", w.Use),
+		slog.Any("selectors", w.Selectors),
+		slog.Any("selectorGroups", w.SelectorGroups),
+		slog.Any("requiredLabels", w.RequiredLabels),
+		slog.Any("excludedLabels", w.ExcludedLabels),
+		slog.Any("authors", w.Authors),
+		slog.Any("assignees", w.Assignees),
+		slog.Any("searchLabels", w.SearchLabels),
+		slog.Any("bodyRegex", w.BodyRegex),
+		slog.Any("commentRegex", w.CommentRegex),
+		slog.Bool("allowFullBodyScan", w.AllowFullBodyScan),
+		slog.Any("titleRegex", w.TitleRegex),
+		slog.String("titlePrefix", w.TitlePrefix),
+		slog.String("titleSuffix", w.TitleSuffix),
+		slog.String("titleContains", w.TitleContains),
+		slog.Bool("titleCaseSensitive", w.TitleCaseSensitive),
+		slog.Bool("caseSensitive", w.CaseSensitive),
+		slog.Any("states", w.States),
+		slog.Duration("updatedSince", w.UpdatedSince),
+		slog.Duration("updatedWithin", time.Duration(w.UpdatedWithin)),
+		slog.Duration("createdWithin", time.Duration(w.CreatedWithin)),
+		slog.Duration("notUpdatedWithin", time.Duration(w.NotUpdatedWithin)),
+		slog.String("milestone", w.Milestone),
+		slog.Any("itemTypes", w.ItemTypes),
+		slog.Any("lastActivityBy", w.LastActivityBy),
+		slog.Bool("excludeDrafts", w.ExcludeDrafts),
+		slog.Any("numberRange", w.NumberRange),
+		slog.Int("referencesIssue", w.ReferencesIssue),
+		slog.Bool("referencesIssueTimeline", w.ReferencesIssueTimeline),
+		slog.Any("report", w.Report.LogValue()),
+		slog.Duration("interval", w.Interval),
+		slog.Any("tags", w.Tags),
+	)
+}
+
+// DefaultRepoConcurrency is the default maximum number of repositories a watch lists items from at once.
+const DefaultRepoConcurrency = 4
+
+// getRepoConcurrency returns the maximum number of repositories the Watch lists items from at once.
+func (w *Watch) getRepoConcurrency() int {
+	if w.repoConcurrency <= 0 {
+		return DefaultRepoConcurrency
+	}
+
+	return w.repoConcurrency
+}
+
+// IsEnabled returns if the Watch is polled, which is the case unless Enabled is set to false.
+func (w *Watch) IsEnabled() bool {
+	return w.Enabled == nil || *w.Enabled
+}
+
+// ValidateAndPopulate ensures that the Watch struct has its fields properly set and populates fields as necessary
+// when the struct was unmarshalled from a YAML config. For instance, the field BodyRegex has an associated
+// unexported field bodyRegex of the type []string, which is populated during unmarshalling. After calling
+// ValidateAndPopulate, the field BodyRegex of the type []*regex.Regexp will be populated with the parsed regex strings
+// found in the bodyRegex field.
+func (w *Watch) ValidateAndPopulate(ctx context.Context, gh GitHubinator) error {
+	if len(w.Name) == 0 {
+		return fmt.Errorf("name cannot be empty")
+	}
+
+	if err := w.resolveRepositories(ctx); err != nil {
+		return err
+	}
+
+	if w.Self && w.hasRepositories() {
+		return fmt.Errorf("self and repos cannot both be set")
+	}
+
+	w.search = ""
+
+	if w.Search != "" {
+		if err := w.populateSearch(ctx, gh); err != nil {
+			return err
+		}
+	} else if !w.Self && !w.hasRepositories() {
+		return fmt.Errorf("expected at least one repository")
+	}
+
+	if !w.hasFilter() {
+		return fmt.Errorf("expected at least one filter type")
+	}
+
+	if w.NumberRange != nil {
+		if err := w.NumberRange.Validate(ctx); err != nil {
+			return err
+		}
+	}
+
+	if w.ReferencesIssue < 0 {
+		return fmt.Errorf("referencesIssue must be an issue number, got %d", w.ReferencesIssue)
+	}
+
+	if w.ReferencesIssueTimeline && w.ReferencesIssue == 0 {
+		return fmt.Errorf("referencesIssueTimeline requires referencesIssue to be set")
+	}
+
+	if w.UpdatedSince < 0 {
+		return fmt.Errorf("updatedSince cannot be negative, got %s", w.UpdatedSince)
+	}
+
+	if w.UpdatedWithin < 0 {
+		return fmt.Errorf("updatedWithin cannot be negative, got %s", w.UpdatedWithin)
+	}
+
+	if w.CreatedWithin < 0 {
+		return fmt.Errorf("createdWithin cannot be negative, got %s", w.CreatedWithin)
+	}
+
+	if w.NotUpdatedWithin < 0 {
+		return fmt.Errorf("notUpdatedWithin cannot be negative, got %s", w.NotUpdatedWithin)
+	}
+
+	if w.UpdatedWithin > 0 && w.NotUpdatedWithin > 0 && w.UpdatedWithin <= w.NotUpdatedWithin {
+		return fmt.Errorf(
+			"updatedWithin must be greater than notUpdatedWithin, got %s and %s, as no item could match",
+			w.UpdatedWithin, w.NotUpdatedWithin,
+		)
+	}
+
+	if w.Interval < 0 {
+		return fmt.Errorf("interval must be greater than zero if set, got %s", w.Interval)
+	}
+
+	if !w.offline {
+		for _, r := range w.Repositories {
+			if err := gh.CheckRepository(ctx, r); err != nil {
+				return fmt.Errorf("unable to validate repository %+v: %w", r, err)
+			}
+		}
+	}
+
+	if err := w.PopulateMatchers(); err != nil {
+		return err
+	}
+
+	if err := w.checkFullBodyScan(ctx, gh); err != nil {
+		return err
+	}
+
+	if w.LastActivityBy != nil && len(w.LastActivityBy.Logins) == 0 {
+		return fmt.Errorf("lastActivityBy requires at least one login")
+	}
+
+	if err := w.checkItemTypes(); err != nil {
+		return err
+	}
+
+	if err := w.Actions.Validate(ctx); err != nil {
+		return err
+	}
+
+	if err := w.Report.Validate(ctx); err != nil {
+		return fmt.Errorf("invalid report: %w", err)
+	}
+
+	if err := w.checkActionRepos(); err != nil {
+		return err
+	}
+
+	if err := w.checkTags(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+const (
+	// MaxWatchTags is the maximum number of tags a Watch may have.
+	MaxWatchTags = 10
+	// maxWatchTagValueLength is the maximum length of a tag value.
+	maxWatchTagValueLength = 63
+)
+
+var (
+	watchTagKeyRegex   = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	watchTagValueRegex = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+)
+
+// checkTags ensures the Watch's tags are safe to use as Prometheus labels and email headers. Keys must be valid
+// Prometheus label names which don't start with the reserved '__' prefix, and values may only contain letters,
+// digits, '_', '.' and '-'.
+func (w *Watch) checkTags() error {
+	if len(w.Tags) > MaxWatchTags {
+		return fmt.Errorf("expected at most %d tags, got %d", MaxWatchTags, len(w.Tags))
+	}
+
+	for k, v := range w.Tags {
+		if !watchTagKeyRegex.MatchString(k) || strings.HasPrefix(k, "__") {
+			return fmt.Errorf(
+				"invalid tag key '%s', expected letters, digits and '_', not starting with a digit or '__'", k,
+			)
+		}
+
+		if !watchTagValueRegex.MatchString(v) || len(v) > maxWatchTagValueLength {
+			return fmt.Errorf(
+				"invalid value '%s' for tag '%s', expected at most %d letters, digits, '_', '.' or '-'",
+				v, k, maxWatchTagValueLength,
+			)
+		}
+	}
+
+	return nil
+}
+
+// FormatTags formats the given tags as a comma separated list of key=value pairs, sorted by key.
+func FormatTags(tags map[string]string) string {
+	pairs := []string{}
+	for k, v := range tags {
+		pairs = append(pairs, k+"="+v)
+	}
+
+	slices.Sort(pairs)
+
+	return strings.Join(pairs, ", ")
+}
+
+// resolveSelectorAliases returns the given selector with any keys found in aliases replaced by their canonical key.
+func resolveSelectorAliases(s labels.Selector, aliases map[string]string) (labels.Selector, error) {
+	// The internal selector that is created through labels.Parse will always
+	// return 'true' for selectable here, so ignore it.
+	requirements, _ := s.Requirements()
+	resolved := labels.NewSelector()
+
+	for _, r := range requirements {
+		key, ok := aliases[r.Key()]
+		if !ok {
+			resolved = resolved.Add(r)
+
+			continue
+		}
+
+		canonical, err := labels.NewRequirement(key, r.Operator(), r.Values().List())
+		if err != nil {
+			return nil, err
+		}
+
+		resolved = resolved.Add(*canonical)
+	}
+
+	return resolved, nil
+}
+
+// parseSelector parses the given label selector, resolving the Watch's selector aliases.
+func (w *Watch) parseSelector(s string) (labels.Selector, error) {
+	parsed, err := labels.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse label selector %+v: %w", s, err)
+	}
+
+	resolved, err := resolveSelectorAliases(parsed, w.selectorAliases)
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve aliases in label selector %+v: %w", s, err)
+	}
+
+	return resolved, nil
+}
+
+// checkSelector ensures the given selector only uses keys of a GitHubItem, and known age buckets.
+func (w *Watch) checkSelector(s labels.Selector) error {
+	// The internal selector that is created through labels.Parse will always
+	// return 'true' for selectable here, so ignore it.
+	requirements, _ := s.Requirements()
+
+	for _, r := range requirements {
+		if key := r.Key(); !isGitHubItemField(key) {
+			return fmt.Errorf("unknown key '%s' in selector", key)
+		}
+
+		if r.Key() == "ageBucket" {
+			if err := checkAgeBucketSelectorValues(w.getAgeBuckets(), r.Values().List()); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// populateSelectorGroups parses the selectors of each of the Watch's SelectorGroups.
+func (w *Watch) populateSelectorGroups() error {
+	w.selectorGroups = [][]labels.Selector{}
+
+	for i, g := range w.SelectorGroups {
+		if g.Name == "" {
+			return fmt.Errorf("selector group %d must have a name", i)
+		}
+
+		if len(g.Selectors) == 0 && len(g.Labels) == 0 {
+			return fmt.Errorf("selector group '%s' must have at least one selector or label", g.Name)
+		}
+
+		group := []labels.Selector{}
+
+		for _, s := range g.Selectors {
+			parsed, err := w.parseSelector(s)
+			if err != nil {
+				return fmt.Errorf("invalid selector group '%s': %w", g.Name, err)
+			}
+
+			if err := w.checkSelector(parsed); err != nil {
+				return fmt.Errorf("invalid selector group '%s': %w", g.Name, err)
+			}
+
+			group = append(group, parsed)
+		}
+
+		w.selectorGroups = append(w.selectorGroups, group)
+	}
+
+	return nil
+}
+
+// PopulateMatchers parses the Watch's Selectors, SelectorGroups, BodyRegex and TitleRegex, which are used by
+// GetMatchinator. It is called by ValidateAndPopulate, but doesn't need access to GitHub, so it can be used to match
+// items offline. Config.PopulateSelectorAliases must be called beforehand for selector aliases to be resolved.
+func (w *Watch) PopulateMatchers() error {
+	w.selectors = []labels.Selector{}
+	for _, s := range w.Selectors {
+		resolved, err := w.parseSelector(s)
+		if err != nil {
+			return err
+		}
+
+		w.selectors = append(w.selectors, resolved)
+	}
+
+	// Do this double loop here so we can test how we handle w.Selectors without needing to also set w.selectors.
+	for _, s := range w.selectors {
+		if err := w.checkSelector(s); err != nil {
+			return err
+		}
+	}
+
+	if err := w.populateSelectorGroups(); err != nil {
+		return err
+	}
+
+	w.bodyRegex = []*regexp.Regexp{}
+	for _, r := range w.BodyRegex {
+		compiled, err := regexp.Compile(r)
+		if err != nil {
+			return fmt.Errorf("unable to compile regex '%s': %w'", r, err)
+		}
+
+		w.bodyRegex = append(w.bodyRegex, compiled)
+	}
+
+	w.commentRegex = []*regexp.Regexp{}
+	for _, r := range w.CommentRegex {
+		compiled, err := regexp.Compile(r)
+		if err != nil {
+			return fmt.Errorf("unable to compile regex '%s': %w", r, err)
+		}
+
+		w.commentRegex = append(w.commentRegex, compiled)
+	}
+
+	w.titleRegex = []*regexp.Regexp{}
+	for _, r := range w.TitleRegex {
+		compiled, err := regexp.Compile(r)
+		if err != nil {
+			return fmt.Errorf("unable to compile regex '%s': '%w'", r, err)
+		}
+
+		w.titleRegex = append(w.titleRegex, compiled)
+	}
+
+	return nil
+}
+
+// searchRepositories returns the repositories named by the repo: qualifiers of the given search query.
+func searchRepositories(query string) []GitHubRepository {
+	repos := []GitHubRepository{}
+
+	for _, field := range strings.Fields(query) {
+		name, ok := strings.CutPrefix(field, "repo:")
+		if !ok {
+			continue
+		}
+
+		if r, err := ParseGitHubRepository(name); err == nil {
+			repos = append(repos, r)
+		}
+	}
+
+	return repos
+}
+
+// checkActionRepos ensures that the repos each action is limited to are part of the Watch's Repositories, or of the
+// repo: qualifiers of its search. If Self is set, the search isn't limited to repos, or the ReposCommand was skipped,
+// the Watch's repositories aren't known, so the check is skipped.
+func (w *Watch) checkActionRepos() error {
+	repos := w.Repositories
+	if w.search != "" {
+		repos = searchRepositories(w.search)
+	}
+
+	if w.Self || (w.search != "" && len(repos) == 0) || w.reposCommandSkipped() {
+		return nil
+	}
+
+	for name, opts := range w.Actions.options() {
+		for _, r := range opts.Repos {
+			if !containsRepository(repos, r) {
+				return fmt.Errorf(
+					"action '%s' is limited to repo %s/%s, which is not in the watch's repos", name, r.Owner, r.Name,
+				)
+			}
+		}
+	}
+
+	return nil
+}
+
+// hasFilter returns if the Watch sets at least one filter, so it doesn't match every item of its repositories. It
+// must be called after the Watch's selectors, selector groups, body regexes and search are populated.
+func (w *Watch) hasFilter() bool {
+	return len(w.selectors) > 0 || len(w.selectorGroups) > 0 || len(w.bodyRegex) > 0 || len(w.CommentRegex) > 0 ||
+		len(w.RequiredLabels) > 0 || len(w.ExcludedLabels) > 0 || len(w.States) > 0 || w.search != "" ||
+		w.LastActivityBy != nil || w.ReferencesIssue != 0 ||
+		w.TitlePrefix != "" || w.TitleSuffix != "" || w.TitleContains != "" ||
+		len(w.Assignees) > 0 || len(w.Authors) > 0 || w.Milestone != "" ||
+		w.UpdatedWithin != 0 || w.CreatedWithin != 0 || w.NotUpdatedWithin != 0 || w.NumberRange != nil
+}
+
+// populateSearch validates the Watch's Search and parses it into the search query that is executed on each tick.
+func (w *Watch) populateSearch(ctx context.Context, gh GitHubinator) error {
+	if w.Self || w.hasRepositories() {
+		return fmt.Errorf("search cannot be combined with repos or self, add repo: qualifiers to the search instead")
+	}
+
+	if len(w.SearchLabels) > 0 || len(w.States) > 0 {
+		return fmt.Errorf(
+			"searchLabels and states cannot be combined with search, add label: and state: qualifiers to the " +
+				"search instead",
+		)
+	}
+
+	if w.UpdatedSince != 0 {
+		return fmt.Errorf(
+			"updatedSince cannot be combined with search, add an updated: qualifier to the search instead",
+		)
+	}
+
+	if w.Milestone != "" {
+		return fmt.Errorf("milestone cannot be combined with search, add a milestone: qualifier to the search instead")
+	}
+
+	if len(w.Authors) > 0 {
+		return fmt.Errorf("authors cannot be combined with search, add author: qualifiers to the search instead")
+	}
+
+	query, err := ParseGitHubSearch(w.Search)
+	if err != nil {
+		return fmt.Errorf("unable to parse search '%s': %w", w.Search, err)
+	}
+
+	if !w.offline {
+		if err := gh.CheckSearch(ctx, query); err != nil {
+			return fmt.Errorf("unable to validate search '%s': %w", query, err)
+		}
+	}
+
+	w.search = query
+
+	return nil
+}
+
+// ParseGitHubSearch parses the given search into a GitHub search query. The search can be one of:
+//   - A search URL copied from GitHub, such as https://github.com/search?q=is%3Aissue+label%3Abug&type=issues. For
+//     URLs scoped to a repository, such as https://github.com/owner/repo/issues?q=is%3Aopen, a 'repo:' qualifier
+//     is added to the query.
+//   - The 'q=' query string of a search URL, such as q=is%3Aissue+label%3Abug.
+//   - A search query, such as 'is:issue label:bug'.
+func ParseGitHubSearch(search string) (string, error) {
+	search = strings.TrimSpace(search)
+
+	var query string
+
+	switch {
+	case strings.HasPrefix(search, "https://") || strings.HasPrefix(search, "http://"):
+		u, err := url.Parse(search)
+		if err != nil {
+			return "", fmt.Errorf("unable to parse url: %w", err)
+		}
+
+		if !u.Query().Has("q") {
+			return "", fmt.Errorf("url does not have a 'q' query parameter")
+		}
+
+		query = u.Query().Get("q")
+
+		// Repository scoped searches have the path /owner/repo/issues or /owner/repo/pulls.
+		parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+		if len(parts) == 3 && (parts[2] == "issues" || parts[2] == "pulls") {
+			query = fmt.Sprintf("repo:%s/%s %s", parts[0], parts[1], query)
+		}
+	case strings.HasPrefix(search, "q="):
+		values, err := url.ParseQuery(search)
+		if err != nil {
+			return "", fmt.Errorf("unable to parse query string: %w", err)
+		}
+
+		query = values.Get("q")
+	default:
+		query = search
+	}
+
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return "", fmt.Errorf("search query cannot be empty")
+	}
+
+	return query, nil
+}
+
+// GetSearchQuery returns the GitHub search query used to find items for the Watch, or an empty string if the Watch
+// lists items from its repositories instead. It is populated by ValidateAndPopulate.
+func (w *Watch) GetSearchQuery() string {
+	return w.search
+}
+
+// ListItems returns the items matching the Watch, using its search query if set or its repositories otherwise.
+// Filters relative to the current time, such as UpdatedSince and UpdatedWithin, use the given Clock.
+func (w *Watch) ListItems(ctx context.Context, gh GitHubinator, clock Clock) ([]*GitHubItem, error) {
+	return w.listItems(ctx, gh, w.GetMatchinator().WithClock(clock), clock.Now())
+}
+
+// listItems implements ListItems, using the given Matchinator. UpdatedSince is relative to the given time.
+func (w *Watch) listItems(
+	ctx context.Context, gh GitHubinator, matcher Matchinator, now time.Time,
+) ([]*GitHubItem, error) {
+	if w.search != "" {
+		items, err := gh.SearchIssues(ctx, w.search, matcher)
+		if err != nil {
+			return nil, fmt.Errorf("unable to search issues: %w", err)
+		}
+
+		return items, nil
+	}
+
+	repos, err := w.ListRepositories(ctx, gh)
+	if err != nil {
+		return nil, err
+	}
+
+	issueFilter := w.GetIssueFilter(now)
+	items := []*GitHubItem{}
+
+	for _, r := range repos {
+		issues, err := w.listRepositoryItems(ctx, gh, r, issueFilter, matcher)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list issues for %s/%s: %w", r.Owner, r.Name, err)
+		}
+
+		items = append(items, issues...)
+	}
+
+	return items, nil
+}
+
+// ListRepositories returns the repositories the Watch applies to. If Self is set, the repositories the viewer owns
+// or collaborates on are listed from GitHub, otherwise Repositories is returned.
+func (w *Watch) ListRepositories(ctx context.Context, gh GitHubinator) ([]GitHubRepository, error) {
+	if !w.Self {
+		return w.Repositories, nil
+	}
+
+	repos, err := gh.ListViewerRepositories(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list viewer repositories: %w", err)
+	}
+
+	return repos, nil
+}
+
+// checkFullBodyScan returns an error if the Watch would need to fetch the body or comments of every issue in its
+// repositories on every tick, which happens when BodyRegex, CommentRegex or ReferencesIssue is set without any
+// server-side filters. The error includes an estimate of the number of bodies that would be fetched, based on the
+// number of open issues in the repositories. When validating offline, the estimate is left out.
+func (w *Watch) checkFullBodyScan(ctx context.Context, gh GitHubinator) error {
+	if (len(w.bodyRegex) == 0 && len(w.commentRegex) == 0 && w.ReferencesIssue == 0) || len(w.SearchLabels) > 0 ||
+		len(w.States) > 0 || w.search != "" || w.AllowFullBodyScan {
+		return nil
+	}
+
+	var scope string
+
+	switch {
+	case w.offline && w.Self:
+		scope = "across every repo the user owns or collaborates on"
+	case w.reposCommandSkipped():
+		scope = "across every repo listed by reposCommand"
+	case w.offline:
+		scope = fmt.Sprintf("across %d repos", len(w.Repositories))
+	default:
+		// The repositories of self watches are only known once they're listed.
+		repos, err := w.ListRepositories(ctx, gh)
+		if err != nil {
+			return err
+		}
+
+		numOpenIssues := 0
+
+		for _, r := range repos {
+			n, err := gh.CountOpenIssues(ctx, r)
+			if err != nil {
+				return fmt.Errorf("unable to count open issues in repository %+v: %w", r, err)
+			}
+
+			numOpenIssues += n
+		}
+
+		scope = fmt.Sprintf("at least %d open issues across %d repos", numOpenIssues, len(repos))
+	}
+
+	return fmt.Errorf(
+		"bodyRegex, commentRegex or referencesIssue without searchLabels or states requires fetching the body or "+
+			"comments of every issue on each tick (%s), narrow the watch using searchLabels or states or set "+
+			"allowFullBodyScan to true",
+		scope,
+	)
+}
+
+// getItemTypes returns the Watch's ItemTypes, or only issues if they haven't been set.
+func (w *Watch) getItemTypes() []GitHubItemType {
+	if len(w.ItemTypes) == 0 {
+		return []GitHubItemType{GitHubItemIssue}
+	}
+
+	return w.ItemTypes
+}
+
+// checkItemTypes ensures the Watch's ItemTypes are known and unique, and that its States and filters can be applied
+// to each of them.
+func (w *Watch) checkItemTypes() error {
+	if len(w.ItemTypes) > 0 && w.Search != "" {
+		return fmt.Errorf("itemTypes cannot be set with search, use 'is:issue' or 'is:pr' in the search instead")
+	}
+
+	seen := map[GitHubItemType]bool{}
+
+	for _, t := range w.ItemTypes {
+		switch t {
+		case GitHubItemIssue, GitHubItemPullRequest, GitHubItemDiscussion:
+		default:
+			return fmt.Errorf(
+				"unknown item type '%s', expected '%s', '%s' or '%s'",
+				t, GitHubItemIssue, GitHubItemPullRequest, GitHubItemDiscussion,
+			)
+		}
+
+		if seen[t] {
+			return fmt.Errorf("duplicate item type '%s'", t)
+		}
+
+		seen[t] = true
+	}
+
+	onlyPullRequests := len(seen) == 1 && seen[GitHubItemPullRequest]
+
+	for _, s := range w.States {
+		switch s {
+		case string(githubv4.IssueStateClosed), string(githubv4.IssueStateOpen):
+			continue
+		case string(githubv4.PullRequestStateMerged):
+			if onlyPullRequests {
+				continue
+			}
+
+			return fmt.Errorf("state %s requires itemTypes to only contain '%s'", s, GitHubItemPullRequest)
+		default:
+			return fmt.Errorf("unknown issue state %s", s)
+		}
+	}
+
+	hint := ""
+
+	// Searches return both issues and pull requests, unless they're limited to issues.
+	if w.Search != "" {
+		if searchOnlyFindsIssues(w.search) {
+			return nil
+		}
+
+		hint = ", add 'is:issue' to the search"
+	} else if !seen[GitHubItemPullRequest] && !seen[GitHubItemDiscussion] {
+		return nil
+	}
+
+	// The queries for these fields only support issues.
+	if w.LastActivityBy != nil {
+		return fmt.Errorf("lastActivityBy can only be used when watching issues%s", hint)
+	}
+
+	if len(w.CommentRegex) > 0 {
+		return fmt.Errorf("commentRegex can only be used when watching issues%s", hint)
+	}
+
+	if w.GetMatchinator().HasProjectFields() {
+		return fmt.Errorf("selectors on project fields can only be used when watching issues%s", hint)
+	}
+
+	return nil
+}
+
+// searchOnlyFindsIssues returns if the given search query is limited to issues by an 'is:issue' or 'type:issue'
+// qualifier.
+func searchOnlyFindsIssues(query string) bool {
+	for _, term := range strings.Fields(query) {
+		if strings.EqualFold(term, "is:issue") || strings.EqualFold(term, "type:issue") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// listRepositoryItems lists the items of each of the Watch's ItemTypes in the given repository, using the given
+// filter and Matchinator. Items are sorted by number.
+func (w *Watch) listRepositoryItems(
+	ctx context.Context, gh GitHubinator, ghr GitHubRepository, filter *GitHubIssueFilter, matcher Matchinator,
+) ([]*GitHubItem, error) {
+	items := []*GitHubItem{}
+
+	for _, t := range w.getItemTypes() {
+		var (
+			listed []*GitHubItem
+			err    error
+		)
+
+		switch t {
+		case GitHubItemPullRequest:
+			listed, err = gh.ListPullRequests(ctx, ghr, filter, matcher)
+		case GitHubItemDiscussion:
+			listed, err = gh.ListDiscussions(ctx, ghr, filter, matcher)
+		default:
+			listed, err = gh.ListIssues(ctx, ghr, filter, matcher)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, listed...)
+	}
+
+	SortGitHubItems(items)
+
+	return items, nil
+}
+
+// GetIssueFilter returns a GitHubIssueFilter based on the Watch's specified SearchLabels, States, Milestone, Authors
+// and UpdatedSince, which is relative to the given time. It can be passed to a GitHubinator for listing issues that
+// match the Watch.
+func (w *Watch) GetIssueFilter(now time.Time) *GitHubIssueFilter {
+	filter := &GitHubIssueFilter{
+		Labels:    w.SearchLabels,
+		States:    w.States,
+		Milestone: w.Milestone,
+	}
+
+	// GitHub can only filter by a single author, so multiple authors are matched by the Matchinator instead.
+	if len(w.Authors) == 1 {
+		filter.CreatedBy = w.Authors[0]
+	}
+
+	if w.UpdatedSince > 0 {
+		since := now.Add(-w.UpdatedSince)
+		filter.Since = &since
+	}
+
+	return filter
+}
+
+// getLastActivityByLogins returns the logins configured in LastActivityBy, or nil if it is not set.
+func (w *Watch) getLastActivityByLogins() []string {
+	if w.LastActivityBy == nil {
+		return nil
+	}
+
+	return w.LastActivityBy.Logins
+}
+
+// getNumberRange returns the bounds of the Watch's NumberRange, or zeros if it is not set.
+func (w *Watch) getNumberRange() (int, int) {
+	if w.NumberRange == nil {
+		return 0, 0
+	}
+
+	return w.NumberRange.Min, w.NumberRange.Max
+}
+
+// getAgeBuckets returns the Watch's age buckets, or DefaultAgeBuckets if they haven't been set.
+func (w *Watch) getAgeBuckets() []AgeBucket {
+	if len(w.ageBuckets) == 0 {
+		return DefaultAgeBuckets
+	}
+
+	return w.ageBuckets
+}
+
+// GetMatchinator returns a Matchinator based on the Watch's specified BodyRegex, Selectors, SelectorGroups,
+// RequiredLabels, ExcludedLabels, Authors and Assignees fields.
+// It can be passed to a GitHubinator for listing issues that match the Watch.
+func (w *Watch) GetMatchinator() Matchinator {
+	m := NewMatchinator().
+		WithBodyRegexes(w.CaseSensitive, w.bodyRegex...).
+		WithCommentRegexes(w.CaseSensitive, w.commentRegex...).
+		WithTitleRegexes(w.CaseSensitive, w.titleRegex...).
+		WithTitleStrings(w.TitlePrefix, w.TitleSuffix, w.TitleContains, w.TitleCaseSensitive).
+		WithSelectors(w.selectors...)
+
+	for i, g := range w.selectorGroups {
+		m = m.WithSelectorGroup(w.SelectorGroups[i].Name, g, w.SelectorGroups[i].Labels)
+	}
+
+	return m.
+		WithRequiredLabels(w.RequiredLabels...).
+		WithExcludedLabels(w.ExcludedLabels...).
+		WithAuthors(w.Authors...).
+		WithAssignees(w.Assignees...).
+		WithLastActivityBy(w.getLastActivityByLogins()...).
+		WithExcludeDrafts(w.ExcludeDrafts).
+		WithNumberRange(w.getNumberRange()).
+		WithUpdatedWithin(time.Duration(w.UpdatedWithin)).
+		WithCreatedWithin(time.Duration(w.CreatedWithin)).
+		WithNotUpdatedWithin(time.Duration(w.NotUpdatedWithin)).
+		WithReferencesIssue(w.ReferencesIssue, w.ReferencesIssueTimeline).
+		WithAgeBuckets(w.getAgeBuckets()...)
+}
+
+func (w *Watch) GetActioninator(gh GitHubinator, emailinator Emailinator) Actioninator {
+	a := NewActioninator().WithPolicy(w.Actions.Policy)
+
+	if w.Actions.Subscribe.Enabled {
+		action := NewSubscribeAction(gh)
+		action.DependsOn = w.Actions.Subscribe.DependsOn
+		action.Repos = w.Actions.Subscribe.Repos
+		a = a.WithAction(action)
+	}
+
+	if w.Actions.Unsubscribe.Enabled {
+		action := NewUnsubscribeAction(gh)
+		action.DependsOn = w.Actions.Unsubscribe.DependsOn
+		action.Repos = w.Actions.Unsubscribe.Repos
+		a = a.WithAction(action)
+	}
+
+	if w.Actions.Ignore.Enabled {
+		action := NewIgnoreAction(gh)
+		action.DependsOn = w.Actions.Ignore.DependsOn
+		action.Repos = w.Actions.Ignore.Repos
+		a = a.WithAction(action)
+	}
+
+	if w.Actions.Email.Enabled {
+		var action GitHubItemAction
+
+		if w.Actions.Email.Digest {
+			action = NewDigestEmailAction(
+				emailinator, w.Actions.Email.SendTo, w.Name, w.Actions.Email.MaxBodySize, w.Tags,
+			)
+		} else {
+			// Templates are checked during validation, so this can't fail.
+			templates, _ := w.Actions.Email.templates()
+			action = NewEmailAction(
+				emailinator, w.Actions.Email.SendTo, w.Actions.Email.RenderBodyHTML, w.Actions.Email.MaxBodySize,
+				w.Tags, templates,
+			)
+		}
+
+		action.DependsOn = w.Actions.Email.DependsOn
+		action.Repos = w.Actions.Email.Repos
+
+		if buffer := w.Actions.Email.Buffer; buffer.Size > 0 {
+			// Size is checked during validation, so this can't fail.
+			action, _ = NewBufferedAction(action, buffer.Size, buffer.getDropPolicy())
+		}
+
+		a = a.WithAction(action)
+	}
+
+	if w.Actions.Webhook.Enabled {
+		action := NewWebhookAction(
+			w.Actions.Webhook.URL, w.Actions.Webhook.Headers, w.Actions.Webhook.Secret,
+			w.Actions.Webhook.HTTPStatusPolicy, w.Tags,
+		)
+		action.DependsOn = w.Actions.Webhook.DependsOn
+		action.Repos = w.Actions.Webhook.Repos
+		a = a.WithAction(action)
+	}
+
+	if w.Actions.Slack.Enabled {
+		action := NewSlackAction(w.Actions.Slack.WebhookURL, w.Actions.Slack.Channel, w.Actions.Slack.SkipSubscribed)
+		action.DependsOn = w.Actions.Slack.DependsOn
+		action.Repos = w.Actions.Slack.Repos
+		a = a.WithAction(action)
+	}
+
+	if w.Actions.Exec.Enabled {
+		action := NewExecAction(w.Actions.Exec.Command, w.Actions.Exec.Timeout)
+		action.DependsOn = w.Actions.Exec.DependsOn
+		action.Repos = w.Actions.Exec.Repos
+		a = a.WithAction(action)
+	}
+
+	if w.Actions.Label.Enabled {
+		action := NewLabelAction(gh, w.Actions.Label.Labels)
+		action.DependsOn = w.Actions.Label.DependsOn
+		action.Repos = w.Actions.Label.Repos
+		a = a.WithAction(action)
+	}
+
+	if w.Actions.Comment.Enabled {
+		action := NewCommentAction(gh, w.Actions.Comment.Template)
+		action.DependsOn = w.Actions.Comment.DependsOn
+		action.Repos = w.Actions.Comment.Repos
+		a = a.WithAction(action)
+	}
+
+	return a
+}
+
+// NewTestWatch creates a new Watch instance with pre-populated fields. It can be used in unit tests.
+func NewTestWatch() *Watch {
+	return &Watch{
+		Name: "name",
+		Repositories: []GitHubRepository{{
+			Owner: "owner",
+			Name:  "repo",
+		}},
+		Selectors:      []string{"type==issue"},
+		RequiredLabels: []string{"a/requiredLabel"},
+		SearchLabels:   []string{"a/searchLabel"},
+		BodyRegex:      []string{".*"},
+		TitleRegex:     []string{".*"},
+		States:         []string{"OPEN"},
+		Actions: ActionConfig{
+			Subscribe: SubscribeActionConfig{
+				Enabled: true,
+			},
+			Email: EmailActionConfig{
+				Enabled: true,
+				SendTo:  "test@example.com",
+			},
+		},
+	}
+}
+
+// Config specifies the list of Watches to create.
+type Config struct {
+	// User is the GitHub username the watch will apply to.
+	User string `yaml:"user"`
+	// PATFile is the file containing the user's PAT used for authentication. It cannot be set alongside AppAuth.
+	PATFile string `yaml:"patFile"`
+	// PATEnv is the name of an environment variable containing the user's PAT, which is used instead of PATFile. If
+	// the variable is empty, PATFile is read instead. It cannot be set alongside AppAuth.
+	PATEnv string `yaml:"patEnv"`
+	// PAT is the PAT contained in the PATEnv or PATFile.
+	PAT string `yaml:"-"`
+	// PATFiles are files containing additional PATs of the same user. Requests are spread across every PAT, including
+	// the one in PATFile, so their rate limits are combined. It cannot be set alongside AppAuth.
+	PATFiles []string `yaml:"patFiles"`
+	// PATs are the PATs contained in PATEnv or PATFile, and PATFiles, in that order.
+	PATs []string `yaml:"-"`
+	// AppAuth authenticates as the installation of a GitHub App instead of with a PAT. User must then be the login of
+	// the app's bot user, such as 'my-app[bot]'.
+	AppAuth *GitHubAppAuthConfig `yaml:"appAuth"`
+	// BaseURL is the URL of the GitHub Enterprise Server instance to watch, such as 'https://github.example.com'. If
+	// empty, github.com is used.
+	BaseURL string `yaml:"baseURL"`
+	// PageSize is the number of issues and labels requested per page when listing them. Lowering it reduces the cost
+	// of each query on large repositories. Values above GitHubMaxPageSize are clamped to it. If zero, the page size
+	// isn't changed.
+	PageSize int `yaml:"pageSize"`
+	// Interval used to determine when to update watches.
+	Interval time.Duration `yaml:"interval"`
+	// InitialScan determines if watches scan for items as soon as the config is loaded, or only after the first
+	// Interval. It can be overridden per watch, and defaults to true.
+	InitialScan *bool `yaml:"initialScan"`
+	// RepoConcurrency is the maximum number of repositories each watch lists items from at once. If zero,
+	// DefaultRepoConcurrency is used. Set it to 1 to list repositories one at a time.
+	RepoConcurrency int `yaml:"repoConcurrency"`
+	// ActionConcurrency is the maximum number of actions performed at once, across every watch and item. If zero,
+	// DefaultActionConcurrency is used.
+	ActionConcurrency int `yaml:"actionConcurrency"`
+	// SpreadTicks spreads the polls of the watches evenly across their interval, in the order they are listed, rather
+	// than polling every watch at once. This smooths out usage of the GitHub API. Initial scans are spread out too.
+	SpreadTicks bool `yaml:"spreadTicks"`
+	// Jitter randomly delays the start of each poll by up to this fraction of its interval, between 0 and 1, so
+	// polls on the same interval don't fire in lockstep. It is applied on top of SpreadTicks.
+	Jitter float64 `yaml:"jitter"`
+	// StateFile is the file the actions performed on items are recorded in, so they aren't repeated after a restart or
+	// config reload. Actions are then only performed on an item again once it's updated. If empty, actions are only
+	// recorded in memory, for watches with a notifyCooldown.
+	StateFile string `yaml:"stateFile"`
+	// Email sender configuration for email action.
+	Email EmailConfig `yaml:"email"`
+	// ValidationRetry configures how network checks performed during validation, such as checking the PAT and
+	// dialing the SMTP service, are retried.
+	ValidationRetry RetryConfig `yaml:"validationRetry"`
+	// AllowedActions restricts the actions watches are allowed to enable. If empty, all actions are allowed. It is
+	// overridden by the AllowedActionsEnvVar environment variable, so operators can restrict the actions of configs
+	// they do not author.
+	AllowedActions []string `yaml:"allowedActions"`
+	// SelectorAliases maps aliases which can be used as keys in watch selectors to their canonical key, such as
+	// 'author' to 'author.login'. They are added to, and take precedence over, DefaultSelectorAliases.
+	SelectorAliases map[string]string `yaml:"selectorAliases"`
+	// AgeBuckets are the buckets items are grouped in by age, for use with the 'ageBucket' selector key. They must be
+	// in order of increasing maxAge, with the last bucket not setting maxAge. If empty, DefaultAgeBuckets are used.
+	AgeBuckets []AgeBucket `yaml:"ageBuckets"`
+	// Matchers are named sets of matchers which watches can reference with Watch.Use, so common selectors and
+	// regexes don't need to be repeated across watches.
+	Matchers map[string]*MatcherSet `yaml:"matchers"`
+	// Watches is a list of Watch definitions.
+	Watches []*Watch `yaml:"watches"`
+	// Profiles are named sets of watches, interval and email sender configuration, which replace the top-level
+	// fields when selected with ApplyProfile. This allows a single config file to hold, for instance, both a
+	// production and a staging set of watches.
+	Profiles map[string]*ConfigProfile `yaml:"profiles"`
+	// Profile is the name of the profile applied to the Config, or empty if none was applied.
+	Profile string `yaml:"-"`
+}
+
+// ConfigProfile is a named set of Config fields. Fields which are set replace the Config's when the profile is
+// applied, while unset fields are inherited from the Config.
+type ConfigProfile struct {
+	Interval time.Duration `yaml:"interval"`
+	Email    *EmailConfig  `yaml:"email"`
+	Watches  []*Watch      `yaml:"watches"`
+}
+
+// ApplyProfile replaces the Config's watches, interval and email sender configuration with the ones set in the
+// profile with the given name. An empty name leaves the Config as is. If the profile doesn't exist, an error listing
+// the available profiles is returned.
+func (c *Config) ApplyProfile(name string) error {
+	if name == "" {
+		return nil
+	}
+
+	p, ok := c.Profiles[name]
+	if !ok || p == nil {
+		available := []string{}
+		for n := range c.Profiles {
+			available = append(available, n)
+		}
+
+		slices.Sort(available)
+
+		if len(available) == 0 {
+			return fmt.Errorf("unknown profile '%s', the config doesn't define any profiles", name)
+		}
+
+		return fmt.Errorf("unknown profile '%s', available profiles: %s", name, strings.Join(available, ", "))
+	}
+
+	if p.Interval != 0 {
+		c.Interval = p.Interval
+	}
+
+	if p.Email != nil {
+		c.Email = *p.Email
+	}
+
+	if p.Watches != nil {
+		c.Watches = p.Watches
+	}
+
+	c.Profile = name
+
+	return nil
+}
+
+// DefaultSelectorAliases are the selector key aliases which are available in every config.
+var DefaultSelectorAliases = map[string]string{
+	"author":  "author.login",
+	"repo":    "repo.name",
+	"owner":   "repo.owner",
+	"isDraft": "draft",
+}
+
+// getSelectorAliases returns DefaultSelectorAliases merged with the configured SelectorAliases.
+func (c *Config) getSelectorAliases() map[string]string {
+	aliases := map[string]string{}
+
+	for alias, key := range DefaultSelectorAliases {
+		aliases[alias] = key
+	}
+
+	for alias, key := range c.SelectorAliases {
+		aliases[alias] = key
+	}
+
+	return aliases
+}
+
+// PopulateSelectorAliases ensures each selector alias maps to a valid key and doesn't shadow one, then sets the
+// aliases on each Watch so they are resolved when its selectors are parsed.
+func (c *Config) PopulateSelectorAliases() error {
+	aliases := c.getSelectorAliases()
+
+	for alias, key := range aliases {
+		if isGitHubItemField(alias) {
+			return fmt.Errorf("selector alias '%s' cannot shadow an existing key", alias)
+		}
+
+		if !isGitHubItemField(key) {
+			return fmt.Errorf("selector alias '%s' refers to unknown key '%s'", alias, key)
+		}
+	}
+
+	for _, w := range c.Watches {
+		w.selectorAliases = aliases
+	}
+
+	return nil
+}
+
+// PopulateAgeBuckets validates the configured age buckets, then sets them on each Watch so they can be used when its
+// selectors are parsed and items are matched.
+func (c *Config) PopulateAgeBuckets() error {
+	buckets := c.AgeBuckets
+	if len(buckets) == 0 {
+		buckets = DefaultAgeBuckets
+	}
+
+	if err := ValidateAgeBuckets(buckets); err != nil {
+		return fmt.Errorf("invalid ageBuckets: %w", err)
+	}
+
+	for _, w := range c.Watches {
+		w.ageBuckets = buckets
+	}
+
+	return nil
+}
+
+// ExpandMatchers adds the matchers of the Config.Matchers referenced by each Watch's Use field to the Watch, after its
+// own. Each Watch is only expanded once, so it is safe to call more than once. An error is returned if a Watch
+// references a matcher set which doesn't exist.
+func (c *Config) ExpandMatchers() error {
+	for name, m := range c.Matchers {
+		if m == nil {
+			return fmt.Errorf("matcher set '%s' cannot be empty", name)
+		}
+	}
+
+	for _, w := range c.Watches {
+		if w.matchersExpanded {
+			continue
+		}
+
+		for _, name := range w.Use {
+			m, ok := c.Matchers[name]
+			if !ok {
+				available := []string{}
+				for n := range c.Matchers {
+					available = append(available, n)
+				}
+
+				slices.Sort(available)
+
+				return fmt.Errorf(
+					"watch '%s' uses unknown matcher set '%s', available matcher sets: %v", w.Name, name, available,
+				)
+			}
+
+			w.Selectors = append(w.Selectors, m.Selectors...)
+			w.SelectorGroups = append(w.SelectorGroups, m.SelectorGroups...)
+			w.RequiredLabels = append(w.RequiredLabels, m.RequiredLabels...)
+			w.ExcludedLabels = append(w.ExcludedLabels, m.ExcludedLabels...)
+			w.BodyRegex = append(w.BodyRegex, m.BodyRegex...)
+			w.CommentRegex = append(w.CommentRegex, m.CommentRegex...)
+			w.TitleRegex = append(w.TitleRegex, m.TitleRegex...)
+		}
+
+		w.matchersExpanded = true
+	}
+
+	return nil
+}
+
+// AllowedActionsEnvVar is the environment variable which overrides Config.AllowedActions. It holds a
+// comma-separated list of action names.
+const AllowedActionsEnvVar = "WATCHINATOR_ALLOWED_ACTIONS"
+
+// knownActions holds the names of every action which can be configured in a Watch. reposCommand isn't an action, but
+// runs a command like exec, so it is allowed the same way.
+var knownActions = []string{
+	"subscribe", "unsubscribe", "ignore", "email", "webhook", "slack", "exec", "label", "comment", "report",
+	"reposCommand",
+}
+
+// getAllowedActions returns the actions watches are allowed to enable, taking AllowedActionsEnvVar into account.
+// If all actions are allowed, nil is returned.
+func (c *Config) getAllowedActions() []string {
+	if env := os.Getenv(AllowedActionsEnvVar); env != "" {
+		allowed := []string{}
+
+		for _, a := range strings.Split(env, ",") {
+			if a = strings.TrimSpace(a); a != "" {
+				allowed = append(allowed, a)
+			}
+		}
+
+		return allowed
+	}
+
+	if len(c.AllowedActions) == 0 {
+		return nil
+	}
+
+	return c.AllowedActions
+}
+
+// checkWatchNames ensures no two watches share a name. Polls are keyed by the name of their watch, so a watch with a
+// duplicate name would replace the other's poll and never run. Empty names are left to Watch.ValidateAndPopulate.
+func (c *Config) checkWatchNames() error {
+	seen := map[string]int{}
+
+	for i, w := range c.Watches {
+		if w.Name == "" {
+			continue
+		}
+
+		if j, ok := seen[w.Name]; ok {
+			return fmt.Errorf("duplicate watch name '%s', used by watches %d and %d", w.Name, j+1, i+1)
+		}
+
+		seen[w.Name] = i
+	}
+
+	return nil
+}
+
+// checkAllowedActions ensures the allowed actions are known and that no watch enables an action which isn't allowed.
+func (c *Config) checkAllowedActions() error {
+	allowed := c.getAllowedActions()
+	if allowed == nil {
+		return nil
+	}
+
+	for _, a := range allowed {
+		if !slices.Contains(knownActions, a) {
+			return fmt.Errorf("unknown action '%s' in allowedActions", a)
+		}
+	}
+
+	for _, w := range c.Watches {
+		for name := range w.Actions.options() {
+			if !slices.Contains(allowed, name) {
+				return fmt.Errorf("watch '%s' enables action '%s', which is not in allowedActions", w.Name, name)
+			}
+		}
+
+		if w.Report.Enabled && !slices.Contains(allowed, "report") {
+			return fmt.Errorf("watch '%s' enables action 'report', which is not in allowedActions", w.Name)
+		}
+
+		if len(w.ReposCommand) > 0 && !slices.Contains(allowed, "reposCommand") {
+			return fmt.Errorf("watch '%s' sets reposCommand, which is not in allowedActions", w.Name)
+		}
+	}
+
+	return nil
+}
+
+func (c *Config) LogValue() slog.Value {
+	watchValues := []slog.Attr{}
+	for _, watch := range c.Watches {
+		watchValues = append(watchValues, slog.Any(watch.Name, watch.LogValue()))
+	}
+
+	return slog.GroupValue(
+		slog.String("user", c.User),
+		slog.String("profile", c.Profile),
+		slog.String("baseURL", c.BaseURL),
+		slog.Int("pageSize", c.PageSize),
+		slog.Bool("appAuth", c.AppAuth != nil),
+		slog.Duration("interval", c.Interval),
+		slog.Bool("initialScan", c.InitialScan == nil || *c.InitialScan),
+		slog.Bool("spreadTicks", c.SpreadTicks),
+		slog.Float64("jitter", c.Jitter),
+		slog.String("stateFile", c.StateFile),
+		slog.Int("repoConcurrency", c.RepoConcurrency),
+		slog.Int("actionConcurrency", c.ActionConcurrency),
+		slog.Any("email", c.Email.LogValue()),
+		slog.Any("validationRetry", c.ValidationRetry.LogValue()),
+		slog.Any("allowedActions", c.AllowedActions),
+		slog.Any("watches", watchValues),
+	)
+}
+
+// checkBaseURL ensures the BaseURL, if set, is an absolute http or https URL.
+func (c *Config) checkBaseURL() error {
+	if c.BaseURL == "" {
+		return nil
+	}
+
+	u, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return fmt.Errorf("invalid baseURL '%s': %w", c.BaseURL, err)
+	}
+
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid baseURL '%s', expected an absolute http or https URL", c.BaseURL)
+	}
+
+	return nil
+}
+
+// GetGitHubinator returns the given GitHubinator authenticated with the Config's PAT or GitHub App. If set, BaseURL
+// points it at the Config's GitHub Enterprise Server instance and PageSize sets its page size. LoadCredentials must be
+// called first.
+func (c *Config) GetGitHubinator(gh GitHubinator) GitHubinator {
+	if c.BaseURL != "" {
+		gh = gh.WithBaseURL(c.BaseURL)
+	}
+
+	if c.PageSize > 0 {
+		gh = gh.WithPageSize(c.PageSize)
+	}
+
+	if c.AppAuth != nil {
+		return gh.WithAppAuth(c.AppAuth.AppID, c.AppAuth.InstallationID, c.AppAuth.PrivateKey)
+	}
+
+	if len(c.PATs) > 1 {
+		return gh.WithTokens(c.PATs...)
+	}
+
+	return gh.WithToken(c.PAT)
+}
+
+// LoadCredentials loads the GitHub App's private key if AppAuth is set, otherwise the PATEnv or PATFile, and
+// PATFiles. Setting both is an error.
+func (c *Config) LoadCredentials(ctx context.Context) error {
+	if c.AppAuth == nil {
+		return c.LoadPATFile(ctx)
+	}
+
+	if len(c.PATFile) != 0 {
+		return errors.New("patFile and appAuth are mutually exclusive, only one can be set")
+	}
+
+	if len(c.PATFiles) != 0 {
+		return errors.New("patFiles and appAuth are mutually exclusive, only one can be set")
+	}
+
+	if len(c.PATEnv) != 0 {
+		return errors.New("patEnv and appAuth are mutually exclusive, only one can be set")
+	}
+
+	if err := c.AppAuth.Load(); err != nil {
+		return fmt.Errorf("unable to load appAuth: %w", err)
+	}
+
+	return nil
+}
+
+// LoadPATFile reads the Config's PATEnv or PATFile, and PATFiles, into the PATs field. The first PAT is also set in
+// the PAT field.
+func (c *Config) LoadPATFile(ctx context.Context) error {
+	c.PATs = []string{}
+
+	if len(c.PATEnv) != 0 || len(c.PATFile) != 0 {
+		pat, err := readSecret("pat", c.PATEnv, c.PATFile)
+		if err != nil {
+			return err
+		}
+
+		c.PATs = append(c.PATs, pat)
+	} else if len(c.PATFiles) == 0 {
+		return errors.New("pat file cannot be empty")
+	}
+
+	for _, f := range c.PATFiles {
+		pat, err := ReadFirstLineFromFile(f)
+		if err != nil {
+			return fmt.Errorf("unable to read PAT from pat file %s: %w", f, err)
+		}
+
+		c.PATs = append(c.PATs, pat)
+	}
+
+	c.PAT = c.PATs[0]
+
+	return nil
+}
+
+// checkUser ensures the PAT, or the GitHub App's installation token, belongs to the configured User.
+func (c *Config) checkUser(ctx context.Context, gh GitHubinator) error {
+	var user string
+
+	if err := c.ValidationRetry.Do(ctx, func() error {
+		var err error
+
+		user, err = gh.WhoAmI(ctx)
+
+		return err
+	}); err != nil {
+		return fmt.Errorf("unable to validate pat: %w", err)
+	}
+
+	if user != c.User {
+		return fmt.Errorf("configured user '%s' does not match PAT user '%s'", user, c.User)
+	}
+
+	return nil
+}
+
+// ValidateOptions controls which checks are performed by Config.Validate.
+type ValidateOptions struct {
+	// SkipEmailConnection skips the test connection to the SMTP service. The email config is still checked for
+	// missing or invalid fields.
+	SkipEmailConnection bool
+	// Offline skips every check which needs GitHub or the SMTP service, such as checking the PAT and that the watched
+	// repositories exist, so configs can be linted without network access. Local checks, such as parsing selectors
+	// and regexes, are still performed, and credentials are still loaded.
+	Offline bool
+}
+
+// DefaultValidateOptions performs every check.
+var DefaultValidateOptions = ValidateOptions{
+	SkipEmailConnection: false,
+	Offline:             false,
+}
+
+// Validate ensures that the Config struct is populated correctly. If a field is not properly set, an error is
+// returned explaining why. Referenced values, such as the watched repositories, are checked with GitHub and the SMTP
+// service. If no ValidateOptions are provided, then DefaultValidateOptions are used. Only the first ValidateOptions
+// provided to the function will be recognized, the rest will be ignored.
+func (c *Config) Validate(ctx context.Context, gh GitHubinator, e Emailinator, _vo ...*ValidateOptions) error {
+	vo := &DefaultValidateOptions
+
+	if len(_vo) >= 1 {
+		vo = _vo[0]
+	}
+
+	if len(c.User) == 0 {
+		return errors.New("user cannot be empty")
+	}
+
+	if err := c.LoadCredentials(ctx); err != nil {
+		return err
+	}
+
+	if len(c.Watches) == 0 {
+		return errors.New("expected at least one watch")
+	}
+
+	if c.Interval <= 0 {
+		return fmt.Errorf("interval must be greater than zero '%s'", c.Interval)
+	}
+
+	if err := c.checkWatchNames(); err != nil {
+		return err
+	}
+
+	if err := c.checkAllowedActions(); err != nil {
+		return err
+	}
+
+	if err := c.ExpandMatchers(); err != nil {
+		return err
+	}
+
+	if err := c.PopulateSelectorAliases(); err != nil {
+		return err
+	}
+
+	if err := c.PopulateAgeBuckets(); err != nil {
+		return err
+	}
+
+	if err := c.checkBaseURL(); err != nil {
+		return err
+	}
+
+	if c.PageSize < 0 {
+		return fmt.Errorf("pageSize cannot be negative, got %d", c.PageSize)
 	}
 
-	if len(w.selectors) == 0 && len(w.bodyRegex) == 0 && len(w.RequiredLabels) == 0 && len(w.States) == 0 {
-		return fmt.Errorf("expected at least one filter type")
+	if c.RepoConcurrency < 0 {
+		return fmt.Errorf("repoConcurrency cannot be negative, got %d", c.RepoConcurrency)
 	}
 
-	for _, r := range w.Repositories {
-		if err := gh.CheckRepository(ctx, r); err != nil {
-			return fmt.Errorf("unable to validate repository %+v: %w", r, err)
-		}
+	if c.ActionConcurrency < 0 {
+		return fmt.Errorf("actionConcurrency cannot be negative, got %d", c.ActionConcurrency)
 	}
 
-	w.selectors = []labels.Selector{}
-	for _, s := range w.Selectors {
-		parsed, err := labels.Parse(s)
-		if err != nil {
-			return fmt.Errorf("unable to parse label selector %+v: %w", s, err)
-		}
+	if c.Jitter < 0 || c.Jitter > 1 {
+		return fmt.Errorf("jitter must be between 0 and 1, got %g", c.Jitter)
+	}
 
-		w.selectors = append(w.selectors, parsed)
+	if err := c.validateStateFile(); err != nil {
+		return err
 	}
 
-	// Do this double loop here so we can test how we handle w.Selectors without needing to also set w.selectors.
-	for _, s := range w.selectors {
-		// The internal selector that is created through labels.Parse will always
-		// return 'true' for selectable here, so ignore it.
-		requirements, _ := s.Requirements()
+	gh = c.GetGitHubinator(gh)
 
-		for _, r := range requirements {
-			if key := r.Key(); !isGitHubItemField(key) {
-				return fmt.Errorf("unknown key '%s' in selector", key)
-			}
+	if !vo.Offline {
+		if err := c.checkUser(ctx, gh); err != nil {
+			return err
 		}
 	}
 
-	w.bodyRegex = []*regexp.Regexp{}
-	for _, r := range w.BodyRegex {
-		compiled, err := regexp.Compile(r)
-		if err != nil {
-			return fmt.Errorf("unable to compile regex '%s': %w'", r, err)
-		}
+	c.Email.retry = c.ValidationRetry
+	c.Email.skipConnection = vo.SkipEmailConnection || vo.Offline
 
-		w.bodyRegex = append(w.bodyRegex, compiled)
-	}
+	emailValidated := false
 
-	w.titleRegex = []*regexp.Regexp{}
-	for _, r := range w.TitleRegex {
-		compiled, err := regexp.Compile(r)
-		if err != nil {
-			return fmt.Errorf("unable to compile regex '%s': '%w'", r, err)
+	for _, w := range c.Watches {
+		w.offline = vo.Offline || !w.IsEnabled()
+		w.repoConcurrency = c.RepoConcurrency
+
+		if err := w.ValidateAndPopulate(ctx, gh); err != nil {
+			return fmt.Errorf("unable to validate watch %+v: %w", w, err)
 		}
 
-		w.titleRegex = append(w.titleRegex, compiled)
-	}
+		if (w.Actions.Email.Enabled || w.Report.Enabled) && !emailValidated {
+			if err := c.Email.Validate(ctx, e); err != nil {
+				return fmt.Errorf("unable to validate email sender confg: %w", err)
+			}
 
-	for _, s := range w.States {
-		switch githubv4.IssueState(s) {
-		case githubv4.IssueStateClosed, githubv4.IssueStateOpen:
-			continue
-		default:
-			return fmt.Errorf("unknown issue state %s", s)
+			emailValidated = true
 		}
 	}
 
-	if err := w.Actions.Validate(ctx); err != nil {
-		return err
-	}
-
 	return nil
 }
 
-// GetIssueFilter returns a GitHubIssueFilter based on the Watch's specified SearchLabels and States. It can
-// be passed to a GitHubinator for listing issues that match the Watch.
-func (w *Watch) GetIssueFilter() *GitHubIssueFilter {
-	return &GitHubIssueFilter{
-		Labels: w.SearchLabels,
-		States: w.States,
+// DefaultActionConcurrency is the default maximum number of actions performed at once, across every watch.
+const DefaultActionConcurrency = 4
+
+// GetActionConcurrency returns the maximum number of actions performed at once, across every watch.
+func (c *Config) GetActionConcurrency() int {
+	if c.ActionConcurrency <= 0 {
+		return DefaultActionConcurrency
 	}
-}
 
-// GetMatchinator returns a Matchinator based on the Watch's specified BodyRegex, Selectors, and RequiredLabels fields.
-// It can be passed to a GitHubinator for listing issues that match the Watch.
-func (w *Watch) GetMatchinator() Matchinator {
-	return NewMatchinator().
-		WithBodyRegexes(w.bodyRegex...).
-		WithTitleRegexes(w.titleRegex...).
-		WithSelectors(w.selectors...).
-		WithRequiredLabels(w.RequiredLabels...)
+	return c.ActionConcurrency
 }
 
-func (w *Watch) GetActioninator(gh GitHubinator, emailinator Emailinator) Actioninator {
-	a := NewActioninator()
+// validateStateFile ensures the StateFile, if set, can be loaded and is in a directory which exists.
+func (c *Config) validateStateFile() error {
+	if c.StateFile == "" {
+		return nil
+	}
 
-	if w.Actions.Subscribe.Enabled {
-		a = a.WithAction(NewSubscribeAction(gh))
+	path, err := GetAbsolutePath(c.StateFile)
+	if err != nil {
+		return fmt.Errorf("invalid stateFile: %w", err)
 	}
 
-	if w.Actions.Email.Enabled {
-		a = a.WithAction(NewEmailAction(emailinator, w.Actions.Email.SendTo))
+	if info, err := os.Stat(filepath.Dir(path)); err != nil || !info.IsDir() {
+		return fmt.Errorf("invalid stateFile, directory of %s does not exist", c.StateFile)
 	}
 
-	return a
+	if _, err := NewFileSeenStore(path); err != nil {
+		return fmt.Errorf("invalid stateFile: %w", err)
+	}
+
+	return nil
 }
 
-// NewTestWatch creates a new Watch instance with pre-populated fields. It can be used in unit tests.
-func NewTestWatch() *Watch {
-	return &Watch{
-		Name: "name",
-		Repositories: []GitHubRepository{{
-			Owner: "owner",
-			Name:  "repo",
-		}},
-		Selectors:      []string{"type==issue"},
-		RequiredLabels: []string{"a/requiredLabel"},
-		SearchLabels:   []string{"a/searchLabel"},
-		BodyRegex:      []string{".*"},
-		TitleRegex:     []string{".*"},
-		States:         []string{"OPEN"},
-		Actions: ActionConfig{
-			Subscribe: SubscribeActionConfig{
-				Enabled: true,
-			},
-			Email: EmailActionConfig{
-				Enabled: true,
-				SendTo:  "test@example.com",
-			},
-		},
+// GetInitialScan returns if the given Watch scans for items as soon as the config is loaded. The Watch's InitialScan
+// takes precedence over the Config's, and if neither is set, true is returned.
+func (c *Config) GetInitialScan(w *Watch) bool {
+	switch {
+	case w.InitialScan != nil:
+		return *w.InitialScan
+	case c.InitialScan != nil:
+		return *c.InitialScan
+	default:
+		return true
 	}
 }
 
-// Config specifies the list of Watches to create.
-type Config struct {
-	// User is the GitHub username the watch will apply to.
-	User string `yaml:"user"`
-	// PATFile is the file containing the user's PAT used for authentication.
-	PATFile string `yaml:"patFile"`
-	// PAT is the PAT contained in the PATFile.
-	PAT string `yaml:"-"`
-	// Interval used to determine when to update watches.
-	Interval time.Duration `yaml:"interval"`
-	// Email sender configuration for email action.
-	Email EmailConfig `yaml:"email"`
-	// Watches is a list of Watch definitions.
-	Watches []*Watch `yaml:"watches"`
+// GetInterval returns the interval the given Watch is polled on. The Watch's Interval takes precedence over the
+// Config's.
+func (c *Config) GetInterval(w *Watch) time.Duration {
+	if w.Interval > 0 {
+		return w.Interval
+	}
+
+	return c.Interval
 }
 
-func (c *Config) LogValue() slog.Value {
-	watchValues := []slog.Attr{}
-	for _, watch := range c.Watches {
-		watchValues = append(watchValues, slog.Any(watch.Name, watch.LogValue()))
+// GetWatch returns a pointer to the Watch with the given name. If the Watch is not present in the config, nil
+// is returned.
+func (c *Config) GetWatch(name string) *Watch {
+	for _, w := range c.Watches {
+		if w.Name == name {
+			return w
+		}
 	}
 
-	return slog.GroupValue(
-		slog.String("user", c.User),
-		slog.Duration("interval", c.Interval),
-		slog.Any("email", c.Email.LogValue()),
-		slog.Any("watches", watchValues),
-	)
+	return nil
 }
 
-// LoadPATFile reads the Config's PATFile into the PAT field.
-func (c *Config) LoadPATFile(ctx context.Context) error {
-	if len(c.PATFile) == 0 {
-		return errors.New("pat file cannot be empty")
+// envVarName matches the valid names of environment variables referenced in a config.
+var envVarName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ExpandEnv replaces references to environment variables in the given string with their values, using lookup to get
+// them. '${VAR}' is replaced with the value of VAR, and is an error if VAR isn't set. '${VAR:-default}' is replaced
+// with 'default' if VAR is unset or empty. '$$' is replaced with a literal '$', while any other '$', such as the end
+// of a regex, is left as is.
+func ExpandEnv(s string, lookup func(string) (string, bool)) (string, error) {
+	if !strings.Contains(s, "$") {
+		return s, nil
 	}
 
-	pat, err := ReadFirstLineFromFile(c.PATFile)
-	if err != nil {
-		return fmt.Errorf("unable to read PAT from pat file %s: %w", c.PATFile, err)
+	b := strings.Builder{}
+
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 == len(s) {
+			b.WriteByte(s[i])
+
+			continue
+		}
+
+		switch s[i+1] {
+		case '$':
+			b.WriteByte('$')
+			i++
+		case '{':
+			end := strings.IndexByte(s[i+2:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated environment variable reference in '%s'", s)
+			}
+
+			name, def, hasDef := strings.Cut(s[i+2:i+2+end], ":-")
+			if !envVarName.MatchString(name) {
+				return "", fmt.Errorf("invalid environment variable name '%s' in '%s'", name, s)
+			}
+
+			value, ok := lookup(name)
+			if hasDef && value == "" {
+				value = def
+			} else if !ok {
+				return "", fmt.Errorf("environment variable '%s' is not set and has no default", name)
+			}
+
+			b.WriteString(value)
+			i += 2 + end
+		default:
+			b.WriteByte('$')
+		}
 	}
 
-	c.PAT = pat
+	return b.String(), nil
+}
+
+// expandEnvInNode calls ExpandEnv on every scalar value in the given YAML node, so environment variables can be used
+// in any field of a config. Mapping keys aren't expanded. Plain scalars whose value changed have their tag reset, so
+// their type is resolved again and, for instance, '${PAGE_SIZE:-50}' can be decoded into an int.
+func expandEnvInNode(n *yaml.Node, lookup func(string) (string, bool)) error {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		value, err := ExpandEnv(n.Value, lookup)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", n.Line, err)
+		}
+
+		// Quoted and explicitly tagged scalars keep their tag.
+		if value != n.Value && n.Style == 0 {
+			n.Tag = ""
+		}
+
+		n.Value = value
+	case yaml.MappingNode:
+		for i := 1; i < len(n.Content); i += 2 {
+			if err := expandEnvInNode(n.Content[i], lookup); err != nil {
+				return err
+			}
+		}
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, c := range n.Content {
+			if err := expandEnvInNode(c, lookup); err != nil {
+				return err
+			}
+		}
+	}
 
 	return nil
 }
 
-// Validate ensures that the Config struct is populated correctly. If a field is not properly set, an error is
-// returned explaining why.
-func (c *Config) Validate(ctx context.Context, gh GitHubinator, e Emailinator) error {
-	if len(c.User) == 0 {
-		return errors.New("user cannot be empty")
+// ConfigDirBaseFile is the file in a config directory which holds every field of the Config other than its watches,
+// see NewConfigFromFile.
+const ConfigDirBaseFile = "config.yaml"
+
+// decodeConfigFile unmarshals the YAML file at the given path into out, after expanding references to environment
+// variables in its values, see ExpandEnv. The names of the top-level keys set in the file are returned.
+func decodeConfigFile(path string, out any) ([]string, error) {
+	configBody, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := c.LoadPATFile(ctx); err != nil {
-		return err
+	doc := &yaml.Node{}
+
+	if err := yaml.Unmarshal(configBody, doc); err != nil {
+		return nil, err
 	}
 
-	if len(c.Watches) == 0 {
-		return errors.New("expected at least one watch")
+	if err := expandEnvInNode(doc, os.LookupEnv); err != nil {
+		return nil, err
 	}
 
-	if c.Interval <= 0 {
-		return fmt.Errorf("interval must be greater than zero '%s'", c.Interval)
+	// An empty file doesn't have a document to decode.
+	if doc.Kind == 0 {
+		return []string{}, nil
 	}
 
-	gh = gh.WithToken(c.PAT)
-	user, err := gh.WhoAmI(ctx)
+	if err := doc.Decode(out); err != nil {
+		return nil, err
+	}
 
-	if err != nil {
-		return fmt.Errorf("unable to validate pat: %w", err)
+	keys := []string{}
+
+	if root := doc.Content[0]; root.Kind == yaml.MappingNode {
+		for i := 0; i < len(root.Content); i += 2 {
+			keys = append(keys, root.Content[i].Value)
+		}
 	}
 
-	if user != c.User {
-		return fmt.Errorf("configured user '%s' does not match PAT user '%s'", user, c.User)
+	return keys, nil
+}
+
+// listConfigDir returns the paths of the config files in the given directory, which are the files with a '.yaml'
+// extension that aren't hidden, sorted by name.
+func listConfigDir(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
 	}
 
-	emailValidated := false
+	paths := []string{}
 
-	for _, w := range c.Watches {
-		if err := w.ValidateAndPopulate(ctx, gh); err != nil {
-			return fmt.Errorf("unable to validate watch %+v: %w", w, err)
+	for _, entry := range entries {
+		if !isConfigDirFile(entry.Name()) {
+			continue
 		}
 
-		if w.Actions.Email.Enabled && !emailValidated {
-			if err := c.Email.Validate(ctx, e); err != nil {
-				return fmt.Errorf("unable to validate email sender confg: %w", err)
-			}
+		path := filepath.Join(dir, entry.Name())
 
-			emailValidated = true
+		// Entries may be symlinks, such as in configmaps mounted by Kubernetes, so they're followed.
+		if info, err := os.Stat(path); err != nil || info.IsDir() {
+			continue
 		}
+
+		paths = append(paths, path)
 	}
 
-	return nil
+	return paths, nil
 }
 
-// GetWatch returns a pointer to the Watch with the given name. If the Watch is not present in the config, nil
-// is returned.
-func (c *Config) GetWatch(name string) *Watch {
-	for _, w := range c.Watches {
-		if w.Name == name {
-			return w
+// isConfigDirFile returns if the file with the given name is loaded from a config directory.
+func isConfigDirFile(name string) bool {
+	return filepath.Ext(name) == ".yaml" && !strings.HasPrefix(name, ".")
+}
+
+// newConfigFromDir loads the config files in the given directory. Every field is taken from the ConfigDirBaseFile,
+// while the other files may only set watches, which are appended to the base file's in the order of the files' names.
+func newConfigFromDir(dir string) (*Config, error) {
+	c := &Config{}
+
+	basePath := filepath.Join(dir, ConfigDirBaseFile)
+	if _, err := decodeConfigFile(basePath, c); err != nil {
+		return nil, fmt.Errorf("unable to load base config file %s: %w", basePath, err)
+	}
+
+	paths, err := listConfigDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range paths {
+		if path == basePath {
+			continue
+		}
+
+		watches := struct {
+			Watches []*Watch `yaml:"watches"`
+		}{}
+
+		keys, err := decodeConfigFile(path, &watches)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load config file %s: %w", path, err)
+		}
+
+		for _, k := range keys {
+			if k != "watches" {
+				return nil, fmt.Errorf(
+					"config file %s sets '%s', but only %s can set fields other than watches",
+					path, k, ConfigDirBaseFile,
+				)
+			}
 		}
+
+		c.Watches = append(c.Watches, watches.Watches...)
 	}
 
-	return nil
+	return c, nil
 }
 
-// NewConfigFromFile opens the given path and attempts to unmarshal it into a Config struct. Config.Validate is not
-// called and still needs to be executed by the user.
-func NewConfigFromFile(path string) (*Config, error) {
+// NewConfigFromFile opens the given path and attempts to unmarshal it into a Config struct, applying the profile with
+// the given name if it isn't empty. References to environment variables in the config's values are expanded first,
+// see ExpandEnv. Config.Validate is not called and still needs to be executed by the user.
+//
+// If the path is a directory, every '.yaml' file in it is loaded. The ConfigDirBaseFile sets the fields of the
+// Config, and the watches of the other files, which may not set anything else, are appended to its watches in the
+// order of the files' names. A profile's watches replace all of them.
+func NewConfigFromFile(path string, profile string) (*Config, error) {
 	absPath, err := GetAbsolutePath(path)
 	if err != nil {
 		return nil, err
 	}
 
-	configBody, err := os.ReadFile(absPath)
+	info, err := os.Stat(absPath)
 	if err != nil {
 		return nil, err
 	}
 
 	c := &Config{}
 
-	err = yaml.Unmarshal(configBody, c)
+	if info.IsDir() {
+		c, err = newConfigFromDir(absPath)
+	} else {
+		_, err = decodeConfigFile(absPath, c)
+	}
+
 	if err != nil {
 		return nil, err
 	}
 
+	if err := c.ApplyProfile(profile); err != nil {
+		return nil, err
+	}
+
 	return c, nil
 }
 
@@ -557,6 +3019,14 @@ func NewTestConfig() (*Config, func(), error) {
 			Password:     "password",
 			Host:         "my.email.server.com",
 			Port:         587,
+			retry: RetryConfig{
+				Attempts: DefaultRetryAttempts,
+				Backoff:  time.Millisecond,
+			},
+		},
+		ValidationRetry: RetryConfig{
+			Attempts: DefaultRetryAttempts,
+			Backoff:  time.Millisecond,
 		},
 		Watches: []*Watch{NewTestWatch()},
 	}, cleanup, nil
@@ -568,6 +3038,9 @@ type Configinator interface {
 	// If a change occurs, the new config will be validated and sent to the given callback.
 	// If an error occurs, the watch stops and the error is returned.
 	Watch(ctx context.Context, path string, callback func(*Config), gh GitHubinator, e Emailinator) error
+
+	// WithProfile sets the name of the profile applied to each loaded config, see Config.ApplyProfile.
+	WithProfile(profile string) Configinator
 }
 
 // NewConfiginator creates a new Configinator instance based on the packages internal implementation.
@@ -579,29 +3052,94 @@ func NewConfiginator(logger *slog.Logger) Configinator {
 
 // configinator implements the Configinator interface.
 type configinator struct {
-	logger *slog.Logger
+	logger  *slog.Logger
+	profile string
 }
 
-// setupWatcher creates a new fsnotify.Watcher to watch for changes to the given path.
-func (c *configinator) setupWatcher(path string) (*fsnotify.Watcher, error) {
+func (c *configinator) WithProfile(profile string) Configinator {
+	c.profile = profile
+
+	return c
+}
+
+// setupWatcher creates a new fsnotify.Watcher to watch for changes to the given path. The directory containing the path
+// is watched rather than the path itself, as editors and Kubernetes replace files by renaming a new file over them,
+// which would drop a watch on the file. If the path is a symlink into another directory, that directory is watched too,
+// see watchRealDir. If the path is a config directory, only the directory is watched.
+func (c *configinator) setupWatcher(path string, isDir bool) (*fsnotify.Watcher, error) {
 	w, err := fsnotify.NewWatcher()
 	if err != nil {
 		return nil, err
 	}
 
-	err = w.Add(path)
-	if err != nil {
+	if isDir {
+		if err := w.Add(path); err != nil {
+			w.Close()
+
+			return nil, err
+		}
+
+		return w, nil
+	}
+
+	if err := w.Add(filepath.Dir(path)); err != nil {
+		w.Close()
+
 		return nil, err
 	}
 
+	c.watchRealDir(w, path, resolveConfigPath(path))
+
 	return w, nil
 }
 
+// resolveConfigPath returns the path the given config path points to after following symlinks, or an empty string if
+// it doesn't exist.
+func resolveConfigPath(path string) string {
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return ""
+	}
+
+	return realPath
+}
+
+// watchRealDir adds the directory containing realPath, which the config path resolves to, to the watcher if it
+// differs from the config path's directory, so edits to the target of a symlink are seen. The directory may be
+// removed later, such as when Kubernetes swaps a mounted configmap, so failures are only logged.
+func (c *configinator) watchRealDir(w *fsnotify.Watcher, path string, realPath string) {
+	if realPath == "" || filepath.Dir(realPath) == filepath.Dir(path) {
+		return
+	}
+
+	if err := w.Add(filepath.Dir(realPath)); err != nil {
+		c.logger.Warn("unable to watch directory of config file", "path", realPath, LogKeyError, err)
+	}
+}
+
+// isConfigChange returns if the given event in one of the watched directories changes the config at path, which
+// currently resolves to newRealPath and previously resolved to realPath. This is the case if the config file is
+// written or created, including by being renamed into place, or if a symlink along the path was swapped to point
+// somewhere else.
+func isConfigChange(event fsnotify.Event, path string, realPath string, newRealPath string) bool {
+	if newRealPath == "" {
+		return false
+	}
+
+	if newRealPath != realPath {
+		return true
+	}
+
+	name := filepath.Clean(event.Name)
+
+	return (name == path || name == realPath) && (event.Op.Has(fsnotify.Write) || event.Op.Has(fsnotify.Create))
+}
+
 // loadConfig attempts to unmarshal and validate the config at the given path.
 func (c *configinator) loadConfig(ctx context.Context, gh GitHubinator, e Emailinator, path string) (*Config, error) {
 	MetricConfigLoadTotal.Inc()
 
-	config, err := NewConfigFromFile(path)
+	config, err := NewConfigFromFile(path, c.profile)
 	if err != nil {
 		MetricConfigLoadErrorTotal.Inc()
 
@@ -618,8 +3156,23 @@ func (c *configinator) loadConfig(ctx context.Context, gh GitHubinator, e Emaili
 	return config, nil
 }
 
+// isConfigDirChange returns if the given event in a config directory changes the config, which is the case if a config
+// file is written, created, removed or renamed, or if Kubernetes swapped the '..data' symlink of a mounted configmap.
+func isConfigDirChange(event fsnotify.Event) bool {
+	name := filepath.Base(event.Name)
+	if name == "..data" {
+		return event.Op.Has(fsnotify.Create)
+	}
+
+	return isConfigDirFile(name) && (event.Op.Has(fsnotify.Write) || event.Op.Has(fsnotify.Create) ||
+		event.Op.Has(fsnotify.Remove) || event.Op.Has(fsnotify.Rename))
+}
+
 // Watch will continually watch for new changes to the Config located at the given path. If a change occurs,
-// the Config will be unmarshalled, validated, and passed to the given callback.
+// the Config will be unmarshalled, validated, and passed to the given callback. If the path is a directory, a change to
+// any of its config files reloads them all, see NewConfigFromFile. Files which are replaced rather than
+// written to, such as by editors saving atomically or by Kubernetes updating a mounted configmap, are reloaded once the
+// new file is in place.
 func (c *configinator) Watch(
 	ctx context.Context, path string, callback func(*Config), gh GitHubinator, e Emailinator,
 ) error {
@@ -628,12 +3181,22 @@ func (c *configinator) Watch(
 		return err
 	}
 
-	w, err := c.setupWatcher(absPath)
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return err
+	}
+
+	isDir := info.IsDir()
+
+	w, err := c.setupWatcher(absPath, isDir)
 	if err != nil {
 		return fmt.Errorf("unable to setup fsnotify watcher for '%s': %w", absPath, err)
 	}
 	defer w.Close()
 
+	// The path is resolved before loading, so a change made while loading is picked up by the first event.
+	realPath := resolveConfigPath(absPath)
+
 	c.logger.Debug("attempting initial load of config file")
 
 	config, err := c.loadConfig(ctx, gh, e, absPath)
@@ -662,12 +3225,24 @@ func (c *configinator) Watch(
 				continue
 			}
 
-			// Filter out non-write events
-			if !event.Op.Has(fsnotify.Write) {
-				continue
+			if isDir {
+				if !isConfigDirChange(event) {
+					continue
+				}
+			} else {
+				newRealPath := resolveConfigPath(absPath)
+
+				if !isConfigChange(event, absPath, realPath, newRealPath) {
+					continue
+				}
+
+				if newRealPath != realPath {
+					c.watchRealDir(w, absPath, newRealPath)
+					realPath = newRealPath
+				}
 			}
 
-			c.logger.Info("config file changed")
+			c.logger.Info("config file changed", "event", event.String())
 
 			config, err := c.loadConfig(ctx, gh, e, absPath)
 			if err != nil {
@@ -3,10 +3,14 @@ package pkg
 import (
 	"context"
 	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/shurcooL/githubv4"
+	"github.com/wneessen/go-mail"
 	"gopkg.in/yaml.v3"
 	"gotest.tools/v3/assert"
 	"gotest.tools/v3/assert/cmp"
@@ -118,7 +122,7 @@ func TestConfigValidateChecksRequiredKeysNotEmpty(t *testing.T) {
 	)
 }
 
-func TestConfigValidateEnsuresEmailPortIsTLSOrSSL(t *testing.T) {
+func TestConfigValidateChecksEmailPortAndTLSPolicy(t *testing.T) {
 	ctx := context.Background()
 	gh := NewMockGitHubinator()
 	e := NewMockEmailinator()
@@ -135,9 +139,89 @@ func TestConfigValidateEnsuresEmailPortIsTLSOrSSL(t *testing.T) {
 
 	c.Email.Port = 465
 	assert.NilError(t, c.Validate(ctx, gh, e))
+	assert.Equal(t, c.Email.getTLSPolicy(), EmailTLSPolicySSL)
 
-	c.Email.Port = 123
-	assert.ErrorContains(t, c.Validate(ctx, gh, e), "port must be")
+	// Ports other than 587 and 465 have no default policy.
+	c.Email.Port = 25
+	assert.ErrorContains(t, c.Validate(ctx, gh, e), "tlsPolicy must be set for port 25")
+
+	c.Email.TLSPolicy = "STARTTLS"
+	assert.NilError(t, c.Validate(ctx, gh, e))
+	assert.Equal(t, c.Email.getTLSPolicy(), EmailTLSPolicySTARTTLS)
+
+	c.Email.TLSPolicy = "opportunistic"
+	assert.NilError(t, c.Validate(ctx, gh, e))
+	assert.Equal(t, c.Email.getTLSPolicy(), EmailTLSPolicyOpportunistic)
+
+	c.Email.TLSPolicy = "ssl-please"
+	assert.ErrorContains(
+		t, c.Validate(ctx, gh, e), "tlsPolicy must be one of none, opportunistic, starttls, tls or ssl",
+	)
+
+	c.Email.Port = 70000
+	assert.ErrorContains(t, c.Validate(ctx, gh, e), "port must be between 1 and 65535")
+}
+
+func TestEmailinatorRequiresSTARTTLS(t *testing.T) {
+	cfg := &EmailConfig{Username: "me@example.com", Password: "1234", Host: "smtp.example.com", Port: 25}
+
+	for policy, expected := range map[string]mail.TLSPolicy{
+		EmailTLSPolicyOpportunistic: mail.TLSOpportunistic,
+		EmailTLSPolicySTARTTLS:      mail.TLSMandatory,
+		EmailTLSPolicyTLS:           mail.TLSMandatory,
+		EmailTLSPolicyNone:          mail.NoTLS,
+	} {
+		cfg.TLSPolicy = policy
+
+		c, err := NewEmailinator(NewLogger()).WithConfig(cfg).(*emailinator).newClient()
+		assert.NilError(t, err)
+		assert.Equal(t, c.TLSPolicy(), expected.String(), policy)
+	}
+}
+
+func TestConfigValidateChecksEmailAuthMechanism(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	e := NewMockEmailinator()
+	c, cleanup, err := NewTestConfig()
+
+	assert.NilError(t, err)
+
+	defer cleanup()
+
+	assert.NilError(t, c.Validate(ctx, gh, e))
+	assert.Equal(t, c.Email.getAuthType(), mail.SMTPAuthPlain)
+
+	c.Email.AuthMechanism = "xoauth2"
+	assert.NilError(t, c.Validate(ctx, gh, e))
+	assert.Equal(t, c.Email.getAuthType(), mail.SMTPAuthXOAUTH2)
+
+	// Access tokens are read again for each connection, so refreshed tokens are picked up without a reload.
+	assert.NilError(t, os.WriteFile(c.Email.PasswordFile, []byte("refreshed-token\n"), 0o600))
+
+	password, err := c.Email.getPassword()
+	assert.NilError(t, err)
+	assert.Equal(t, password, "refreshed-token")
+
+	c.Email.AuthMechanism = "NTLM"
+	assert.ErrorContains(
+		t, c.Validate(ctx, gh, e), "authMechanism must be one of PLAIN, LOGIN, CRAM-MD5, XOAUTH2 or NONE",
+	)
+
+	// Other passwords are only read when the config is validated.
+	c.Email.AuthMechanism = "PLAIN"
+	assert.NilError(t, c.Validate(ctx, gh, e))
+	assert.NilError(t, os.WriteFile(c.Email.PasswordFile, []byte("changed\n"), 0o600))
+
+	password, err = c.Email.getPassword()
+	assert.NilError(t, err)
+	assert.Equal(t, password, "refreshed-token")
+
+	// Relays which don't authenticate don't need a password.
+	c.Email.AuthMechanism = "NONE"
+	c.Email.PasswordFile = ""
+	assert.NilError(t, c.Validate(ctx, gh, e))
+	assert.Equal(t, c.Email.getAuthType(), EmailAuthMechanismNone)
 }
 
 func TestConfigValidatesCanCreateNewEmail(t *testing.T) {
@@ -172,6 +256,91 @@ func TestConfigValidateChecksIntervalIsGreaterThanZero(t *testing.T) {
 	assert.ErrorContains(t, c.Validate(ctx, gh, e), "must be greater than zero")
 }
 
+func TestConfigValidateChecksStateFile(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	e := NewMockEmailinator()
+	c, cleanup, err := NewTestConfig()
+	assert.NilError(t, err)
+
+	defer cleanup()
+
+	dir := t.TempDir()
+
+	c.StateFile = filepath.Join(dir, "state.json")
+	assert.NilError(t, c.Validate(ctx, gh, e))
+
+	c.StateFile = filepath.Join(dir, "missing", "state.json")
+	assert.ErrorContains(t, c.Validate(ctx, gh, e), "invalid stateFile, directory of")
+
+	c.StateFile = filepath.Join(dir, "state.json")
+	assert.NilError(t, os.WriteFile(c.StateFile, []byte("not json"), 0o600))
+	assert.ErrorContains(t, c.Validate(ctx, gh, e), "invalid stateFile: unable to parse seen store")
+}
+
+func TestConfigValidateChecksJitter(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	e := NewMockEmailinator()
+	c, cleanup, err := NewTestConfig()
+
+	assert.NilError(t, err)
+
+	defer cleanup()
+
+	c.Jitter = 0.25
+	assert.NilError(t, c.Validate(ctx, gh, e))
+
+	c.Jitter = -0.1
+	assert.ErrorContains(t, c.Validate(ctx, gh, e), "jitter must be between 0 and 1, got -0.1")
+
+	c.Jitter = 1.5
+	assert.ErrorContains(t, c.Validate(ctx, gh, e), "jitter must be between 0 and 1, got 1.5")
+}
+
+func TestConfigValidateChecksRepoConcurrency(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	e := NewMockEmailinator()
+	c, cleanup, err := NewTestConfig()
+
+	assert.NilError(t, err)
+
+	defer cleanup()
+
+	c.RepoConcurrency = 2
+	assert.NilError(t, c.Validate(ctx, gh, e))
+	assert.Equal(t, c.Watches[0].getRepoConcurrency(), 2)
+
+	c.RepoConcurrency = 0
+	assert.NilError(t, c.Validate(ctx, gh, e))
+	assert.Equal(t, c.Watches[0].getRepoConcurrency(), DefaultRepoConcurrency)
+
+	c.RepoConcurrency = -1
+	assert.ErrorContains(t, c.Validate(ctx, gh, e), "repoConcurrency cannot be negative")
+}
+
+func TestConfigValidateChecksActionConcurrency(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	e := NewMockEmailinator()
+	c, cleanup, err := NewTestConfig()
+
+	assert.NilError(t, err)
+
+	defer cleanup()
+
+	assert.NilError(t, c.Validate(ctx, gh, e))
+	assert.Equal(t, c.GetActionConcurrency(), DefaultActionConcurrency)
+
+	c.ActionConcurrency = 1
+	assert.NilError(t, c.Validate(ctx, gh, e))
+	assert.Equal(t, c.GetActionConcurrency(), 1)
+
+	c.ActionConcurrency = -1
+	assert.ErrorContains(t, c.Validate(ctx, gh, e), "actionConcurrency cannot be negative")
+}
+
 func TestConfigValidateChecksValuesWithGitHub(t *testing.T) {
 	ctx := context.Background()
 	gh := NewMockGitHubinator()
@@ -224,6 +393,52 @@ func TestConfigValidateLoadsPATFromFile(t *testing.T) {
 	assert.Equal(t, c.PAT, "mycoolpat")
 }
 
+func TestConfigValidateLoadsSecretsFromEnv(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	e := NewMockEmailinator()
+	c, cleanup, err := NewTestConfig()
+
+	assert.NilError(t, err)
+
+	defer cleanup()
+
+	assert.NilError(t, os.WriteFile(c.PATFile, []byte("filepat"), 0600))
+	assert.NilError(t, os.WriteFile(c.Email.PasswordFile, []byte("filepassword"), 0600))
+
+	c.PATEnv = "WATCHINATOR_TEST_PAT"
+	c.Email.PasswordEnv = "WATCHINATOR_TEST_PASSWORD"
+	c.Watches[0].Actions.Email.Enabled = true
+
+	t.Setenv(c.PATEnv, "envpat\n")
+	t.Setenv(c.Email.PasswordEnv, "envpassword")
+
+	assert.NilError(t, c.Validate(ctx, gh, e))
+	assert.Equal(t, c.PAT, "envpat")
+	assert.Equal(t, c.Email.Password, "envpassword")
+
+	// Empty variables fall back to the files.
+	t.Setenv(c.PATEnv, "")
+	t.Setenv(c.Email.PasswordEnv, "")
+
+	assert.NilError(t, c.Validate(ctx, gh, e))
+	assert.Equal(t, c.PAT, "filepat")
+	assert.Equal(t, c.Email.Password, "filepassword")
+
+	c.PATFile = ""
+	assert.ErrorContains(
+		t, c.Validate(ctx, gh, e),
+		"environment variable WATCHINATOR_TEST_PAT holding the pat is empty and no pat file is set",
+	)
+
+	t.Setenv(c.PATEnv, "envpat")
+	c.Email.PasswordFile = ""
+	assert.ErrorContains(
+		t, c.Validate(ctx, gh, e),
+		"environment variable WATCHINATOR_TEST_PASSWORD holding the password is empty and no password file is set",
+	)
+}
+
 func TestWatchValidateChecksSelectorsAreValid(t *testing.T) {
 	ctx := context.Background()
 	gh := NewMockGitHubinator()
@@ -383,3 +598,1565 @@ func TestConfiginatorCanWatchForChanges(t *testing.T) {
 
 	assert.ErrorIs(t, err, context.Canceled)
 }
+
+func TestConfiginatorReloadsReplacedFiles(t *testing.T) {
+	gh := NewMockGitHubinator()
+	e := NewMockEmailinator()
+
+	initialConfig, cleanup, err := NewTestConfig()
+	assert.NilError(t, err)
+
+	defer cleanup()
+
+	newConfig, cleanup, err := NewTestConfig()
+	assert.NilError(t, err)
+
+	defer cleanup()
+
+	newConfig.Interval = 2 * time.Hour
+
+	initialConfigYAML, err := yaml.Marshal(initialConfig)
+	assert.NilError(t, err)
+
+	newConfigYAML, err := yaml.Marshal(newConfig)
+	assert.NilError(t, err)
+
+	cases := []struct {
+		name string
+		// setup writes the initial config and returns the path to watch.
+		setup func(dir string) string
+		// replace swaps the initial config for the new one.
+		replace func(dir string)
+	}{
+		{
+			name: "rename",
+			setup: func(dir string) string {
+				assert.NilError(t, os.WriteFile(filepath.Join(dir, "config.yaml"), initialConfigYAML, 0600))
+
+				return filepath.Join(dir, "config.yaml")
+			},
+			replace: func(dir string) {
+				assert.NilError(t, os.WriteFile(filepath.Join(dir, "config.yaml.tmp"), newConfigYAML, 0600))
+				assert.NilError(t, os.Rename(filepath.Join(dir, "config.yaml.tmp"), filepath.Join(dir, "config.yaml")))
+			},
+		},
+		{
+			// Kubernetes mounts configmaps as symlinks into a '..data' directory, which is itself a symlink that is
+			// swapped to a new directory on updates.
+			name: "configmap",
+			setup: func(dir string) string {
+				assert.NilError(t, os.Mkdir(filepath.Join(dir, "..v1"), 0700))
+				assert.NilError(t, os.WriteFile(filepath.Join(dir, "..v1", "config.yaml"), initialConfigYAML, 0600))
+				assert.NilError(t, os.Symlink("..v1", filepath.Join(dir, "..data")))
+				assert.NilError(
+					t, os.Symlink(filepath.Join("..data", "config.yaml"), filepath.Join(dir, "config.yaml")),
+				)
+
+				return filepath.Join(dir, "config.yaml")
+			},
+			replace: func(dir string) {
+				assert.NilError(t, os.Mkdir(filepath.Join(dir, "..v2"), 0700))
+				assert.NilError(t, os.WriteFile(filepath.Join(dir, "..v2", "config.yaml"), newConfigYAML, 0600))
+				assert.NilError(t, os.Symlink("..v2", filepath.Join(dir, "..data_tmp")))
+				assert.NilError(t, os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")))
+				assert.NilError(t, os.RemoveAll(filepath.Join(dir, "..v1")))
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := c.setup(dir)
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+			defer cancel()
+
+			intervals := []time.Duration{}
+
+			err := NewConfiginator(NewLogger()).Watch(ctx, path, func(observedConfig *Config) {
+				intervals = append(intervals, observedConfig.Interval)
+
+				if len(intervals) == 1 {
+					c.replace(dir)
+
+					return
+				}
+
+				cancel()
+			}, gh, e)
+
+			assert.ErrorIs(t, err, context.Canceled)
+			assert.DeepEqual(t, intervals, []time.Duration{time.Hour, 2 * time.Hour})
+		})
+	}
+}
+
+func TestConfiginatorWatchesConfigDirectories(t *testing.T) {
+	gh := NewMockGitHubinator()
+	e := NewMockEmailinator()
+
+	c, cleanup, err := NewTestConfig()
+	assert.NilError(t, err)
+
+	defer cleanup()
+
+	baseYAML, err := yaml.Marshal(c)
+	assert.NilError(t, err)
+
+	extra := NewTestWatch()
+	extra.Name = "extra"
+
+	extraYAML, err := yaml.Marshal(map[string][]*Watch{"watches": {extra}})
+	assert.NilError(t, err)
+
+	dir := t.TempDir()
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, ConfigDirBaseFile), baseYAML, 0600))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	watchNames := [][]string{}
+
+	err = NewConfiginator(NewLogger()).Watch(ctx, dir, func(observedConfig *Config) {
+		names := []string{}
+		for _, w := range observedConfig.Watches {
+			names = append(names, w.Name)
+		}
+
+		watchNames = append(watchNames, names)
+
+		if len(watchNames) == 1 {
+			assert.NilError(t, os.WriteFile(filepath.Join(dir, "extra.yaml"), extraYAML, 0600))
+
+			return
+		}
+
+		cancel()
+	}, gh, e)
+
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// Writing the new file can emit more than one event, so it may be loaded more than once.
+	assert.Assert(t, len(watchNames) >= 2)
+	assert.DeepEqual(t, watchNames[:2], [][]string{{"name"}, {"name", "extra"}})
+}
+
+func TestWatchWithMultipleStatesQueriesAllStatesInOnePass(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	w := NewTestWatch()
+
+	w.States = []string{"OPEN", "CLOSED"}
+	assert.NilError(t, w.ValidateAndPopulate(ctx, gh))
+
+	filters := w.GetIssueFilter(time.Now()).asGithubv4IssueFilters()
+	assert.Assert(t, filters.States != nil)
+	assert.DeepEqual(
+		t, *filters.States, []githubv4.IssueState{githubv4.IssueStateOpen, githubv4.IssueStateClosed},
+	)
+}
+
+func TestWatchValidateChecksSelfAndReposAreExclusive(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	w := NewTestWatch()
+
+	w.Self = true
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "self and repos cannot both be set")
+
+	w.Repositories = []GitHubRepository{}
+	assert.NilError(t, w.ValidateAndPopulate(ctx, gh))
+
+	w.Self = false
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "expected at least one repository")
+}
+
+func TestWatchListRepositoriesExpandsSelf(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	w := NewTestWatch()
+
+	repos, err := w.ListRepositories(ctx, gh)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, repos, w.Repositories)
+	assert.Equal(t, gh.ListViewerRepositoriesRequests, 0)
+
+	w.Self = true
+	w.Repositories = []GitHubRepository{}
+
+	repos, err = w.ListRepositories(ctx, gh)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, repos, gh.ListViewerRepositoriesReturn)
+	assert.Equal(t, gh.ListViewerRepositoriesRequests, 1)
+
+	gh.ListViewerRepositoriesError = errors.New("my test error")
+
+	_, err = w.ListRepositories(ctx, gh)
+	assert.ErrorContains(t, err, "my test error")
+}
+
+func TestRetryConfigRetriesWithBackoff(t *testing.T) {
+	ctx := context.Background()
+	r := RetryConfig{Attempts: 3, Backoff: time.Millisecond}
+
+	calls := 0
+	err := r.Do(ctx, func() error {
+		calls += 1
+		if calls < 3 {
+			return errors.New("my test error")
+		}
+
+		return nil
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, calls, 3)
+
+	calls = 0
+	err = r.Do(ctx, func() error {
+		calls += 1
+
+		return fmt.Errorf("my test error %d", calls)
+	})
+	assert.ErrorContains(t, err, "my test error 3")
+	assert.Equal(t, calls, 3)
+}
+
+func TestConfigValidateRetriesWhoAmI(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	e := NewMockEmailinator()
+	c, cleanup, err := NewTestConfig()
+
+	assert.NilError(t, err)
+
+	defer cleanup()
+
+	gh.WhoAmIError = errors.New("my test error")
+	assert.ErrorContains(t, c.Validate(ctx, gh, e), "my test error")
+	assert.Equal(t, gh.WhoAmIRequests, DefaultRetryAttempts)
+}
+
+func TestWatchValidateAllowsProjectFieldSelectors(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	w := NewTestWatch()
+
+	w.Selectors = []string{"project.3.field.severity in (high, critical)"}
+	assert.NilError(t, w.ValidateAndPopulate(ctx, gh))
+
+	w.Selectors = []string{"project.field.severity==high"}
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "unknown key")
+}
+
+func TestWatchValidateAllowsLabelSelectors(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	w := NewTestWatch()
+
+	w.Selectors = []string{"label.kind/bug=true,!label.wontfix"}
+	assert.NilError(t, w.ValidateAndPopulate(ctx, gh))
+}
+
+func TestWatchValidateRejectsFullBodyScanUnlessAllowed(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	w := NewTestWatch()
+
+	w.SearchLabels = []string{}
+	w.States = []string{}
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "at least 10 open issues across 1 repos")
+
+	w.AllowFullBodyScan = true
+	assert.NilError(t, w.ValidateAndPopulate(ctx, gh))
+
+	w.AllowFullBodyScan = false
+	w.States = []string{"OPEN"}
+	assert.NilError(t, w.ValidateAndPopulate(ctx, gh))
+
+	w.States = []string{}
+	w.BodyRegex = []string{}
+	assert.NilError(t, w.ValidateAndPopulate(ctx, gh))
+
+	// Comments are fetched for each issue too.
+	w.CommentRegex = []string{"reproduced"}
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "at least 10 open issues across 1 repos")
+
+	w.AllowFullBodyScan = true
+	assert.NilError(t, w.ValidateAndPopulate(ctx, gh))
+}
+
+func TestWatchValidateFullBodyScanCountsSelfRepositories(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	w := NewTestWatch()
+
+	w.Self = true
+	w.Repositories = []GitHubRepository{}
+	w.SearchLabels = []string{}
+	w.States = []string{}
+	gh.ListViewerRepositoriesReturn = []GitHubRepository{
+		{Owner: "learnitall", Name: "watchinator"},
+		{Owner: "learnitall", Name: "dotfiles"},
+	}
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "at least 20 open issues across 2 repos")
+}
+
+func TestActionConfigValidateChecksDependencies(t *testing.T) {
+	ctx := context.Background()
+	a := NewTestWatch().Actions
+
+	a.Email.DependsOn = []string{"subscribe"}
+	assert.NilError(t, a.Validate(ctx))
+
+	a.Subscribe.DependsOn = []string{"email"}
+	assert.ErrorContains(t, a.Validate(ctx), "dependency cycle: email -> subscribe -> email")
+
+	a.Subscribe.DependsOn = []string{}
+	a.Email.DependsOn = []string{"comment"}
+	assert.ErrorContains(t, a.Validate(ctx), "depends on unknown or disabled action 'comment'")
+
+	a.Email.DependsOn = []string{"subscribe"}
+	a.Subscribe.Enabled = false
+	assert.ErrorContains(t, a.Validate(ctx), "depends on unknown or disabled action 'subscribe'")
+
+	// A digest is sent after the poll, so nothing can wait for it.
+	a.Email.DependsOn = []string{}
+	a.Subscribe.Enabled = true
+	a.Subscribe.DependsOn = []string{"email"}
+	a.Email.Digest = true
+	assert.ErrorContains(t, a.Validate(ctx), "action 'subscribe' cannot depend on action 'email', which sends a digest")
+
+	// Neither are buffered emails, which are sent in the background.
+	a.Email.Digest = false
+	a.Email.Buffer = NotificationBufferConfig{Size: 10}
+	assert.ErrorContains(t, a.Validate(ctx), "action 'subscribe' cannot depend on action 'email', which is buffered")
+}
+
+func TestActionConfigValidateRejectsConflictingSubscriptionActions(t *testing.T) {
+	ctx := context.Background()
+	a := NewTestWatch().Actions
+
+	a.Unsubscribe.Enabled = true
+	assert.ErrorContains(t, a.Validate(ctx), "only one of the subscribe, unsubscribe and ignore actions")
+
+	a.Subscribe.Enabled = false
+	assert.NilError(t, a.Validate(ctx))
+	assert.DeepEqual(t, a.options(), map[string]ActionOptions{"unsubscribe": {}, "email": a.Email.ActionOptions})
+
+	a.Ignore.Enabled = true
+	assert.ErrorContains(t, a.Validate(ctx), "only one of the subscribe, unsubscribe and ignore actions")
+
+	a.Unsubscribe.Enabled = false
+	assert.NilError(t, a.Validate(ctx))
+	assert.DeepEqual(t, a.options(), map[string]ActionOptions{"ignore": {}, "email": a.Email.ActionOptions})
+}
+
+func TestActionConfigValidateChecksWebhook(t *testing.T) {
+	ctx := context.Background()
+	a := NewTestWatch().Actions
+
+	a.Webhook.Enabled = true
+	assert.ErrorContains(t, a.Validate(ctx), "url cannot be empty if webhook action is enabled")
+
+	a.Webhook.URL = "example.com/hook"
+	assert.ErrorContains(t, a.Validate(ctx), "invalid webhook url, expected an absolute http or https URL")
+
+	a.Webhook.URL = "https://example.com/hook"
+	a.Webhook.RetryStatuses = []string{"5xx", "2xx"}
+	assert.ErrorContains(
+		t, a.Validate(ctx), "invalid webhook statuses: status 200 cannot be both a success and retryable",
+	)
+
+	a.Webhook.RetryStatuses = nil
+	assert.NilError(t, a.Validate(ctx))
+	assert.Equal(t, a.Webhook.Secret, "")
+	assert.DeepEqual(t, a.options()["webhook"], a.Webhook.ActionOptions)
+
+	secretFile := t.TempDir() + "/secret"
+	assert.NilError(t, os.WriteFile(secretFile, []byte("my secret\n"), 0o600))
+
+	a.Webhook.SecretFile = secretFile
+	assert.NilError(t, a.Validate(ctx))
+	assert.Equal(t, a.Webhook.Secret, "my secret")
+
+	t.Setenv("WATCHINATOR_TEST_WEBHOOK_SECRET", "my env secret")
+
+	a.Webhook.SecretEnv = "WATCHINATOR_TEST_WEBHOOK_SECRET"
+	assert.NilError(t, a.Validate(ctx))
+	assert.Equal(t, a.Webhook.Secret, "my env secret")
+
+	a.Webhook.SecretFile = secretFile + ".missing"
+	a.Webhook.SecretEnv = ""
+	assert.ErrorContains(t, a.Validate(ctx), "unable to read webhook secret from file")
+}
+
+func TestActionConfigValidateChecksSlack(t *testing.T) {
+	ctx := context.Background()
+	a := NewTestWatch().Actions
+
+	a.Slack.Enabled = true
+	assert.ErrorContains(t, a.Validate(ctx), "webhookURL cannot be empty if slack action is enabled")
+
+	for _, u := range []string{
+		"http://hooks.slack.com/services/T000/B000/XXXX",
+		"https://example.com/services/T000/B000/XXXX",
+		"https://hooks.slack.com/other/T000",
+		"hooks.slack.com/services/T000/B000/XXXX",
+	} {
+		a.Slack.WebhookURL = u
+		assert.ErrorContains(t, a.Validate(ctx), "invalid slack webhookURL", u)
+	}
+
+	a.Slack.WebhookURL = "https://hooks.slack.com/services/T000/B000/XXXX"
+	assert.NilError(t, a.Validate(ctx))
+	assert.DeepEqual(t, a.options()["slack"], a.Slack.ActionOptions)
+}
+
+func TestActionConfigValidateChecksExec(t *testing.T) {
+	ctx := context.Background()
+	a := NewTestWatch().Actions
+
+	a.Exec.Enabled = true
+	assert.ErrorContains(t, a.Validate(ctx), "command cannot be empty if exec action is enabled")
+
+	a.Exec.Command = []string{"notify-send", "new item"}
+	a.Exec.Timeout = -time.Second
+	assert.ErrorContains(t, a.Validate(ctx), "timeout cannot be negative")
+
+	a.Exec.Timeout = 0
+	assert.NilError(t, a.Validate(ctx))
+	assert.DeepEqual(t, a.options()["exec"], a.Exec.ActionOptions)
+}
+
+func TestActionConfigValidateChecksEmailTemplates(t *testing.T) {
+	ctx := context.Background()
+	a := NewTestWatch().Actions
+
+	a.Email.SubjectTemplate = "{{ .Title"
+	assert.ErrorContains(t, a.Validate(ctx), "invalid subjectTemplate")
+
+	a.Email.SubjectTemplate = "{{ .Title }}"
+	a.Email.BodyTemplate = "{{ .Missing }}"
+	assert.ErrorContains(t, a.Validate(ctx), "invalid bodyTemplate")
+
+	a.Email.BodyTemplate = "{{ .Title }}\n{{ .URL }}"
+	assert.NilError(t, a.Validate(ctx))
+
+	templates, err := a.Email.templates()
+	assert.NilError(t, err)
+	assert.Assert(t, templates.Subject != nil && templates.Body != nil)
+}
+
+func TestActionConfigValidateChecksEmailDigest(t *testing.T) {
+	ctx := context.Background()
+	a := NewTestWatch().Actions
+
+	a.Email.Digest = true
+	assert.NilError(t, a.Validate(ctx))
+
+	a.Email.BodyTemplate = "{{ .Title }}"
+	assert.ErrorContains(t, a.Validate(ctx), "subjectTemplate and bodyTemplate cannot be used with digest")
+
+	a.Email.BodyTemplate = ""
+	a.Email.Buffer.Size = 10
+	assert.ErrorContains(t, a.Validate(ctx), "buffer cannot be used with digest")
+}
+
+func TestActionConfigValidateChecksPolicy(t *testing.T) {
+	ctx := context.Background()
+	a := NewTestWatch().Actions
+
+	a.Policy = ActionPolicy{MaxRetries: 3, Timeout: time.Minute}
+	assert.NilError(t, a.Validate(ctx))
+	assert.Equal(t, a.Policy.getBackoff(), DefaultActionRetryBackoff)
+
+	a.Policy.MaxRetries = -1
+	assert.ErrorContains(t, a.Validate(ctx), "invalid policy: maxRetries cannot be negative")
+
+	a.Policy.MaxRetries = 0
+	a.Policy.Timeout = -time.Second
+	assert.ErrorContains(t, a.Validate(ctx), "invalid policy: timeout cannot be negative")
+}
+
+func TestActionConfigValidateChecksLabel(t *testing.T) {
+	ctx := context.Background()
+	a := NewTestWatch().Actions
+
+	a.Label.Enabled = true
+	assert.ErrorContains(t, a.Validate(ctx), "labels cannot be empty if label action is enabled")
+
+	a.Label.Labels = []string{"triage", ""}
+	assert.ErrorContains(t, a.Validate(ctx), "labels cannot contain an empty label")
+
+	a.Label.Labels = []string{"triage"}
+	assert.NilError(t, a.Validate(ctx))
+	assert.DeepEqual(t, a.options()["label"], a.Label.ActionOptions)
+}
+
+func TestActionConfigValidateChecksComment(t *testing.T) {
+	ctx := context.Background()
+	a := NewTestWatch().Actions
+
+	a.Comment.Enabled = true
+	assert.ErrorContains(t, a.Validate(ctx), "template cannot be empty if comment action is enabled")
+
+	a.Comment.Template = "Thanks for opening {{ .Title"
+	assert.ErrorContains(t, a.Validate(ctx), "invalid comment template")
+
+	a.Comment.Template = "Thanks for opening {{ .Titel }}!"
+	assert.ErrorContains(t, a.Validate(ctx), "invalid comment template")
+
+	a.Comment.Template = "Thanks for opening {{ .Title }}!"
+	assert.NilError(t, a.Validate(ctx))
+	assert.DeepEqual(t, a.options()["comment"], a.Comment.ActionOptions)
+}
+
+func TestWatchValidateChecksActionReposAreWatched(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	w := NewTestWatch()
+
+	w.Actions.Email.Repos = []GitHubRepository{{Owner: "owner", Name: "repo"}}
+	assert.NilError(t, w.ValidateAndPopulate(ctx, gh))
+
+	w.Actions.Email.Repos = []GitHubRepository{{Owner: "owner", Name: "another-repo"}}
+	assert.ErrorContains(
+		t, w.ValidateAndPopulate(ctx, gh),
+		"action 'email' is limited to repo owner/another-repo, which is not in the watch's repos",
+	)
+
+	w.Actions.Email.Enabled = false
+	assert.NilError(t, w.ValidateAndPopulate(ctx, gh))
+}
+
+func TestWatchValidateChecksActionReposAgainstSearch(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	w := NewTestWatch()
+
+	w.Repositories, w.SearchLabels, w.States = nil, nil, nil
+	w.Search = "repo:owner/repo is:open"
+	w.Actions.Email.Repos = []GitHubRepository{{Owner: "owner", Name: "repo"}}
+	assert.NilError(t, w.ValidateAndPopulate(ctx, gh))
+
+	w.Actions.Email.Repos = []GitHubRepository{{Owner: "owner", Name: "another-repo"}}
+	assert.ErrorContains(
+		t, w.ValidateAndPopulate(ctx, gh),
+		"action 'email' is limited to repo owner/another-repo, which is not in the watch's repos",
+	)
+
+	// A search which isn't limited to repos may list items from any repo.
+	w.Search = "org:owner is:open"
+	assert.NilError(t, w.ValidateAndPopulate(ctx, gh))
+}
+
+func TestParseGitHubSearchAcceptsURLsAndQueries(t *testing.T) {
+	for search, expected := range map[string]string{
+		"https://github.com/search?q=is%3Aissue+label%3Abug&type=issues": "is:issue label:bug",
+		"https://github.com/owner/repo/issues?q=is%3Aissue+is%3Aopen":    "repo:owner/repo is:issue is:open",
+		"https://github.com/owner/repo/pulls?q=is%3Aopen":                "repo:owner/repo is:open",
+		"q=is%3Aissue+label%3Abug":                                       "is:issue label:bug",
+		"  is:issue label:bug ":                                          "is:issue label:bug",
+	} {
+		query, err := ParseGitHubSearch(search)
+		assert.NilError(t, err, search)
+		assert.Equal(t, query, expected, search)
+	}
+
+	for _, search := range []string{"", "https://github.com/search", "q=", "https://github.com/search?q=+"} {
+		_, err := ParseGitHubSearch(search)
+		assert.Assert(t, err != nil, search)
+	}
+}
+
+func TestWatchValidateChecksSearch(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	w := NewTestWatch()
+
+	w.Search = "https://github.com/owner/repo/issues?q=is%3Aopen"
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "search cannot be combined with repos or self")
+
+	w.Repositories = []GitHubRepository{}
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "searchLabels and states cannot be combined with search")
+
+	w.SearchLabels = []string{}
+	w.States = []string{}
+	w.Actions.Email.Repos = nil
+	assert.NilError(t, w.ValidateAndPopulate(ctx, gh))
+	assert.Equal(t, w.GetSearchQuery(), "repo:owner/repo is:open")
+	assert.DeepEqual(t, gh.CheckSearchRequests, []string{"repo:owner/repo is:open"})
+
+	gh.CheckSearchError = errors.New("my test error")
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "my test error")
+
+	gh.CheckSearchError = nil
+	assert.NilError(t, w.ValidateAndPopulate(ctx, gh))
+
+	item := NewTestGitHubItem()
+	gh.SearchIssuesReturn = []*GitHubItem{item}
+
+	items, err := w.ListItems(ctx, gh, NewSystemClock())
+	assert.NilError(t, err)
+	assert.Equal(t, len(items), 1)
+	assert.Equal(t, items[0], item)
+	assert.DeepEqual(t, gh.SearchIssuesRequests, []string{"repo:owner/repo is:open"})
+}
+
+func TestConfigValidateRejectsDuplicateWatchNames(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	e := NewMockEmailinator()
+
+	c, cleanup, err := NewTestConfig()
+	assert.NilError(t, err)
+
+	defer cleanup()
+
+	other := NewTestWatch()
+	other.Name = "other"
+	c.Watches = append(c.Watches, other)
+	assert.NilError(t, c.Validate(ctx, gh, e))
+
+	c.Watches = append(c.Watches, NewTestWatch())
+	assert.ErrorContains(t, c.Validate(ctx, gh, e), "duplicate watch name 'name', used by watches 1 and 3")
+}
+
+func TestConfigValidateSkipsGitHubChecksForDisabledWatches(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	gh.CheckRepositoryError = errors.New("my repository error")
+	e := NewMockEmailinator()
+
+	c, cleanup, err := NewTestConfig()
+	assert.NilError(t, err)
+
+	defer cleanup()
+
+	assert.ErrorContains(t, c.Validate(ctx, gh, e), "my repository error")
+
+	disabled := false
+	c.Watches[0].Enabled = &disabled
+	assert.NilError(t, c.Validate(ctx, gh, e))
+	assert.Equal(t, len(gh.CheckRepositoryRequests), 1)
+
+	// The rest of the watch is still validated.
+	c.Watches[0].BodyRegex = []string{"("}
+	assert.ErrorContains(t, c.Validate(ctx, gh, e), "unable to compile regex '('")
+}
+
+func TestConfigValidateChecksAllowedActions(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	e := NewMockEmailinator()
+
+	c, cleanup, err := NewTestConfig()
+	assert.NilError(t, err)
+
+	defer cleanup()
+
+	c.Watches[0].Actions.Email.Enabled = false
+	c.Watches[0].Actions.Subscribe.Enabled = true
+
+	c.AllowedActions = []string{"subscribe"}
+	assert.NilError(t, c.Validate(ctx, gh, e))
+
+	c.AllowedActions = []string{"tweet"}
+	assert.ErrorContains(t, c.Validate(ctx, gh, e), "unknown action 'tweet' in allowedActions")
+
+	c.AllowedActions = []string{"email"}
+	assert.ErrorContains(
+		t, c.Validate(ctx, gh, e), "watch 'name' enables action 'subscribe', which is not in allowedActions",
+	)
+
+	// The environment variable takes precedence over the config.
+	t.Setenv(AllowedActionsEnvVar, "email, subscribe")
+	assert.NilError(t, c.Validate(ctx, gh, e))
+
+	c.AllowedActions = nil
+	t.Setenv(AllowedActionsEnvVar, "email")
+	assert.ErrorContains(t, c.Validate(ctx, gh, e), "which is not in allowedActions")
+}
+
+func TestConfigValidateChecksReposCommandIsAllowed(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	e := NewMockEmailinator()
+
+	c, cleanup, err := NewTestConfig()
+	assert.NilError(t, err)
+
+	defer cleanup()
+
+	c.Watches[0].Repositories = nil
+	c.Watches[0].ReposCommand = []string{"echo", "owner/repo"}
+
+	c.AllowedActions = []string{"email", "subscribe"}
+	assert.ErrorContains(t, c.Validate(ctx, gh, e), "watch 'name' sets reposCommand, which is not in allowedActions")
+
+	c.AllowedActions = []string{"email", "subscribe", "reposCommand"}
+	assert.NilError(t, c.Validate(ctx, gh, e))
+}
+
+func TestWatchValidateChecksLastActivityByHasLogins(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	w := NewTestWatch()
+
+	w.LastActivityBy = &LastActivityByConfig{}
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "lastActivityBy requires at least one login")
+
+	w.LastActivityBy.Logins = []string{"maintainer"}
+	assert.NilError(t, w.ValidateAndPopulate(ctx, gh))
+	assert.Equal(t, w.GetMatchinator().HasLastActivityBy(), true)
+}
+
+func TestConfigValidateCanSkipEmailConnection(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	e := NewMockEmailinator()
+	e.TestConnectionError = errors.New("my test error")
+
+	c, cleanup, err := NewTestConfig()
+	assert.NilError(t, err)
+
+	defer cleanup()
+
+	assert.ErrorContains(t, c.Validate(ctx, gh, e), "my test error")
+	assert.NilError(t, c.Validate(ctx, gh, e, &ValidateOptions{SkipEmailConnection: true}))
+
+	// The email config is still checked for invalid fields.
+	c.Email.Port = 25
+	assert.ErrorContains(
+		t, c.Validate(ctx, gh, e, &ValidateOptions{SkipEmailConnection: true}), "tlsPolicy must be set for port 25",
+	)
+}
+
+func TestConfigValidateOfflineSkipsNetworkChecks(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	gh.WhoAmIError = errors.New("my whoami error")
+	gh.CheckRepositoryError = errors.New("my repository error")
+	gh.CheckSearchError = errors.New("my search error")
+	gh.CountOpenIssuesError = errors.New("my count error")
+
+	e := NewMockEmailinator()
+	e.TestConnectionError = errors.New("my test error")
+
+	c, cleanup, err := NewTestConfig()
+	assert.NilError(t, err)
+
+	defer cleanup()
+
+	searchWatch := NewTestWatch()
+	searchWatch.Name = "search"
+	searchWatch.Repositories = nil
+	searchWatch.Search = "is:issue label:bug"
+	searchWatch.SearchLabels = nil
+	searchWatch.States = nil
+
+	scanWatch := NewTestWatch()
+	scanWatch.Name = "scan"
+	scanWatch.States = nil
+	scanWatch.SearchLabels = nil
+
+	c.Watches = append(c.Watches, searchWatch)
+	c.Watches[0].Actions.Email.Enabled = true
+
+	offline := &ValidateOptions{Offline: true}
+
+	assert.ErrorContains(t, c.Validate(ctx, gh, e), "my whoami error")
+	assert.NilError(t, c.Validate(ctx, gh, e, offline))
+	assert.Equal(t, gh.WhoAmIRequests, DefaultRetryAttempts)
+	assert.Equal(t, len(gh.CheckRepositoryRequests), 0)
+	assert.Equal(t, len(gh.CheckSearchRequests), 0)
+
+	// Selectors and regexes are still parsed.
+	assert.Equal(t, len(c.Watches[0].bodyRegex), len(c.Watches[0].BodyRegex))
+
+	c.Watches[1].TitleRegex = []string{"("}
+	assert.ErrorContains(t, c.Validate(ctx, gh, e, offline), "unable to compile regex '('")
+
+	// Full body scans are still rejected, without counting the open issues.
+	c.Watches = append(c.Watches[:1], scanWatch)
+	assert.ErrorContains(
+		t, c.Validate(ctx, gh, e, offline),
+		"requires fetching the body or comments of every issue on each tick (across 1 repos)",
+	)
+}
+
+func TestWatchValidateChecksTags(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	w := NewTestWatch()
+
+	w.Tags = map[string]string{"team": "platform", "env": "prod-1.2"}
+	assert.NilError(t, w.ValidateAndPopulate(ctx, gh))
+	assert.Equal(t, FormatTags(w.Tags), "env=prod-1.2, team=platform")
+
+	w.Tags = map[string]string{"1team": "platform"}
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "invalid tag key '1team'")
+
+	w.Tags = map[string]string{"__name__": "platform"}
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "invalid tag key '__name__'")
+
+	w.Tags = map[string]string{"team": "plat form"}
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "invalid value 'plat form' for tag 'team'")
+
+	w.Tags = map[string]string{"team": ""}
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "invalid value '' for tag 'team'")
+
+	w.Tags = map[string]string{}
+	for i := 0; i <= MaxWatchTags; i++ {
+		w.Tags[fmt.Sprintf("tag%d", i)] = "value"
+	}
+
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "expected at most 10 tags")
+}
+
+func TestConfigValidateResolvesSelectorAliases(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	e := NewMockEmailinator()
+
+	c, cleanup, err := NewTestConfig()
+	assert.NilError(t, err)
+
+	defer cleanup()
+
+	w := c.Watches[0]
+	w.RequiredLabels = []string{}
+	w.BodyRegex = []string{}
+	w.TitleRegex = []string{}
+	w.Selectors = []string{"author=actor,repo in (repo),kind=issue"}
+	c.SelectorAliases = map[string]string{"kind": "type"}
+
+	assert.NilError(t, c.Validate(ctx, gh, e))
+
+	item := NewTestGitHubItem()
+	matches, reason := w.GetMatchinator().Matches(item)
+	assert.Assert(t, matches, reason)
+
+	item.Author.Login = "someone-else"
+	matches, _ = w.GetMatchinator().Matches(item)
+	assert.Assert(t, !matches)
+
+	// Without aliases, the keys are unknown.
+	w.selectorAliases = nil
+	assert.ErrorContains(t, w.PopulateMatchers(), "unknown key 'author' in selector")
+
+	c.SelectorAliases = map[string]string{"title": "body"}
+	assert.ErrorContains(t, c.Validate(ctx, gh, e), "selector alias 'title' cannot shadow an existing key")
+
+	c.SelectorAliases = map[string]string{"kind": "unknown"}
+	assert.ErrorContains(t, c.Validate(ctx, gh, e), "selector alias 'kind' refers to unknown key 'unknown'")
+}
+
+func TestConfigGetInterval(t *testing.T) {
+	c := &Config{Interval: time.Hour}
+	w := NewTestWatch()
+
+	assert.Equal(t, c.GetInterval(w), time.Hour)
+
+	w.Interval = 5 * time.Minute
+	assert.Equal(t, c.GetInterval(w), 5*time.Minute)
+
+	w.Interval = -time.Minute
+	assert.ErrorContains(
+		t, w.ValidateAndPopulate(context.Background(), NewMockGitHubinator()),
+		"interval must be greater than zero if set, got -1m0s",
+	)
+}
+
+func TestConfigValidateExpandsMatcherSets(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	e := NewMockEmailinator()
+
+	c, cleanup, err := NewTestConfig()
+	assert.NilError(t, err)
+
+	defer cleanup()
+
+	w := c.Watches[0]
+	w.RequiredLabels = []string{"a/test/label"}
+	w.BodyRegex = []string{}
+	w.TitleRegex = []string{}
+	w.Selectors = []string{}
+	w.Use = []string{"bugs", "by-actor"}
+	c.Matchers = map[string]*MatcherSet{
+		"bugs":     {RequiredLabels: []string{"another/label"}, TitleRegex: []string{"^a test"}},
+		"by-actor": {Selectors: []string{"author.login=actor"}},
+	}
+
+	assert.NilError(t, c.Validate(ctx, gh, e))
+	assert.DeepEqual(t, w.RequiredLabels, []string{"a/test/label", "another/label"})
+	assert.DeepEqual(t, w.TitleRegex, []string{"^a test"})
+	assert.DeepEqual(t, w.Selectors, []string{"author.login=actor"})
+
+	item := NewTestGitHubItem()
+	matches, reason := w.GetMatchinator().Matches(item)
+	assert.Assert(t, matches, reason)
+
+	item.Author.Login = "someone-else"
+	matches, _ = w.GetMatchinator().Matches(item)
+	assert.Assert(t, !matches)
+
+	// Validating again doesn't add the matchers twice.
+	assert.NilError(t, c.Validate(ctx, gh, e))
+	assert.DeepEqual(t, w.RequiredLabels, []string{"a/test/label", "another/label"})
+
+	c, cleanup, err = NewTestConfig()
+	assert.NilError(t, err)
+
+	defer cleanup()
+
+	c.Watches[0].Use = []string{"unknown"}
+	c.Matchers = map[string]*MatcherSet{"bugs": {}}
+	assert.ErrorContains(
+		t, c.Validate(ctx, gh, e), "watch 'name' uses unknown matcher set 'unknown', available matcher sets: [bugs]",
+	)
+
+	c.Matchers = map[string]*MatcherSet{"bugs": nil}
+	assert.ErrorContains(t, c.Validate(ctx, gh, e), "matcher set 'bugs' cannot be empty")
+}
+
+func TestExpandEnv(t *testing.T) {
+	lookup := func(name string) (string, bool) {
+		v, ok := map[string]string{"HOST": "smtp.example.com", "EMPTY": ""}[name]
+
+		return v, ok
+	}
+
+	cases := []struct {
+		in       string
+		expected string
+		err      string
+	}{
+		{in: "no references", expected: "no references"},
+		{in: "${HOST}:587", expected: "smtp.example.com:587"},
+		{in: "${EMPTY}", expected: ""},
+		{in: "${UNSET:-default}", expected: "default"},
+		{in: "${EMPTY:-default}", expected: "default"},
+		{in: "${HOST:-default}", expected: "smtp.example.com"},
+		{in: "${UNSET:-}", expected: ""},
+		{in: "$${HOST} costs $$5", expected: "${HOST} costs $5"},
+		{in: "^bug$", expected: "^bug$"},
+		{in: "$HOST", expected: "$HOST"},
+		{in: "${UNSET}", err: "environment variable 'UNSET' is not set and has no default"},
+		{in: "${HOST", err: "unterminated environment variable reference"},
+		{in: "${not-a-name}", err: "invalid environment variable name 'not-a-name'"},
+	}
+
+	for _, c := range cases {
+		actual, err := ExpandEnv(c.in, lookup)
+		if c.err != "" {
+			assert.ErrorContains(t, err, c.err, c.in)
+
+			continue
+		}
+
+		assert.NilError(t, err, c.in)
+		assert.Equal(t, actual, c.expected, c.in)
+	}
+}
+
+func TestNewConfigFromFileExpandsEnv(t *testing.T) {
+	t.Setenv("WATCHINATOR_TEST_HOST", "smtp.example.com")
+	t.Setenv("WATCHINATOR_TEST_REPO", "watchinator")
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte(`
+# Comments can mention ${WATCHINATOR_TEST_UNSET}.
+interval: ${WATCHINATOR_TEST_INTERVAL:-1h}
+pageSize: ${WATCHINATOR_TEST_PAGE_SIZE:-50}
+email:
+  host: ${WATCHINATOR_TEST_HOST}
+watches:
+- name: "${WATCHINATOR_TEST_REPO} bugs"
+  repos:
+  - owner: learnitall
+    name: ${WATCHINATOR_TEST_REPO}
+  titleRegex: ["^bug$", "costs $$5"]
+`), 0600)
+	assert.NilError(t, err)
+
+	c, err := NewConfigFromFile(path, "")
+	assert.NilError(t, err)
+	assert.Equal(t, c.Interval, time.Hour)
+	assert.Equal(t, c.PageSize, 50)
+	assert.Equal(t, c.Email.Host, "smtp.example.com")
+	assert.Equal(t, c.Watches[0].Name, "watchinator bugs")
+	assert.DeepEqual(t, c.Watches[0].Repositories, []GitHubRepository{{Owner: "learnitall", Name: "watchinator"}})
+	assert.DeepEqual(t, c.Watches[0].TitleRegex, []string{"^bug$", "costs $5"})
+
+	err = os.WriteFile(path, []byte("email:\n  host: ${WATCHINATOR_TEST_UNSET}\n"), 0600)
+	assert.NilError(t, err)
+
+	_, err = NewConfigFromFile(path, "")
+	assert.ErrorContains(t, err, "line 2: environment variable 'WATCHINATOR_TEST_UNSET' is not set")
+}
+
+func TestNewConfigFromFileLoadsDirectories(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, body string) {
+		assert.NilError(t, os.WriteFile(filepath.Join(dir, name), []byte(body), 0600))
+	}
+
+	write(ConfigDirBaseFile, `
+user: learnitall
+interval: 1h
+watches:
+- name: base
+`)
+	write("20-second.yaml", "watches:\n- name: second\n")
+	write("10-first.yaml", "watches:\n- name: first\n- name: also-first\n")
+	write("empty.yaml", "")
+	write(".hidden.yaml", "interval: 5m\n")
+	write("notes.txt", "interval: 5m\n")
+
+	c, err := NewConfigFromFile(dir, "")
+	assert.NilError(t, err)
+	assert.Equal(t, c.User, "learnitall")
+	assert.Equal(t, c.Interval, time.Hour)
+
+	names := []string{}
+	for _, w := range c.Watches {
+		names = append(names, w.Name)
+	}
+
+	assert.DeepEqual(t, names, []string{"base", "first", "also-first", "second"})
+
+	write("30-conflict.yaml", "interval: 5m\nwatches:\n- name: conflict\n")
+	_, err = NewConfigFromFile(dir, "")
+	assert.ErrorContains(
+		t, err, "30-conflict.yaml sets 'interval', but only config.yaml can set fields other than watches",
+	)
+
+	assert.NilError(t, os.Remove(filepath.Join(dir, "30-conflict.yaml")))
+	assert.NilError(t, os.Remove(filepath.Join(dir, ConfigDirBaseFile)))
+	_, err = NewConfigFromFile(dir, "")
+	assert.ErrorContains(t, err, "unable to load base config file")
+}
+
+func TestNewConfigFromFileUnmarshalsMatcherSets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte(`
+interval: 1h
+matchers:
+  bugs:
+    requiredLabels: ["kind/bug"]
+    bodyRegex: ["panic"]
+    selectorGroups:
+    - name: severe
+      labels: ["p0", "p1"]
+watches:
+- name: bugs
+  use: ["bugs"]
+`), 0600)
+	assert.NilError(t, err)
+
+	c, err := NewConfigFromFile(path, "")
+	assert.NilError(t, err)
+	assert.NilError(t, c.ExpandMatchers())
+
+	w := c.Watches[0]
+	assert.DeepEqual(t, w.RequiredLabels, []string{"kind/bug"})
+	assert.DeepEqual(t, w.BodyRegex, []string{"panic"})
+	assert.DeepEqual(t, w.SelectorGroups, []SelectorGroup{{Name: "severe", Labels: []string{"p0", "p1"}}})
+}
+
+func TestConfigValidateChecksAgeBuckets(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	e := NewMockEmailinator()
+
+	c, cleanup, err := NewTestConfig()
+	assert.NilError(t, err)
+
+	defer cleanup()
+
+	w := c.Watches[0]
+	w.Selectors = []string{"ageBucket in (fresh)"}
+	c.AgeBuckets = []AgeBucket{{Name: "fresh", MaxAge: time.Hour}, {Name: "old"}}
+	assert.NilError(t, c.Validate(ctx, gh, e))
+	assert.DeepEqual(t, w.getAgeBuckets(), c.AgeBuckets)
+
+	// The default buckets don't include 'fresh'.
+	c.AgeBuckets = nil
+	assert.ErrorContains(
+		t, c.Validate(ctx, gh, e), "unknown age bucket 'fresh', expected one of 'new', 'week', 'month', 'stale'",
+	)
+
+	w.Selectors = []string{"ageBucket==stale"}
+
+	for _, tc := range []struct {
+		buckets  []AgeBucket
+		expected string
+	}{
+		{[]AgeBucket{{Name: "fresh", MaxAge: time.Hour}}, "last age bucket 'fresh' cannot set maxAge"},
+		{[]AgeBucket{{Name: "a", MaxAge: time.Hour}, {Name: "a"}}, "duplicate age bucket 'a'"},
+		{[]AgeBucket{{Name: "a b"}}, "invalid age bucket name 'a b'"},
+		{
+			[]AgeBucket{{Name: "a", MaxAge: time.Hour}, {Name: "b", MaxAge: time.Minute}, {Name: "c"}},
+			"maxAge of age bucket 'b' must be greater than 1h0m0s",
+		},
+	} {
+		c.AgeBuckets = tc.buckets
+		assert.ErrorContains(t, c.Validate(ctx, gh, e), tc.expected)
+	}
+}
+
+func TestWatchValidateChecksReferencesIssue(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	w := NewTestWatch()
+
+	w.ReferencesIssue = 100
+	w.ReferencesIssueTimeline = true
+	assert.NilError(t, w.ValidateAndPopulate(ctx, gh))
+	assert.Assert(t, w.GetMatchinator().HasReferencesIssue())
+	assert.Equal(t, w.GetMatchinator().ReferencesIssueTimeline(), 100)
+
+	w.ReferencesIssue = -1
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "referencesIssue must be an issue number, got -1")
+
+	w.ReferencesIssue = 0
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "referencesIssueTimeline requires referencesIssue")
+
+	// Referenced issues are parsed from the body, so a full body scan needs to be allowed.
+	w = NewTestWatch()
+	w.BodyRegex = []string{}
+	w.SearchLabels = []string{}
+	w.States = []string{}
+	w.ReferencesIssue = 100
+	assert.ErrorContains(
+		t, w.ValidateAndPopulate(ctx, gh), "bodyRegex, commentRegex or referencesIssue without searchLabels",
+	)
+}
+
+func TestConfigGetInitialScan(t *testing.T) {
+	enabled, disabled := true, false
+	c := &Config{}
+	w := NewTestWatch()
+
+	assert.Equal(t, c.GetInitialScan(w), true)
+
+	c.InitialScan = &disabled
+	assert.Equal(t, c.GetInitialScan(w), false)
+
+	w.InitialScan = &enabled
+	assert.Equal(t, c.GetInitialScan(w), true)
+
+	c.InitialScan = nil
+	w.InitialScan = &disabled
+	assert.Equal(t, c.GetInitialScan(w), false)
+}
+
+func TestConfigApplyProfile(t *testing.T) {
+	stagingWatch := NewTestWatch()
+	stagingWatch.Name = "staging"
+
+	c := &Config{
+		Interval: time.Hour,
+		Watches:  []*Watch{NewTestWatch()},
+		Profiles: map[string]*ConfigProfile{
+			"staging": {Interval: time.Minute, Watches: []*Watch{stagingWatch}},
+			"prod":    {},
+		},
+	}
+
+	assert.NilError(t, c.ApplyProfile(""))
+	assert.Equal(t, c.Profile, "")
+
+	assert.ErrorContains(t, c.ApplyProfile("dev"), "unknown profile 'dev', available profiles: prod, staging")
+
+	assert.NilError(t, c.ApplyProfile("staging"))
+	assert.Equal(t, c.Profile, "staging")
+	assert.Equal(t, c.Interval, time.Minute)
+	assert.Equal(t, len(c.Watches), 1)
+	assert.Equal(t, c.Watches[0].Name, "staging")
+
+	c = &Config{Interval: time.Hour}
+	assert.ErrorContains(t, c.ApplyProfile("dev"), "the config doesn't define any profiles")
+}
+
+func TestNewConfigFromFileAppliesProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte(`
+interval: 1h
+email:
+  host: smtp.example.com
+watches:
+- name: prod
+profiles:
+  staging:
+    interval: 5m
+    watches:
+    - name: staging
+`), 0600)
+	assert.NilError(t, err)
+
+	c, err := NewConfigFromFile(path, "")
+	assert.NilError(t, err)
+	assert.Equal(t, c.Interval, time.Hour)
+	assert.Equal(t, c.Watches[0].Name, "prod")
+
+	c, err = NewConfigFromFile(path, "staging")
+	assert.NilError(t, err)
+	assert.Equal(t, c.Interval, 5*time.Minute)
+	assert.Equal(t, c.Watches[0].Name, "staging")
+	assert.Equal(t, c.Email.Host, "smtp.example.com")
+
+	_, err = NewConfigFromFile(path, "dev")
+	assert.ErrorContains(t, err, "unknown profile 'dev', available profiles: staging")
+}
+
+func TestWatchValidateChecksItemTypes(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+
+	w := NewTestWatch()
+	w.ItemTypes = []GitHubItemType{GitHubItemIssue, GitHubItemPullRequest}
+	assert.NilError(t, w.ValidateAndPopulate(ctx, gh))
+
+	w.ItemTypes = []GitHubItemType{"commit"}
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "unknown item type 'commit'")
+
+	w.ItemTypes = []GitHubItemType{GitHubItemPullRequest, GitHubItemPullRequest}
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "duplicate item type 'pullRequest'")
+
+	w.ItemTypes = []GitHubItemType{GitHubItemPullRequest}
+	w.States = []string{"MERGED"}
+	assert.NilError(t, w.ValidateAndPopulate(ctx, gh))
+
+	w.ItemTypes = nil
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "state MERGED requires itemTypes to only contain")
+
+	w = NewTestWatch()
+	w.ItemTypes = []GitHubItemType{GitHubItemPullRequest}
+	w.LastActivityBy = &LastActivityByConfig{Logins: []string{"maintainer"}}
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "lastActivityBy can only be used when watching issues")
+}
+
+func TestWatchListItemsListsEachItemType(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+
+	issue := NewTestGitHubItem()
+	issue.Number = 2
+	pr := NewTestGitHubPullRequestItem()
+	gh.ListIssuesReturn = []*GitHubItem{issue}
+	gh.ListPullRequestsReturn = []*GitHubItem{pr}
+
+	w := NewTestWatch()
+	assert.NilError(t, w.ValidateAndPopulate(ctx, gh))
+
+	items, err := w.ListItems(ctx, gh, NewSystemClock())
+	assert.NilError(t, err)
+	assert.Equal(t, len(items), 1)
+	assert.Equal(t, len(gh.ListPullRequestsRequests), 0)
+
+	w.ItemTypes = []GitHubItemType{GitHubItemIssue, GitHubItemPullRequest}
+	items, err = w.ListItems(ctx, gh, NewSystemClock())
+	assert.NilError(t, err)
+	assert.Equal(t, len(items), 2)
+	assert.Equal(t, items[0].Type, GitHubItemPullRequest)
+	assert.Equal(t, items[1].Type, GitHubItemIssue)
+}
+
+func TestWatchListItemsUsesClock(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	now := time.Date(2023, time.January, 2, 0, 0, 0, 0, time.UTC)
+
+	w := NewTestWatch()
+	w.UpdatedSince = 24 * time.Hour
+	assert.NilError(t, w.ValidateAndPopulate(ctx, gh))
+
+	_, err := w.ListItems(ctx, gh, NewFakeClock(now))
+	assert.NilError(t, err)
+	assert.Equal(t, len(gh.ListIssuesFilters), 1)
+	assert.Equal(t, *gh.ListIssuesFilters[0].Since, now.Add(-24*time.Hour))
+}
+
+func TestConfigValidateChecksBaseURL(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	e := NewMockEmailinator()
+	c, cleanup, err := NewTestConfig()
+
+	assert.NilError(t, err)
+
+	defer cleanup()
+
+	c.BaseURL = "https://github.example.com"
+	assert.NilError(t, c.Validate(ctx, gh, e))
+
+	c.BaseURL = "github.example.com"
+	assert.ErrorContains(t, c.Validate(ctx, gh, e), "expected an absolute http or https URL")
+
+	c.BaseURL = "ftp://github.example.com"
+	assert.ErrorContains(t, c.Validate(ctx, gh, e), "expected an absolute http or https URL")
+}
+
+func TestWatchUpdatedSinceFiltersIssuesRelativeToTick(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	w := NewTestWatch()
+	now := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	assert.NilError(t, w.ValidateAndPopulate(ctx, gh))
+	assert.Assert(t, w.GetIssueFilter(now).asGithubv4IssueFilters().Since == nil)
+
+	w.UpdatedSince = 24 * time.Hour
+	assert.NilError(t, w.ValidateAndPopulate(ctx, gh))
+
+	filter := w.GetIssueFilter(now)
+	since := filter.asGithubv4IssueFilters().Since
+	assert.Assert(t, since != nil)
+	assert.Equal(t, since.Time, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	old := NewTestGitHubItem()
+	old.UpdatedAt = now.Add(-48 * time.Hour)
+	assert.Assert(t, filter.isBeforeSince(old))
+
+	recent := NewTestGitHubItem()
+	recent.UpdatedAt = now.Add(-time.Hour)
+	assert.Assert(t, !filter.isBeforeSince(recent))
+
+	w.UpdatedSince = -time.Hour
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "updatedSince cannot be negative")
+
+	w.UpdatedSince = time.Hour
+	w.Repositories, w.SearchLabels, w.States = nil, nil, nil
+	w.Search = "repo:owner/repo is:open"
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "updatedSince cannot be combined with search")
+}
+
+func TestWatchMilestoneFiltersItems(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	w := NewTestWatch()
+
+	assert.NilError(t, w.ValidateAndPopulate(ctx, gh))
+	assert.Assert(t, w.GetIssueFilter(time.Now()).asGithubv4IssueFilters().Milestone == nil)
+
+	w.Milestone = "v1.2.0"
+	assert.NilError(t, w.ValidateAndPopulate(ctx, gh))
+
+	// GitHub expects a milestone number, so titles are only matched after listing.
+	filter := w.GetIssueFilter(time.Now())
+	assert.Assert(t, filter.asGithubv4IssueFilters().Milestone == nil)
+
+	item := NewTestGitHubPullRequestItem()
+	assert.Assert(t, !filter.inMilestone(item))
+
+	item.Milestone = "v1.2.0"
+	assert.Assert(t, filter.inMilestone(item))
+
+	filter.Milestone = "*"
+	milestone := filter.asGithubv4IssueFilters().Milestone
+	assert.Assert(t, milestone != nil)
+	assert.Equal(t, *milestone, githubv4.String("*"))
+	assert.Assert(t, filter.inMilestone(item))
+	assert.Assert(t, !filter.inMilestone(NewTestGitHubItem()))
+
+	w.Repositories, w.SearchLabels, w.States = nil, nil, nil
+	w.Search = "repo:owner/repo is:open"
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "milestone cannot be combined with search")
+}
+
+func TestWatchAuthorsFiltersServerSideForSingleAuthor(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	w := NewTestWatch()
+
+	w.Authors = []string{"maintainer"}
+	assert.NilError(t, w.ValidateAndPopulate(ctx, gh))
+
+	filter := w.GetIssueFilter(time.Now())
+	createdBy := filter.asGithubv4IssueFilters().CreatedBy
+	assert.Assert(t, createdBy != nil)
+	assert.Equal(t, *createdBy, githubv4.String("maintainer"))
+
+	item := NewTestGitHubPullRequestItem()
+	assert.Assert(t, !filter.isCreatedBy(item))
+
+	item.Author.Login = "Maintainer"
+	assert.Assert(t, filter.isCreatedBy(item))
+
+	// Several authors can't be filtered by GitHub, so they're only matched.
+	w.Authors = []string{"maintainer", "another-maintainer"}
+	assert.NilError(t, w.ValidateAndPopulate(ctx, gh))
+	assert.Assert(t, w.GetIssueFilter(time.Now()).asGithubv4IssueFilters().CreatedBy == nil)
+
+	item.Author.Login = "another-maintainer"
+	assert.Equal(t, AuthorGitHubItemMatcher(w.Authors).Matcher(item), true)
+
+	item.Author.Login = "someone-else"
+	assert.Equal(t, AuthorGitHubItemMatcher(w.Authors).Matcher(item), false)
+
+	w.Repositories, w.SearchLabels, w.States = nil, nil, nil
+	w.Search = "repo:owner/repo is:open"
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "authors cannot be combined with search")
+}
+
+func TestWatchCommentRegexOnlySupportsIssues(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	w := NewTestWatch()
+
+	w.CommentRegex = []string{"backport"}
+	assert.NilError(t, w.ValidateAndPopulate(ctx, gh))
+	assert.Equal(t, w.GetMatchinator().HasCommentRegex(), true)
+
+	w.ItemTypes = []GitHubItemType{GitHubItemIssue, GitHubItemPullRequest}
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "commentRegex can only be used when watching issues")
+
+	w.ItemTypes = nil
+	w.CommentRegex = []string{"("}
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "unable to compile regex '('")
+
+	// Searches return pull requests too, unless they're limited to issues.
+	w.CommentRegex = []string{"backport"}
+	w.Repositories = []GitHubRepository{}
+	w.SearchLabels = []string{}
+	w.States = []string{}
+	w.Actions.Email.Repos = nil
+	w.Search = "repo:owner/repo"
+	assert.ErrorContains(
+		t, w.ValidateAndPopulate(ctx, gh),
+		"commentRegex can only be used when watching issues, add 'is:issue' to the search",
+	)
+
+	w.Search = "repo:owner/repo is:issue"
+	assert.NilError(t, w.ValidateAndPopulate(ctx, gh))
+}
+
+func TestConfigLoadPATFileReadsPATFiles(t *testing.T) {
+	ctx := context.Background()
+	c, cleanup, err := NewTestConfig()
+
+	assert.NilError(t, err)
+
+	defer cleanup()
+
+	extra := t.TempDir() + "/extra"
+	assert.NilError(t, os.WriteFile(extra, []byte("5678\n"), 0o600))
+
+	c.PATFiles = []string{extra}
+	assert.NilError(t, c.LoadCredentials(ctx))
+	assert.Equal(t, c.PAT, "1234")
+	assert.DeepEqual(t, c.PATs, []string{"1234", "5678"})
+
+	// patFiles can be used on its own.
+	c.PATFile = ""
+	assert.NilError(t, c.LoadCredentials(ctx))
+	assert.DeepEqual(t, c.PATs, []string{"5678"})
+
+	c.AppAuth = &GitHubAppAuthConfig{AppID: 1234, InstallationID: 5678}
+	assert.ErrorContains(t, c.LoadCredentials(ctx), "patFiles and appAuth are mutually exclusive")
+}
+
+func TestWatchSelectorGroups(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	w := NewTestWatch()
+	w.RequiredLabels = nil
+	w.SelectorGroups = []SelectorGroup{{
+		Name:      "triage",
+		Selectors: []string{"author.login==someone"},
+		Labels:    []string{"foo", "bar"},
+	}}
+	assert.NilError(t, w.ValidateAndPopulate(ctx, gh))
+
+	item := NewTestGitHubItem()
+	item.Labels = []string{"bar"}
+	matches, reason := w.GetMatchinator().Matches(item)
+	assert.Assert(t, matches, reason)
+
+	item.Labels = []string{}
+	item.Author.Login = "someone"
+	matches, reason = w.GetMatchinator().Matches(item)
+	assert.Assert(t, matches, reason)
+
+	item.Author.Login = "someone-else"
+	matches, reason = w.GetMatchinator().Matches(item)
+	assert.Assert(t, !matches)
+	assert.Assert(t, cmp.Contains(reason, "anyOf 'triage'"))
+
+	w.SelectorGroups[0].Selectors = []string{"unknown==a"}
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "invalid selector group 'triage': unknown key 'unknown'")
+
+	w.SelectorGroups[0].Selectors = nil
+	w.SelectorGroups[0].Labels = nil
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "must have at least one selector or label")
+
+	w.SelectorGroups[0].Name = ""
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "selector group 0 must have a name")
+}
+
+func TestWatchValidateChecksNumberRange(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	w := NewTestWatch()
+
+	w.NumberRange = &NumberRangeConfig{Min: 1000}
+	assert.NilError(t, w.ValidateAndPopulate(ctx, gh))
+
+	w.NumberRange = &NumberRangeConfig{}
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "numberRange must set at least one of min and max")
+
+	w.NumberRange = &NumberRangeConfig{Min: -1}
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "numberRange bounds cannot be negative")
+
+	w.NumberRange = &NumberRangeConfig{Min: 20, Max: 10}
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "numberRange min 20 cannot be greater than max 10")
+}
+
+func TestWatchValidateChecksTimeWindows(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	w := NewTestWatch()
+
+	w.UpdatedWithin = Duration(72 * time.Hour)
+	w.CreatedWithin = Duration(24 * time.Hour)
+	assert.NilError(t, w.ValidateAndPopulate(ctx, gh))
+
+	w.UpdatedWithin = Duration(-time.Hour)
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "updatedWithin cannot be negative")
+
+	w.UpdatedWithin = 0
+	w.CreatedWithin = Duration(-time.Hour)
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "createdWithin cannot be negative")
+
+	w.CreatedWithin = 0
+	w.NotUpdatedWithin = Duration(-time.Hour)
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "notUpdatedWithin cannot be negative")
+
+	w.NotUpdatedWithin = Duration(30 * 24 * time.Hour)
+	assert.NilError(t, w.ValidateAndPopulate(ctx, gh))
+
+	w.UpdatedWithin = Duration(7 * 24 * time.Hour)
+	assert.ErrorContains(
+		t, w.ValidateAndPopulate(ctx, gh),
+		"updatedWithin must be greater than notUpdatedWithin, got 168h0m0s and 720h0m0s",
+	)
+}
+
+func TestWatchTimeWindowsAcceptDays(t *testing.T) {
+	w := &Watch{}
+	assert.NilError(t, yaml.Unmarshal(
+		[]byte("updatedWithin: 36h\ncreatedWithin: 1d12h\nnotUpdatedWithin: 30d\n"), w,
+	))
+	assert.Equal(t, w.UpdatedWithin, Duration(36*time.Hour))
+	assert.Equal(t, w.CreatedWithin, Duration(36*time.Hour))
+	assert.Equal(t, w.NotUpdatedWithin, Duration(30*24*time.Hour))
+
+	for _, invalid := range []string{"30", "xd", "1d1x", "-1d"} {
+		_, err := ParseDuration(invalid)
+		assert.Assert(t, err != nil, invalid)
+	}
+}
@@ -0,0 +1,148 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+const (
+	// ControlStatusPath is the path of the control endpoint which reports the current ControlStatus.
+	ControlStatusPath = "/status"
+	// ControlPausePath is the path of the control endpoint which pauses all polls.
+	ControlPausePath = "/pause"
+	// ControlResumePath is the path of the control endpoint which resumes all polls.
+	ControlResumePath = "/resume"
+	// ControlReprocessPath is the path of the control endpoint which reprocesses an item through a watch.
+	ControlReprocessPath = "/reprocess"
+)
+
+// ControlStatus is returned by each control endpoint to report the state of the Pollinator.
+type ControlStatus struct {
+	Paused bool     `json:"paused"`
+	Polls  []string `json:"polls"`
+}
+
+// ReprocessRequest is the body of a request to the reprocess control endpoint.
+type ReprocessRequest struct {
+	Watch string `json:"watch"`
+	// Item references the item to reprocess, in the form 'owner/repo#number'.
+	Item  string `json:"item"`
+	Force bool   `json:"force"`
+}
+
+// controlStatus returns the ControlStatus of the given Pollinator.
+func controlStatus(p Pollinator) ControlStatus {
+	polls := p.List()
+	sort.Strings(polls)
+
+	return ControlStatus{
+		Paused: p.Paused(),
+		Polls:  polls,
+	}
+}
+
+// writeControlStatus writes the ControlStatus of the given Pollinator as JSON.
+func writeControlStatus(w http.ResponseWriter, p Pollinator) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(controlStatus(p)); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// NewControlHandler creates a new http.Handler which serves the control endpoints for the given Pollinator.
+// The status endpoint accepts GET requests, while the pause and resume endpoints accept POST requests.
+func NewControlHandler(p Pollinator) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc(ControlStatusPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		writeControlStatus(w, p)
+	})
+
+	mux.HandleFunc(ControlPausePath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		p.Pause()
+		writeControlStatus(w, p)
+	})
+
+	mux.HandleFunc(ControlResumePath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		p.Resume()
+		writeControlStatus(w, p)
+	})
+
+	return mux
+}
+
+// HandleControlEndpoints registers the control endpoints for the given Pollinator on the default http mux, so they
+// are served alongside the prometheus metrics by ServePromEndpoint.
+func HandleControlEndpoints(p Pollinator) {
+	h := NewControlHandler(p)
+
+	for _, path := range []string{ControlStatusPath, ControlPausePath, ControlResumePath} {
+		http.Handle(path, h)
+	}
+}
+
+// NewReprocessHandler creates a new http.Handler which serves the reprocess control endpoint for the given
+// Watchinator. It accepts POST requests with a JSON ReprocessRequest body, and responds with a JSON ReprocessResult.
+func NewReprocessHandler(w Watchinator) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		req := ReprocessRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(rw, fmt.Sprintf("unable to decode request: %s", err), http.StatusBadRequest)
+
+			return
+		}
+
+		ghr, number, err := ParseGitHubItemRef(req.Item)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		result, err := w.Reprocess(r.Context(), req.Watch, ghr, number, req.Force)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+
+		if err := json.NewEncoder(rw).Encode(result); err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// HandleReprocessEndpoint registers the reprocess control endpoint for the given Watchinator on the default http
+// mux, so it is served alongside the prometheus metrics by ServePromEndpoint.
+func HandleReprocessEndpoint(w Watchinator) {
+	http.Handle(ControlReprocessPath, NewReprocessHandler(w))
+}
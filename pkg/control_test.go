@@ -0,0 +1,101 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shurcooL/githubv4"
+	"gotest.tools/v3/assert"
+)
+
+func TestControlHandlerCanPauseAndResumePolls(t *testing.T) {
+	p := NewPollinator(context.Background(), debugLogger).WithClock(NewFakeClock(time.Now()))
+	p.Add("test-1", time.Millisecond*50, func(_ time.Time) error { return nil }, false)
+
+	defer p.StopAll()
+
+	server := httptest.NewServer(NewControlHandler(p))
+	defer server.Close()
+
+	doRequest := func(method string, path string) ControlStatus {
+		req, err := http.NewRequestWithContext(context.Background(), method, server.URL+path, nil)
+		assert.NilError(t, err)
+
+		resp, err := http.DefaultClient.Do(req)
+		assert.NilError(t, err)
+
+		defer resp.Body.Close()
+
+		assert.Equal(t, resp.StatusCode, http.StatusOK)
+
+		status := ControlStatus{}
+		assert.NilError(t, json.NewDecoder(resp.Body).Decode(&status))
+
+		return status
+	}
+
+	assert.DeepEqual(
+		t, doRequest(http.MethodGet, ControlStatusPath), ControlStatus{Paused: false, Polls: []string{"test-1"}},
+	)
+	assert.Equal(t, doRequest(http.MethodPost, ControlPausePath).Paused, true)
+	assert.Equal(t, p.Paused(), true)
+	assert.Equal(t, doRequest(http.MethodGet, ControlStatusPath).Paused, true)
+	assert.Equal(t, doRequest(http.MethodPost, ControlResumePath).Paused, false)
+	assert.Equal(t, p.Paused(), false)
+
+	resp, err := http.Get(server.URL + ControlPausePath)
+	assert.NilError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, resp.StatusCode, http.StatusMethodNotAllowed)
+}
+
+func TestReprocessHandler(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	gh.GetIssueReturn.Labels = []string{"a/requiredLabel"}
+
+	watch := NewTestWatch()
+	watch.Actions.Email.Enabled = false
+	assert.NilError(t, watch.ValidateAndPopulate(ctx, gh))
+
+	w := NewWatchinator(NewLogger(), gh, nil, nil, NewMockEmailinator()).(*watchinator)
+	w.config, w.gh, w.e = &Config{Watches: []*Watch{watch}}, gh, NewMockEmailinator()
+
+	server := httptest.NewServer(NewReprocessHandler(w))
+	defer server.Close()
+
+	doRequest := func(method string, body string) *http.Response {
+		req, err := http.NewRequestWithContext(ctx, method, server.URL, strings.NewReader(body))
+		assert.NilError(t, err)
+
+		resp, err := http.DefaultClient.Do(req)
+		assert.NilError(t, err)
+
+		t.Cleanup(func() { resp.Body.Close() })
+
+		return resp
+	}
+
+	assert.Equal(t, doRequest(http.MethodGet, "").StatusCode, http.StatusMethodNotAllowed)
+	assert.Equal(t, doRequest(http.MethodPost, "{").StatusCode, http.StatusBadRequest)
+	assert.Equal(
+		t, doRequest(http.MethodPost, `{"watch": "name", "item": "owner/repo"}`).StatusCode, http.StatusBadRequest,
+	)
+	assert.Equal(
+		t, doRequest(http.MethodPost, `{"watch": "unknown", "item": "owner/repo#1"}`).StatusCode,
+		http.StatusInternalServerError,
+	)
+
+	resp := doRequest(http.MethodPost, `{"watch": "name", "item": "owner/repo#1"}`)
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+
+	result := ReprocessResult{}
+	assert.NilError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.DeepEqual(t, result, ReprocessResult{Watch: "name", Item: "owner/repo#1", Matched: true})
+	assert.DeepEqual(t, gh.SetSubscriptionRequests, []githubv4.ID{gh.GetIssueReturn.ID})
+}
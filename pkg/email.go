@@ -5,6 +5,7 @@ import (
 	"crypto/tls"
 	"fmt"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/wneessen/go-mail"
@@ -77,39 +78,120 @@ type emailinator struct {
 	logger *slog.Logger
 }
 
-func (e *emailinator) newClient() (*mail.Client, error) {
-	if e.cfg == nil {
-		return nil, fmt.Errorf("unable to create email client, no config set")
+// EmailAuthMechanismNone skips authenticating to the SMTP service, such as for relays which only accept mail from
+// trusted hosts.
+const EmailAuthMechanismNone mail.SMTPAuthType = "NONE"
+
+// EmailAuthMechanisms are the SMTP authentication mechanisms which can be used to login to the SMTP service.
+var EmailAuthMechanisms = []mail.SMTPAuthType{
+	mail.SMTPAuthPlain,
+	mail.SMTPAuthLogin,
+	mail.SMTPAuthCramMD5,
+	mail.SMTPAuthXOAUTH2,
+	EmailAuthMechanismNone,
+}
+
+// getAuthType returns the SMTP authentication mechanism set in the config, defaulting to PLAIN. Mechanisms are case
+// insensitive.
+func (e *EmailConfig) getAuthType() mail.SMTPAuthType {
+	if e.AuthMechanism == "" {
+		return mail.SMTPAuthPlain
+	}
+
+	return mail.SMTPAuthType(strings.ToUpper(e.AuthMechanism))
+}
+
+// getPassword returns the password used to login to the SMTP service. XOAUTH2 access tokens expire, so they are read
+// again on each call, letting a refreshed token in the PasswordFile be picked up without reloading the config. Other
+// passwords are read once, when the config is validated.
+func (e *EmailConfig) getPassword() (string, error) {
+	if e.getAuthType() != mail.SMTPAuthXOAUTH2 {
+		return e.Password, nil
 	}
 
-	var authOption mail.Option
+	return readSecret("password", e.PasswordEnv, e.PasswordFile)
+}
+
+const (
+	// EmailTLSPolicyNone sends mail over an unencrypted connection.
+	EmailTLSPolicyNone = "none"
+	// EmailTLSPolicyOpportunistic upgrades the connection with STARTTLS if the SMTP service supports it, and otherwise
+	// sends mail unencrypted. Anyone able to strip STARTTLS from the connection can read the mail and credentials.
+	EmailTLSPolicyOpportunistic = "opportunistic"
+	// EmailTLSPolicySTARTTLS requires the connection to be upgraded with STARTTLS.
+	EmailTLSPolicySTARTTLS = "starttls"
+	// EmailTLSPolicyTLS requires the connection to be upgraded with STARTTLS, like EmailTLSPolicySTARTTLS. It is the
+	// default for port 587.
+	EmailTLSPolicyTLS = "tls"
+	// EmailTLSPolicySSL connects with TLS from the start, also known as SMTPS. It is the default for port 465.
+	EmailTLSPolicySSL = "ssl"
+)
+
+// EmailTLSPolicies are the ways the connection to the SMTP service can be encrypted.
+var EmailTLSPolicies = []string{
+	EmailTLSPolicyNone, EmailTLSPolicyOpportunistic, EmailTLSPolicySTARTTLS, EmailTLSPolicyTLS, EmailTLSPolicySSL,
+}
 
-	switch e.cfg.Port {
+// getTLSPolicy returns the TLS policy set in the config. If unset, it is inferred from the port, and is empty for ports
+// without a default. Policies are case insensitive.
+func (e *EmailConfig) getTLSPolicy() string {
+	if e.TLSPolicy != "" {
+		return strings.ToLower(e.TLSPolicy)
+	}
+
+	switch e.Port {
 	case 587:
-		authOption = mail.WithTLSConfig(&tls.Config{
-			ServerName: e.cfg.Host,
-			MinVersion: tls.VersionTLS12,
-		})
+		return EmailTLSPolicyTLS
 	case 465:
-		authOption = mail.WithSSLPort(false)
+		return EmailTLSPolicySSL
 	default:
-		return nil, fmt.Errorf("unrecognized port: %d", e.cfg.Port)
+		return ""
+	}
+}
+
+func (e *emailinator) newClient() (*mail.Client, error) {
+	if e.cfg == nil {
+		return nil, fmt.Errorf("unable to create email client, no config set")
+	}
+
+	password, err := e.cfg.getPassword()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create email client: %w", err)
 	}
 
-	c, err := mail.NewClient(
-		e.cfg.Host,
+	opts := []mail.Option{
 		mail.WithPort(e.cfg.Port),
 		mail.WithUsername(e.cfg.Username),
-		mail.WithPassword(e.cfg.Password),
+		mail.WithPassword(password),
 		mail.WithLogger(newGoMailLogConnector(e.logger)),
-		mail.WithSMTPAuth(mail.SMTPAuthPlain),
-		authOption,
-	)
+		mail.WithTLSConfig(&tls.Config{
+			ServerName: e.cfg.Host,
+			MinVersion: tls.VersionTLS12,
+		}),
+	}
 
+	if auth := e.cfg.getAuthType(); auth != EmailAuthMechanismNone {
+		opts = append(opts, mail.WithSMTPAuth(auth))
+	}
+
+	c, err := mail.NewClient(e.cfg.Host, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create email client: %w", err)
 	}
 
+	switch policy := e.cfg.getTLSPolicy(); policy {
+	case EmailTLSPolicyNone:
+		c.SetTLSPolicy(mail.NoTLS)
+	case EmailTLSPolicyOpportunistic:
+		c.SetTLSPolicy(mail.TLSOpportunistic)
+	case EmailTLSPolicySTARTTLS, EmailTLSPolicyTLS:
+		c.SetTLSPolicy(mail.TLSMandatory)
+	case EmailTLSPolicySSL:
+		c.SetSSL(true)
+	default:
+		return nil, fmt.Errorf("unrecognized tls policy '%s' for port %d", policy, e.cfg.Port)
+	}
+
 	c.SetDebugLog(e.logger.Enabled(context.Background(), slog.LevelDebug))
 
 	return c, nil
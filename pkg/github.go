@@ -1,9 +1,20 @@
 package pkg
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/hashicorp/go-retryablehttp"
@@ -17,8 +28,10 @@ import (
 type GitHubItemType string
 
 const (
-	GitHubItemIssue      GitHubItemType = "issue"
-	gitHubNotFoundErrStr string         = "Could not resolve to a"
+	GitHubItemIssue       GitHubItemType = "issue"
+	GitHubItemPullRequest GitHubItemType = "pullRequest"
+	GitHubItemDiscussion  GitHubItemType = "discussion"
+	gitHubNotFoundErrStr  string         = "Could not resolve to a"
 )
 
 // GitHubNotFoundError is raised when a GitHubinator cannot find the given item. It is a special error that can be
@@ -32,6 +45,20 @@ type GitHubActor struct {
 	Login string `json:"login"`
 }
 
+// GitHubGhostLogin is the login used for actors whose account has been deleted. GitHub returns a null actor in
+// this case, and shows the actor as "ghost" in its UI.
+const GitHubGhostLogin = "ghost"
+
+// asGitHubActorOrGhost returns the given actor, or an actor with the GitHubGhostLogin if the given actor is nil or
+// has an empty login.
+func asGitHubActorOrGhost(a *GitHubActor) GitHubActor {
+	if a == nil || a.Login == "" {
+		return GitHubActor{Login: GitHubGhostLogin}
+	}
+
+	return *a
+}
+
 func (a GitHubActor) LogValue() slog.Value {
 	return slog.GroupValue(
 		slog.String("login", a.Login),
@@ -44,6 +71,17 @@ func (a GitHubActor) LogValue() slog.Value {
 type GitHubRepository struct {
 	Owner string `json:"owner" yaml:"owner"`
 	Name  string `json:"name" yaml:"name"`
+	// Language is the repository's primary language, or empty if GitHub hasn't detected one. It is only populated
+	// when a selector references the repository's language or topics.
+	Language string `json:"language,omitempty" yaml:"-"`
+	// Topics are the repository's topics. It is only populated when a selector references the repository's
+	// language or topics.
+	Topics []string `json:"topics,omitempty" yaml:"-"`
+}
+
+// fullName returns the repository's name in the form 'owner/name', which identifies the repository.
+func (r GitHubRepository) fullName() string {
+	return r.Owner + "/" + r.Name
 }
 
 func (r GitHubRepository) LogValue() slog.Value {
@@ -53,6 +91,114 @@ func (r GitHubRepository) LogValue() slog.Value {
 	)
 }
 
+// ParseGitHubRepository parses a repository in the form 'owner/name'.
+func ParseGitHubRepository(repo string) (GitHubRepository, error) {
+	owner, name, ok := strings.Cut(strings.TrimSpace(repo), "/")
+
+	if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+		return GitHubRepository{}, fmt.Errorf("invalid repository '%s', expected 'owner/name'", repo)
+	}
+
+	return GitHubRepository{Owner: owner, Name: name}, nil
+}
+
+// ParseGitHubItemRef parses a reference to an item in the form 'owner/repo#number', returning the item's repository
+// and number.
+func ParseGitHubItemRef(ref string) (GitHubRepository, int, error) {
+	repo, number, ok := strings.Cut(strings.TrimSpace(ref), "#")
+	owner, name, hasName := strings.Cut(repo, "/")
+
+	if !ok || !hasName || owner == "" || name == "" || strings.Contains(name, "/") {
+		return GitHubRepository{}, 0, fmt.Errorf("invalid item '%s', expected 'owner/repo#number'", ref)
+	}
+
+	n, err := strconv.Atoi(number)
+	if err != nil || n <= 0 {
+		return GitHubRepository{}, 0, fmt.Errorf("invalid item number in '%s', expected 'owner/repo#number'", ref)
+	}
+
+	return GitHubRepository{Owner: owner, Name: name}, n, nil
+}
+
+var (
+	// issueReferenceRegex matches references to issues by number alone, such as '#100'.
+	issueReferenceRegex = regexp.MustCompile(`(?:^|[^\w/#])#(\d+)\b`)
+	// qualifiedIssueReferenceRegex matches references to issues with their repository, such as 'owner/repo#100'.
+	qualifiedIssueReferenceRegex = regexp.MustCompile(`(?:^|[^\w/.-])([\w.-]+)/([\w.-]+)#(\d+)\b`)
+	// issueURLReferenceRegex matches links to issues and pull requests on github.com.
+	issueURLReferenceRegex = issueURLReferenceRegexFor("github.com")
+)
+
+// issueURLReferenceRegexFor returns a regex matching links to issues and pull requests on the GitHub instance at the
+// given host.
+func issueURLReferenceRegexFor(host string) *regexp.Regexp {
+	return regexp.MustCompile(
+		`https?://` + regexp.QuoteMeta(host) + `/([\w.-]+)/([\w.-]+)/(?:issues|pull)/(\d+)\b`,
+	)
+}
+
+// GitHubHostFor returns the host of the GitHub Enterprise Server instance at the given base URL, such as
+// 'github.example.com' for 'https://github.example.com'. If the base URL is empty or can't be parsed, 'github.com' is
+// returned.
+func GitHubHostFor(baseURL string) string {
+	u, err := url.Parse(baseURL)
+	if baseURL == "" || err != nil || u.Host == "" {
+		return "github.com"
+	}
+
+	return u.Host
+}
+
+// ParseIssueReferences returns the numbers of the issues and pull requests in the given repository which are
+// referenced in the given Markdown, sorted and without duplicates. References may be a number alone, such as
+// '#100', a number with the repository, such as 'owner/repo#100', or a link to the issue or pull request on the
+// GitHub instance at the given host, see GitHubHostFor. Repository names are compared case-insensitively, as on
+// GitHub, and references to other repositories are ignored.
+func ParseIssueReferences(md string, ghr GitHubRepository, host string) []int {
+	seen := map[int]bool{}
+	numbers := []int{}
+
+	add := func(number string) {
+		n, err := strconv.Atoi(number)
+		if err == nil && n > 0 && !seen[n] {
+			seen[n] = true
+			numbers = append(numbers, n)
+		}
+	}
+
+	for _, m := range issueReferenceRegex.FindAllStringSubmatch(md, -1) {
+		add(m[1])
+	}
+
+	urlRegex := issueURLReferenceRegex
+	if !strings.EqualFold(host, "github.com") {
+		urlRegex = issueURLReferenceRegexFor(host)
+	}
+
+	for _, re := range []*regexp.Regexp{qualifiedIssueReferenceRegex, urlRegex} {
+		for _, m := range re.FindAllStringSubmatch(md, -1) {
+			if strings.EqualFold((GitHubRepository{Owner: m[1], Name: m[2]}).fullName(), ghr.fullName()) {
+				add(m[3])
+			}
+		}
+	}
+
+	sort.Ints(numbers)
+
+	return numbers
+}
+
+// containsRepository returns if the given repository is in the given slice of repositories.
+func containsRepository(repos []GitHubRepository, r GitHubRepository) bool {
+	for _, repo := range repos {
+		if repo.fullName() == r.fullName() {
+			return true
+		}
+	}
+
+	return false
+}
+
 // GitHubLabel represents an issue or PR label on GitHub.
 // Is is associated with the following GraphQL object:
 // https://docs.github.com/en/graphql/reference/objects#label.
@@ -60,10 +206,57 @@ type GitHubLabel struct {
 	Name string `json:"name"`
 }
 
+// GitHubProjectFieldValue represents the value of a custom field set on an item in a GitHub project (v2). Only
+// single select and text fields are supported.
+// It is associated with the following GraphQL union:
+// https://docs.github.com/en/graphql/reference/unions#projectv2itemfieldvalue.
+type GitHubProjectFieldValue struct {
+	ProjectNumber int    `json:"projectNumber"`
+	Field         string `json:"field"`
+	Value         string `json:"value"`
+}
+
+// LabelKey returns the key used for the field value in GitHubItemAsLabelSet. Fields are namespaced by their
+// project's number, as an item can belong to multiple projects. For instance, the field 'Severity' in project
+// number 3 has the key "project.3.field.severity".
+func (v GitHubProjectFieldValue) LabelKey() string {
+	return fmt.Sprintf("project.%d.field.%s", v.ProjectNumber, sanitizeLabelKeyPart(v.Field))
+}
+
+// invalidLabelKeyChars matches characters that cannot be used within a label selector key.
+var invalidLabelKeyChars = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// sanitizeLabelKeyPart converts the given string into a form that can be used as part of a label selector key,
+// by lowercasing it and replacing any invalid characters with a dash.
+func sanitizeLabelKeyPart(s string) string {
+	return strings.Trim(invalidLabelKeyChars.ReplaceAllString(strings.ToLower(s), "-"), "-._")
+}
+
+// labelKeyPrefix is prepended to each of an item's labels to form their keys in GitHubItemAsLabelSet.
+const labelKeyPrefix = "label."
+
+// gitHubLabelKey returns the key used for the given label in GitHubItemAsLabelSet. Like project fields, the label is
+// lowercased and any invalid characters are replaced with a dash, except that the first slash is kept as the key's
+// prefix separator. For instance, the label 'kind/bug' has the key "label.kind/bug", and 'Good First Issue' has the
+// key "label.good-first-issue".
+func gitHubLabelKey(label string) string {
+	prefix, name, found := strings.Cut(label, "/")
+	if !found {
+		return labelKeyPrefix + sanitizeLabelKeyPart(label)
+	}
+
+	// The prefix of a key must be a DNS subdomain, which can't contain underscores.
+	prefix = strings.ReplaceAll(sanitizeLabelKeyPart(prefix), "_", "-")
+
+	return labelKeyPrefix + prefix + "/" + sanitizeLabelKeyPart(name)
+}
+
 // GitHubIssue represents an issue on GitHub.
 // It is associated with the following GraphQL object:
 // https://docs.github.com/en/graphql/reference/objects#issue.
 type GitHubIssue struct {
+	// Author is the actor who opened the issue. If the author's account has been deleted, its login is
+	// GitHubGhostLogin.
 	Author       GitHubActor                `json:"author"`
 	Body         string                     `json:"body"`
 	Labels       []string                   `json:"labels"`
@@ -71,7 +264,35 @@ type GitHubIssue struct {
 	State        githubv4.IssueState        `json:"state"`
 	Subscription githubv4.SubscriptionState `json:"Subscription"`
 	Title        string                     `json:"title"`
+	CreatedAt    time.Time                  `json:"createdAt"`
 	UpdatedAt    time.Time                  `json:"updatedAt"`
+	// ClosedAt is when the issue was last closed. It is nil for issues which are open or were never closed.
+	ClosedAt *time.Time `json:"closedAt,omitempty"`
+	// URL is the HTML URL of the issue, such as 'https://github.com/owner/repo/issues/1'.
+	URL string `json:"url"`
+	// AuthorAssociation is the author's association with the repository, such as 'MEMBER' or
+	// 'FIRST_TIME_CONTRIBUTOR'.
+	AuthorAssociation githubv4.CommentAuthorAssociation `json:"authorAssociation"`
+	// ProjectFieldValues holds the custom field values set on the issue in GitHub projects. It is only populated
+	// when a selector references a project field.
+	ProjectFieldValues []GitHubProjectFieldValue `json:"projectFieldValues,omitempty"`
+	// LastActivityBy is the login of the actor of the issue's most recent timeline activity, such as a comment or a
+	// label change. It is only populated when a watch matches on it, and is empty if the issue has no activity.
+	LastActivityBy string `json:"lastActivityBy,omitempty"`
+	// ReferencedIssues are the numbers of the issues and pull requests in the same repository which the issue
+	// references, see ParseIssueReferences. It is only populated when a watch matches on it.
+	ReferencedIssues []int `json:"referencedIssues,omitempty"`
+	// BodyMarkdown is the raw Markdown of the issue body, whereas Body holds its plain text. It is populated
+	// alongside Body and is used to render the body as HTML.
+	BodyMarkdown string `json:"-"`
+	// Assignees are the logins of the users assigned to the issue. For issues, they are only populated when a watch
+	// matches on them.
+	Assignees []string `json:"assignees,omitempty"`
+	// Milestone is the title of the milestone the issue is in, or empty if it isn't in one.
+	Milestone string `json:"milestone,omitempty"`
+	// Comments holds the plain text of the issue's comments, oldest first. It is only populated when a watch matches
+	// on them.
+	Comments []string `json:"comments,omitempty"`
 }
 
 func (i GitHubIssue) LogValue() slog.Value {
@@ -82,7 +303,11 @@ func (i GitHubIssue) LogValue() slog.Value {
 		slog.String("state", string(i.State)),
 		slog.String("subscription", string(i.Subscription)),
 		slog.String("title", i.Title),
+		slog.Time("createdAt", i.CreatedAt),
 		slog.Time("updatedAt", i.UpdatedAt),
+		slog.Any("closedAt", i.ClosedAt),
+		slog.String("url", i.URL),
+		slog.String("authorAssociation", string(i.AuthorAssociation)),
 	)
 }
 
@@ -93,25 +318,103 @@ func NewTestGitHubIssue() *GitHubIssue {
 			Login: "actor",
 		},
 		Body:         "issue body",
+		BodyMarkdown: "issue **body**",
 		Labels:       []string{"a/test/label", "another/label"},
 		Number:       1,
 		State:        "OPEN",
 		Subscription: "UNSUBSCRIBED",
 		Title:        "a test issue",
+		URL:          "https://github.com/owner/repo/issues/1",
+		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
 	}
 }
 
+// GitHubPullRequest holds the fields of a pull request on GitHub which are not shared with issues.
+// It is associated with the following GraphQL object:
+// https://docs.github.com/en/graphql/reference/objects#pullrequest.
+type GitHubPullRequest struct {
+	IsDraft bool `json:"isDraft"`
+	Merged  bool `json:"merged"`
+}
+
+func (p GitHubPullRequest) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Bool("isDraft", p.IsDraft),
+		slog.Bool("merged", p.Merged),
+	)
+}
+
+// GitHubDiscussion holds the fields of a discussion on GitHub which are not shared with issues.
+// It is associated with the following GraphQL object:
+// https://docs.github.com/en/graphql/reference/objects#discussion.
+type GitHubDiscussion struct {
+	Category   string `json:"category"`
+	IsAnswered bool   `json:"isAnswered"`
+}
+
+func (d GitHubDiscussion) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("category", d.Category),
+		slog.Bool("isAnswered", d.IsAnswered),
+	)
+}
+
+// NewTestGitHubPullRequestItem creates a new instance of a GitHubItem for a pull request with pre-populated fields.
+// It can be used in unit tests.
+func NewTestGitHubPullRequestItem() *GitHubItem {
+	i := NewTestGitHubItem()
+	i.Type = GitHubItemPullRequest
+	i.Title = "a test pull request"
+	i.PullRequest = &GitHubPullRequest{}
+
+	return i
+}
+
 // GitHubIssueFilter is a filter that can be used when listing issues on GitHub.
 // It is associated with (but decoupled from) the following GraphQL input object:
 // https://docs.github.com/en/graphql/reference/input-objects#issuefilters.
 type GitHubIssueFilter struct {
 	Labels []string
 	States []string
+	// Since only lists items updated at or after the given time, if set. GitHub only supports it for issues, so it's
+	// applied to other items after listing them.
+	Since *time.Time
+	// Milestone only lists items in the milestone with the given title, or in any milestone if '*'. GitHub's issue
+	// filter takes a milestone number rather than a title, so only '*' is passed along to it, and titles are compared
+	// against every item after listing it.
+	Milestone string
+	// CreatedBy only lists items opened by the given login, compared case-insensitively. Like Since, it's applied to
+	// items other than issues after listing them. GitHub only accepts a single login, so filtering on several authors
+	// is left to a Matchinator, see WithAuthors.
+	CreatedBy string
+}
+
+// isBeforeSince returns if the given item was last updated before the filter's Since.
+func (f *GitHubIssueFilter) isBeforeSince(item *GitHubItem) bool {
+	return f.Since != nil && item.UpdatedAt.Before(*f.Since)
+}
+
+// isCreatedBy returns if the given item was opened by the filter's CreatedBy, or if no CreatedBy is set.
+func (f *GitHubIssueFilter) isCreatedBy(item *GitHubItem) bool {
+	return f.CreatedBy == "" || strings.EqualFold(item.Author.Login, f.CreatedBy)
+}
+
+// inMilestone returns if the given item is in the filter's Milestone, or if no Milestone is set.
+func (f *GitHubIssueFilter) inMilestone(item *GitHubItem) bool {
+	switch f.Milestone {
+	case "":
+		return true
+	case "*":
+		return item.Milestone != ""
+	default:
+		return item.Milestone == f.Milestone
+	}
 }
 
 // asGithubv4IssueFilters converts the GitHubIssueFilter into a githubv4.IssueFilters struct for usage in the
 // githubv4 GraphQL library. It performs specific type conversions and formats issue states in all caps.
+// GitHub ORs the given states together, so multiple states can be queried in a single pass.
 func (f *GitHubIssueFilter) asGithubv4IssueFilters() githubv4.IssueFilters {
 	var labels *[]githubv4.String = nil
 
@@ -132,9 +435,31 @@ func (f *GitHubIssueFilter) asGithubv4IssueFilters() githubv4.IssueFilters {
 		}
 	}
 
+	var since *githubv4.DateTime = nil
+
+	if f.Since != nil {
+		since = &githubv4.DateTime{Time: *f.Since}
+	}
+
+	var milestone *githubv4.String = nil
+
+	// GitHub reads any other value as a milestone number, titles are matched by inMilestone instead.
+	if f.Milestone == "*" {
+		milestone = githubv4.NewString(githubv4.String(f.Milestone))
+	}
+
+	var createdBy *githubv4.String = nil
+
+	if f.CreatedBy != "" {
+		createdBy = githubv4.NewString(githubv4.String(f.CreatedBy))
+	}
+
 	return githubv4.IssueFilters{
-		Labels: labels,
-		States: states,
+		Labels:    labels,
+		States:    states,
+		Since:     since,
+		Milestone: milestone,
+		CreatedBy: createdBy,
 	}
 }
 
@@ -142,9 +467,59 @@ func (f *GitHubIssueFilter) asGithubv4IssueFilters() githubv4.IssueFilters {
 // a common format for label selectors.
 type GitHubItem struct {
 	GitHubIssue
-	Type GitHubItemType   `json:"type"`
-	Repo GitHubRepository `json:"repo"`
-	ID   githubv4.ID      `json:"id"`
+	// PullRequest holds the pull request specific fields of the item. It is nil unless Type is
+	// GitHubItemPullRequest.
+	PullRequest *GitHubPullRequest `json:"pullRequest,omitempty"`
+	// Discussion holds the discussion specific fields of the item. It is nil unless Type is GitHubItemDiscussion.
+	Discussion *GitHubDiscussion `json:"discussion,omitempty"`
+	Type       GitHubItemType    `json:"type"`
+	Repo       GitHubRepository  `json:"repo"`
+	ID         githubv4.ID       `json:"id"`
+	// AgeBucket is the name of the age bucket the item falls in, based on its CreatedAt. It is derived by the
+	// Matchinator when matching the item, see AgeBucket.
+	AgeBucket string `json:"-"`
+	// LabelsFetched, BodyFetched and AssigneesFetched report if all of the item's labels, its body and its assignees
+	// were fetched along with the item, so they don't need to be fetched separately.
+	LabelsFetched    bool `json:"-"`
+	BodyFetched      bool `json:"-"`
+	AssigneesFetched bool `json:"-"`
+}
+
+// IsDraft returns if the item is a draft pull request. Issues are never drafts.
+func (i *GitHubItem) IsDraft() bool {
+	return i.PullRequest != nil && i.PullRequest.IsDraft
+}
+
+// Category returns the category of the item if it is a discussion. Other items have no category.
+func (i *GitHubItem) Category() string {
+	if i.Discussion == nil {
+		return ""
+	}
+
+	return i.Discussion.Category
+}
+
+// IsMerged returns if the item is a merged pull request. Issues are never merged.
+func (i *GitHubItem) IsMerged() bool {
+	return i.PullRequest != nil && i.PullRequest.Merged
+}
+
+// SortGitHubItems sorts the given items in place by repository, then by number, so they are handled in a stable
+// order.
+func SortGitHubItems(items []*GitHubItem) {
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+
+		if a.Repo.Owner != b.Repo.Owner {
+			return a.Repo.Owner < b.Repo.Owner
+		}
+
+		if a.Repo.Name != b.Repo.Name {
+			return a.Repo.Name < b.Repo.Name
+		}
+
+		return a.Number < b.Number
+	})
 }
 
 // NewTestGitHubItem creates a new instance of a GitHubItem with pre-populated fields. It can be used in unit tests.
@@ -165,51 +540,124 @@ func (i GitHubItem) LogValue() slog.Value {
 	switch i.Type {
 	case GitHubItemIssue:
 		embeddedAttr = slog.Any("issue", i.GitHubIssue.LogValue())
+	case GitHubItemPullRequest:
+		embeddedAttr = slog.Any("pullRequest", i.GitHubIssue.LogValue())
+	case GitHubItemDiscussion:
+		embeddedAttr = slog.Any("discussion", i.GitHubIssue.LogValue())
 	default:
 		embeddedAttr = slog.String("embedded", "<none>")
 	}
 
-	return slog.GroupValue(
+	attrs := []slog.Attr{
 		embeddedAttr,
 		slog.String("type", string(i.Type)),
 		slog.Any("repo", i.Repo.LogValue()),
 		slog.Any("id", i.ID),
-	)
+	}
+
+	if i.PullRequest != nil {
+		attrs = append(attrs, slog.Any("pullRequestFields", i.PullRequest.LogValue()))
+	}
+
+	if i.Discussion != nil {
+		attrs = append(attrs, slog.Any("discussionFields", i.Discussion.LogValue()))
+	}
+
+	return slog.GroupValue(attrs...)
 }
 
 // GitHubItemAsLabelSet converts the given GitHubItem into a k8s.io/apimachinery/pkg/labels.Set, for applying label
 // selectors specified in a Watch. Fields are convered into lowercase keys in the map, and values are converted
 // into strings. Nested structs in a GitHubItem will have their fields writtin with dot-notation. For instance,
-// GitHubItem.Repo.Name will have the key "repo.name" in the returned set.
+// GitHubItem.Repo.Name will have the key "repo.name" in the returned set. The keys "draft" and "merged" are "true"
+// only for draft and merged pull requests respectively. The key "category" holds the category of discussions, which is
+// lowercased with invalid characters replaced so it can be selected, such as "q-a" for "Q&A".
+// The repository's topics are not part of the set, as an item can have many, see
+// SelectorAsGitHubItemMatcher. The key "assignees" holds the logins of the item's assignees joined by commas, and is
+// only set if the item has any, so 'assignees' and '!assignees' select assigned and unassigned items. The keys
+// "createdAt" and "closedAt" hold Unix timestamps in seconds, which selectors can compare with '>' and '<' as label
+// values can't hold the colons of RFC3339 timestamps, and "closedAt" is only set for items which were closed.
+// The key "url" holds the item's HTML URL, and "author.association" holds the author's association with the
+// repository, such as "FIRST_TIME_CONTRIBUTOR". Each of the item's labels is added with the value "true", using the key
+// from gitHubLabelKey, such as "label.kind/bug".
+// Project field values are added using the key from GitHubProjectFieldValue.LabelKey.
 // This function does not use reflect, and is therefore coupled with the GitHubItem definition.
 func GitHubItemAsLabelSet(i *GitHubItem) labels.Set {
 	m := map[string]string{
-		"type":         string(i.Type),
-		"repo.owner":   i.Repo.Owner,
-		"repo.name":    i.Repo.Name,
-		"author.login": i.Author.Login,
-		"body":         i.Body,
-		"number":       strconv.Itoa(i.Number),
-		"title":        i.Title,
-		"state":        string(i.State),
-		"subscription": string(i.Subscription),
+		"type":               string(i.Type),
+		"repo.owner":         i.Repo.Owner,
+		"repo.name":          i.Repo.Name,
+		"repo.language":      i.Repo.Language,
+		"author.login":       i.Author.Login,
+		"author.association": string(i.AuthorAssociation),
+		"body":               i.Body,
+		"number":             strconv.Itoa(i.Number),
+		"title":              i.Title,
+		"state":              string(i.State),
+		"subscription":       string(i.Subscription),
+		"draft":              strconv.FormatBool(i.IsDraft()),
+		"merged":             strconv.FormatBool(i.IsMerged()),
+		"category":           sanitizeLabelKeyPart(i.Category()),
+		"ageBucket":          i.AgeBucket,
+		"milestone":          i.Milestone,
+		"createdAt":          strconv.FormatInt(i.CreatedAt.Unix(), 10),
+		"url":                i.URL,
+	}
+
+	if i.ClosedAt != nil {
+		m["closedAt"] = strconv.FormatInt(i.ClosedAt.Unix(), 10)
+	}
+
+	if len(i.Assignees) > 0 {
+		m["assignees"] = strings.Join(i.Assignees, ",")
+	}
+
+	for _, l := range i.Labels {
+		m[gitHubLabelKey(l)] = "true"
+	}
+
+	for _, v := range i.ProjectFieldValues {
+		m[v.LabelKey()] = v.Value
 	}
 
 	return labels.Set(m)
 }
 
+// projectFieldKey matches label selector keys which target a project field value.
+var projectFieldKey = regexp.MustCompile(`^project\.[0-9]+\.field\..+$`)
+
+// isProjectFieldKey returns if the given label selector key targets a project field value.
+func isProjectFieldKey(f string) bool {
+	return projectFieldKey.MatchString(f)
+}
+
 // isGitHubItemField is used to validate if a label selector is targeting an actual field present in a GitHubItem.
 // This function does not use reflect, and is therefore coupled with the GitHubItem definition.
 func isGitHubItemField(f string) bool {
 	switch f {
-	case "type", "repo.owner", "repo.name", "author.login", "body", "number", "title", "state", "subscription":
+	case "type", "repo.owner", "repo.name", "author.login", "body", "number", "title", "state", "subscription",
+		"draft", "merged", "category", "repo.language", "repo.topic", "ageBucket", "assignees", "milestone",
+		"createdAt", "closedAt", "url", "author.association":
 		return true
 	}
 
-	return false
+	return isProjectFieldKey(f) || isLabelKey(f)
+}
+
+// isLabelKey returns if the given label selector key targets one of the item's labels.
+func isLabelKey(f string) bool {
+	return strings.HasPrefix(f, labelKeyPrefix) && len(f) > len(labelKeyPrefix)
+}
+
+// isRepositoryMetadataKey returns if the given label selector key targets the repository's language or topics,
+// which are fetched separately from the item.
+func isRepositoryMetadataKey(f string) bool {
+	return f == "repo.language" || f == "repo.topic"
 }
 
 type gitHubViewerQuery struct {
+	gitHubQueryRateLimit
+
 	Viewer struct {
 		Login    githubv4.String
 		IsViewer githubv4.Boolean
@@ -224,6 +672,8 @@ func (q gitHubViewerQuery) LogValue() slog.Value {
 }
 
 type gitHubRepositoryQuery struct {
+	gitHubQueryRateLimit
+
 	Repository struct {
 		Name githubv4.String
 	} `graphql:"repository(owner: $owner, name: $name)"`
@@ -235,6 +685,23 @@ func (q gitHubRepositoryQuery) LogValue() slog.Value {
 	)
 }
 
+// gitHubOpenIssueCountQuery is used to query GitHub's graphql API for the number of open issues in a repository.
+type gitHubOpenIssueCountQuery struct {
+	gitHubQueryRateLimit
+
+	Repository struct {
+		Issues struct {
+			TotalCount githubv4.Int
+		} `graphql:"issues(states: OPEN)"`
+	} `graphql:"repository(owner: $owner, name: $name)"`
+}
+
+func (q gitHubOpenIssueCountQuery) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("totalCount", int(q.Repository.Issues.TotalCount)),
+	)
+}
+
 type gitHubRepositoryQueryVars struct {
 	Name  githubv4.String
 	Owner githubv4.String
@@ -254,8 +721,151 @@ func (v *gitHubRepositoryQueryVars) AsMap() map[string]any {
 	}
 }
 
+// gitHubRepositoryMetadataQuery is used to query GitHub's graphql API for a repository's primary language and topics.
+type gitHubRepositoryMetadataQuery struct {
+	gitHubQueryRateLimit
+
+	Repository struct {
+		PrimaryLanguage *struct {
+			Name githubv4.String
+		}
+		RepositoryTopics struct {
+			Nodes []struct {
+				Topic struct {
+					Name githubv4.String
+				}
+			}
+		} `graphql:"repositoryTopics(first: 100)"`
+	} `graphql:"repository(owner: $owner, name: $name)"`
+}
+
+// AsGitHubRepository returns the given repository with its language and topics set from the query.
+func (q *gitHubRepositoryMetadataQuery) AsGitHubRepository(ghr GitHubRepository) GitHubRepository {
+	ghr.Language = ""
+	if q.Repository.PrimaryLanguage != nil {
+		ghr.Language = string(q.Repository.PrimaryLanguage.Name)
+	}
+
+	ghr.Topics = []string{}
+	for _, n := range q.Repository.RepositoryTopics.Nodes {
+		ghr.Topics = append(ghr.Topics, string(n.Topic.Name))
+	}
+
+	return ghr
+}
+
+func (q gitHubRepositoryMetadataQuery) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Any("primaryLanguage", q.Repository.PrimaryLanguage),
+		slog.Any("repositoryTopics", q.Repository.RepositoryTopics.Nodes),
+	)
+}
+
+// gitHubViewerRepositoriesQuery is used to query GitHub's graphql API for the repositories the viewer owns or
+// collaborates on.
+type gitHubViewerRepositoriesQuery struct {
+	gitHubQueryRateLimit
+
+	Viewer struct {
+		Repositories struct {
+			Nodes []struct {
+				Name  githubv4.String
+				Owner struct {
+					Login githubv4.String
+				}
+			}
+			PageInfo struct {
+				EndCursor   githubv4.String
+				HasNextPage githubv4.Boolean
+			}
+		} `graphql:"repositories(first: $n, after: $reposCursor, ownerAffiliations: [OWNER, COLLABORATOR])"`
+	}
+}
+
+func (q gitHubViewerRepositoriesQuery) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("endCursor", string(q.Viewer.Repositories.PageInfo.EndCursor)),
+		slog.Bool("hasNextPage", bool(q.Viewer.Repositories.PageInfo.HasNextPage)),
+		slog.Any("nodes", q.Viewer.Repositories.Nodes),
+	)
+}
+
+// gitHubViewerRepositoriesQueryVars represents the variables that can be passed to a gitHubViewerRepositoriesQuery.
+type gitHubViewerRepositoriesQueryVars struct {
+	N           githubv4.Int
+	ReposCursor *githubv4.String
+}
+
+func (v *gitHubViewerRepositoriesQueryVars) AsMap() map[string]any {
+	return map[string]any{
+		"n":           v.N,
+		"reposCursor": v.ReposCursor,
+	}
+}
+
+func (v gitHubViewerRepositoriesQueryVars) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("n", int(v.N)),
+		slog.Any("reposCursor", v.ReposCursor),
+	)
+}
+
+// GitHubRateLimit represents the state of the viewer's GraphQL API rate limit.
+// It is associated with the following GraphQL object:
+// https://docs.github.com/en/graphql/reference/objects#ratelimit.
+type GitHubRateLimit struct {
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Used      int       `json:"used"`
+	ResetAt   time.Time `json:"resetAt"`
+}
+
+func (r GitHubRateLimit) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("limit", r.Limit),
+		slog.Int("remaining", r.Remaining),
+		slog.Int("used", r.Used),
+		slog.Time("resetAt", r.ResetAt),
+	)
+}
+
+type gitHubRateLimitQuery struct {
+	RateLimit struct {
+		Limit     githubv4.Int
+		Remaining githubv4.Int
+		Used      githubv4.Int
+		ResetAt   githubv4.DateTime
+	}
+}
+
+func (q gitHubRateLimitQuery) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("limit", int(q.RateLimit.Limit)),
+		slog.Int("remaining", int(q.RateLimit.Remaining)),
+		slog.Int("used", int(q.RateLimit.Used)),
+		slog.Time("resetAt", q.RateLimit.ResetAt.Time),
+	)
+}
+
+// gitHubQueryRateLimit is embedded in queries to fetch the state of the viewer's rate limit, and the cost of the
+// query, along with the query's results. See gitHubinator.query.
+type gitHubQueryRateLimit struct {
+	RateLimit struct {
+		Cost      githubv4.Int
+		Remaining githubv4.Int
+		ResetAt   githubv4.DateTime
+	}
+}
+
+// queryRateLimit returns the gitHubQueryRateLimit, so it can be retrieved from any query embedding it.
+func (r *gitHubQueryRateLimit) queryRateLimit() *gitHubQueryRateLimit {
+	return r
+}
+
 // gitHubLabelQuery is used to query GitHub's graphql API for labels on an issue.
 type gitHubLabelQuery struct {
+	gitHubQueryRateLimit
+
 	Repository struct {
 		Issue struct {
 			Labels struct {
@@ -308,428 +918,3298 @@ func (v gitHubLabelQueryVars) LogValue() slog.Value {
 	)
 }
 
-type gitHubIssueBodyQuery struct {
+// gitHubLabelIDQuery is used to query GitHub's graphql API for the ID of a label in a repository.
+type gitHubLabelIDQuery struct {
+	gitHubQueryRateLimit
+
 	Repository struct {
-		Issue struct {
-			BodyText githubv4.String
-		} `graphql:"issue(number: $issueNumber)"`
+		Label *struct {
+			ID githubv4.ID
+		} `graphql:"label(name: $labelName)"`
 	} `graphql:"repository(owner: $owner, name: $name)"`
 }
 
-func (q gitHubIssueBodyQuery) LogValue() slog.Value {
-	return slog.GroupValue(
-		slog.String("bodyText", string(q.Repository.Issue.BodyText)),
-	)
+func (q gitHubLabelIDQuery) LogValue() slog.Value {
+	return slog.GroupValue(slog.Any("label", q.Repository.Label))
 }
 
-type gitHubIssueBodyQueryVars struct {
-	Owner       githubv4.String
-	Name        githubv4.String
-	IssueNumber githubv4.Int
+// gitHubLabelIDQueryVars represents the variables that can be passed to a gitHubLabelIDQuery.
+type gitHubLabelIDQueryVars struct {
+	Owner     githubv4.String
+	Name      githubv4.String
+	LabelName githubv4.String
 }
 
-func (v *gitHubIssueBodyQueryVars) AsMap() map[string]any {
+func (v *gitHubLabelIDQueryVars) AsMap() map[string]any {
 	return map[string]any{
-		"owner":       v.Owner,
-		"name":        v.Name,
-		"issueNumber": v.IssueNumber,
+		"owner":     v.Owner,
+		"name":      v.Name,
+		"labelName": v.LabelName,
 	}
 }
 
-func (v gitHubIssueBodyQueryVars) LogValue() slog.Value {
+func (v gitHubLabelIDQueryVars) LogValue() slog.Value {
 	return slog.GroupValue(
 		slog.String("owner", string(v.Owner)),
 		slog.String("name", string(v.Name)),
-		slog.Int("issueNumber", int(v.IssueNumber)),
+		slog.String("labelName", string(v.LabelName)),
 	)
 }
 
-// gitHubIssueQuery is used to query the GitHub graphql for an issue.
-// Because the GitHub issue contains paginated labels, we need to do a special work around to query the issue and
-// labels separately to fill in a GitHubIssue struct.
-type gitHubIssueQuery struct {
-	Repository struct {
-		Issues struct {
-			Nodes []struct {
-				Author             GitHubActor
-				ID                 githubv4.ID
-				Number             githubv4.Int
-				Title              githubv4.String
-				State              githubv4.IssueState
-				UpdatedAt          githubv4.DateTime
-				ViewerSubscription githubv4.SubscriptionState
-			}
-			PageInfo struct {
-				EndCursor   githubv4.String
-				HasNextPage githubv4.Boolean
-			}
-		} `graphql:"issues(first: $n, after: $issuesCursor, filterBy: $filters)"`
-	} `graphql:"repository(owner: $owner, name: $name)"`
+// gitHubComments holds the most recent comments on an issue or pull request.
+type gitHubComments struct {
+	Comments struct {
+		Nodes []struct {
+			Body            githubv4.String
+			ViewerDidAuthor githubv4.Boolean
+		}
+	} `graphql:"comments(last: 100)"`
 }
 
-// AsGitHubIssue converts the gitHubIssueQuery into a map of the contained issues to their IDs.
-func (q *gitHubIssueQuery) AsGitHubIssues() map[githubv4.ID]*GitHubIssue {
-	issues := map[githubv4.ID]*GitHubIssue{}
+// gitHubCommentQuery is used to query GitHub's graphql API for the last 100 comments on an issue or pull request.
+type gitHubCommentQuery struct {
+	gitHubQueryRateLimit
 
-	for _, n := range q.Repository.Issues.Nodes {
-		issues[n.ID] = &GitHubIssue{
-			Author:       n.Author,
-			Body:         "",
-			Labels:       []string{},
-			Number:       int(n.Number),
-			State:        n.State,
-			Subscription: n.ViewerSubscription,
-			Title:        string(n.Title),
-			UpdatedAt:    n.UpdatedAt.Time,
-		}
+	Node struct {
+		Issue       gitHubComments `graphql:"... on Issue"`
+		PullRequest gitHubComments `graphql:"... on PullRequest"`
+	} `graphql:"node(id: $id)"`
+}
+
+func (q gitHubCommentQuery) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("issueComments", len(q.Node.Issue.Comments.Nodes)),
+		slog.Int("pullRequestComments", len(q.Node.PullRequest.Comments.Nodes)),
+	)
+}
+
+// ViewerCommented returns if the viewer authored any of the queried comments containing the given marker.
+func (q *gitHubCommentQuery) ViewerCommented(marker string) bool {
+	for _, c := range []gitHubComments{q.Node.Issue, q.Node.PullRequest} {
+		for _, n := range c.Comments.Nodes {
+			if bool(n.ViewerDidAuthor) && strings.Contains(string(n.Body), marker) {
+				return true
+			}
+		}
 	}
 
-	return issues
+	return false
 }
 
-func (q gitHubIssueQuery) LogValue() slog.Value {
+// gitHubAssigneeQuery is used to query GitHub's graphql API for the assignees of an issue.
+type gitHubAssigneeQuery struct {
+	gitHubQueryRateLimit
+
+	Repository struct {
+		Issue struct {
+			Assignees struct {
+				Nodes []struct {
+					Login string
+				}
+				PageInfo struct {
+					EndCursor   githubv4.String
+					HasNextPage githubv4.Boolean
+				}
+			} `graphql:"assignees(first: $n, after: $assigneesCursor)"`
+		} `graphql:"issue(number: $issueNumber)"`
+	} `graphql:"repository(owner: $owner, name: $name)"`
+}
+
+func (q gitHubAssigneeQuery) LogValue() slog.Value {
 	return slog.GroupValue(
-		slog.String("endCursor", string(q.Repository.Issues.PageInfo.EndCursor)),
-		slog.Bool("hasNextPage", bool(q.Repository.Issues.PageInfo.HasNextPage)),
-		slog.Any("nodes", q.Repository.Issues.Nodes),
+		slog.String("endCursor", string(q.Repository.Issue.Assignees.PageInfo.EndCursor)),
+		slog.Bool("hasNextPage", bool(q.Repository.Issue.Assignees.PageInfo.HasNextPage)),
+		slog.Any("nodes", q.Repository.Issue.Assignees.Nodes),
 	)
 }
 
-// gitHubIssueQueryVars represents the variables that can be passed to a gitHubIssueQuery.
-type gitHubIssueQueryVars struct {
-	Owner        githubv4.String
-	Name         githubv4.String
-	Filters      githubv4.IssueFilters
-	IssuesCursor *githubv4.String
-	N            githubv4.Int
+// gitHubAssigneeQueryVars represents the variables that can be passed to a gitHubAssigneeQuery.
+type gitHubAssigneeQueryVars struct {
+	Owner           githubv4.String
+	Name            githubv4.String
+	IssueNumber     githubv4.Int
+	N               githubv4.Int
+	AssigneesCursor *githubv4.String
 }
 
-func (q gitHubIssueQueryVars) AsMap() map[string]any {
+func (v *gitHubAssigneeQueryVars) AsMap() map[string]any {
 	return map[string]any{
-		"owner":        q.Owner,
-		"name":         q.Name,
-		"filters":      q.Filters,
-		"issuesCursor": q.IssuesCursor,
-		"n":            q.N,
+		"owner":           v.Owner,
+		"name":            v.Name,
+		"issueNumber":     v.IssueNumber,
+		"n":               v.N,
+		"assigneesCursor": v.AssigneesCursor,
 	}
 }
 
-func (q gitHubIssueQueryVars) LogValue() slog.Value {
+func (v gitHubAssigneeQueryVars) LogValue() slog.Value {
 	return slog.GroupValue(
-		slog.String("owner", string(q.Owner)),
-		slog.String("name", string(q.Name)),
-		slog.Any("issuesCursor", q.IssuesCursor),
-		slog.Int("n", int(q.N)),
-		slog.Any("filters", q.Filters),
+		slog.String("owner", string(v.Owner)),
+		slog.String("name", string(v.Name)),
+		slog.Int("issueNumber", int(v.IssueNumber)),
+		slog.Int("n", int(v.N)),
+		slog.Any("assigneesCursor", v.AssigneesCursor),
 	)
 }
 
-// GitHubinator is used to fetch and update data from GitHub. The With* builder methods return a new instance of
-// a GitHubinator.
-type GitHubinator interface {
-	// WithRetries will set the number of retries the GitHubinator will use when fetching or updating data.
-	WithRetries(retries int) GitHubinator
-
-	// WithTimeout sets the amount of time per try that the GitHubinator will wait for success before assuming a
-	// request has failed.
-	WithTimeout(timeout time.Duration) GitHubinator
+// gitHubIssueCommentsQuery is used to query GitHub's graphql API for the comments on an issue.
+type gitHubIssueCommentsQuery struct {
+	gitHubQueryRateLimit
 
-	// WithToken sets the authentication token to use for the GH API, such as a PAT.
-	// A test request will be sent to GitHub to verify authentication.
-	WithToken(token string) GitHubinator
+	Repository struct {
+		Issue struct {
+			Comments struct {
+				Nodes []struct {
+					BodyText githubv4.String
+				}
+				PageInfo struct {
+					EndCursor   githubv4.String
+					HasNextPage githubv4.Boolean
+				}
+			} `graphql:"comments(first: $n, after: $commentsCursor)"`
+		} `graphql:"issue(number: $issueNumber)"`
+	} `graphql:"repository(owner: $owner, name: $name)"`
+}
 
-	// WhoAmI will make a test query to GitHub to get the name and login for the given PAT.
-	WhoAmI(ctx context.Context) (string, error)
+func (q gitHubIssueCommentsQuery) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("endCursor", string(q.Repository.Issue.Comments.PageInfo.EndCursor)),
+		slog.Bool("hasNextPage", bool(q.Repository.Issue.Comments.PageInfo.HasNextPage)),
+		slog.Int("count", len(q.Repository.Issue.Comments.Nodes)),
+	)
+}
 
-	// CheckRepository checks if the given repository exists.
-	CheckRepository(ctx context.Context, ghr GitHubRepository) error
+// gitHubIssueCommentsQueryVars represents the variables that can be passed to a gitHubIssueCommentsQuery.
+type gitHubIssueCommentsQueryVars struct {
+	Owner          githubv4.String
+	Name           githubv4.String
+	IssueNumber    githubv4.Int
+	N              githubv4.Int
+	CommentsCursor *githubv4.String
+}
 
-	// ListIssues returns a list of issues for the given repository.
-	ListIssues(
-		ctx context.Context, ghr GitHubRepository, filter *GitHubIssueFilter, matcher Matchinator,
-	) ([]*GitHubItem, error)
+func (v *gitHubIssueCommentsQueryVars) AsMap() map[string]any {
+	return map[string]any{
+		"owner":          v.Owner,
+		"name":           v.Name,
+		"issueNumber":    v.IssueNumber,
+		"n":              v.N,
+		"commentsCursor": v.CommentsCursor,
+	}
+}
 
-	// SetSubscription sets the subscription state of the given item for the viewer.
-	SetSubscription(ctx context.Context, id githubv4.ID, state githubv4.SubscriptionState) error
+func (v gitHubIssueCommentsQueryVars) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("owner", string(v.Owner)),
+		slog.String("name", string(v.Name)),
+		slog.Int("issueNumber", int(v.IssueNumber)),
+		slog.Int("n", int(v.N)),
+		slog.Any("commentsCursor", v.CommentsCursor),
+	)
 }
 
-// MockGitHubinator implements the GitHubinator interface. The returned values from its methods can be controlled,
-// allowing for it to be used for unit testing.
-type MockGitHubinator struct {
-	// CheckRepositoryRequests holds th parameters to calls to CheckRepository.
-	CheckRepositoryRequests []GitHubRepository
+type gitHubIssueBodyQuery struct {
+	gitHubQueryRateLimit
 
-	// CheckRepositoryError holds the returned error for CheckRepository.
-	CheckRepositoryError error
+	Repository struct {
+		Issue struct {
+			Body     githubv4.String
+			BodyText githubv4.String
+		} `graphql:"issue(number: $issueNumber)"`
+	} `graphql:"repository(owner: $owner, name: $name)"`
+}
 
-	// WhoAmIRequests holds the number of times WhoAmI has been called
-	WhoAmIRequests int
+func (q gitHubIssueBodyQuery) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("bodyText", string(q.Repository.Issue.BodyText)),
+	)
+}
 
-	// WhoAmIReturn holds the user returned from calls to WhoAmI.
-	WhoAmIReturn string
+type gitHubIssueBodyQueryVars struct {
+	Owner       githubv4.String
+	Name        githubv4.String
+	IssueNumber githubv4.Int
+}
 
-	// WhoAmIError holds the errors that will be returned from WhoAmI.
-	WhoAmIError error
+func (v *gitHubIssueBodyQueryVars) AsMap() map[string]any {
+	return map[string]any{
+		"owner":       v.Owner,
+		"name":        v.Name,
+		"issueNumber": v.IssueNumber,
+	}
+}
 
-	// SetSubscriptionRequests holds the issue IDs passed to SetSubscription.
-	SetSubscriptionRequests []githubv4.ID
+func (v gitHubIssueBodyQueryVars) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("owner", string(v.Owner)),
+		slog.String("name", string(v.Name)),
+		slog.Int("issueNumber", int(v.IssueNumber)),
+	)
+}
 
-	// SetSubscriptionError holds the returned error for SetSubscription
-	SetSubscriptionError error
+// gitHubTimelineEventActor holds the actor of a timeline event.
+type gitHubTimelineEventActor struct {
+	Actor *GitHubActor
 }
 
-func (t *MockGitHubinator) WithRetries(_ int) GitHubinator { return t }
+// asURL returns the given URI as a string, or an empty string if it wasn't set.
+func asURL(u githubv4.URI) string {
+	if u.URL == nil {
+		return ""
+	}
 
-func (t *MockGitHubinator) WithTimeout(_ time.Duration) GitHubinator { return t }
+	return u.URL.String()
+}
 
-func (t *MockGitHubinator) WithToken(_ string) GitHubinator { return t }
+// asClosedAt returns the time of the given closedAt field, which is null for items which aren't closed, or nil if it
+// is nil.
+func asClosedAt(closedAt *githubv4.DateTime) *time.Time {
+	if closedAt == nil {
+		return nil
+	}
 
-func (t *MockGitHubinator) WhoAmI(_ context.Context) (string, error) {
-	t.WhoAmIRequests += 1
+	return &closedAt.Time
+}
 
-	return t.WhoAmIReturn, t.WhoAmIError
+// gitHubMilestone holds the title of the milestone an issue or pull request is in. It is null in responses for items
+// which aren't in a milestone.
+type gitHubMilestone struct {
+	Title githubv4.String
 }
 
-func (t *MockGitHubinator) CheckRepository(ctx context.Context, ghr GitHubRepository) error {
-	t.CheckRepositoryRequests = append(t.CheckRepositoryRequests, ghr)
+// asMilestoneTitle returns the title of the given milestone, or an empty string if it is nil.
+func asMilestoneTitle(m *gitHubMilestone) string {
+	if m == nil {
+		return ""
+	}
 
-	return t.CheckRepositoryError
+	return string(m.Title)
 }
 
-func (t *MockGitHubinator) ListIssues(
-	ctx context.Context, ghr GitHubRepository, filter *GitHubIssueFilter, matcher Matchinator,
-) ([]*GitHubItem, error) {
-	return nil, nil
+// gitHubGetIssueQuery is used to query GitHub's graphql API for a single issue by its number, along with its first
+// 100 labels. The body is fetched separately, so it can be served from the issue cache.
+type gitHubGetIssueQuery struct {
+	gitHubQueryRateLimit
+
+	Repository struct {
+		Issue struct {
+			Author             *GitHubActor
+			CreatedAt          githubv4.DateTime
+			ClosedAt           *githubv4.DateTime
+			URL                githubv4.URI
+			AuthorAssociation  githubv4.CommentAuthorAssociation
+			ID                 githubv4.ID
+			Number             githubv4.Int
+			Title              githubv4.String
+			State              githubv4.IssueState
+			UpdatedAt          githubv4.DateTime
+			ViewerSubscription githubv4.SubscriptionState
+			Milestone          *gitHubMilestone
+			Labels             struct {
+				Nodes []struct {
+					Name string
+				}
+				PageInfo struct {
+					HasNextPage githubv4.Boolean
+				}
+			} `graphql:"labels(first: 100)"`
+		} `graphql:"issue(number: $issueNumber)"`
+	} `graphql:"repository(owner: $owner, name: $name)"`
 }
 
-func (t *MockGitHubinator) SetSubscription(
-	ctx context.Context, id githubv4.ID, state githubv4.SubscriptionState,
-) error {
-	t.SetSubscriptionRequests = append(t.SetSubscriptionRequests, id)
+// AsGitHubItem converts the gitHubGetIssueQuery into a GitHubItem in the given repository. If the issue has more
+// labels than were fetched, LabelsFetched is false.
+func (q *gitHubGetIssueQuery) AsGitHubItem(ghr GitHubRepository) *GitHubItem {
+	i := q.Repository.Issue
 
-	return t.SetSubscriptionError
+	labels := []string{}
+	for _, l := range i.Labels.Nodes {
+		labels = append(labels, l.Name)
+	}
+
+	return &GitHubItem{
+		Type:          GitHubItemIssue,
+		Repo:          ghr,
+		ID:            i.ID,
+		LabelsFetched: !bool(i.Labels.PageInfo.HasNextPage),
+		GitHubIssue: GitHubIssue{
+			Author:            asGitHubActorOrGhost(i.Author),
+			Body:              "",
+			Labels:            labels,
+			Number:            int(i.Number),
+			State:             i.State,
+			Subscription:      i.ViewerSubscription,
+			Title:             string(i.Title),
+			CreatedAt:         i.CreatedAt.Time,
+			ClosedAt:          asClosedAt(i.ClosedAt),
+			URL:               asURL(i.URL),
+			AuthorAssociation: i.AuthorAssociation,
+			UpdatedAt:         i.UpdatedAt.Time,
+			Milestone:         asMilestoneTitle(i.Milestone),
+		},
+	}
 }
 
-// NewMockGitHubinator creates a new MockGitHubinator instance with pre-populated, non-error return values.
-func NewMockGitHubinator() *MockGitHubinator {
-	return &MockGitHubinator{
-		CheckRepositoryRequests: []GitHubRepository{},
-		CheckRepositoryError:    nil,
-		WhoAmIRequests:          0,
-		WhoAmIReturn:            "user",
-		WhoAmIError:             nil,
-		SetSubscriptionRequests: []githubv4.ID{},
-		SetSubscriptionError:    nil,
+func (q gitHubGetIssueQuery) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Any("id", q.Repository.Issue.ID),
+		slog.Int("number", int(q.Repository.Issue.Number)),
+		slog.String("title", string(q.Repository.Issue.Title)),
+	)
+}
+
+// gitHubReferenceSubject holds an issue or pull request which referenced another.
+type gitHubReferenceSubject struct {
+	Number     githubv4.Int
+	Repository struct {
+		Name  githubv4.String
+		Owner struct {
+			Login githubv4.String
+		}
 	}
 }
 
-// gitHubinator is the packages internal implementation of the GitHubinator interface.
-// The With* builder functions will set the internal field 'client' to nil to signal that the client needs to be
-// setup. Any function which uses the client must perform a nil check.
-type gitHubinator struct {
-	retries int
-	timeout time.Duration
-	token   oauth2.TokenSource
-	client  *githubv4.Client
-	logger  *slog.Logger
+// gitHubIssueCrossReferencesQuery is used to query GitHub's graphql API for the issues and pull requests which
+// referenced an issue, which are recorded as cross-reference events in the issue's timeline.
+type gitHubIssueCrossReferencesQuery struct {
+	gitHubQueryRateLimit
+
+	Repository struct {
+		Issue struct {
+			TimelineItems struct {
+				Nodes []struct {
+					CrossReferencedEvent struct {
+						Source struct {
+							Typename    githubv4.String        `graphql:"__typename"`
+							Issue       gitHubReferenceSubject `graphql:"... on Issue"`
+							PullRequest gitHubReferenceSubject `graphql:"... on PullRequest"`
+						}
+					} `graphql:"... on CrossReferencedEvent"`
+				}
+				PageInfo struct {
+					EndCursor   githubv4.String
+					HasNextPage githubv4.Boolean
+				}
+			} `graphql:"timelineItems(first: $n, after: $timelineCursor, itemTypes: [CROSS_REFERENCED_EVENT])"`
+		} `graphql:"issue(number: $issueNumber)"`
+	} `graphql:"repository(owner: $owner, name: $name)"`
 }
 
-func (gh *gitHubinator) WithRetries(retries int) GitHubinator {
-	return &gitHubinator{
-		retries: retries,
-		timeout: gh.timeout,
-		token:   gh.token,
-		client:  nil,
-		logger:  gh.logger,
+// gitHubIssueCrossReferencesQueryVars represents the variables that can be passed to a
+// gitHubIssueCrossReferencesQuery.
+type gitHubIssueCrossReferencesQueryVars struct {
+	Owner          githubv4.String
+	Name           githubv4.String
+	IssueNumber    githubv4.Int
+	N              githubv4.Int
+	TimelineCursor *githubv4.String
+}
+
+func (v *gitHubIssueCrossReferencesQueryVars) AsMap() map[string]any {
+	return map[string]any{
+		"owner":          v.Owner,
+		"name":           v.Name,
+		"issueNumber":    v.IssueNumber,
+		"n":              v.N,
+		"timelineCursor": v.TimelineCursor,
 	}
 }
 
-func (gh *gitHubinator) WithTimeout(timeout time.Duration) GitHubinator {
-	return &gitHubinator{
-		retries: gh.retries,
-		timeout: timeout,
-		token:   gh.token,
-		client:  nil,
-		logger:  gh.logger,
+func (v gitHubIssueCrossReferencesQueryVars) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("owner", string(v.Owner)),
+		slog.String("name", string(v.Name)),
+		slog.Int("issueNumber", int(v.IssueNumber)),
+		slog.Int("n", int(v.N)),
+		slog.Any("timelineCursor", v.TimelineCursor),
+	)
+}
+
+// AsReferencingIssues returns the numbers of the issues and pull requests in the given repository which referenced
+// the queried issue in the fetched page of its timeline, sorted and without duplicates.
+func (q *gitHubIssueCrossReferencesQuery) AsReferencingIssues(ghr GitHubRepository) []int {
+	seen := map[int]bool{}
+	numbers := []int{}
+
+	for _, n := range q.Repository.Issue.TimelineItems.Nodes {
+		var source gitHubReferenceSubject
+
+		switch n.CrossReferencedEvent.Source.Typename {
+		case "Issue":
+			source = n.CrossReferencedEvent.Source.Issue
+		case "PullRequest":
+			source = n.CrossReferencedEvent.Source.PullRequest
+		default:
+			continue
+		}
+
+		repo := GitHubRepository{Owner: string(source.Repository.Owner.Login), Name: string(source.Repository.Name)}
+		if number := int(source.Number); strings.EqualFold(repo.fullName(), ghr.fullName()) && !seen[number] {
+			seen[number] = true
+			numbers = append(numbers, number)
+		}
 	}
+
+	sort.Ints(numbers)
+
+	return numbers
 }
 
-func (gh *gitHubinator) WithToken(token string) GitHubinator {
-	return &gitHubinator{
-		retries: gh.retries,
-		timeout: gh.timeout,
-		token: oauth2.StaticTokenSource(
-			&oauth2.Token{AccessToken: token},
-		),
-		client: nil,
-		logger: gh.logger,
+// gitHubIssueLastActivityQuery is used to query GitHub's graphql API for the most recent timeline item of an issue.
+// Only timeline items made by a person, such as comments and label changes, are considered.
+type gitHubIssueLastActivityQuery struct {
+	gitHubQueryRateLimit
+
+	Repository struct {
+		Issue struct {
+			TimelineItems struct {
+				Nodes []struct {
+					Typename     githubv4.String `graphql:"__typename"`
+					IssueComment struct {
+						Author *GitHubActor
+					} `graphql:"... on IssueComment"`
+					LabeledEvent      gitHubTimelineEventActor `graphql:"... on LabeledEvent"`
+					UnlabeledEvent    gitHubTimelineEventActor `graphql:"... on UnlabeledEvent"`
+					AssignedEvent     gitHubTimelineEventActor `graphql:"... on AssignedEvent"`
+					UnassignedEvent   gitHubTimelineEventActor `graphql:"... on UnassignedEvent"`
+					ClosedEvent       gitHubTimelineEventActor `graphql:"... on ClosedEvent"`
+					ReopenedEvent     gitHubTimelineEventActor `graphql:"... on ReopenedEvent"`
+					RenamedTitleEvent gitHubTimelineEventActor `graphql:"... on RenamedTitleEvent"`
+				}
+			} `graphql:"timelineItems(last: 1, itemTypes: $itemTypes)"`
+		} `graphql:"issue(number: $issueNumber)"`
+	} `graphql:"repository(owner: $owner, name: $name)"`
+}
+
+// AsLastActivityBy returns the login of the actor of the returned timeline item, or an empty string if the issue
+// has no timeline items. Actors whose account has been deleted are returned as GitHubGhostLogin.
+func (q *gitHubIssueLastActivityQuery) AsLastActivityBy() string {
+	nodes := q.Repository.Issue.TimelineItems.Nodes
+	if len(nodes) == 0 {
+		return ""
+	}
+
+	n := nodes[0]
+
+	var actor *GitHubActor
+
+	switch n.Typename {
+	case "IssueComment":
+		actor = n.IssueComment.Author
+	case "LabeledEvent":
+		actor = n.LabeledEvent.Actor
+	case "UnlabeledEvent":
+		actor = n.UnlabeledEvent.Actor
+	case "AssignedEvent":
+		actor = n.AssignedEvent.Actor
+	case "UnassignedEvent":
+		actor = n.UnassignedEvent.Actor
+	case "ClosedEvent":
+		actor = n.ClosedEvent.Actor
+	case "ReopenedEvent":
+		actor = n.ReopenedEvent.Actor
+	case "RenamedTitleEvent":
+		actor = n.RenamedTitleEvent.Actor
+	default:
+		return ""
 	}
+
+	return asGitHubActorOrGhost(actor).Login
+}
+
+func (q gitHubIssueLastActivityQuery) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("lastActivityBy", q.AsLastActivityBy()),
+	)
+}
+
+// gitHubLastActivityItemTypes are the types of timeline items made by a person, which are considered by a
+// gitHubIssueLastActivityQuery.
+var gitHubLastActivityItemTypes = []githubv4.IssueTimelineItemsItemType{
+	githubv4.IssueTimelineItemsItemTypeIssueComment,
+	githubv4.IssueTimelineItemsItemTypeLabeledEvent,
+	githubv4.IssueTimelineItemsItemTypeUnlabeledEvent,
+	githubv4.IssueTimelineItemsItemTypeAssignedEvent,
+	githubv4.IssueTimelineItemsItemTypeUnassignedEvent,
+	githubv4.IssueTimelineItemsItemTypeClosedEvent,
+	githubv4.IssueTimelineItemsItemTypeReopenedEvent,
+	githubv4.IssueTimelineItemsItemTypeRenamedTitleEvent,
+}
+
+// gitHubIssueLastActivityQueryVars represents the variables that can be passed to a gitHubIssueLastActivityQuery.
+// It shares its variables with the issue body query, along with the types of timeline items to consider.
+type gitHubIssueLastActivityQueryVars struct {
+	gitHubIssueBodyQueryVars
+	ItemTypes []githubv4.IssueTimelineItemsItemType
 }
 
-func (gh *gitHubinator) setupClient() {
-	oauthClient := oauth2.NewClient(context.TODO(), gh.token)
+func (v *gitHubIssueLastActivityQueryVars) AsMap() map[string]any {
+	m := v.gitHubIssueBodyQueryVars.AsMap()
+	m["itemTypes"] = v.ItemTypes
+
+	return m
+}
+
+// gitHubProjectFieldName holds the name of a project field, which is common across all project field types.
+type gitHubProjectFieldName struct {
+	Common struct {
+		Name githubv4.String
+	} `graphql:"... on ProjectV2FieldCommon"`
+}
+
+// gitHubIssueProjectFieldsQuery is used to query GitHub's graphql API for the custom field values set on an issue
+// in the projects it belongs to. Pagination is not performed, so only the first $n projects and field values
+// are returned.
+type gitHubIssueProjectFieldsQuery struct {
+	gitHubQueryRateLimit
+
+	Repository struct {
+		Issue struct {
+			ProjectItems struct {
+				Nodes []struct {
+					Project struct {
+						Number githubv4.Int
+					}
+					FieldValues struct {
+						Nodes []struct {
+							SingleSelect struct {
+								Name  githubv4.String
+								Field gitHubProjectFieldName
+							} `graphql:"... on ProjectV2ItemFieldSingleSelectValue"`
+							Text struct {
+								Text  githubv4.String
+								Field gitHubProjectFieldName
+							} `graphql:"... on ProjectV2ItemFieldTextValue"`
+						}
+					} `graphql:"fieldValues(first: $n)"`
+				}
+			} `graphql:"projectItems(first: $n)"`
+		} `graphql:"issue(number: $issueNumber)"`
+	} `graphql:"repository(owner: $owner, name: $name)"`
+}
+
+// AsGitHubProjectFieldValues converts the gitHubIssueProjectFieldsQuery into a list of field values. Field values
+// that are not single select or text values are skipped.
+func (q *gitHubIssueProjectFieldsQuery) AsGitHubProjectFieldValues() []GitHubProjectFieldValue {
+	values := []GitHubProjectFieldValue{}
+
+	for _, item := range q.Repository.Issue.ProjectItems.Nodes {
+		for _, v := range item.FieldValues.Nodes {
+			value := GitHubProjectFieldValue{ProjectNumber: int(item.Project.Number)}
+
+			switch {
+			case v.SingleSelect.Field.Common.Name != "":
+				value.Field = string(v.SingleSelect.Field.Common.Name)
+				value.Value = string(v.SingleSelect.Name)
+			case v.Text.Field.Common.Name != "":
+				value.Field = string(v.Text.Field.Common.Name)
+				value.Value = string(v.Text.Text)
+			default:
+				continue
+			}
+
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
+
+func (q gitHubIssueProjectFieldsQuery) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Any("nodes", q.Repository.Issue.ProjectItems.Nodes),
+	)
+}
+
+// gitHubIssueProjectFieldsQueryVars represents the variables that can be passed to a gitHubIssueProjectFieldsQuery.
+type gitHubIssueProjectFieldsQueryVars struct {
+	Owner       githubv4.String
+	Name        githubv4.String
+	IssueNumber githubv4.Int
+	N           githubv4.Int
+}
+
+func (v *gitHubIssueProjectFieldsQueryVars) AsMap() map[string]any {
+	return map[string]any{
+		"owner":       v.Owner,
+		"name":        v.Name,
+		"issueNumber": v.IssueNumber,
+		"n":           v.N,
+	}
+}
+
+func (v gitHubIssueProjectFieldsQueryVars) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("owner", string(v.Owner)),
+		slog.String("name", string(v.Name)),
+		slog.Int("issueNumber", int(v.IssueNumber)),
+		slog.Int("n", int(v.N)),
+	)
+}
+
+// gitHubIssueQuery is used to query the GitHub graphql for an issue.
+// The body and the first 100 labels and assignees of each issue are fetched along with it. Because labels and
+// assignees are paginated, the rest of an issue with more than 100 of either need to be queried separately, see
+// TruncatedLabels and TruncatedAssignees.
+type gitHubIssueQuery struct {
+	gitHubQueryRateLimit
+
+	Repository struct {
+		Issues struct {
+			Nodes []struct {
+				Author             *GitHubActor
+				Body               githubv4.String
+				BodyText           githubv4.String
+				CreatedAt          githubv4.DateTime
+				ClosedAt           *githubv4.DateTime
+				URL                githubv4.URI
+				AuthorAssociation  githubv4.CommentAuthorAssociation
+				ID                 githubv4.ID
+				Number             githubv4.Int
+				Title              githubv4.String
+				State              githubv4.IssueState
+				UpdatedAt          githubv4.DateTime
+				ViewerSubscription githubv4.SubscriptionState
+				Milestone          *gitHubMilestone
+				Labels             struct {
+					Nodes []struct {
+						Name string
+					}
+					PageInfo struct {
+						HasNextPage githubv4.Boolean
+					}
+				} `graphql:"labels(first: 100)"`
+				Assignees struct {
+					Nodes []struct {
+						Login string
+					}
+					PageInfo struct {
+						HasNextPage githubv4.Boolean
+					}
+				} `graphql:"assignees(first: 100)"`
+			}
+			PageInfo struct {
+				EndCursor   githubv4.String
+				HasNextPage githubv4.Boolean
+			}
+		} `graphql:"issues(first: $n, after: $issuesCursor, filterBy: $filters)"`
+	} `graphql:"repository(owner: $owner, name: $name)"`
+}
+
+// AsGitHubIssue converts the gitHubIssueQuery into a map of the contained issues to their IDs.
+func (q *gitHubIssueQuery) AsGitHubIssues() map[githubv4.ID]*GitHubIssue {
+	issues := map[githubv4.ID]*GitHubIssue{}
+
+	for _, n := range q.Repository.Issues.Nodes {
+		labels := []string{}
+		for _, l := range n.Labels.Nodes {
+			labels = append(labels, l.Name)
+		}
+
+		assignees := []string{}
+		for _, a := range n.Assignees.Nodes {
+			assignees = append(assignees, a.Login)
+		}
+
+		issues[n.ID] = &GitHubIssue{
+			Author:            asGitHubActorOrGhost(n.Author),
+			Body:              string(n.BodyText),
+			BodyMarkdown:      string(n.Body),
+			Labels:            labels,
+			Assignees:         assignees,
+			Number:            int(n.Number),
+			State:             n.State,
+			Subscription:      n.ViewerSubscription,
+			Title:             string(n.Title),
+			CreatedAt:         n.CreatedAt.Time,
+			ClosedAt:          asClosedAt(n.ClosedAt),
+			URL:               asURL(n.URL),
+			AuthorAssociation: n.AuthorAssociation,
+			UpdatedAt:         n.UpdatedAt.Time,
+			Milestone:         asMilestoneTitle(n.Milestone),
+		}
+	}
+
+	return issues
+}
+
+// TruncatedLabels returns the IDs of the issues in the gitHubIssueQuery which have more labels than were fetched.
+func (q *gitHubIssueQuery) TruncatedLabels() map[githubv4.ID]bool {
+	truncated := map[githubv4.ID]bool{}
+
+	for _, n := range q.Repository.Issues.Nodes {
+		if n.Labels.PageInfo.HasNextPage {
+			truncated[n.ID] = true
+		}
+	}
+
+	return truncated
+}
+
+// TruncatedAssignees returns the IDs of the issues in the gitHubIssueQuery which have more assignees than were
+// fetched.
+func (q *gitHubIssueQuery) TruncatedAssignees() map[githubv4.ID]bool {
+	truncated := map[githubv4.ID]bool{}
+
+	for _, n := range q.Repository.Issues.Nodes {
+		if n.Assignees.PageInfo.HasNextPage {
+			truncated[n.ID] = true
+		}
+	}
+
+	return truncated
+}
+
+func (q gitHubIssueQuery) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("endCursor", string(q.Repository.Issues.PageInfo.EndCursor)),
+		slog.Bool("hasNextPage", bool(q.Repository.Issues.PageInfo.HasNextPage)),
+		slog.Any("nodes", q.Repository.Issues.Nodes),
+	)
+}
+
+// gitHubIssueQueryVars represents the variables that can be passed to a gitHubIssueQuery.
+type gitHubIssueQueryVars struct {
+	Owner        githubv4.String
+	Name         githubv4.String
+	Filters      githubv4.IssueFilters
+	IssuesCursor *githubv4.String
+	N            githubv4.Int
+}
+
+func (q gitHubIssueQueryVars) AsMap() map[string]any {
+	return map[string]any{
+		"owner":        q.Owner,
+		"name":         q.Name,
+		"filters":      q.Filters,
+		"issuesCursor": q.IssuesCursor,
+		"n":            q.N,
+	}
+}
+
+func (q gitHubIssueQueryVars) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("owner", string(q.Owner)),
+		slog.String("name", string(q.Name)),
+		slog.Any("issuesCursor", q.IssuesCursor),
+		slog.Int("n", int(q.N)),
+		slog.Any("filters", q.Filters),
+	)
+}
+
+// gitHubPullRequestQuery is used to query GitHub's graphql API for the pull requests in a repository. Unlike
+// gitHubIssueQuery, the labels, body and assignees of each pull request are fetched along with it, as the follow-up
+// queries used for issues only support issues. Only the first 100 labels and assignees of each pull request are
+// fetched.
+type gitHubPullRequestQuery struct {
+	gitHubQueryRateLimit
+
+	Repository struct {
+		PullRequests struct {
+			Nodes []struct {
+				Author             *GitHubActor
+				Body               githubv4.String
+				BodyText           githubv4.String
+				CreatedAt          githubv4.DateTime
+				ClosedAt           *githubv4.DateTime
+				URL                githubv4.URI
+				AuthorAssociation  githubv4.CommentAuthorAssociation
+				ID                 githubv4.ID
+				IsDraft            githubv4.Boolean
+				Merged             githubv4.Boolean
+				Number             githubv4.Int
+				Title              githubv4.String
+				State              githubv4.PullRequestState
+				UpdatedAt          githubv4.DateTime
+				ViewerSubscription githubv4.SubscriptionState
+				Milestone          *gitHubMilestone
+				Labels             struct {
+					Nodes []struct {
+						Name string
+					}
+				} `graphql:"labels(first: 100)"`
+				Assignees struct {
+					Nodes []struct {
+						Login string
+					}
+				} `graphql:"assignees(first: 100)"`
+			}
+			PageInfo struct {
+				EndCursor   githubv4.String
+				HasNextPage githubv4.Boolean
+			}
+		} `graphql:"pullRequests(first: $n, after: $pullRequestsCursor, labels: $labels, states: $states)"`
+	} `graphql:"repository(owner: $owner, name: $name)"`
+}
+
+// AsGitHubItems converts the gitHubPullRequestQuery into a list of the contained pull requests in the given
+// repository. The state of a merged pull request is MERGED.
+func (q *gitHubPullRequestQuery) AsGitHubItems(ghr GitHubRepository) []*GitHubItem {
+	items := []*GitHubItem{}
+
+	for _, n := range q.Repository.PullRequests.Nodes {
+		labels := []string{}
+		for _, l := range n.Labels.Nodes {
+			labels = append(labels, l.Name)
+		}
+
+		assignees := []string{}
+		for _, a := range n.Assignees.Nodes {
+			assignees = append(assignees, a.Login)
+		}
+
+		items = append(items, &GitHubItem{
+			Type: GitHubItemPullRequest,
+			Repo: ghr,
+			ID:   n.ID,
+			GitHubIssue: GitHubIssue{
+				Author:            asGitHubActorOrGhost(n.Author),
+				Body:              string(n.BodyText),
+				BodyMarkdown:      string(n.Body),
+				Labels:            labels,
+				Assignees:         assignees,
+				Number:            int(n.Number),
+				State:             githubv4.IssueState(n.State),
+				Subscription:      n.ViewerSubscription,
+				Title:             string(n.Title),
+				CreatedAt:         n.CreatedAt.Time,
+				ClosedAt:          asClosedAt(n.ClosedAt),
+				URL:               asURL(n.URL),
+				AuthorAssociation: n.AuthorAssociation,
+				UpdatedAt:         n.UpdatedAt.Time,
+				Milestone:         asMilestoneTitle(n.Milestone),
+			},
+			PullRequest: &GitHubPullRequest{
+				IsDraft: bool(n.IsDraft),
+				Merged:  bool(n.Merged),
+			},
+			LabelsFetched:    true,
+			BodyFetched:      true,
+			AssigneesFetched: true,
+		})
+	}
+
+	return items
+}
+
+func (q gitHubPullRequestQuery) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("endCursor", string(q.Repository.PullRequests.PageInfo.EndCursor)),
+		slog.Bool("hasNextPage", bool(q.Repository.PullRequests.PageInfo.HasNextPage)),
+		slog.Any("nodes", q.Repository.PullRequests.Nodes),
+	)
+}
+
+// gitHubPullRequestQueryVars represents the variables that can be passed to a gitHubPullRequestQuery.
+type gitHubPullRequestQueryVars struct {
+	Owner              githubv4.String
+	Name               githubv4.String
+	Labels             *[]githubv4.String
+	States             *[]githubv4.PullRequestState
+	PullRequestsCursor *githubv4.String
+	N                  githubv4.Int
+}
+
+// newGitHubPullRequestQueryVars creates the variables of a gitHubPullRequestQuery for the given repository, applying
+// the labels and states of the given filter.
+func newGitHubPullRequestQueryVars(ghr GitHubRepository, filter *GitHubIssueFilter) *gitHubPullRequestQueryVars {
+	issueFilters := filter.asGithubv4IssueFilters()
+
+	var states *[]githubv4.PullRequestState = nil
+
+	if issueFilters.States != nil {
+		states = &[]githubv4.PullRequestState{}
+		for _, s := range *issueFilters.States {
+			*states = append(*states, githubv4.PullRequestState(s))
+		}
+	}
+
+	return &gitHubPullRequestQueryVars{
+		Owner:              githubv4.String(ghr.Owner),
+		Name:               githubv4.String(ghr.Name),
+		Labels:             issueFilters.Labels,
+		States:             states,
+		PullRequestsCursor: (*githubv4.String)(nil),
+		N:                  100,
+	}
+}
+
+func (q gitHubPullRequestQueryVars) AsMap() map[string]any {
+	return map[string]any{
+		"owner":              q.Owner,
+		"name":               q.Name,
+		"labels":             q.Labels,
+		"states":             q.States,
+		"pullRequestsCursor": q.PullRequestsCursor,
+		"n":                  q.N,
+	}
+}
+
+func (q gitHubPullRequestQueryVars) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("owner", string(q.Owner)),
+		slog.String("name", string(q.Name)),
+		slog.Any("labels", q.Labels),
+		slog.Any("states", q.States),
+		slog.Any("pullRequestsCursor", q.PullRequestsCursor),
+		slog.Int("n", int(q.N)),
+	)
+}
+
+// gitHubDiscussionQuery is used to query GitHub's graphql API for the discussions in a repository. Like
+// gitHubPullRequestQuery, the labels and body of each discussion are fetched along with it. Only the first 100 labels
+// of each discussion are fetched.
+type gitHubDiscussionQuery struct {
+	gitHubQueryRateLimit
+
+	Repository struct {
+		Discussions struct {
+			Nodes []struct {
+				Author            *GitHubActor
+				Body              githubv4.String
+				BodyText          githubv4.String
+				CreatedAt         githubv4.DateTime
+				ClosedAt          *githubv4.DateTime
+				URL               githubv4.URI
+				AuthorAssociation githubv4.CommentAuthorAssociation
+				ID                githubv4.ID
+				Closed            githubv4.Boolean
+				Category          struct {
+					Name githubv4.String
+				}
+				IsAnswered         githubv4.Boolean
+				Number             githubv4.Int
+				Title              githubv4.String
+				UpdatedAt          githubv4.DateTime
+				ViewerSubscription githubv4.SubscriptionState
+				Labels             struct {
+					Nodes []struct {
+						Name string
+					}
+				} `graphql:"labels(first: 100)"`
+			}
+			PageInfo struct {
+				EndCursor   githubv4.String
+				HasNextPage githubv4.Boolean
+			}
+		} `graphql:"discussions(first: $n, after: $discussionsCursor, states: $states)"`
+	} `graphql:"repository(owner: $owner, name: $name)"`
+}
+
+// AsGitHubItems converts the gitHubDiscussionQuery into a list of the contained discussions in the given repository.
+// Discussions don't have a state like issues, so their state is set to OPEN or CLOSED depending on if they're closed.
+func (q *gitHubDiscussionQuery) AsGitHubItems(ghr GitHubRepository) []*GitHubItem {
+	items := []*GitHubItem{}
+
+	for _, n := range q.Repository.Discussions.Nodes {
+		labels := []string{}
+		for _, l := range n.Labels.Nodes {
+			labels = append(labels, l.Name)
+		}
+
+		state := githubv4.IssueStateOpen
+		if n.Closed {
+			state = githubv4.IssueStateClosed
+		}
+
+		items = append(items, &GitHubItem{
+			Type: GitHubItemDiscussion,
+			Repo: ghr,
+			ID:   n.ID,
+			GitHubIssue: GitHubIssue{
+				Author:            asGitHubActorOrGhost(n.Author),
+				Body:              string(n.BodyText),
+				BodyMarkdown:      string(n.Body),
+				Labels:            labels,
+				Number:            int(n.Number),
+				State:             state,
+				Subscription:      n.ViewerSubscription,
+				Title:             string(n.Title),
+				CreatedAt:         n.CreatedAt.Time,
+				ClosedAt:          asClosedAt(n.ClosedAt),
+				URL:               asURL(n.URL),
+				AuthorAssociation: n.AuthorAssociation,
+				UpdatedAt:         n.UpdatedAt.Time,
+			},
+			Discussion: &GitHubDiscussion{
+				Category:   string(n.Category.Name),
+				IsAnswered: bool(n.IsAnswered),
+			},
+			LabelsFetched: true,
+			BodyFetched:   true,
+			// Discussions can't be assigned.
+			AssigneesFetched: true,
+		})
+	}
+
+	return items
+}
+
+func (q gitHubDiscussionQuery) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("endCursor", string(q.Repository.Discussions.PageInfo.EndCursor)),
+		slog.Bool("hasNextPage", bool(q.Repository.Discussions.PageInfo.HasNextPage)),
+		slog.Any("nodes", q.Repository.Discussions.Nodes),
+	)
+}
+
+// gitHubDiscussionQueryVars represents the variables that can be passed to a gitHubDiscussionQuery.
+type gitHubDiscussionQueryVars struct {
+	Owner             githubv4.String
+	Name              githubv4.String
+	States            *[]githubv4.DiscussionState
+	DiscussionsCursor *githubv4.String
+	N                 githubv4.Int
+}
+
+// newGitHubDiscussionQueryVars creates the variables of a gitHubDiscussionQuery for the given repository, applying
+// the states of the given filter. GitHub can't filter discussions by label, see ListDiscussions.
+func newGitHubDiscussionQueryVars(ghr GitHubRepository, filter *GitHubIssueFilter) *gitHubDiscussionQueryVars {
+	var states *[]githubv4.DiscussionState = nil
+
+	if filter.States != nil {
+		states = &[]githubv4.DiscussionState{}
+		for _, s := range filter.States {
+			*states = append(*states, githubv4.DiscussionState(s))
+		}
+	}
+
+	return &gitHubDiscussionQueryVars{
+		Owner:             githubv4.String(ghr.Owner),
+		Name:              githubv4.String(ghr.Name),
+		States:            states,
+		DiscussionsCursor: (*githubv4.String)(nil),
+		N:                 100,
+	}
+}
+
+func (q gitHubDiscussionQueryVars) AsMap() map[string]any {
+	return map[string]any{
+		"owner":             q.Owner,
+		"name":              q.Name,
+		"states":            q.States,
+		"discussionsCursor": q.DiscussionsCursor,
+		"n":                 q.N,
+	}
+}
+
+func (q gitHubDiscussionQueryVars) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("owner", string(q.Owner)),
+		slog.String("name", string(q.Name)),
+		slog.Any("states", q.States),
+		slog.Any("discussionsCursor", q.DiscussionsCursor),
+		slog.Int("n", int(q.N)),
+	)
+}
+
+// hasAnyLabel returns if the given item has any of the given labels, or if no labels are given.
+func hasAnyLabel(i *GitHubItem, labels []string) bool {
+	if len(labels) == 0 {
+		return true
+	}
+
+	for _, l := range labels {
+		if slices.Contains(i.Labels, l) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// gitHubSearchIssuesQuery is used to search GitHub's graphql API for issues and pull requests. Like
+// gitHubPullRequestQuery, the labels, body and assignees of each pull request are fetched along with it. Search
+// results which are neither, such as when the query is invalid for a type, are skipped.
+type gitHubSearchIssuesQuery struct {
+	gitHubQueryRateLimit
+
+	Search struct {
+		Nodes []struct {
+			Typename string `graphql:"__typename"`
+			Issue    struct {
+				Author             *GitHubActor
+				CreatedAt          githubv4.DateTime
+				ClosedAt           *githubv4.DateTime
+				URL                githubv4.URI
+				AuthorAssociation  githubv4.CommentAuthorAssociation
+				ID                 githubv4.ID
+				Number             githubv4.Int
+				Title              githubv4.String
+				State              githubv4.IssueState
+				UpdatedAt          githubv4.DateTime
+				ViewerSubscription githubv4.SubscriptionState
+				Milestone          *gitHubMilestone
+				Repository         gitHubSearchRepository
+			} `graphql:"... on Issue"`
+			PullRequest struct {
+				Author            *GitHubActor
+				Body              githubv4.String
+				BodyText          githubv4.String
+				CreatedAt         githubv4.DateTime
+				ClosedAt          *githubv4.DateTime
+				URL               githubv4.URI
+				AuthorAssociation githubv4.CommentAuthorAssociation
+				ID                githubv4.ID
+				IsDraft           githubv4.Boolean
+				Merged            githubv4.Boolean
+				Number            githubv4.Int
+				Title             githubv4.String
+				// Aliased, as GitHub rejects a state field with a different type than the one of issues.
+				State              githubv4.PullRequestState `graphql:"pullRequestState: state"`
+				UpdatedAt          githubv4.DateTime
+				ViewerSubscription githubv4.SubscriptionState
+				Milestone          *gitHubMilestone
+				Repository         gitHubSearchRepository
+				Labels             struct {
+					Nodes []struct {
+						Name string
+					}
+				} `graphql:"labels(first: 100)"`
+				Assignees struct {
+					Nodes []struct {
+						Login string
+					}
+				} `graphql:"assignees(first: 100)"`
+			} `graphql:"... on PullRequest"`
+		}
+		PageInfo struct {
+			EndCursor   githubv4.String
+			HasNextPage githubv4.Boolean
+		}
+	} `graphql:"search(query: $query, type: ISSUE, first: $n, after: $searchCursor)"`
+}
+
+// gitHubSearchRepository is the repository of an item returned by a gitHubSearchIssuesQuery.
+type gitHubSearchRepository struct {
+	Name  githubv4.String
+	Owner struct {
+		Login githubv4.String
+	}
+}
+
+// AsGitHubRepository converts the gitHubSearchRepository into a GitHubRepository.
+func (r gitHubSearchRepository) AsGitHubRepository() GitHubRepository {
+	return GitHubRepository{Owner: string(r.Owner.Login), Name: string(r.Name)}
+}
+
+// AsGitHubItems converts the gitHubSearchIssuesQuery into a list of the contained issues and pull requests. The
+// state of a merged pull request is MERGED.
+func (q *gitHubSearchIssuesQuery) AsGitHubItems() []*GitHubItem {
+	items := []*GitHubItem{}
+
+	for _, n := range q.Search.Nodes {
+		// Fields shared by both fragments are decoded into each of them, so the type name tells them apart.
+		if n.Typename == "PullRequest" {
+			pr := n.PullRequest
+
+			labels := []string{}
+			for _, l := range pr.Labels.Nodes {
+				labels = append(labels, l.Name)
+			}
+
+			assignees := []string{}
+			for _, a := range pr.Assignees.Nodes {
+				assignees = append(assignees, a.Login)
+			}
+
+			items = append(items, &GitHubItem{
+				Type: GitHubItemPullRequest,
+				Repo: pr.Repository.AsGitHubRepository(),
+				ID:   pr.ID,
+				GitHubIssue: GitHubIssue{
+					Author:            asGitHubActorOrGhost(pr.Author),
+					Body:              string(pr.BodyText),
+					BodyMarkdown:      string(pr.Body),
+					Labels:            labels,
+					Assignees:         assignees,
+					Number:            int(pr.Number),
+					State:             githubv4.IssueState(pr.State),
+					Subscription:      pr.ViewerSubscription,
+					Title:             string(pr.Title),
+					CreatedAt:         pr.CreatedAt.Time,
+					ClosedAt:          asClosedAt(pr.ClosedAt),
+					URL:               asURL(pr.URL),
+					AuthorAssociation: pr.AuthorAssociation,
+					UpdatedAt:         pr.UpdatedAt.Time,
+					Milestone:         asMilestoneTitle(pr.Milestone),
+				},
+				PullRequest: &GitHubPullRequest{
+					IsDraft: bool(pr.IsDraft),
+					Merged:  bool(pr.Merged),
+				},
+				LabelsFetched:    true,
+				BodyFetched:      true,
+				AssigneesFetched: true,
+			})
+
+			continue
+		}
+
+		if n.Issue.ID == nil || n.Issue.ID == "" {
+			continue
+		}
+
+		items = append(items, &GitHubItem{
+			Type: GitHubItemIssue,
+			Repo: n.Issue.Repository.AsGitHubRepository(),
+			ID:   n.Issue.ID,
+			GitHubIssue: GitHubIssue{
+				Author:            asGitHubActorOrGhost(n.Issue.Author),
+				Body:              "",
+				Labels:            []string{},
+				Number:            int(n.Issue.Number),
+				State:             n.Issue.State,
+				Subscription:      n.Issue.ViewerSubscription,
+				Title:             string(n.Issue.Title),
+				CreatedAt:         n.Issue.CreatedAt.Time,
+				ClosedAt:          asClosedAt(n.Issue.ClosedAt),
+				URL:               asURL(n.Issue.URL),
+				AuthorAssociation: n.Issue.AuthorAssociation,
+				UpdatedAt:         n.Issue.UpdatedAt.Time,
+				Milestone:         asMilestoneTitle(n.Issue.Milestone),
+			},
+		})
+	}
+
+	return items
+}
+
+func (q gitHubSearchIssuesQuery) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("endCursor", string(q.Search.PageInfo.EndCursor)),
+		slog.Bool("hasNextPage", bool(q.Search.PageInfo.HasNextPage)),
+		slog.Any("nodes", q.Search.Nodes),
+	)
+}
+
+// gitHubSearchIssuesQueryVars represents the variables that can be passed to a gitHubSearchIssuesQuery.
+type gitHubSearchIssuesQueryVars struct {
+	Query        githubv4.String
+	N            githubv4.Int
+	SearchCursor *githubv4.String
+}
+
+func (v *gitHubSearchIssuesQueryVars) AsMap() map[string]any {
+	return map[string]any{
+		"query":        v.Query,
+		"n":            v.N,
+		"searchCursor": v.SearchCursor,
+	}
+}
+
+func (v gitHubSearchIssuesQueryVars) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("query", string(v.Query)),
+		slog.Int("n", int(v.N)),
+		slog.Any("searchCursor", v.SearchCursor),
+	)
+}
+
+// GitHubinator is used to fetch and update data from GitHub. The With* builder methods return a new instance of
+// a GitHubinator.
+type GitHubinator interface {
+	// WithRetries will set the number of retries the GitHubinator will use when fetching or updating data.
+	WithRetries(retries int) GitHubinator
+
+	// WithTimeout sets the amount of time per try that the GitHubinator will wait for success before assuming a
+	// request has failed.
+	WithTimeout(timeout time.Duration) GitHubinator
+
+	// WithPageSize sets the number of issues and labels requested per page when listing them. Values above
+	// GitHubMaxPageSize, GitHub's limit, are clamped to it. Zero or less uses GitHubMaxPageSize.
+	WithPageSize(n int) GitHubinator
+
+	// WithToken sets the authentication token to use for the GH API, such as a PAT.
+	// A test request will be sent to GitHub to verify authentication.
+	WithToken(token string) GitHubinator
+
+	// WithTokens sets a pool of authentication tokens to use for the GH API, such as several PATs of the same user.
+	// Each request uses the next token in the pool, so their rate limits are combined. WhoAmI checks that every
+	// token belongs to the same user.
+	WithTokens(tokens ...string) GitHubinator
+
+	// WithBaseURL points the GitHubinator at the GitHub Enterprise Server instance at the given URL, such as
+	// 'https://github.example.com', instead of github.com. An empty URL uses github.com.
+	WithBaseURL(baseURL string) GitHubinator
+
+	// WithAppAuth authenticates as the installation of a GitHub App with the given IDs, using the app's PEM-encoded
+	// private key to mint installation tokens, which are refreshed as they expire. It replaces any token set with
+	// WithToken. WhoAmI returns the login of the app's bot user.
+	WithAppAuth(appID int64, installationID int64, privateKeyPEM []byte) GitHubinator
+
+	// WhoAmI will make a test query to GitHub to get the name and login for the given PAT.
+	WhoAmI(ctx context.Context) (string, error)
+
+	// CheckRepository checks if the given repository exists.
+	CheckRepository(ctx context.Context, ghr GitHubRepository) error
+
+	// CountOpenIssues returns the number of open issues in the given repository.
+	CountOpenIssues(ctx context.Context, ghr GitHubRepository) (int, error)
+
+	// ListViewerRepositories returns the repositories the viewer owns or collaborates on.
+	ListViewerRepositories(ctx context.Context) ([]GitHubRepository, error)
+
+	// RateLimit returns the current state of the viewer's GraphQL API rate limit.
+	RateLimit(ctx context.Context) (*GitHubRateLimit, error)
+
+	// ListIssues returns a list of issues for the given repository, sorted by number.
+	ListIssues(
+		ctx context.Context, ghr GitHubRepository, filter *GitHubIssueFilter, matcher Matchinator,
+	) ([]*GitHubItem, error)
+
+	// ListPullRequests returns a list of pull requests for the given repository, sorted by number. The filter's
+	// states may include MERGED.
+	ListPullRequests(
+		ctx context.Context, ghr GitHubRepository, filter *GitHubIssueFilter, matcher Matchinator,
+	) ([]*GitHubItem, error)
+
+	// ListDiscussions returns a list of discussions for the given repository, sorted by number. The filter's labels
+	// are applied after listing, as GitHub can't filter discussions by label.
+	ListDiscussions(
+		ctx context.Context, ghr GitHubRepository, filter *GitHubIssueFilter, matcher Matchinator,
+	) ([]*GitHubItem, error)
+
+	// SearchIssues returns a list of issues and pull requests matching the given GitHub search query. GitHub returns
+	// at most 1000 results for a search.
+	SearchIssues(ctx context.Context, query string, matcher Matchinator) ([]*GitHubItem, error)
+
+	// CheckSearch checks if the given GitHub search query can be executed.
+	CheckSearch(ctx context.Context, query string) error
+
+	// GetIssue returns the issue with the given number from the given repository. The issue's labels and body are
+	// always populated, along with the fields the given matcher needs, but the issue is not matched.
+	GetIssue(ctx context.Context, ghr GitHubRepository, number int, matcher Matchinator) (*GitHubItem, error)
+
+	// SetSubscription sets the subscription state of the given item for the viewer.
+	SetSubscription(ctx context.Context, id githubv4.ID, state githubv4.SubscriptionState) error
+
+	// GetLabelIDs returns the IDs of the labels with the given names in the given repository, in the same order. IDs
+	// are cached for LabelIDCacheTTL. An error is returned if any of the labels don't exist.
+	GetLabelIDs(ctx context.Context, ghr GitHubRepository, names []string) ([]githubv4.ID, error)
+
+	// SetLabels adds the labels with the given IDs to the given item. Labels the item already has are kept.
+	SetLabels(ctx context.Context, id githubv4.ID, labelIDs []githubv4.ID) error
+
+	// HasComment returns if the viewer left a comment containing the given marker on the given issue or pull request.
+	// Only the item's last 100 comments are checked.
+	HasComment(ctx context.Context, id githubv4.ID, marker string) (bool, error)
+
+	// AddComment adds a comment with the given markdown body to the given issue or pull request.
+	AddComment(ctx context.Context, id githubv4.ID, body string) error
+}
+
+// MockGitHubinator implements the GitHubinator interface. The returned values from its methods can be controlled,
+// allowing for it to be used for unit testing.
+type MockGitHubinator struct {
+	// CheckRepositoryRequests holds th parameters to calls to CheckRepository.
+	CheckRepositoryRequests []GitHubRepository
+
+	// CheckRepositoryError holds the returned error for CheckRepository.
+	CheckRepositoryError error
+
+	// WhoAmIRequests holds the number of times WhoAmI has been called
+	WhoAmIRequests int
+
+	// WhoAmIReturn holds the user returned from calls to WhoAmI.
+	WhoAmIReturn string
+
+	// WhoAmIError holds the errors that will be returned from WhoAmI.
+	WhoAmIError error
+
+	// SetSubscriptionRequests holds the issue IDs passed to SetSubscription.
+	SetSubscriptionRequests []githubv4.ID
+
+	// SetSubscriptionError holds the returned error for SetSubscription
+	SetSubscriptionError error
+
+	// CountOpenIssuesReturn holds the count returned from calls to CountOpenIssues.
+	CountOpenIssuesReturn int
+
+	// CountOpenIssuesError holds the returned error for CountOpenIssues.
+	CountOpenIssuesError error
+
+	// ListViewerRepositoriesRequests holds the number of times ListViewerRepositories has been called.
+	ListViewerRepositoriesRequests int
+
+	// ListViewerRepositoriesReturn holds the repositories returned from calls to ListViewerRepositories.
+	ListViewerRepositoriesReturn []GitHubRepository
+
+	// ListViewerRepositoriesError holds the returned error for ListViewerRepositories.
+	ListViewerRepositoriesError error
+
+	// RateLimitReturn holds the rate limit returned from calls to RateLimit.
+	RateLimitReturn GitHubRateLimit
+
+	// RateLimitError holds the returned error for RateLimit.
+	RateLimitError error
+
+	// ListIssuesRequests holds the repositories passed to ListIssues.
+	ListIssuesRequests []GitHubRepository
+
+	// ListIssuesFilters holds the filters passed to ListIssues, in the same order as ListIssuesRequests.
+	ListIssuesFilters []*GitHubIssueFilter
+
+	// ListIssuesReturn holds the items returned from calls to ListIssues.
+	ListIssuesReturn []*GitHubItem
+
+	// ListIssuesError holds the returned error for ListIssues.
+	ListIssuesError error
+
+	// ListPullRequestsRequests holds the repositories passed to ListPullRequests.
+	ListPullRequestsRequests []GitHubRepository
+
+	// ListPullRequestsReturn holds the items returned from calls to ListPullRequests.
+	ListPullRequestsReturn []*GitHubItem
+
+	// ListPullRequestsError holds the returned error for ListPullRequests.
+	ListPullRequestsError error
+
+	// ListDiscussionsRequests holds the repositories passed to ListDiscussions.
+	ListDiscussionsRequests []GitHubRepository
+
+	// ListDiscussionsReturn holds the items returned from calls to ListDiscussions.
+	ListDiscussionsReturn []*GitHubItem
+
+	// ListDiscussionsError holds the returned error for ListDiscussions.
+	ListDiscussionsError error
+
+	// SearchIssuesRequests holds the queries passed to SearchIssues.
+	SearchIssuesRequests []string
+
+	// SearchIssuesReturn holds the items returned from calls to SearchIssues.
+	SearchIssuesReturn []*GitHubItem
+
+	// SearchIssuesError holds the returned error for SearchIssues.
+	SearchIssuesError error
+
+	// CheckSearchRequests holds the queries passed to CheckSearch.
+	CheckSearchRequests []string
+
+	// CheckSearchError holds the returned error for CheckSearch.
+	CheckSearchError error
+
+	// GetIssueRequests holds the repositories and numbers passed to GetIssue, as 'owner/repo#number'.
+	GetIssueRequests []string
+
+	// GetIssueReturn holds the item returned from calls to GetIssue.
+	GetIssueReturn *GitHubItem
+
+	// GetIssueError holds the returned error for GetIssue.
+	GetIssueError error
+
+	// GetLabelIDsRequests holds the labels passed to GetLabelIDs, as 'owner/repo:label'.
+	GetLabelIDsRequests []string
+
+	// GetLabelIDsError holds the returned error for GetLabelIDs. Otherwise, the ID of each label is its name.
+	GetLabelIDsError error
+
+	// SetLabelsRequests holds the item IDs passed to SetLabels.
+	SetLabelsRequests []githubv4.ID
+
+	// SetLabelsLabelIDs holds the label IDs passed to SetLabels, in the same order as SetLabelsRequests.
+	SetLabelsLabelIDs [][]githubv4.ID
+
+	// SetLabelsError holds the returned error for SetLabels.
+	SetLabelsError error
+
+	// HasCommentRequests holds the item IDs passed to HasComment. HasComment returns if any of the bodies passed to
+	// AddComment for the item contain the marker.
+	HasCommentRequests []githubv4.ID
+
+	// HasCommentError holds the returned error for HasComment.
+	HasCommentError error
+
+	// AddCommentRequests holds the item IDs passed to AddComment.
+	AddCommentRequests []githubv4.ID
+
+	// AddCommentBodies holds the bodies passed to AddComment, in the same order as AddCommentRequests.
+	AddCommentBodies []string
+
+	// AddCommentError holds the returned error for AddComment.
+	AddCommentError error
+
+	// listLock guards the requests recorded by ListIssues, ListPullRequests and ListDiscussions, which a watch calls
+	// concurrently for each of its repositories.
+	listLock sync.Mutex
+}
+
+func (t *MockGitHubinator) WithRetries(_ int) GitHubinator { return t }
+
+func (t *MockGitHubinator) WithTimeout(_ time.Duration) GitHubinator { return t }
+
+func (t *MockGitHubinator) WithToken(_ string) GitHubinator { return t }
+
+func (t *MockGitHubinator) WithTokens(_ ...string) GitHubinator { return t }
+
+func (t *MockGitHubinator) WithBaseURL(_ string) GitHubinator { return t }
+
+func (t *MockGitHubinator) WithPageSize(_ int) GitHubinator { return t }
+
+func (t *MockGitHubinator) WithAppAuth(_ int64, _ int64, _ []byte) GitHubinator { return t }
+
+func (t *MockGitHubinator) WhoAmI(_ context.Context) (string, error) {
+	t.WhoAmIRequests += 1
+
+	return t.WhoAmIReturn, t.WhoAmIError
+}
+
+func (t *MockGitHubinator) CheckRepository(ctx context.Context, ghr GitHubRepository) error {
+	t.CheckRepositoryRequests = append(t.CheckRepositoryRequests, ghr)
+
+	return t.CheckRepositoryError
+}
+
+func (t *MockGitHubinator) CountOpenIssues(_ context.Context, _ GitHubRepository) (int, error) {
+	return t.CountOpenIssuesReturn, t.CountOpenIssuesError
+}
+
+func (t *MockGitHubinator) ListViewerRepositories(_ context.Context) ([]GitHubRepository, error) {
+	t.ListViewerRepositoriesRequests += 1
+
+	return t.ListViewerRepositoriesReturn, t.ListViewerRepositoriesError
+}
+
+func (t *MockGitHubinator) RateLimit(_ context.Context) (*GitHubRateLimit, error) {
+	rl := t.RateLimitReturn
+
+	return &rl, t.RateLimitError
+}
+
+func (t *MockGitHubinator) ListIssues(
+	ctx context.Context, ghr GitHubRepository, filter *GitHubIssueFilter, matcher Matchinator,
+) ([]*GitHubItem, error) {
+	t.listLock.Lock()
+	defer t.listLock.Unlock()
+
+	t.ListIssuesRequests = append(t.ListIssuesRequests, ghr)
+	t.ListIssuesFilters = append(t.ListIssuesFilters, filter)
+
+	return t.ListIssuesReturn, t.ListIssuesError
+}
+
+func (t *MockGitHubinator) ListPullRequests(
+	ctx context.Context, ghr GitHubRepository, filter *GitHubIssueFilter, matcher Matchinator,
+) ([]*GitHubItem, error) {
+	t.listLock.Lock()
+	defer t.listLock.Unlock()
+
+	t.ListPullRequestsRequests = append(t.ListPullRequestsRequests, ghr)
+
+	return t.ListPullRequestsReturn, t.ListPullRequestsError
+}
+
+func (t *MockGitHubinator) ListDiscussions(
+	ctx context.Context, ghr GitHubRepository, filter *GitHubIssueFilter, matcher Matchinator,
+) ([]*GitHubItem, error) {
+	t.listLock.Lock()
+	defer t.listLock.Unlock()
+
+	t.ListDiscussionsRequests = append(t.ListDiscussionsRequests, ghr)
+
+	return t.ListDiscussionsReturn, t.ListDiscussionsError
+}
+
+func (t *MockGitHubinator) SearchIssues(_ context.Context, query string, _ Matchinator) ([]*GitHubItem, error) {
+	t.SearchIssuesRequests = append(t.SearchIssuesRequests, query)
+
+	return t.SearchIssuesReturn, t.SearchIssuesError
+}
+
+func (t *MockGitHubinator) CheckSearch(_ context.Context, query string) error {
+	t.CheckSearchRequests = append(t.CheckSearchRequests, query)
+
+	return t.CheckSearchError
+}
+
+func (t *MockGitHubinator) GetIssue(
+	_ context.Context, ghr GitHubRepository, number int, _ Matchinator,
+) (*GitHubItem, error) {
+	t.GetIssueRequests = append(t.GetIssueRequests, fmt.Sprintf("%s/%s#%d", ghr.Owner, ghr.Name, number))
+
+	return t.GetIssueReturn, t.GetIssueError
+}
+
+func (t *MockGitHubinator) SetSubscription(
+	ctx context.Context, id githubv4.ID, state githubv4.SubscriptionState,
+) error {
+	t.SetSubscriptionRequests = append(t.SetSubscriptionRequests, id)
+
+	return t.SetSubscriptionError
+}
+
+func (t *MockGitHubinator) GetLabelIDs(
+	_ context.Context, ghr GitHubRepository, names []string,
+) ([]githubv4.ID, error) {
+	ids := []githubv4.ID{}
+
+	for _, n := range names {
+		t.GetLabelIDsRequests = append(t.GetLabelIDsRequests, ghr.fullName()+":"+n)
+		ids = append(ids, githubv4.ID(n))
+	}
+
+	if t.GetLabelIDsError != nil {
+		return nil, t.GetLabelIDsError
+	}
+
+	return ids, nil
+}
+
+func (t *MockGitHubinator) SetLabels(_ context.Context, id githubv4.ID, labelIDs []githubv4.ID) error {
+	t.SetLabelsRequests = append(t.SetLabelsRequests, id)
+	t.SetLabelsLabelIDs = append(t.SetLabelsLabelIDs, labelIDs)
+
+	return t.SetLabelsError
+}
+
+func (t *MockGitHubinator) HasComment(_ context.Context, id githubv4.ID, marker string) (bool, error) {
+	t.HasCommentRequests = append(t.HasCommentRequests, id)
+
+	if t.HasCommentError != nil {
+		return false, t.HasCommentError
+	}
+
+	for i, commented := range t.AddCommentRequests {
+		if commented == id && strings.Contains(t.AddCommentBodies[i], marker) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (t *MockGitHubinator) AddComment(_ context.Context, id githubv4.ID, body string) error {
+	if t.AddCommentError != nil {
+		return t.AddCommentError
+	}
+
+	t.AddCommentRequests = append(t.AddCommentRequests, id)
+	t.AddCommentBodies = append(t.AddCommentBodies, body)
+
+	return nil
+}
+
+// NewMockGitHubinator creates a new MockGitHubinator instance with pre-populated, non-error return values.
+func NewMockGitHubinator() *MockGitHubinator {
+	return &MockGitHubinator{
+		CheckRepositoryRequests:        []GitHubRepository{},
+		CheckRepositoryError:           nil,
+		WhoAmIRequests:                 0,
+		WhoAmIReturn:                   "user",
+		WhoAmIError:                    nil,
+		SetSubscriptionRequests:        []githubv4.ID{},
+		SetSubscriptionError:           nil,
+		CountOpenIssuesReturn:          10,
+		CountOpenIssuesError:           nil,
+		ListViewerRepositoriesRequests: 0,
+		ListViewerRepositoriesReturn: []GitHubRepository{{
+			Owner: "user",
+			Name:  "repo",
+		}},
+		ListViewerRepositoriesError: nil,
+		RateLimitReturn: GitHubRateLimit{
+			Limit:     5000,
+			Remaining: 5000,
+			Used:      0,
+		},
+		RateLimitError:           nil,
+		ListIssuesRequests:       []GitHubRepository{},
+		ListIssuesFilters:        []*GitHubIssueFilter{},
+		ListIssuesReturn:         []*GitHubItem{},
+		ListIssuesError:          nil,
+		ListPullRequestsRequests: []GitHubRepository{},
+		ListPullRequestsReturn:   []*GitHubItem{},
+		ListPullRequestsError:    nil,
+		ListDiscussionsRequests:  []GitHubRepository{},
+		ListDiscussionsReturn:    []*GitHubItem{},
+		ListDiscussionsError:     nil,
+		SearchIssuesRequests:     []string{},
+		SearchIssuesReturn:       []*GitHubItem{},
+		SearchIssuesError:        nil,
+		CheckSearchRequests:      []string{},
+		CheckSearchError:         nil,
+		GetIssueRequests:         []string{},
+		GetIssueReturn:           NewTestGitHubItem(),
+		GetIssueError:            nil,
+		GetLabelIDsRequests:      []string{},
+		GetLabelIDsError:         nil,
+		SetLabelsRequests:        []githubv4.ID{},
+		SetLabelsLabelIDs:        [][]githubv4.ID{},
+		SetLabelsError:           nil,
+		HasCommentRequests:       []githubv4.ID{},
+		HasCommentError:          nil,
+		AddCommentRequests:       []githubv4.ID{},
+		AddCommentBodies:         []string{},
+		AddCommentError:          nil,
+	}
+}
+
+// gitHubinator is the packages internal implementation of the GitHubinator interface.
+// The With* builder functions will set the internal field 'client' to nil to signal that the client needs to be
+// setup. Any function which uses the client must perform a nil check.
+// If app is set, the GitHub App's installation tokens are used instead of token. They are minted by appSource, which
+// is created alongside the client.
+type gitHubinator struct {
+	retries   int
+	timeout   time.Duration
+	token     oauth2.TokenSource
+	tokens    []string
+	baseURL   string
+	app       *gitHubAppAuth
+	appSource *gitHubAppTokenSource
+	pageSize  int
+	client    *githubv4.Client
+	logger    *slog.Logger
+	// repoCache caches the repositories which were checked, keyed by their full name, see CheckRepository.
+	repoCache *ttlCache[string, GitHubRepository]
+	// repoMetadataCache caches the language and topics of repositories, keyed by their full name.
+	repoMetadataCache *ttlCache[string, GitHubRepository]
+	// crossReferenceCache caches the issues which referenced an issue, keyed by the issue, see
+	// getIssueCrossReferences.
+	crossReferenceCache *ttlCache[string, []int]
+	// issueCache caches the labels and bodies fetched for issues, so they aren't fetched again until the issue is
+	// updated.
+	issueCache *issueCache
+	// labelIDCache caches the IDs of labels in repositories, see GetLabelIDs.
+	labelIDCache *ttlCache[string, githubv4.ID]
+}
+
+const (
+	// CheckRepositoryCacheTTL is how long a successful CheckRepository result is cached for.
+	CheckRepositoryCacheTTL = time.Minute
+	// RepositoryMetadataCacheTTL is how long the language and topics of a repository are cached for.
+	RepositoryMetadataCacheTTL = 10 * time.Minute
+	// LabelIDCacheTTL is how long the ID of a label in a repository is cached for. Labels are rarely recreated, but
+	// the cache expires in case they are.
+	LabelIDCacheTTL = 10 * time.Minute
+	// IssueCrossReferenceCacheTTL is how long the issues which referenced an issue are cached for. Each item a watch
+	// checks for a reference to an issue needs the same cross-references, so caching them saves a query per item.
+	IssueCrossReferenceCacheTTL = time.Minute
+	// IssueCacheTTL is how long the labels and body fetched for an item are cached for. Watches list the same items
+	// on each tick, so this is longer than a typical interval, while items which are no longer listed are dropped.
+	IssueCacheTTL = 24 * time.Hour
+	// GitHubRateLimitWarningThreshold is the number of remaining rate limit points below which a warning is logged
+	// after each query, so throttling can be anticipated. GitHub allows 5000 points per hour by default.
+	GitHubRateLimitWarningThreshold = 500
+	// GitHubMaxPageSize is the largest number of nodes GitHub returns in a page of a connection, and the default page
+	// size of issue and label queries.
+	GitHubMaxPageSize = 100
+)
+
+// ttlCacheEntry is a value held in a ttlCache.
+type ttlCacheEntry[V any] struct {
+	value   V
+	expires time.Time
+}
+
+// ttlCache caches values fetched from GitHub until they expire after the TTL, such as repositories keyed by their full
+// name, so repeated requests for the same value, such as when a repository appears in multiple watches, don't
+// re-query GitHub. Only successful requests are cached, so transient errors are retried. Expired entries are dropped
+// once per TTL, so values which are no longer requested don't stay cached forever.
+type ttlCache[K comparable, V any] struct {
+	lock    *sync.Mutex
+	ttl     time.Duration
+	clock   Clock
+	entries map[K]ttlCacheEntry[V]
+	// nextSweep is when expired entries are next dropped.
+	nextSweep time.Time
+}
+
+// get returns the value cached for the given key, if it was cached within the TTL.
+func (c *ttlCache[K, V]) get(key K) (V, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || !c.clock.Now().Before(entry.expires) {
+		var zero V
+
+		return zero, false
+	}
+
+	return entry.value, true
+}
+
+// add caches the given value for the given key.
+func (c *ttlCache[K, V]) add(key K, value V) {
+	c.update(key, func(V, bool) V { return value })
+}
+
+// update caches the value returned by the given function for the given key. The function is passed the value cached
+// for the key within the TTL, if any, and is called while the cache is locked, so concurrent updates aren't lost.
+func (c *ttlCache[K, V]) update(key K, fn func(previous V, ok bool) V) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	now := c.clock.Now()
+
+	if !now.Before(c.nextSweep) {
+		for k, entry := range c.entries {
+			if !now.Before(entry.expires) {
+				delete(c.entries, k)
+			}
+		}
+
+		c.nextSweep = now.Add(c.ttl)
+	}
+
+	previous, ok := c.entries[key]
+
+	c.entries[key] = ttlCacheEntry[V]{value: fn(previous.value, ok), expires: now.Add(c.ttl)}
+}
+
+// newTTLCache creates a new, empty ttlCache whose entries expire after the given TTL.
+func newTTLCache[K comparable, V any](ttl time.Duration) *ttlCache[K, V] {
+	return &ttlCache[K, V]{
+		lock:    &sync.Mutex{},
+		ttl:     ttl,
+		clock:   NewSystemClock(),
+		entries: map[K]ttlCacheEntry[V]{},
+	}
+}
+
+// issueCacheKey returns the key of the given issue in a ttlCache.
+func issueCacheKey(ghr GitHubRepository, number int) string {
+	return fmt.Sprintf("%s#%d", ghr.fullName(), number)
+}
+
+// labelIDCacheKey returns the key of the given label in a ttlCache.
+func labelIDCacheKey(ghr GitHubRepository, name string) string {
+	return ghr.fullName() + ":" + name
+}
+
+// issueCache caches the labels and body fetched separately for an item, keyed by the item's ID. An entry is only used
+// while the item's UpdatedAt is unchanged, as any change to the item's labels or body updates it, and until it
+// expires after the TTL. A nil issueCache caches nothing.
+type issueCache struct {
+	cache *ttlCache[githubv4.ID, GitHubItem]
+}
+
+// restore copies the cached labels and body of the given item into it, if they were cached at the item's UpdatedAt
+// and weren't fetched along with the item.
+func (c *issueCache) restore(item *GitHubItem) {
+	if c == nil {
+		return
+	}
+
+	entry, ok := c.cache.get(item.ID)
+	if !ok || !entry.UpdatedAt.Equal(item.UpdatedAt) {
+		return
+	}
+
+	if entry.LabelsFetched && !item.LabelsFetched {
+		item.GitHubIssue.Labels = slices.Clone(entry.Labels)
+		item.LabelsFetched = true
+	}
+
+	if entry.BodyFetched && !item.BodyFetched {
+		item.GitHubIssue.Body = entry.Body
+		item.GitHubIssue.BodyMarkdown = entry.BodyMarkdown
+		item.BodyFetched = true
+	}
+}
+
+// add caches the labels and body of the given item, if they were fetched.
+func (c *issueCache) add(item *GitHubItem) {
+	if c == nil || item.ID == nil || (!item.LabelsFetched && !item.BodyFetched) {
+		return
+	}
+
+	entry := GitHubItem{
+		GitHubIssue: GitHubIssue{
+			Labels:       slices.Clone(item.Labels),
+			Body:         item.Body,
+			BodyMarkdown: item.BodyMarkdown,
+			UpdatedAt:    item.UpdatedAt,
+		},
+		LabelsFetched: item.LabelsFetched,
+		BodyFetched:   item.BodyFetched,
+	}
+
+	c.cache.update(item.ID, func(previous GitHubItem, ok bool) GitHubItem {
+		// Keep what was fetched previously for the same update, so a body fetched for one matcher and labels
+		// fetched for another are both cached.
+		if !ok || !previous.UpdatedAt.Equal(item.UpdatedAt) {
+			return entry
+		}
+
+		if previous.LabelsFetched && !entry.LabelsFetched {
+			entry.GitHubIssue.Labels = previous.Labels
+			entry.LabelsFetched = true
+		}
+
+		if previous.BodyFetched && !entry.BodyFetched {
+			entry.GitHubIssue.Body = previous.Body
+			entry.GitHubIssue.BodyMarkdown = previous.BodyMarkdown
+			entry.BodyFetched = true
+		}
+
+		return entry
+	})
+}
+
+// newIssueCache creates a new, empty issueCache whose entries expire after the given TTL.
+func newIssueCache(ttl time.Duration) *issueCache {
+	return &issueCache{cache: newTTLCache[githubv4.ID, GitHubItem](ttl)}
+}
+
+func (gh *gitHubinator) WithRetries(retries int) GitHubinator {
+	return &gitHubinator{
+		retries:             retries,
+		timeout:             gh.timeout,
+		token:               gh.token,
+		tokens:              gh.tokens,
+		baseURL:             gh.baseURL,
+		app:                 gh.app,
+		pageSize:            gh.pageSize,
+		client:              nil,
+		logger:              gh.logger,
+		repoCache:           gh.repoCache,
+		repoMetadataCache:   gh.repoMetadataCache,
+		crossReferenceCache: gh.crossReferenceCache,
+		issueCache:          gh.issueCache,
+		labelIDCache:        gh.labelIDCache,
+	}
+}
+
+func (gh *gitHubinator) WithPageSize(n int) GitHubinator {
+	return &gitHubinator{
+		retries:             gh.retries,
+		timeout:             gh.timeout,
+		token:               gh.token,
+		tokens:              gh.tokens,
+		baseURL:             gh.baseURL,
+		app:                 gh.app,
+		pageSize:            n,
+		client:              nil,
+		logger:              gh.logger,
+		repoCache:           gh.repoCache,
+		repoMetadataCache:   gh.repoMetadataCache,
+		crossReferenceCache: gh.crossReferenceCache,
+		issueCache:          gh.issueCache,
+		labelIDCache:        gh.labelIDCache,
+	}
+}
+
+// getPageSize returns the number of issues or labels to request per page, which is GitHubMaxPageSize unless a
+// smaller size was set with WithPageSize.
+func (gh *gitHubinator) getPageSize() githubv4.Int {
+	if gh.pageSize <= 0 || gh.pageSize > GitHubMaxPageSize {
+		return GitHubMaxPageSize
+	}
+
+	return githubv4.Int(gh.pageSize)
+}
+
+func (gh *gitHubinator) WithTimeout(timeout time.Duration) GitHubinator {
+	return &gitHubinator{
+		retries:             gh.retries,
+		timeout:             timeout,
+		token:               gh.token,
+		tokens:              gh.tokens,
+		baseURL:             gh.baseURL,
+		app:                 gh.app,
+		pageSize:            gh.pageSize,
+		client:              nil,
+		logger:              gh.logger,
+		repoCache:           gh.repoCache,
+		repoMetadataCache:   gh.repoMetadataCache,
+		crossReferenceCache: gh.crossReferenceCache,
+		issueCache:          gh.issueCache,
+		labelIDCache:        gh.labelIDCache,
+	}
+}
+
+func (gh *gitHubinator) WithToken(token string) GitHubinator {
+	return gh.WithTokens(token)
+}
+
+func (gh *gitHubinator) WithTokens(tokens ...string) GitHubinator {
+	return &gitHubinator{
+		retries:             gh.retries,
+		timeout:             gh.timeout,
+		token:               newRoundRobinTokenSource(tokens),
+		tokens:              tokens,
+		baseURL:             gh.baseURL,
+		pageSize:            gh.pageSize,
+		client:              nil,
+		logger:              gh.logger,
+		repoCache:           newTTLCache[string, GitHubRepository](CheckRepositoryCacheTTL),
+		repoMetadataCache:   newTTLCache[string, GitHubRepository](RepositoryMetadataCacheTTL),
+		crossReferenceCache: newTTLCache[string, []int](IssueCrossReferenceCacheTTL),
+		issueCache:          newIssueCache(IssueCacheTTL),
+		labelIDCache:        newTTLCache[string, githubv4.ID](LabelIDCacheTTL),
+	}
+}
+
+func (gh *gitHubinator) WithAppAuth(appID int64, installationID int64, privateKeyPEM []byte) GitHubinator {
+	return &gitHubinator{
+		retries: gh.retries,
+		timeout: gh.timeout,
+		token:   nil,
+		baseURL: gh.baseURL,
+		app: &gitHubAppAuth{
+			appID:          appID,
+			installationID: installationID,
+			privateKeyPEM:  privateKeyPEM,
+		},
+		pageSize:            gh.pageSize,
+		client:              nil,
+		logger:              gh.logger,
+		repoCache:           newTTLCache[string, GitHubRepository](CheckRepositoryCacheTTL),
+		repoMetadataCache:   newTTLCache[string, GitHubRepository](RepositoryMetadataCacheTTL),
+		crossReferenceCache: newTTLCache[string, []int](IssueCrossReferenceCacheTTL),
+		issueCache:          newIssueCache(IssueCacheTTL),
+		labelIDCache:        newTTLCache[string, githubv4.ID](LabelIDCacheTTL),
+	}
+}
+
+func (gh *gitHubinator) WithBaseURL(baseURL string) GitHubinator {
+	return &gitHubinator{
+		retries:             gh.retries,
+		timeout:             gh.timeout,
+		token:               gh.token,
+		tokens:              gh.tokens,
+		baseURL:             baseURL,
+		app:                 gh.app,
+		pageSize:            gh.pageSize,
+		client:              nil,
+		logger:              gh.logger,
+		repoCache:           newTTLCache[string, GitHubRepository](CheckRepositoryCacheTTL),
+		repoMetadataCache:   newTTLCache[string, GitHubRepository](RepositoryMetadataCacheTTL),
+		crossReferenceCache: newTTLCache[string, []int](IssueCrossReferenceCacheTTL),
+		issueCache:          newIssueCache(IssueCacheTTL),
+		labelIDCache:        newTTLCache[string, githubv4.ID](LabelIDCacheTTL),
+	}
+}
+
+// GitHubGraphQLURL returns the URL of the GraphQL API of the GitHub Enterprise Server instance at the given base URL,
+// such as 'https://github.example.com/api/graphql' for 'https://github.example.com'. If the base URL already points
+// at the GraphQL API, it is returned as is.
+func GitHubGraphQLURL(baseURL string) string {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	if strings.HasSuffix(baseURL, "/api/graphql") {
+		return baseURL
+	}
+
+	return baseURL + "/api/graphql"
+}
+
+// roundRobinTokenSource is an oauth2.TokenSource which returns each of its tokens in turn, so requests are spread
+// across them. It must not be wrapped with oauth2.ReuseTokenSource, which would keep returning the first token.
+type roundRobinTokenSource struct {
+	tokens []*oauth2.Token
+	next   *atomic.Uint64
+}
+
+func newRoundRobinTokenSource(tokens []string) *roundRobinTokenSource {
+	s := &roundRobinTokenSource{next: &atomic.Uint64{}}
+
+	for _, t := range tokens {
+		s.tokens = append(s.tokens, &oauth2.Token{AccessToken: t})
+	}
+
+	return s
+}
+
+// Token returns the next token in the pool.
+func (s *roundRobinTokenSource) Token() (*oauth2.Token, error) {
+	if len(s.tokens) == 0 {
+		return nil, fmt.Errorf("no tokens configured")
+	}
+
+	i := (s.next.Add(1) - 1) % uint64(len(s.tokens))
+
+	return s.tokens[i], nil
+}
+
+func (gh *gitHubinator) setupClient() {
+	token := gh.token
+
+	if gh.app != nil {
+		gh.appSource = newGitHubAppTokenSource(
+			gh.app, GitHubRESTURLFor(gh.baseURL), &http.Client{Timeout: gh.timeout},
+		)
+		token = oauth2.ReuseTokenSource(nil, gh.appSource)
+	}
+
+	// oauth2.NewClient would wrap the token source with oauth2.ReuseTokenSource, which only asks a pool of tokens for
+	// its first one. Building the transport directly asks the source for a token on every request, including retries.
+	oauthClient := &http.Client{}
+	if token != nil {
+		oauthClient.Transport = &oauth2.Transport{Source: token}
+	}
+
+	rclient := retryablehttp.NewClient()
+	rclient.RetryMax = gh.retries
+	rclient.HTTPClient = oauthClient
+	rclient.Logger = gh.logger
+	rclient.CheckRetry = gitHubCheckRetry
+	rclient.Backoff = gitHubBackoff
+	// Return the last response once retries are exhausted, so the GraphQL client can report GitHub's error.
+	rclient.ErrorHandler = retryablehttp.PassthroughErrorHandler
+
+	if gh.timeout > 0 {
+		rclient.RetryWaitMax = gh.timeout
+	}
+
+	httpClient := rclient.StandardClient()
+
+	if gh.baseURL != "" {
+		gh.client = githubv4.NewEnterpriseClient(GitHubGraphQLURL(gh.baseURL), httpClient)
+
+		return
+	}
+
+	gh.client = githubv4.NewClient(httpClient)
+}
+
+// GitHubSecondaryRateLimitWait is how long to wait before retrying a request which hit one of GitHub's secondary rate
+// limits, if GitHub doesn't say how long to wait.
+const GitHubSecondaryRateLimitWait = time.Minute
+
+// isGitHubRateLimited returns if the given response is GitHub rejecting a request due to a rate limit, such as a
+// secondary rate limit from making too many requests at once.
+func isGitHubRateLimited(resp *http.Response) bool {
+	if resp == nil || (resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests) {
+		return false
+	}
+
+	if resp.Header.Get("Retry-After") != "" || resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		return true
+	}
+
+	// Secondary rate limits aren't always accompanied by headers, but are explained in the body. The body is
+	// restored after it's read, so it can still be read by the caller.
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return false
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	message := strings.ToLower(string(body))
+
+	return strings.Contains(message, "secondary rate limit") || strings.Contains(message, "abuse detection")
+}
+
+// gitHubRateLimitWait returns how long to wait before retrying the given rate limited response. The Retry-After
+// header is used if set, followed by the time the primary rate limit resets, falling back to
+// GitHubSecondaryRateLimitWait.
+func gitHubRateLimitWait(resp *http.Response, now time.Time) time.Duration {
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			return max(time.Unix(reset, 0).Sub(now), 0)
+		}
+	}
+
+	return GitHubSecondaryRateLimitWait
+}
+
+// gitHubRateLimitWaitHeader is set by gitHubCheckRetry on rate limited responses to the time to wait before retrying,
+// for gitHubBackoff to read. retryablehttp drains and closes the body before calling the backoff, so secondary rate
+// limits which are only explained in the body need to be spotted beforehand.
+const gitHubRateLimitWaitHeader = "X-Watchinator-Rate-Limit-Wait"
+
+// gitHubCheckRetry implements retryablehttp.CheckRetry, retrying requests which were rate limited in addition to
+// those retried by retryablehttp.DefaultRetryPolicy. Rate limited requests are counted in
+// MetricRateLimitBackoffTotal, and have how long to wait recorded under gitHubRateLimitWaitHeader.
+func gitHubCheckRetry(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+
+	if err == nil && isGitHubRateLimited(resp) {
+		MetricRateLimitBackoffTotal.Inc()
+
+		resp.Header.Set(gitHubRateLimitWaitHeader, gitHubRateLimitWait(resp, time.Now()).String())
+
+		return true, nil
+	}
+
+	return retryablehttp.DefaultRetryPolicy(ctx, resp, err)
+}
+
+// gitHubBackoff implements retryablehttp.Backoff, waiting as long as gitHubCheckRetry found GitHub asks before
+// retrying a rate limited request, up to waitMax. Other requests are retried following retryablehttp.DefaultBackoff.
+func gitHubBackoff(waitMin, waitMax time.Duration, attemptNum int, resp *http.Response) time.Duration {
+	if resp == nil {
+		return retryablehttp.DefaultBackoff(waitMin, waitMax, attemptNum, resp)
+	}
+
+	wait, err := time.ParseDuration(resp.Header.Get(gitHubRateLimitWaitHeader))
+	if err != nil {
+		return retryablehttp.DefaultBackoff(waitMin, waitMax, attemptNum, resp)
+	}
+
+	return min(wait, waitMax)
+}
+
+// query executes the given GraphQL query, recording its duration in MetricGitHubQueryDurationSeconds under the
+// given queryType.
+func (gh *gitHubinator) query(
+	ctx context.Context, queryType string, q any, vars map[string]any,
+) (time.Duration, error) {
+	start := time.Now()
+	err := gh.client.Query(ctx, q, vars)
+	duration := time.Since(start)
+
+	MetricGitHubQueryDurationSeconds.WithLabelValues(queryType).Observe(duration.Seconds())
+
+	if err == nil {
+		gh.recordRateLimit(ctx, queryType, q)
+	}
+
+	return duration, err
+}
+
+// recordRateLimit publishes the rate limit fetched along with the given query, if it embeds a gitHubQueryRateLimit,
+// to MetricGitHubRateLimitRemaining and MetricGitHubQueryCostTotal. A warning is logged if the remaining rate limit
+// is below GitHubRateLimitWarningThreshold.
+func (gh *gitHubinator) recordRateLimit(ctx context.Context, queryType string, q any) {
+	// Queries are passed as either a pointer to the query or a pointer to a pointer to the query.
+	if v := reflect.ValueOf(q); v.Kind() == reflect.Pointer && v.Elem().Kind() == reflect.Pointer {
+		q = v.Elem().Interface()
+	}
+
+	limited, ok := q.(interface{ queryRateLimit() *gitHubQueryRateLimit })
+	if !ok {
+		return
+	}
+
+	rateLimit := limited.queryRateLimit().RateLimit
+
+	// The rate limit wasn't returned, such as by a GitHub Enterprise Server instance with rate limiting disabled.
+	if rateLimit.ResetAt.IsZero() {
+		return
+	}
+
+	MetricGitHubRateLimitRemaining.Set(float64(rateLimit.Remaining))
+	MetricGitHubQueryCostTotal.WithLabelValues(queryType).Add(float64(rateLimit.Cost))
+
+	if int(rateLimit.Remaining) < GitHubRateLimitWarningThreshold {
+		LoggerFromContext(ctx, gh.logger).Warn(
+			"GitHub rate limit is running low",
+			"query", queryType,
+			"remaining", int(rateLimit.Remaining),
+			"cost", int(rateLimit.Cost),
+			"resetAt", rateLimit.ResetAt.Time,
+		)
+	}
+}
+
+// mutate executes the given GraphQL mutation, recording its duration in MetricGitHubQueryDurationSeconds under
+// the given mutationType.
+func (gh *gitHubinator) mutate(
+	ctx context.Context, mutationType string, m any, input githubv4.Input, vars map[string]any,
+) (time.Duration, error) {
+	start := time.Now()
+	err := gh.client.Mutate(ctx, m, input, vars)
+	duration := time.Since(start)
+
+	MetricGitHubQueryDurationSeconds.WithLabelValues(mutationType).Observe(duration.Seconds())
+
+	return duration, err
+}
+
+func (gh *gitHubinator) WhoAmI(ctx context.Context) (string, error) {
+	if gh.client == nil {
+		gh.setupClient()
+	}
+
+	// Installation tokens can't query the viewer, so the app's bot login is looked up instead.
+	if gh.appSource != nil {
+		return gh.appSource.Login(ctx)
+	}
+
+	if len(gh.tokens) > 1 {
+		return gh.whoAmIForTokens(ctx)
+	}
+
+	query := gitHubViewerQuery{}
+	queryLogger := LoggerFromContext(ctx, gh.logger)
+
+	queryLogger.Debug("executing whoami query")
+
+	duration, err := gh.query(ctx, "whoami", &query, nil)
+	if err != nil {
+		queryLogger.Debug("got error on whoami query", LogKeyError, err, "duration", duration)
+
+		return "", err
+	}
+
+	queryLogger.Debug("response on whoami query", "result", query, "duration", duration)
+
+	if !query.Viewer.IsViewer {
+		return "", fmt.Errorf("unexpected result, returned user is not viewer")
+	}
+
+	return string(query.Viewer.Login), nil
+}
+
+// whoAmIForTokens returns the login of the user the GitHubinator's tokens belong to, checking each token in turn. An
+// error is returned if the tokens belong to different users.
+func (gh *gitHubinator) whoAmIForTokens(ctx context.Context) (string, error) {
+	var login string
+
+	for i, t := range gh.tokens {
+		// Each token gets its own client, so the viewer is looked up with that token.
+		single := &gitHubinator{
+			retries: gh.retries,
+			timeout: gh.timeout,
+			token:   newRoundRobinTokenSource([]string{t}),
+			tokens:  []string{t},
+			baseURL: gh.baseURL,
+			logger:  gh.logger,
+		}
+
+		user, err := single.WhoAmI(ctx)
+		if err != nil {
+			return "", fmt.Errorf("unable to check token %d: %w", i+1, err)
+		}
+
+		if i > 0 && user != login {
+			return "", fmt.Errorf("token %d belongs to '%s', but token 1 belongs to '%s'", i+1, user, login)
+		}
+
+		login = user
+	}
+
+	return login, nil
+}
+
+func (gh *gitHubinator) CheckRepository(ctx context.Context, ghr GitHubRepository) error {
+	if gh.client == nil {
+		gh.setupClient()
+	}
+
+	query := gitHubRepositoryQuery{}
+
+	vars := gitHubRepositoryQueryVars{
+		Name:  githubv4.String(ghr.Name),
+		Owner: githubv4.String(ghr.Owner),
+	}
+
+	queryLogger := LoggerFromContext(ctx, gh.logger).With("vars", vars)
+
+	if _, ok := gh.repoCache.get(ghr.fullName()); ok {
+		queryLogger.Debug("using cached check repository result")
+
+		return nil
+	}
+
+	queryLogger.Debug("executing check repository query")
+
+	MetricRepoQueryTotal.Inc()
+
+	duration, err := gh.query(ctx, "repository", &query, vars.AsMap())
+	if err != nil {
+		queryLogger.Debug("got error on check repository query", LogKeyError, err, "duration", duration)
+
+		MetricRepoQueryErrorTotal.Inc()
+
+		return err
+	}
+
+	queryLogger.Debug("response on check repository query", "result", query, "duration", duration)
+
+	gh.repoCache.add(ghr.fullName(), ghr)
+
+	return nil
+}
+
+func (gh *gitHubinator) CountOpenIssues(ctx context.Context, ghr GitHubRepository) (int, error) {
+	if gh.client == nil {
+		gh.setupClient()
+	}
+
+	query := gitHubOpenIssueCountQuery{}
+
+	vars := gitHubRepositoryQueryVars{
+		Name:  githubv4.String(ghr.Name),
+		Owner: githubv4.String(ghr.Owner),
+	}
+
+	queryLogger := LoggerFromContext(ctx, gh.logger).With("vars", vars)
+	queryLogger.Debug("executing count open issues query")
+
+	MetricRepoQueryTotal.Inc()
+
+	duration, err := gh.query(ctx, "open_issue_count", &query, vars.AsMap())
+	if err != nil {
+		queryLogger.Debug("got error on count open issues query", LogKeyError, err, "duration", duration)
+
+		MetricRepoQueryErrorTotal.Inc()
+
+		return 0, err
+	}
+
+	queryLogger.Debug("response on count open issues query", "result", query, "duration", duration)
+
+	return int(query.Repository.Issues.TotalCount), nil
+}
+
+func (gh *gitHubinator) ListViewerRepositories(ctx context.Context) ([]GitHubRepository, error) {
+	if gh.client == nil {
+		gh.setupClient()
+	}
+
+	query := &gitHubViewerRepositoriesQuery{}
+
+	vars := &gitHubViewerRepositoriesQueryVars{
+		N:           100,
+		ReposCursor: (*githubv4.String)(nil),
+	}
+
+	allRepos := []GitHubRepository{}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+			queryLogger := LoggerFromContext(ctx, gh.logger).With("vars", vars)
+			queryLogger.Debug("executing list viewer repositories query")
+
+			MetricRepoQueryTotal.Inc()
+
+			duration, err := gh.query(ctx, "viewer_repositories", &query, vars.AsMap())
+			if err != nil {
+				queryLogger.Debug("got error on list viewer repositories query", LogKeyError, err, "duration", duration)
+
+				MetricRepoQueryErrorTotal.Inc()
+
+				return nil, err
+			}
+
+			queryLogger.Debug("got response on list viewer repositories query", "response", query, "duration", duration)
+
+			for _, n := range query.Viewer.Repositories.Nodes {
+				allRepos = append(allRepos, GitHubRepository{
+					Owner: string(n.Owner.Login),
+					Name:  string(n.Name),
+				})
+			}
+
+			if !query.Viewer.Repositories.PageInfo.HasNextPage {
+				return allRepos, nil
+			}
+
+			vars.ReposCursor = &query.Viewer.Repositories.PageInfo.EndCursor
+		}
+	}
+}
+
+func (gh *gitHubinator) RateLimit(ctx context.Context) (*GitHubRateLimit, error) {
+	if gh.client == nil {
+		gh.setupClient()
+	}
+
+	query := gitHubRateLimitQuery{}
+	queryLogger := LoggerFromContext(ctx, gh.logger)
+
+	queryLogger.Debug("executing rate limit query")
+
+	duration, err := gh.query(ctx, "rate_limit", &query, nil)
+	if err != nil {
+		queryLogger.Debug("got error on rate limit query", LogKeyError, err, "duration", duration)
+
+		return nil, err
+	}
+
+	queryLogger.Debug("response on rate limit query", "result", query, "duration", duration)
+
+	return &GitHubRateLimit{
+		Limit:     int(query.RateLimit.Limit),
+		Remaining: int(query.RateLimit.Remaining),
+		Used:      int(query.RateLimit.Used),
+		ResetAt:   query.RateLimit.ResetAt.Time,
+	}, nil
+}
+
+// listIssueLabels returns a list of labels for the given issue, performing pagination as needed.
+func (gh *gitHubinator) listIssueLabels(
+	ctx context.Context, ghr GitHubRepository, issueNumber int,
+) ([]string, error) {
+	query := &gitHubLabelQuery{}
+
+	vars := gitHubLabelQueryVars{
+		Owner:        githubv4.String(ghr.Owner),
+		Name:         githubv4.String(ghr.Name),
+		IssueNumber:  githubv4.Int(issueNumber),
+		N:            gh.getPageSize(),
+		LabelsCursor: (*githubv4.String)(nil),
+	}
+
+	allLabels := []string{}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+			queryLogger := LoggerFromContext(ctx, gh.logger).With("vars", vars)
+			queryLogger.Debug("executing list issue labels query")
+
+			MetricIssueLabelQueryTotal.Inc()
+
+			duration, err := gh.query(ctx, "issue_labels", &query, vars.AsMap())
+			if err != nil {
+				queryLogger.Debug("got error on list issue labels query", LogKeyError, err, "duration", duration)
+
+				MetricIssueLabelQueryErrorTotal.Inc()
+
+				return nil, err
+			}
+
+			queryLogger.Debug("got response on list labels query", "response", query, "duration", duration)
+
+			for i := range query.Repository.Issue.Labels.Nodes {
+				l := query.Repository.Issue.Labels.Nodes[i]
+				allLabels = append(allLabels, l.Name)
+			}
+
+			if !query.Repository.Issue.Labels.PageInfo.HasNextPage {
+				return allLabels, nil
+			}
+
+			vars.LabelsCursor = &query.Repository.Issue.Labels.PageInfo.EndCursor
+		}
+	}
+}
+
+// listIssueAssignees returns the logins of the given issue's assignees, performing pagination as needed.
+func (gh *gitHubinator) listIssueAssignees(
+	ctx context.Context, ghr GitHubRepository, issueNumber int,
+) ([]string, error) {
+	query := &gitHubAssigneeQuery{}
+
+	vars := gitHubAssigneeQueryVars{
+		Owner:           githubv4.String(ghr.Owner),
+		Name:            githubv4.String(ghr.Name),
+		IssueNumber:     githubv4.Int(issueNumber),
+		N:               gh.getPageSize(),
+		AssigneesCursor: (*githubv4.String)(nil),
+	}
+
+	allAssignees := []string{}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+			queryLogger := LoggerFromContext(ctx, gh.logger).With("vars", vars)
+			queryLogger.Debug("executing list issue assignees query")
+
+			MetricIssueAssigneeQueryTotal.Inc()
+
+			duration, err := gh.query(ctx, "issue_assignees", &query, vars.AsMap())
+			if err != nil {
+				queryLogger.Debug("got error on list issue assignees query", LogKeyError, err, "duration", duration)
+
+				MetricIssueAssigneeQueryErrorTotal.Inc()
+
+				return nil, err
+			}
+
+			queryLogger.Debug("got response on list issue assignees query", "response", query, "duration", duration)
+
+			for _, a := range query.Repository.Issue.Assignees.Nodes {
+				allAssignees = append(allAssignees, a.Login)
+			}
+
+			if !query.Repository.Issue.Assignees.PageInfo.HasNextPage {
+				return allAssignees, nil
+			}
+
+			vars.AssigneesCursor = &query.Repository.Issue.Assignees.PageInfo.EndCursor
+		}
+	}
+}
+
+// listIssueComments returns the plain text of the given issue's comments, oldest first, performing pagination as
+// needed.
+func (gh *gitHubinator) listIssueComments(
+	ctx context.Context, ghr GitHubRepository, issueNumber int,
+) ([]string, error) {
+	query := &gitHubIssueCommentsQuery{}
+
+	vars := gitHubIssueCommentsQueryVars{
+		Owner:          githubv4.String(ghr.Owner),
+		Name:           githubv4.String(ghr.Name),
+		IssueNumber:    githubv4.Int(issueNumber),
+		N:              gh.getPageSize(),
+		CommentsCursor: (*githubv4.String)(nil),
+	}
+
+	allComments := []string{}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+			queryLogger := LoggerFromContext(ctx, gh.logger).With("vars", vars)
+			queryLogger.Debug("executing list issue comments query")
+
+			MetricIssueCommentQueryTotal.Inc()
+
+			duration, err := gh.query(ctx, "issue_comments", &query, vars.AsMap())
+			if err != nil {
+				queryLogger.Debug("got error on list issue comments query", LogKeyError, err, "duration", duration)
+
+				MetricIssueCommentQueryErrorTotal.Inc()
+
+				return nil, err
+			}
+
+			queryLogger.Debug("got response on list issue comments query", "response", query, "duration", duration)
+
+			for _, c := range query.Repository.Issue.Comments.Nodes {
+				allComments = append(allComments, string(c.BodyText))
+			}
+
+			if !query.Repository.Issue.Comments.PageInfo.HasNextPage {
+				return allComments, nil
+			}
+
+			vars.CommentsCursor = &query.Repository.Issue.Comments.PageInfo.EndCursor
+		}
+	}
+}
+
+// getIssueBody returns the plain text and the raw Markdown of the given issue's body.
+func (gh *gitHubinator) getIssueBody(
+	ctx context.Context, ghr GitHubRepository, issueNumber int,
+) (string, string, error) {
+	query := &gitHubIssueBodyQuery{}
+
+	vars := gitHubIssueBodyQueryVars{
+		Owner:       githubv4.String(ghr.Owner),
+		Name:        githubv4.String(ghr.Name),
+		IssueNumber: githubv4.Int(issueNumber),
+	}
+
+	queryLogger := LoggerFromContext(ctx, gh.logger).With("vars", vars)
+	queryLogger.Debug("executing get issue body text query")
+
+	MetricIssueBodyQueryTotal.Inc()
+
+	duration, err := gh.query(ctx, "issue_body", &query, vars.AsMap())
+	if err != nil {
+		queryLogger.Debug("got error on get issue body text query", LogKeyError, err, "duration", duration)
+
+		MetricIssueBodyQueryErrorTotal.Inc()
+
+		return "", "", err
+	}
+
+	queryLogger.Debug("got response on get issue body text query", "response", query, "duration", duration)
+
+	return string(query.Repository.Issue.BodyText), string(query.Repository.Issue.Body), nil
+}
+
+// getIssueLastActivityBy returns the login of the actor of the given issue's most recent timeline activity.
+func (gh *gitHubinator) getIssueLastActivityBy(
+	ctx context.Context, ghr GitHubRepository, issueNumber int,
+) (string, error) {
+	query := &gitHubIssueLastActivityQuery{}
+
+	vars := gitHubIssueLastActivityQueryVars{
+		gitHubIssueBodyQueryVars: gitHubIssueBodyQueryVars{
+			Owner:       githubv4.String(ghr.Owner),
+			Name:        githubv4.String(ghr.Name),
+			IssueNumber: githubv4.Int(issueNumber),
+		},
+		ItemTypes: gitHubLastActivityItemTypes,
+	}
+
+	queryLogger := LoggerFromContext(ctx, gh.logger).With("vars", vars)
+	queryLogger.Debug("executing get issue last activity query")
+
+	MetricIssueTimelineQueryTotal.Inc()
+
+	duration, err := gh.query(ctx, "issue_last_activity", &query, vars.AsMap())
+	if err != nil {
+		queryLogger.Debug("got error on get issue last activity query", LogKeyError, err, "duration", duration)
+
+		MetricIssueTimelineQueryErrorTotal.Inc()
+
+		return "", err
+	}
+
+	queryLogger.Debug("got response on get issue last activity query", "response", query, "duration", duration)
+
+	return query.AsLastActivityBy(), nil
+}
+
+// getIssueCrossReferences returns the numbers of the issues and pull requests in the given repository which
+// referenced the given issue, according to its timeline, performing pagination as needed. Results are cached for
+// IssueCrossReferenceCacheTTL.
+func (gh *gitHubinator) getIssueCrossReferences(
+	ctx context.Context, ghr GitHubRepository, issueNumber int,
+) ([]int, error) {
+	if cached, ok := gh.crossReferenceCache.get(issueCacheKey(ghr, issueNumber)); ok {
+		return cached, nil
+	}
+
+	query := &gitHubIssueCrossReferencesQuery{}
+
+	vars := gitHubIssueCrossReferencesQueryVars{
+		Owner:          githubv4.String(ghr.Owner),
+		Name:           githubv4.String(ghr.Name),
+		IssueNumber:    githubv4.Int(issueNumber),
+		N:              gh.getPageSize(),
+		TimelineCursor: (*githubv4.String)(nil),
+	}
+
+	seen := map[int]bool{}
+	numbers := []int{}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+			queryLogger := LoggerFromContext(ctx, gh.logger).With("vars", vars)
+			queryLogger.Debug("executing get issue cross references query")
+
+			MetricIssueTimelineQueryTotal.Inc()
+
+			duration, err := gh.query(ctx, "issue_cross_references", &query, vars.AsMap())
+			if err != nil {
+				queryLogger.Debug(
+					"got error on get issue cross references query", LogKeyError, err, "duration", duration,
+				)
+
+				MetricIssueTimelineQueryErrorTotal.Inc()
+
+				return nil, err
+			}
+
+			queryLogger.Debug(
+				"got response on get issue cross references query", "response", query, "duration", duration,
+			)
+
+			for _, number := range query.AsReferencingIssues(ghr) {
+				if !seen[number] {
+					seen[number] = true
+					numbers = append(numbers, number)
+				}
+			}
+
+			if !query.Repository.Issue.TimelineItems.PageInfo.HasNextPage {
+				sort.Ints(numbers)
+				gh.crossReferenceCache.add(issueCacheKey(ghr, issueNumber), numbers)
+
+				return numbers, nil
+			}
+
+			vars.TimelineCursor = &query.Repository.Issue.TimelineItems.PageInfo.EndCursor
+		}
+	}
+}
+
+// listIssueProjectFields returns the custom field values set on the given issue in the projects it belongs to.
+func (gh *gitHubinator) listIssueProjectFields(
+	ctx context.Context, ghr GitHubRepository, issueNumber int,
+) ([]GitHubProjectFieldValue, error) {
+	query := &gitHubIssueProjectFieldsQuery{}
+
+	vars := gitHubIssueProjectFieldsQueryVars{
+		Owner:       githubv4.String(ghr.Owner),
+		Name:        githubv4.String(ghr.Name),
+		IssueNumber: githubv4.Int(issueNumber),
+		N:           50,
+	}
+
+	queryLogger := LoggerFromContext(ctx, gh.logger).With("vars", vars)
+	queryLogger.Debug("executing list issue project fields query")
+
+	MetricIssueProjectFieldQueryTotal.Inc()
+
+	duration, err := gh.query(ctx, "issue_project_fields", &query, vars.AsMap())
+	if err != nil {
+		queryLogger.Debug("got error on list issue project fields query", LogKeyError, err, "duration", duration)
+
+		MetricIssueProjectFieldQueryErrorTotal.Inc()
+
+		return nil, err
+	}
+
+	queryLogger.Debug(
+		"got response on list issue project fields query", "response", query, "duration", duration,
+	)
+
+	return query.AsGitHubProjectFieldValues(), nil
+}
+
+// getRepositoryMetadata returns the given repository with its language and topics populated. Results are cached
+// for RepositoryMetadataCacheTTL.
+func (gh *gitHubinator) getRepositoryMetadata(
+	ctx context.Context, ghr GitHubRepository,
+) (GitHubRepository, error) {
+	if cached, ok := gh.repoMetadataCache.get(ghr.fullName()); ok {
+		return cached, nil
+	}
+
+	query := gitHubRepositoryMetadataQuery{}
+
+	vars := gitHubRepositoryQueryVars{
+		Name:  githubv4.String(ghr.Name),
+		Owner: githubv4.String(ghr.Owner),
+	}
+
+	queryLogger := LoggerFromContext(ctx, gh.logger).With("vars", vars)
+	queryLogger.Debug("executing get repository metadata query")
+
+	MetricRepoQueryTotal.Inc()
+
+	duration, err := gh.query(ctx, "repository_metadata", &query, vars.AsMap())
+	if err != nil {
+		queryLogger.Debug("got error on get repository metadata query", LogKeyError, err, "duration", duration)
+
+		MetricRepoQueryErrorTotal.Inc()
+
+		return GitHubRepository{}, err
+	}
+
+	queryLogger.Debug("got response on get repository metadata query", "response", query, "duration", duration)
+
+	repo := query.AsGitHubRepository(ghr)
+	gh.repoMetadataCache.add(repo.fullName(), repo)
+
+	return repo, nil
+}
+
+// populateForMatcher fetches the fields of the given item which the given matcher needs, such as its labels. Labels
+// and bodies which were fetched along with the item, see GitHubItem.LabelsFetched, or which were cached since the
+// item was last updated, are not fetched again.
+func (gh *gitHubinator) populateForMatcher(
+	ctx context.Context, item *GitHubItem, matcher Matchinator, queryLogger *slog.Logger,
+) error {
+	gh.issueCache.restore(item)
+
+	if matcher.HasRepositoryMetadata() {
+		repo, err := gh.getRepositoryMetadata(ctx, item.Repo)
+		if err != nil {
+			return err
+		}
+
+		item.Repo = repo
+	}
+
+	if matcher.HasLabels() && !item.LabelsFetched {
+		labels, err := gh.listIssueLabels(ctx, item.Repo, item.Number)
+		if err != nil {
+			return err
+		}
+
+		item.GitHubIssue.Labels = labels
+		item.LabelsFetched = true
+
+		gh.issueCache.add(item)
+	}
+
+	if matcher.HasAssignees() && !item.AssigneesFetched {
+		assignees, err := gh.listIssueAssignees(ctx, item.Repo, item.Number)
+		if err != nil {
+			return err
+		}
+
+		item.GitHubIssue.Assignees = assignees
+		item.AssigneesFetched = true
+	}
+
+	// The queries for project fields, activity and comments only support issues, so they aren't fetched for pull
+	// requests returned by searches, see Watch.checkItemTypes.
+	isIssue := item.Type != GitHubItemPullRequest && item.Type != GitHubItemDiscussion
+
+	if matcher.HasProjectFields() && isIssue {
+		values, err := gh.listIssueProjectFields(ctx, item.Repo, item.Number)
+		if err != nil {
+			return err
+		}
+
+		item.GitHubIssue.ProjectFieldValues = values
+	}
+
+	if matcher.HasLastActivityBy() && isIssue {
+		lastActivityBy, err := gh.getIssueLastActivityBy(ctx, item.Repo, item.Number)
+		if err != nil {
+			return err
+		}
+
+		item.GitHubIssue.LastActivityBy = lastActivityBy
+	}
+
+	if (matcher.HasBodyRegex() || matcher.HasReferencesIssue()) && !item.BodyFetched {
+		queryLogger.Debug("getting issue body for body regex or reference matching")
+
+		if err := gh.populateIssueBody(ctx, item); err != nil {
+			return err
+		}
+	}
+
+	if matcher.HasCommentRegex() && isIssue {
+		queryLogger.Debug("getting issue comments for comment regex matching")
+
+		comments, err := gh.listIssueComments(ctx, item.Repo, item.Number)
+		if err != nil {
+			return err
+		}
+
+		item.GitHubIssue.Comments = comments
+	}
+
+	if matcher.HasReferencesIssue() {
+		item.GitHubIssue.ReferencedIssues = ParseIssueReferences(
+			item.BodyMarkdown, item.Repo, GitHubHostFor(gh.baseURL),
+		)
+	}
+
+	if number := matcher.ReferencesIssueTimeline(); number != 0 && !slices.Contains(item.ReferencedIssues, number) {
+		referencing, err := gh.getIssueCrossReferences(ctx, item.Repo, number)
+		if err != nil {
+			return err
+		}
+
+		if slices.Contains(referencing, item.Number) {
+			item.GitHubIssue.ReferencedIssues = append(item.GitHubIssue.ReferencedIssues, number)
+			sort.Ints(item.GitHubIssue.ReferencedIssues)
+		}
+	}
+
+	return nil
+}
+
+// populateAndMatch fetches the fields of the given item required by the given matcher and checks if the item
+// matches. If it does, the item's body is fetched.
+func (gh *gitHubinator) populateAndMatch(
+	ctx context.Context, item *GitHubItem, matcher Matchinator, queryLogger *slog.Logger,
+) (bool, error) {
+	if err := gh.populateForMatcher(ctx, item, matcher, queryLogger); err != nil {
+		return false, err
+	}
+
+	if matches, reason := matcher.Matches(item); !matches {
+		queryLogger.Debug("item filtered out by the matcher", "item", item, "reason", reason)
+		MetricFilteredTotal.Inc()
+
+		return false, nil
+	} else {
+		queryLogger.Debug("item matched", "item", item, "reason", reason)
+	}
+
+	if item.BodyFetched {
+		return true, nil
+	}
+
+	if err := gh.populateIssueBody(ctx, item); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// populateIssueBody fetches the body of the given issue.
+func (gh *gitHubinator) populateIssueBody(ctx context.Context, item *GitHubItem) error {
+	bodyText, bodyMarkdown, err := gh.getIssueBody(ctx, item.Repo, item.Number)
+	if err != nil {
+		return err
+	}
+
+	item.GitHubIssue.Body = bodyText
+	item.GitHubIssue.BodyMarkdown = bodyMarkdown
+	item.BodyFetched = true
+
+	gh.issueCache.add(item)
+
+	return nil
+}
+
+func (gh *gitHubinator) GetIssue(
+	ctx context.Context, ghr GitHubRepository, number int, matcher Matchinator,
+) (*GitHubItem, error) {
+	if gh.client == nil {
+		gh.setupClient()
+	}
+
+	query := &gitHubGetIssueQuery{}
+
+	// The query shares its variables with the issue body query.
+	vars := gitHubIssueBodyQueryVars{
+		Owner:       githubv4.String(ghr.Owner),
+		Name:        githubv4.String(ghr.Name),
+		IssueNumber: githubv4.Int(number),
+	}
+
+	queryLogger := LoggerFromContext(ctx, gh.logger).With("vars", vars)
+	queryLogger.Debug("executing get issue query")
+
+	MetricIssueQueryTotal.Inc()
+
+	duration, err := gh.query(ctx, "issue", &query, vars.AsMap())
+	if err != nil {
+		queryLogger.Debug("got error on get issue query", LogKeyError, err, "duration", duration)
+
+		MetricIssueQueryErrorTotal.Inc()
+
+		return nil, err
+	}
+
+	queryLogger.Debug("got response on get issue query", "response", query, "duration", duration)
+
+	item := query.AsGitHubItem(ghr)
+
+	if err := gh.populateForMatcher(ctx, item, matcher, queryLogger); err != nil {
+		return nil, err
+	}
+
+	if !item.LabelsFetched {
+		labels, err := gh.listIssueLabels(ctx, ghr, number)
+		if err != nil {
+			return nil, err
+		}
+
+		item.GitHubIssue.Labels = labels
+		item.LabelsFetched = true
+	}
+
+	if !item.BodyFetched {
+		if err := gh.populateIssueBody(ctx, item); err != nil {
+			return nil, err
+		}
+	}
+
+	return item, nil
+}
+
+func (gh *gitHubinator) ListIssues(
+	ctx context.Context, ghr GitHubRepository, filter *GitHubIssueFilter,
+	matcher Matchinator,
+) ([]*GitHubItem, error) {
+	if gh.client == nil {
+		gh.setupClient()
+	}
+
+	query := &gitHubIssueQuery{}
+
+	vars := &gitHubIssueQueryVars{
+		Owner:        githubv4.String(ghr.Owner),
+		Name:         githubv4.String(ghr.Name),
+		Filters:      filter.asGithubv4IssueFilters(),
+		IssuesCursor: (*githubv4.String)(nil),
+		N:            gh.getPageSize(),
+	}
+
+	allIssues := []*GitHubItem{}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+			queryLogger := LoggerFromContext(ctx, gh.logger).With("vars", vars)
+			queryLogger.Debug("executing list issues query")
+
+			MetricIssueQueryTotal.Inc()
+
+			duration, err := gh.query(ctx, "issues", &query, vars.AsMap())
+			if err != nil {
+				queryLogger.Debug("got error on list issues query", LogKeyError, err, "duration", duration)
+
+				MetricIssueQueryErrorTotal.Inc()
+
+				return nil, err
+			}
+
+			queryLogger.Debug("got response on list issues query", "query", query, "duration", duration)
 
-	rclient := retryablehttp.NewClient()
-	rclient.RetryMax = gh.retries
-	rclient.RetryWaitMax = gh.timeout
-	rclient.HTTPClient = oauthClient
+			truncatedLabels := query.TruncatedLabels()
+			truncatedAssignees := query.TruncatedAssignees()
 
-	gh.client = githubv4.NewClient(oauthClient)
-}
+			for id, issue := range query.AsGitHubIssues() {
+				item := &GitHubItem{
+					Type:             GitHubItemIssue,
+					Repo:             ghr,
+					ID:               id,
+					GitHubIssue:      *issue,
+					LabelsFetched:    !truncatedLabels[id],
+					BodyFetched:      true,
+					AssigneesFetched: !truncatedAssignees[id],
+				}
 
-func (gh *gitHubinator) WhoAmI(ctx context.Context) (string, error) {
-	if gh.client == nil {
-		gh.setupClient()
-	}
+				queryLogger.Debug("got item for list issues query", "issue", item)
 
-	query := gitHubViewerQuery{}
+				if !filter.inMilestone(item) {
+					continue
+				}
 
-	gh.logger.Debug("executing whoami query")
+				matches, err := gh.populateAndMatch(ctx, item, matcher, queryLogger)
+				if err != nil {
+					return nil, err
+				}
 
-	err := gh.client.Query(ctx, &query, nil)
-	if err != nil {
-		gh.logger.Debug("got error on whoami query", LogKeyError, err)
+				if matches {
+					allIssues = append(allIssues, item)
+				}
+			}
 
-		return "", err
-	}
+			if !query.Repository.Issues.PageInfo.HasNextPage {
+				SortGitHubItems(allIssues)
 
-	gh.logger.Debug("response on whoami query", "result", query)
+				return allIssues, nil
+			}
 
-	if !query.Viewer.IsViewer {
-		return "", fmt.Errorf("unexpected result, returned user is not viewer")
+			vars.IssuesCursor = &query.Repository.Issues.PageInfo.EndCursor
+		}
 	}
-
-	return string(query.Viewer.Login), nil
 }
 
-func (gh *gitHubinator) CheckRepository(ctx context.Context, ghr GitHubRepository) error {
+func (gh *gitHubinator) ListPullRequests(
+	ctx context.Context, ghr GitHubRepository, filter *GitHubIssueFilter, matcher Matchinator,
+) ([]*GitHubItem, error) {
 	if gh.client == nil {
 		gh.setupClient()
 	}
 
-	query := gitHubRepositoryQuery{}
+	query := &gitHubPullRequestQuery{}
+	vars := newGitHubPullRequestQueryVars(ghr, filter)
+	allPullRequests := []*GitHubItem{}
 
-	vars := gitHubRepositoryQueryVars{
-		Name:  githubv4.String(ghr.Name),
-		Owner: githubv4.String(ghr.Owner),
-	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+			queryLogger := LoggerFromContext(ctx, gh.logger).With("vars", vars)
+			queryLogger.Debug("executing list pull requests query")
 
-	queryLogger := gh.logger.With("vars", vars)
-	queryLogger.Debug("executing check repository query")
+			MetricPullRequestQueryTotal.Inc()
 
-	MetricRepoQueryTotal.Inc()
+			duration, err := gh.query(ctx, "pull_requests", &query, vars.AsMap())
+			if err != nil {
+				queryLogger.Debug("got error on list pull requests query", LogKeyError, err, "duration", duration)
 
-	err := gh.client.Query(ctx, &query, vars.AsMap())
-	if err != nil {
-		queryLogger.Debug("got error on check repository query", LogKeyError, err)
+				MetricPullRequestQueryErrorTotal.Inc()
 
-		MetricRepoQueryErrorTotal.Inc()
+				return nil, err
+			}
 
-		return err
-	}
+			queryLogger.Debug("got response on list pull requests query", "query", query, "duration", duration)
 
-	queryLogger.Debug("response on check repository query", "result", query)
+			for _, item := range query.AsGitHubItems(ghr) {
+				queryLogger.Debug("got item for list pull requests query", "pullRequest", item)
 
-	return nil
-}
+				if filter.isBeforeSince(item) || !filter.inMilestone(item) || !filter.isCreatedBy(item) {
+					continue
+				}
 
-// listIssueLabels returns a list of labels for the given issue, performing pagination as needed.
-func (gh *gitHubinator) listIssueLabels(
-	ctx context.Context, ghr GitHubRepository, issueNumber int,
-) ([]string, error) {
-	query := &gitHubLabelQuery{}
+				matches, err := gh.populateAndMatch(ctx, item, matcher, queryLogger)
+				if err != nil {
+					return nil, err
+				}
 
-	vars := gitHubLabelQueryVars{
-		Owner:        githubv4.String(ghr.Owner),
-		Name:         githubv4.String(ghr.Name),
-		IssueNumber:  githubv4.Int(issueNumber),
-		N:            100,
-		LabelsCursor: (*githubv4.String)(nil),
+				if matches {
+					allPullRequests = append(allPullRequests, item)
+				}
+			}
+
+			if !query.Repository.PullRequests.PageInfo.HasNextPage {
+				SortGitHubItems(allPullRequests)
+
+				return allPullRequests, nil
+			}
+
+			vars.PullRequestsCursor = &query.Repository.PullRequests.PageInfo.EndCursor
+		}
 	}
+}
 
-	allLabels := []string{}
+func (gh *gitHubinator) ListDiscussions(
+	ctx context.Context, ghr GitHubRepository, filter *GitHubIssueFilter, matcher Matchinator,
+) ([]*GitHubItem, error) {
+	if gh.client == nil {
+		gh.setupClient()
+	}
+
+	query := &gitHubDiscussionQuery{}
+	vars := newGitHubDiscussionQueryVars(ghr, filter)
+	allDiscussions := []*GitHubItem{}
 
 	for {
 		select {
 		case <-ctx.Done():
 			return nil, ctx.Err()
 		default:
-			queryLogger := gh.logger.With("vars", vars)
-			queryLogger.Debug("executing list issue labels query")
+			queryLogger := LoggerFromContext(ctx, gh.logger).With("vars", vars)
+			queryLogger.Debug("executing list discussions query")
 
-			MetricIssueLabelQueryTotal.Inc()
+			MetricDiscussionQueryTotal.Inc()
 
-			err := gh.client.Query(ctx, &query, vars.AsMap())
+			duration, err := gh.query(ctx, "discussions", &query, vars.AsMap())
 			if err != nil {
-				queryLogger.Debug("got error on list issue labels query", LogKeyError, err)
+				queryLogger.Debug("got error on list discussions query", LogKeyError, err, "duration", duration)
 
-				MetricIssueLabelQueryErrorTotal.Inc()
+				MetricDiscussionQueryErrorTotal.Inc()
 
 				return nil, err
 			}
 
-			queryLogger.Debug("got response on list labels query", "response", query)
-
-			for i := range query.Repository.Issue.Labels.Nodes {
-				l := query.Repository.Issue.Labels.Nodes[i]
-				allLabels = append(allLabels, l.Name)
-			}
+			queryLogger.Debug("got response on list discussions query", "query", query, "duration", duration)
 
-			if !query.Repository.Issue.Labels.PageInfo.HasNextPage {
-				return allLabels, nil
-			}
+			for _, item := range query.AsGitHubItems(ghr) {
+				queryLogger.Debug("got item for list discussions query", "discussion", item)
 
-			*vars.LabelsCursor = query.Repository.Issue.Labels.PageInfo.EndCursor
-		}
-	}
-}
+				if filter.isBeforeSince(item) || !filter.inMilestone(item) || !filter.isCreatedBy(item) {
+					continue
+				}
 
-func (gh *gitHubinator) getIssueBody(
-	ctx context.Context, ghr GitHubRepository, issueNumber int,
-) (string, error) {
-	query := &gitHubIssueBodyQuery{}
+				if !hasAnyLabel(item, filter.Labels) {
+					queryLogger.Debug("discussion has none of the search labels", "discussion", item)
 
-	vars := gitHubIssueBodyQueryVars{
-		Owner:       githubv4.String(ghr.Owner),
-		Name:        githubv4.String(ghr.Name),
-		IssueNumber: githubv4.Int(issueNumber),
-	}
+					continue
+				}
 
-	queryLogger := gh.logger.With("vars", vars)
-	queryLogger.Debug("executing get issue body text query")
+				matches, err := gh.populateAndMatch(ctx, item, matcher, queryLogger)
+				if err != nil {
+					return nil, err
+				}
 
-	MetricIssueBodyQueryTotal.Inc()
+				if matches {
+					allDiscussions = append(allDiscussions, item)
+				}
+			}
 
-	err := gh.client.Query(ctx, &query, vars.AsMap())
-	if err != nil {
-		queryLogger.Debug("got error on get issue body regex query", LogKeyError, err)
+			if !query.Repository.Discussions.PageInfo.HasNextPage {
+				SortGitHubItems(allDiscussions)
 
-		MetricIssueLabelQueryErrorTotal.Inc()
+				return allDiscussions, nil
+			}
 
-		return "", err
+			vars.DiscussionsCursor = &query.Repository.Discussions.PageInfo.EndCursor
+		}
 	}
-
-	queryLogger.Debug("got response on get issue body text query", "response", query)
-
-	return string(query.Repository.Issue.BodyText), nil
 }
 
-func (gh *gitHubinator) ListIssues(
-	ctx context.Context, ghr GitHubRepository, filter *GitHubIssueFilter,
-	matcher Matchinator,
+func (gh *gitHubinator) SearchIssues(
+	ctx context.Context, searchQuery string, matcher Matchinator,
 ) ([]*GitHubItem, error) {
 	if gh.client == nil {
 		gh.setupClient()
 	}
 
-	query := &gitHubIssueQuery{}
+	query := &gitHubSearchIssuesQuery{}
 
-	vars := &gitHubIssueQueryVars{
-		Owner:        githubv4.String(ghr.Owner),
-		Name:         githubv4.String(ghr.Name),
-		Filters:      filter.asGithubv4IssueFilters(),
-		IssuesCursor: (*githubv4.String)(nil),
+	vars := &gitHubSearchIssuesQueryVars{
+		Query:        githubv4.String(searchQuery),
 		N:            100,
+		SearchCursor: (*githubv4.String)(nil),
 	}
 
 	allIssues := []*GitHubItem{}
@@ -739,78 +4219,70 @@ func (gh *gitHubinator) ListIssues(
 		case <-ctx.Done():
 			return nil, ctx.Err()
 		default:
-			queryLogger := gh.logger.With("vars", vars)
-			queryLogger.Debug("executing list issues query")
+			queryLogger := LoggerFromContext(ctx, gh.logger).With("vars", vars)
+			queryLogger.Debug("executing search issues query")
 
 			MetricIssueQueryTotal.Inc()
 
-			err := gh.client.Query(ctx, &query, vars.AsMap())
+			duration, err := gh.query(ctx, "search_issues", &query, vars.AsMap())
 			if err != nil {
-				queryLogger.Debug("got error on list issues query", LogKeyError, err)
+				queryLogger.Debug("got error on search issues query", LogKeyError, err, "duration", duration)
 
 				MetricIssueQueryErrorTotal.Inc()
 
 				return nil, err
 			}
 
-			queryLogger.Debug("got response on list issues query", "query", query)
-
-			for id, issue := range query.AsGitHubIssues() {
-				item := &GitHubItem{
-					Type:        GitHubItemIssue,
-					Repo:        ghr,
-					ID:          id,
-					GitHubIssue: *issue,
-				}
+			queryLogger.Debug("got response on search issues query", "query", query, "duration", duration)
 
-				queryLogger.Debug("got item for list issues query", "issue", item)
+			for _, item := range query.AsGitHubItems() {
+				queryLogger.Debug("got item for search issues query", "issue", item)
 
-				if matcher.HasRequiredLabels() {
-					labels, err := gh.listIssueLabels(ctx, ghr, issue.Number)
-					if err != nil {
-						return nil, err
-					}
+				matches, err := gh.populateAndMatch(ctx, item, matcher, queryLogger)
+				if err != nil {
+					return nil, err
+				}
 
-					item.GitHubIssue.Labels = labels
+				if matches {
+					allIssues = append(allIssues, item)
 				}
+			}
 
-				if matcher.HasBodyRegex() {
-					queryLogger.Debug("getting issue body for body regex matching")
+			if !query.Search.PageInfo.HasNextPage {
+				return allIssues, nil
+			}
 
-					bodyText, err := gh.getIssueBody(ctx, ghr, issue.Number)
-					if err != nil {
-						return nil, err
-					}
+			vars.SearchCursor = &query.Search.PageInfo.EndCursor
+		}
+	}
+}
 
-					item.GitHubIssue.Body = bodyText
-				}
+func (gh *gitHubinator) CheckSearch(ctx context.Context, searchQuery string) error {
+	if gh.client == nil {
+		gh.setupClient()
+	}
 
-				if matches, reason := matcher.Matches(item); !matches {
-					queryLogger.Debug("item filtered out by the matcher", "item", item, "reason", reason)
-					MetricFilteredTotal.Inc()
+	query := &gitHubSearchIssuesQuery{}
 
-					continue
-				} else {
-					queryLogger.Debug("item matched", "item", item, "reason", reason)
-				}
+	vars := &gitHubSearchIssuesQueryVars{
+		Query:        githubv4.String(searchQuery),
+		N:            1,
+		SearchCursor: (*githubv4.String)(nil),
+	}
 
-				bodyText, err := gh.getIssueBody(ctx, ghr, issue.Number)
-				if err != nil {
-					return nil, err
-				}
+	queryLogger := LoggerFromContext(ctx, gh.logger).With("vars", vars)
+	queryLogger.Debug("executing check search query")
 
-				item.GitHubIssue.Body = bodyText
+	duration, err := gh.query(ctx, "search_issues", &query, vars.AsMap())
+	if err != nil {
+		queryLogger.Debug("got error on check search query", LogKeyError, err, "duration", duration)
 
-				allIssues = append(allIssues, item)
-			}
+		return fmt.Errorf("unable to execute search '%s': %w", searchQuery, err)
+	}
 
-			if !query.Repository.Issues.PageInfo.HasNextPage {
-				return allIssues, nil
-			}
+	queryLogger.Debug("got response on check search query", "response", query, "duration", duration)
 
-			vars.IssuesCursor = &query.Repository.Issues.PageInfo.EndCursor
-		}
-	}
+	return nil
 }
 
 func (gh *gitHubinator) SetSubscription(ctx context.Context, id githubv4.ID, state githubv4.SubscriptionState) error {
@@ -831,21 +4303,169 @@ func (gh *gitHubinator) SetSubscription(ctx context.Context, id githubv4.ID, sta
 		State:          state,
 	}
 
-	mutateLogger := gh.logger.With("input.state", state).With("input.id", id)
+	mutateLogger := LoggerFromContext(ctx, gh.logger).With("input.state", state).With("input.id", id)
 	mutateLogger.Debug("executing update subscription mutation")
 
 	MetricNewSubscriptionTotal.Inc()
 
-	err := gh.client.Mutate(ctx, &m, input, nil)
+	duration, err := gh.mutate(ctx, "update_subscription", &m, input, nil)
 	if err != nil {
-		mutateLogger.Debug("got error update subscription mutation", LogKeyError, err)
+		mutateLogger.Debug("got error update subscription mutation", LogKeyError, err, "duration", duration)
 
 		MetricNewSubscriptionErrorTotal.Inc()
 
 		return err
 	}
 
-	mutateLogger.Debug("got response on update subscription mutation", "response", m)
+	mutateLogger.Debug("got response on update subscription mutation", "response", m, "duration", duration)
+
+	return nil
+}
+
+func (gh *gitHubinator) GetLabelIDs(
+	ctx context.Context, ghr GitHubRepository, names []string,
+) ([]githubv4.ID, error) {
+	if gh.client == nil {
+		gh.setupClient()
+	}
+
+	ids := []githubv4.ID{}
+
+	for _, name := range names {
+		if cached, ok := gh.labelIDCache.get(labelIDCacheKey(ghr, name)); ok {
+			ids = append(ids, cached)
+
+			continue
+		}
+
+		query := gitHubLabelIDQuery{}
+
+		vars := gitHubLabelIDQueryVars{
+			Owner:     githubv4.String(ghr.Owner),
+			Name:      githubv4.String(ghr.Name),
+			LabelName: githubv4.String(name),
+		}
+
+		queryLogger := LoggerFromContext(ctx, gh.logger).With("vars", vars)
+		queryLogger.Debug("executing get label id query")
+
+		MetricRepoQueryTotal.Inc()
+
+		duration, err := gh.query(ctx, "label_id", &query, vars.AsMap())
+		if err != nil {
+			queryLogger.Debug("got error on get label id query", LogKeyError, err, "duration", duration)
+
+			MetricRepoQueryErrorTotal.Inc()
+
+			return nil, err
+		}
+
+		queryLogger.Debug("got response on get label id query", "response", query, "duration", duration)
+
+		if query.Repository.Label == nil {
+			return nil, fmt.Errorf("label '%s' does not exist in repository %s", name, ghr.fullName())
+		}
+
+		gh.labelIDCache.add(labelIDCacheKey(ghr, name), query.Repository.Label.ID)
+		ids = append(ids, query.Repository.Label.ID)
+	}
+
+	return ids, nil
+}
+
+func (gh *gitHubinator) SetLabels(ctx context.Context, id githubv4.ID, labelIDs []githubv4.ID) error {
+	if gh.client == nil {
+		gh.setupClient()
+	}
+
+	var m struct {
+		AddLabelsToLabelable struct {
+			ClientMutationID githubv4.String
+		} `graphql:"addLabelsToLabelable(input: $input)"`
+	}
+
+	input := githubv4.AddLabelsToLabelableInput{
+		LabelableID: id,
+		LabelIDs:    labelIDs,
+	}
+
+	mutateLogger := LoggerFromContext(ctx, gh.logger).With("input.labelIDs", labelIDs).With("input.id", id)
+	mutateLogger.Debug("executing add labels mutation")
+
+	MetricAddLabelsTotal.Inc()
+
+	duration, err := gh.mutate(ctx, "add_labels", &m, input, nil)
+	if err != nil {
+		mutateLogger.Debug("got error on add labels mutation", LogKeyError, err, "duration", duration)
+
+		MetricAddLabelsErrorTotal.Inc()
+
+		return err
+	}
+
+	mutateLogger.Debug("got response on add labels mutation", "response", m, "duration", duration)
+
+	return nil
+}
+
+func (gh *gitHubinator) HasComment(ctx context.Context, id githubv4.ID, marker string) (bool, error) {
+	if gh.client == nil {
+		gh.setupClient()
+	}
+
+	query := gitHubCommentQuery{}
+	vars := map[string]any{"id": id}
+
+	queryLogger := LoggerFromContext(ctx, gh.logger).With("id", id)
+	queryLogger.Debug("executing comment query")
+
+	MetricIssueQueryTotal.Inc()
+
+	duration, err := gh.query(ctx, "comment", &query, vars)
+	if err != nil {
+		queryLogger.Debug("got error on comment query", LogKeyError, err, "duration", duration)
+
+		MetricIssueQueryErrorTotal.Inc()
+
+		return false, err
+	}
+
+	queryLogger.Debug("got response on comment query", "response", query, "duration", duration)
+
+	return query.ViewerCommented(marker), nil
+}
+
+func (gh *gitHubinator) AddComment(ctx context.Context, id githubv4.ID, body string) error {
+	if gh.client == nil {
+		gh.setupClient()
+	}
+
+	var m struct {
+		AddComment struct {
+			ClientMutationID githubv4.String
+		} `graphql:"addComment(input: $input)"`
+	}
+
+	input := githubv4.AddCommentInput{
+		SubjectID: id,
+		Body:      githubv4.String(body),
+	}
+
+	mutateLogger := LoggerFromContext(ctx, gh.logger).With("input.id", id)
+	mutateLogger.Debug("executing add comment mutation")
+
+	MetricAddCommentTotal.Inc()
+
+	duration, err := gh.mutate(ctx, "add_comment", &m, input, nil)
+	if err != nil {
+		mutateLogger.Debug("got error on add comment mutation", LogKeyError, err, "duration", duration)
+
+		MetricAddCommentErrorTotal.Inc()
+
+		return err
+	}
+
+	mutateLogger.Debug("got response on add comment mutation", "response", m, "duration", duration)
 
 	return nil
 }
@@ -853,10 +4473,15 @@ func (gh *gitHubinator) SetSubscription(ctx context.Context, id githubv4.ID, sta
 // NewGitHubinator creates a new instance of a GitHubinator.
 func NewGitHubinator(logger *slog.Logger) GitHubinator {
 	return &gitHubinator{
-		retries: 0,
-		timeout: 0,
-		token:   oauth2.StaticTokenSource(&oauth2.Token{AccessToken: ""}),
-		client:  nil,
-		logger:  logger,
+		retries:             0,
+		timeout:             0,
+		token:               oauth2.StaticTokenSource(&oauth2.Token{AccessToken: ""}),
+		client:              nil,
+		logger:              logger,
+		repoCache:           newTTLCache[string, GitHubRepository](CheckRepositoryCacheTTL),
+		repoMetadataCache:   newTTLCache[string, GitHubRepository](RepositoryMetadataCacheTTL),
+		crossReferenceCache: newTTLCache[string, []int](IssueCrossReferenceCacheTTL),
+		issueCache:          newIssueCache(IssueCacheTTL),
+		labelIDCache:        newTTLCache[string, githubv4.ID](LabelIDCacheTTL),
 	}
 }
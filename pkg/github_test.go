@@ -0,0 +1,1570 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shurcooL/githubv4"
+	"golang.org/x/exp/slog"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// newTestGraphQLServer creates a new httptest.Server which responds to every GraphQL request with the given body.
+func newTestGraphQLServer(t *testing.T, body string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestGitHubIssueQueryUsesGhostForNullAuthor(t *testing.T) {
+	server := newTestGraphQLServer(t, `{"data": {"repository": {"issues": {
+		"nodes": [
+			{"author": null, "id": "deleted", "number": 1, "title": "a", "state": "OPEN",
+			 "updatedAt": "2023-01-01T00:00:00Z", "viewerSubscription": "UNSUBSCRIBED"},
+			{"author": {"login": "actor"}, "id": "existing", "number": 2, "title": "b", "state": "OPEN",
+			 "updatedAt": "2023-01-01T00:00:00Z", "viewerSubscription": "UNSUBSCRIBED"}
+		],
+		"pageInfo": {"endCursor": "", "hasNextPage": false}
+	}}}}`)
+	client := githubv4.NewEnterpriseClient(server.URL, server.Client())
+
+	q := &gitHubIssueQuery{}
+	vars := gitHubIssueQueryVars{
+		Owner:   "owner",
+		Name:    "repo",
+		N:       10,
+		Filters: githubv4.IssueFilters{},
+	}
+	assert.NilError(t, client.Query(context.Background(), q, vars.AsMap()))
+
+	issues := q.AsGitHubIssues()
+	assert.Equal(t, issues["deleted"].Author.Login, GitHubGhostLogin)
+	assert.Equal(t, issues["existing"].Author.Login, "actor")
+
+	item := &GitHubItem{GitHubIssue: *issues["deleted"]}
+	assert.Equal(t, GitHubItemAsLabelSet(item).Get("author.login"), GitHubGhostLogin)
+}
+
+func TestGitHubIssueQueryPopulatesMilestone(t *testing.T) {
+	server := newTestGraphQLServer(t, `{"data": {"repository": {"issues": {
+		"nodes": [
+			{"id": "none", "number": 1, "milestone": null},
+			{"id": "release", "number": 2, "milestone": {"title": "v1.2.0"}}
+		],
+		"pageInfo": {"endCursor": "", "hasNextPage": false}
+	}}}}`)
+	client := githubv4.NewEnterpriseClient(server.URL, server.Client())
+
+	q := &gitHubIssueQuery{}
+	vars := gitHubIssueQueryVars{Owner: "owner", Name: "repo", N: 10, Filters: githubv4.IssueFilters{}}
+	assert.NilError(t, client.Query(context.Background(), q, vars.AsMap()))
+
+	issues := q.AsGitHubIssues()
+	assert.Equal(t, issues["none"].Milestone, "")
+	assert.Equal(t, issues["release"].Milestone, "v1.2.0")
+
+	selector, err := labels.Parse("milestone==v1.2.0")
+	assert.NilError(t, err)
+
+	matcher := SelectorAsGitHubItemMatcher(selector)
+	assert.Equal(t, matcher.Matcher(&GitHubItem{GitHubIssue: *issues["release"]}), true)
+	assert.Equal(t, matcher.Matcher(&GitHubItem{GitHubIssue: *issues["none"]}), false)
+}
+
+func TestGitHubinatorListIssuesFiltersMilestoneTitles(t *testing.T) {
+	var requests []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NilError(t, err)
+
+		requests = append(requests, string(body))
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": {"repository": {"issues": {
+			"nodes": [
+				{"id": "none", "number": 1, "milestone": null,
+				 "labels": {"nodes": [], "pageInfo": {"hasNextPage": false}}},
+				{"id": "other", "number": 2, "milestone": {"title": "v1.1.0"},
+				 "labels": {"nodes": [], "pageInfo": {"hasNextPage": false}}},
+				{"id": "release", "number": 3, "milestone": {"title": "v1.2.0"},
+				 "labels": {"nodes": [], "pageInfo": {"hasNextPage": false}}}
+			],
+			"pageInfo": {"endCursor": "", "hasNextPage": false}
+		}}}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	gh := &gitHubinator{
+		client:    githubv4.NewEnterpriseClient(server.URL, server.Client()),
+		logger:    NewLogger(),
+		repoCache: newTTLCache[string, GitHubRepository](time.Minute),
+	}
+	repo := GitHubRepository{Owner: "owner", Name: "repo"}
+
+	items, err := gh.ListIssues(
+		context.Background(), repo, &GitHubIssueFilter{Milestone: "v1.2.0"}, NewMatchinator(),
+	)
+	assert.NilError(t, err)
+	assert.Equal(t, len(items), 1)
+	assert.Equal(t, items[0].ID, "release")
+
+	// The title is never sent to GitHub, which would read it as a milestone number.
+	assert.Equal(t, len(requests), 1)
+	assert.Assert(t, !strings.Contains(requests[0], "v1.2.0"), requests[0])
+
+	items, err = gh.ListIssues(context.Background(), repo, &GitHubIssueFilter{Milestone: "*"}, NewMatchinator())
+	assert.NilError(t, err)
+	assert.Equal(t, len(items), 2)
+	assert.Assert(t, strings.Contains(requests[1], `"milestone":"*"`), requests[1])
+}
+
+func TestGitHubinatorListIssuesFetchesAssigneesInline(t *testing.T) {
+	var requests []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NilError(t, err)
+
+		requests = append(requests, string(body))
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": {"repository": {"issues": {
+			"nodes": [
+				{"id": "assigned", "number": 1,
+				 "labels": {"nodes": [], "pageInfo": {"hasNextPage": false}},
+				 "assignees": {"nodes": [{"login": "actor"}], "pageInfo": {"hasNextPage": false}}},
+				{"id": "unassigned", "number": 2,
+				 "labels": {"nodes": [], "pageInfo": {"hasNextPage": false}},
+				 "assignees": {"nodes": [], "pageInfo": {"hasNextPage": false}}}
+			],
+			"pageInfo": {"endCursor": "", "hasNextPage": false}
+		}}}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	gh := &gitHubinator{
+		client:    githubv4.NewEnterpriseClient(server.URL, server.Client()),
+		logger:    NewLogger(),
+		repoCache: newTTLCache[string, GitHubRepository](time.Minute),
+	}
+	repo := GitHubRepository{Owner: "owner", Name: "repo"}
+
+	items, err := gh.ListIssues(
+		context.Background(), repo, &GitHubIssueFilter{}, NewMatchinator().WithAssignees("actor"),
+	)
+	assert.NilError(t, err)
+	assert.Equal(t, len(items), 1)
+	assert.Equal(t, items[0].ID, "assigned")
+	assert.Assert(t, items[0].AssigneesFetched)
+	assert.DeepEqual(t, items[0].Assignees, []string{"actor"})
+
+	// The assignees aren't queried separately.
+	assert.Equal(t, len(requests), 1)
+}
+
+func TestWatchWithMultipleStatesListsItemsInAnyState(t *testing.T) {
+	var requests []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NilError(t, err)
+
+		requests = append(requests, string(body))
+
+		query := struct {
+			Variables struct {
+				States []string `json:"states"`
+			} `json:"variables"`
+		}{}
+		assert.NilError(t, json.Unmarshal(body, &query))
+
+		// Like GitHub, return the pull requests in any of the given states.
+		nodes := []string{}
+		for i, state := range []string{"OPEN", "CLOSED", "MERGED"} {
+			if slices.Contains(query.Variables.States, state) {
+				nodes = append(nodes, fmt.Sprintf(`{"id": "%s", "number": %d, "state": "%s"}`, state, i+1, state))
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"data": {"repository": {"pullRequests": {
+			"nodes": [%s], "pageInfo": {"endCursor": "", "hasNextPage": false}
+		}}}}`, strings.Join(nodes, ","))
+	}))
+	t.Cleanup(server.Close)
+
+	ctx := context.Background()
+	w := NewTestWatch()
+	w.ItemTypes = []GitHubItemType{GitHubItemPullRequest}
+	w.Selectors, w.RequiredLabels, w.BodyRegex, w.TitleRegex = nil, nil, nil, nil
+	w.States = []string{"OPEN", "MERGED"}
+	assert.NilError(t, w.ValidateAndPopulate(ctx, NewMockGitHubinator()))
+
+	gh := &gitHubinator{
+		client:    githubv4.NewEnterpriseClient(server.URL, server.Client()),
+		logger:    NewLogger(),
+		repoCache: newTTLCache[string, GitHubRepository](time.Minute),
+	}
+
+	items, err := w.ListItems(ctx, gh, NewSystemClock())
+	assert.NilError(t, err)
+
+	// Both states are listed in a single query, and only items in one of them are returned.
+	assert.Equal(t, len(requests), 1)
+
+	states := []githubv4.IssueState{}
+	for _, i := range items {
+		states = append(states, i.State)
+	}
+
+	slices.Sort(states)
+	assert.DeepEqual(t, states, []githubv4.IssueState{"MERGED", "OPEN"})
+}
+
+func TestGitHubIssueQueryPopulatesClosedAt(t *testing.T) {
+	server := newTestGraphQLServer(t, `{"data": {"repository": {"issues": {
+		"nodes": [
+			{"id": "open", "number": 1, "createdAt": "2023-01-01T00:00:00Z", "closedAt": null},
+			{"id": "closed", "number": 2, "createdAt": "2023-01-01T00:00:00Z", "closedAt": "2023-02-01T00:00:00Z"}
+		],
+		"pageInfo": {"endCursor": "", "hasNextPage": false}
+	}}}}`)
+	client := githubv4.NewEnterpriseClient(server.URL, server.Client())
+
+	q := &gitHubIssueQuery{}
+	vars := gitHubIssueQueryVars{Owner: "owner", Name: "repo", N: 10, Filters: githubv4.IssueFilters{}}
+	assert.NilError(t, client.Query(context.Background(), q, vars.AsMap()))
+
+	issues := q.AsGitHubIssues()
+	assert.Assert(t, issues["open"].ClosedAt == nil)
+	assert.Equal(t, *issues["closed"].ClosedAt, time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC))
+
+	open := GitHubItemAsLabelSet(&GitHubItem{GitHubIssue: *issues["open"]})
+	assert.Equal(t, open.Get("createdAt"), "1672531200")
+	assert.Equal(t, open.Has("closedAt"), false)
+
+	closed := GitHubItemAsLabelSet(&GitHubItem{GitHubIssue: *issues["closed"]})
+	assert.Equal(t, closed.Get("closedAt"), "1675209600")
+
+	// Open items leave closedAt out when exported, rather than holding the zero time.
+	exported, err := json.Marshal(issues["open"])
+	assert.NilError(t, err)
+	assert.Assert(t, !strings.Contains(string(exported), "closedAt"), string(exported))
+
+	for s, expected := range map[string][2]bool{
+		"!closedAt":              {true, false},
+		"closedAt>1672531200":    {false, true},
+		"createdAt<1672531201":   {true, true},
+		"createdAt>1672531200":   {false, false},
+		"closedAt,createdAt>100": {false, true},
+	} {
+		selector, err := labels.Parse(s)
+		assert.NilError(t, err, s)
+
+		matcher := SelectorAsGitHubItemMatcher(selector)
+		assert.Equal(t, matcher.Matcher(&GitHubItem{GitHubIssue: *issues["open"]}), expected[0], s)
+		assert.Equal(t, matcher.Matcher(&GitHubItem{GitHubIssue: *issues["closed"]}), expected[1], s)
+	}
+}
+
+func TestGitHubSearchIssuesQuerySkipsNonIssues(t *testing.T) {
+	server := newTestGraphQLServer(t, `{"data": {"search": {
+		"nodes": [
+			{"author": {"login": "actor"}, "id": "issue", "number": 1, "title": "a", "state": "OPEN",
+			 "updatedAt": "2023-01-01T00:00:00Z", "viewerSubscription": "UNSUBSCRIBED",
+			 "repository": {"name": "repo", "owner": {"login": "owner"}}},
+			{}
+		],
+		"pageInfo": {"endCursor": "", "hasNextPage": false}
+	}}}`)
+	client := githubv4.NewEnterpriseClient(server.URL, server.Client())
+
+	q := &gitHubSearchIssuesQuery{}
+	vars := gitHubSearchIssuesQueryVars{
+		Query: "is:issue",
+		N:     10,
+	}
+	assert.NilError(t, client.Query(context.Background(), q, vars.AsMap()))
+
+	items := q.AsGitHubItems()
+	assert.Equal(t, len(items), 1)
+	assert.Equal(t, items[0].Number, 1)
+	assert.Equal(t, items[0].Type, GitHubItemIssue)
+	assert.DeepEqual(t, items[0].Repo, GitHubRepository{Owner: "owner", Name: "repo"})
+}
+
+func TestGitHubSearchIssuesQueryReturnsPullRequests(t *testing.T) {
+	server := newTestGraphQLServer(t, `{"data": {"search": {
+		"nodes": [
+			{"__typename": "Issue", "id": "issue", "number": 1, "title": "a", "state": "OPEN",
+			 "repository": {"name": "repo", "owner": {"login": "owner"}}},
+			{"__typename": "PullRequest", "id": "pr", "number": 2, "title": "b", "pullRequestState": "MERGED",
+			 "isDraft": false, "merged": true, "body": "**body**", "bodyText": "body",
+			 "repository": {"name": "repo", "owner": {"login": "owner"}},
+			 "labels": {"nodes": [{"name": "bug"}]}, "assignees": {"nodes": [{"login": "maintainer"}]}}
+		],
+		"pageInfo": {"endCursor": "", "hasNextPage": false}
+	}}}`)
+	client := githubv4.NewEnterpriseClient(server.URL, server.Client())
+
+	q := &gitHubSearchIssuesQuery{}
+	vars := gitHubSearchIssuesQueryVars{Query: "repo:owner/repo", N: 10}
+	assert.NilError(t, client.Query(context.Background(), q, vars.AsMap()))
+
+	items := q.AsGitHubItems()
+	assert.Equal(t, len(items), 2)
+	assert.Equal(t, items[0].Type, GitHubItemIssue)
+	assert.Assert(t, items[0].PullRequest == nil)
+
+	pr := items[1]
+	assert.Equal(t, pr.Type, GitHubItemPullRequest)
+	assert.Equal(t, pr.Number, 2)
+	assert.Equal(t, pr.State, githubv4.IssueState("MERGED"))
+	assert.Assert(t, pr.PullRequest != nil && pr.PullRequest.Merged)
+	assert.Equal(t, pr.Body, "body")
+	assert.DeepEqual(t, pr.Labels, []string{"bug"})
+	assert.DeepEqual(t, pr.Assignees, []string{"maintainer"})
+	assert.Assert(t, pr.LabelsFetched && pr.BodyFetched && pr.AssigneesFetched)
+	assert.DeepEqual(t, pr.Repo, GitHubRepository{Owner: "owner", Name: "repo"})
+}
+
+func TestGitHubinatorSearchIssuesOnlyQueriesIssueFieldsOfIssues(t *testing.T) {
+	issueRequests := []string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NilError(t, err)
+
+		w.Header().Set("Content-Type", "application/json")
+
+		switch {
+		case strings.Contains(string(body), "search("):
+			_, _ = w.Write([]byte(`{"data": {"search": {
+				"nodes": [
+					{"__typename": "Issue", "id": "issue", "number": 1, "title": "a", "state": "OPEN",
+					 "repository": {"name": "repo", "owner": {"login": "owner"}}},
+					{"__typename": "PullRequest", "id": "pr", "number": 2, "title": "b", "pullRequestState": "OPEN",
+					 "repository": {"name": "repo", "owner": {"login": "owner"}},
+					 "labels": {"nodes": []}, "assignees": {"nodes": []}}
+				],
+				"pageInfo": {"endCursor": "", "hasNextPage": false}
+			}}}`))
+		case strings.Contains(string(body), "comments("):
+			issueRequests = append(issueRequests, string(body))
+
+			_, _ = w.Write([]byte(`{"data": {"repository": {"issue": {"comments": {
+				"nodes": [{"bodyText": "reproduced on main"}],
+				"pageInfo": {"endCursor": "", "hasNextPage": false}
+			}}}}}`))
+		default:
+			issueRequests = append(issueRequests, string(body))
+
+			_, _ = w.Write([]byte(`{"data": {"repository": {"issue": {"body": "body", "bodyText": "body"}}}}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	gh := &gitHubinator{
+		client: githubv4.NewEnterpriseClient(server.URL, server.Client()),
+		logger: NewLogger(),
+	}
+	matcher := NewMatchinator().WithCommentRegexes(false, regexp.MustCompile("reproduced"))
+
+	items, err := gh.SearchIssues(context.Background(), "repo:owner/repo", matcher)
+	assert.NilError(t, err)
+	assert.Equal(t, len(items), 1)
+	assert.Equal(t, items[0].Number, 1)
+
+	// The pull request's comments can't be queried as an issue's, so only the issue's comments and body are.
+	assert.Equal(t, len(issueRequests), 2)
+
+	for _, r := range issueRequests {
+		assert.Assert(t, strings.Contains(r, `"issueNumber":1`), r)
+	}
+}
+
+func TestGitHubIssueLastActivityQueryReturnsActor(t *testing.T) {
+	for body, expected := range map[string]string{
+		`{"__typename": "IssueComment", "author": {"login": "maintainer"}}`: "maintainer",
+		`{"__typename": "LabeledEvent", "actor": {"login": "triager"}}`:     "triager",
+		`{"__typename": "ClosedEvent", "actor": null}`:                      GitHubGhostLogin,
+	} {
+		server := newTestGraphQLServer(
+			t, `{"data": {"repository": {"issue": {"timelineItems": {"nodes": [`+body+`]}}}}}`,
+		)
+		client := githubv4.NewEnterpriseClient(server.URL, server.Client())
+
+		q := &gitHubIssueLastActivityQuery{}
+		vars := gitHubIssueLastActivityQueryVars{
+			gitHubIssueBodyQueryVars: gitHubIssueBodyQueryVars{Owner: "owner", Name: "repo", IssueNumber: 1},
+			ItemTypes:                gitHubLastActivityItemTypes,
+		}
+		assert.NilError(t, client.Query(context.Background(), q, vars.AsMap()))
+		assert.Equal(t, q.AsLastActivityBy(), expected)
+	}
+}
+
+func TestGitHubIssueLastActivityQueryWithoutActivity(t *testing.T) {
+	var requests []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NilError(t, err)
+
+		requests = append(requests, string(body))
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": {"repository": {"issue": {"timelineItems": {"nodes": []}}}}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	gh := &gitHubinator{
+		client: githubv4.NewEnterpriseClient(server.URL, server.Client()),
+		logger: NewLogger(),
+	}
+
+	login, err := gh.getIssueLastActivityBy(context.Background(), GitHubRepository{Owner: "owner", Name: "repo"}, 1)
+	assert.NilError(t, err)
+	assert.Equal(t, login, "")
+
+	// Only the types of timeline items made by a person are queried.
+	assert.Equal(t, len(requests), 1)
+	assert.Assert(t, cmp.Contains(requests[0], `$itemTypes:[IssueTimelineItemsItemType!]!`), requests[0])
+	assert.Assert(t, cmp.Contains(requests[0], `"itemTypes":["ISSUE_COMMENT","LABELED_EVENT",`), requests[0])
+}
+
+func TestGitHubinatorCheckRepositoryIsCached(t *testing.T) {
+	numRequests := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		numRequests++
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": {"repository": {"name": "repo"}}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	clock := NewFakeClock(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	cache := newTTLCache[string, GitHubRepository](time.Minute)
+	cache.clock = clock
+
+	gh := &gitHubinator{
+		client:    githubv4.NewEnterpriseClient(server.URL, server.Client()),
+		logger:    NewLogger(),
+		repoCache: cache,
+	}
+	ctx := context.Background()
+	repo := GitHubRepository{Owner: "owner", Name: "repo"}
+
+	assert.NilError(t, gh.CheckRepository(ctx, repo))
+	assert.NilError(t, gh.CheckRepository(ctx, repo))
+	assert.Equal(t, numRequests, 1)
+
+	assert.NilError(t, gh.CheckRepository(ctx, GitHubRepository{Owner: "owner", Name: "other"}))
+	assert.Equal(t, numRequests, 2)
+
+	clock.Advance(2 * time.Minute)
+
+	assert.NilError(t, gh.CheckRepository(ctx, repo))
+	assert.Equal(t, numRequests, 3)
+
+	// Changing the token invalidates the cache.
+	assert.Assert(t, gh.WithToken("token").(*gitHubinator).repoCache != cache)
+	assert.Assert(t, gh.WithRetries(1).(*gitHubinator).repoCache == cache)
+}
+
+func TestParseGitHubItemRef(t *testing.T) {
+	repo, number, err := ParseGitHubItemRef("learnitall/watchinator#42")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, repo, GitHubRepository{Owner: "learnitall", Name: "watchinator"})
+	assert.Equal(t, number, 42)
+
+	for _, ref := range []string{
+		"learnitall/watchinator", "watchinator#42", "/watchinator#42", "a/b/c#1", "a/b#x", "a/b#0",
+	} {
+		_, _, err := ParseGitHubItemRef(ref)
+		assert.ErrorContains(t, err, "expected 'owner/repo#number'", ref)
+	}
+}
+
+func TestGitHubinatorGetIssue(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if strings.Contains(string(body), "bodyText") {
+			_, _ = w.Write([]byte(`{"data": {"repository": {"issue": {"body": "**body**", "bodyText": "body"}}}}`))
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"data": {"repository": {"issue": {
+			"author": null, "id": "an-id", "number": 42, "title": "a", "state": "OPEN",
+			"updatedAt": "2023-01-01T00:00:00Z", "viewerSubscription": "UNSUBSCRIBED",
+			"url": "https://github.com/owner/repo/issues/42", "authorAssociation": "FIRST_TIME_CONTRIBUTOR",
+			"labels": {"nodes": [{"name": "bug"}], "pageInfo": {"hasNextPage": false}}
+		}}}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	gh := &gitHubinator{
+		client:    githubv4.NewEnterpriseClient(server.URL, server.Client()),
+		logger:    NewLogger(),
+		repoCache: newTTLCache[string, GitHubRepository](time.Minute),
+	}
+	repo := GitHubRepository{Owner: "owner", Name: "repo"}
+
+	item, err := gh.GetIssue(context.Background(), repo, 42, NewMatchinator())
+	assert.NilError(t, err)
+	assert.Equal(t, item.ID, githubv4.ID("an-id"))
+	assert.DeepEqual(t, item.Repo, repo)
+	assert.Equal(t, item.Number, 42)
+	assert.Equal(t, item.Type, GitHubItemIssue)
+	assert.Equal(t, item.Author.Login, GitHubGhostLogin)
+	assert.Equal(t, item.URL, "https://github.com/owner/repo/issues/42")
+	assert.Equal(t, item.AuthorAssociation, githubv4.CommentAuthorAssociationFirstTimeContributor)
+	assert.Equal(t, item.Body, "body")
+	assert.Equal(t, item.BodyMarkdown, "**body**")
+
+	// Labels are fetched with the issue, even though the matcher doesn't need them.
+	assert.DeepEqual(t, item.Labels, []string{"bug"})
+	assert.Assert(t, item.LabelsFetched)
+}
+
+func TestGitHubinatorGetRepositoryMetadata(t *testing.T) {
+	numRequests := 0
+	body := `{"data": {"repository": {
+		"primaryLanguage": {"name": "Go"},
+		"repositoryTopics": {"nodes": [{"topic": {"name": "kubernetes"}}, {"topic": {"name": "cli"}}]}
+	}}}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		numRequests++
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	gh := &gitHubinator{
+		client:            githubv4.NewEnterpriseClient(server.URL, server.Client()),
+		logger:            NewLogger(),
+		repoMetadataCache: newTTLCache[string, GitHubRepository](time.Minute),
+	}
+	ctx := context.Background()
+
+	repo, err := gh.getRepositoryMetadata(ctx, GitHubRepository{Owner: "owner", Name: "repo"})
+	assert.NilError(t, err)
+	assert.DeepEqual(
+		t, repo,
+		GitHubRepository{Owner: "owner", Name: "repo", Language: "Go", Topics: []string{"kubernetes", "cli"}},
+	)
+
+	// The second request is served from the cache.
+	_, err = gh.getRepositoryMetadata(ctx, GitHubRepository{Owner: "owner", Name: "repo"})
+	assert.NilError(t, err)
+	assert.Equal(t, numRequests, 1)
+
+	// Repositories without a detected language have an empty language.
+	body = `{"data": {"repository": {"primaryLanguage": null, "repositoryTopics": {"nodes": []}}}}`
+
+	repo, err = gh.getRepositoryMetadata(ctx, GitHubRepository{Owner: "owner", Name: "empty"})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, repo, GitHubRepository{Owner: "owner", Name: "empty", Language: "", Topics: []string{}})
+	assert.Equal(t, numRequests, 2)
+}
+
+func TestParseIssueReferences(t *testing.T) {
+	repo := GitHubRepository{Owner: "owner", Name: "repo"}
+
+	for md, expected := range map[string][]int{
+		"part of #100":                                  {100},
+		"#3, #1 and #3 again":                           {1, 3},
+		"see owner/repo#7 and Owner/Repo#8":             {7, 8},
+		"see other/repo#7 and owner/other#8":            {},
+		"https://github.com/owner/repo/issues/9":        {9},
+		"https://github.com/owner/repo/pull/10#partial": {10},
+		"https://github.com/other/repo/issues/11":       {},
+		"## 12 and abc#13 and ##14 and #0":              {},
+	} {
+		assert.DeepEqual(t, ParseIssueReferences(md, repo, "github.com"), expected)
+	}
+}
+
+func TestParseIssueReferencesOnGitHubEnterprise(t *testing.T) {
+	repo := GitHubRepository{Owner: "owner", Name: "repo"}
+	host := GitHubHostFor("https://github.example.com/api/graphql")
+	assert.Equal(t, host, "github.example.com")
+	assert.Equal(t, GitHubHostFor(""), "github.com")
+
+	for md, expected := range map[string][]int{
+		"https://github.example.com/owner/repo/issues/9": {9},
+		"https://github.example.com/owner/repo/pull/10":  {10},
+		"https://github.com/owner/repo/issues/11":        {},
+		"https://githubXexample.com/owner/repo/issues/1": {},
+		"#12": {12},
+	} {
+		assert.DeepEqual(t, ParseIssueReferences(md, repo, host), expected)
+	}
+}
+
+func TestGitHubIssueCrossReferencesQueryReturnsReferencingIssues(t *testing.T) {
+	server := newTestGraphQLServer(t, `{"data": {"repository": {"issue": {"timelineItems": {"nodes": [
+		{"source": {"__typename": "Issue", "number": 3, "repository": {"name": "repo", "owner": {"login": "owner"}}}},
+		{"source": {"__typename": "PullRequest", "number": 2,
+			"repository": {"name": "repo", "owner": {"login": "owner"}}}},
+		{"source": {"__typename": "Issue", "number": 4, "repository": {"name": "other", "owner": {"login": "owner"}}}},
+		{"source": {"__typename": "Issue", "number": 3, "repository": {"name": "repo", "owner": {"login": "owner"}}}}
+	]}}}}}`)
+	client := githubv4.NewEnterpriseClient(server.URL, server.Client())
+
+	q := &gitHubIssueCrossReferencesQuery{}
+	vars := gitHubIssueCrossReferencesQueryVars{Owner: "owner", Name: "repo", IssueNumber: 100, N: 10}
+	assert.NilError(t, client.Query(context.Background(), q, vars.AsMap()))
+	assert.DeepEqual(t, q.AsReferencingIssues(GitHubRepository{Owner: "owner", Name: "repo"}), []int{2, 3})
+}
+
+func TestGitHubinatorPaginatesIssueCrossReferences(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NilError(t, err)
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if !strings.Contains(string(body), `"timelineCursor":"page-2"`) {
+			_, _ = w.Write([]byte(`{"data": {"repository": {"issue": {"timelineItems": {"nodes": [
+				{"source": {"__typename": "Issue", "number": 3,
+					"repository": {"name": "repo", "owner": {"login": "owner"}}}}
+			], "pageInfo": {"endCursor": "page-2", "hasNextPage": true}}}}}}`))
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"data": {"repository": {"issue": {"timelineItems": {"nodes": [
+			{"source": {"__typename": "Issue", "number": 2,
+				"repository": {"name": "repo", "owner": {"login": "owner"}}}},
+			{"source": {"__typename": "Issue", "number": 3,
+				"repository": {"name": "repo", "owner": {"login": "owner"}}}}
+		], "pageInfo": {"endCursor": "", "hasNextPage": false}}}}}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	gh := &gitHubinator{
+		client:              githubv4.NewEnterpriseClient(server.URL, server.Client()),
+		logger:              NewLogger(),
+		crossReferenceCache: newTTLCache[string, []int](time.Minute),
+	}
+	queriesBefore := CounterValue(MetricIssueTimelineQueryTotal)
+
+	repo := GitHubRepository{Owner: "owner", Name: "repo"}
+
+	numbers, err := gh.getIssueCrossReferences(context.Background(), repo, 100)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, numbers, []int{2, 3})
+	assert.Equal(t, CounterValue(MetricIssueTimelineQueryTotal)-queriesBefore, float64(2))
+}
+
+func TestGitHubinatorPopulatesReferencedIssues(t *testing.T) {
+	numTimelineRequests := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if strings.Contains(string(body), "CROSS_REFERENCED_EVENT") {
+			numTimelineRequests++
+
+			_, _ = w.Write([]byte(`{"data": {"repository": {"issue": {"timelineItems": {"nodes": [
+				{"source": {"__typename": "Issue", "number": 2,
+					"repository": {"name": "repo", "owner": {"login": "owner"}}}}
+			]}}}}}`))
+
+			return
+		}
+
+		// Issue 1 mentions the tracking issue in its body, the others don't.
+		if strings.Contains(string(body), `"issueNumber":1`) {
+			_, _ = w.Write([]byte(`{"data": {"repository": {"issue": {"body": "part of #100", "bodyText": ""}}}}`))
+		} else {
+			_, _ = w.Write([]byte(`{"data": {"repository": {"issue": {"body": "see #5", "bodyText": ""}}}}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	gh := &gitHubinator{
+		client:              githubv4.NewEnterpriseClient(server.URL, server.Client()),
+		logger:              NewLogger(),
+		crossReferenceCache: newTTLCache[string, []int](time.Minute),
+	}
+	ctx := context.Background()
+
+	populate := func(matcher Matchinator, number int) *GitHubItem {
+		item := NewTestGitHubItem()
+		item.Number = number
+		assert.NilError(t, gh.populateForMatcher(ctx, item, matcher, NewLogger()))
+
+		return item
+	}
+
+	// Without the timeline, only references in the body are found.
+	matcher := NewMatchinator().WithReferencesIssue(100, false)
+	assert.DeepEqual(t, populate(matcher, 1).ReferencedIssues, []int{100})
+	assert.DeepEqual(t, populate(matcher, 2).ReferencedIssues, []int{5})
+	assert.Equal(t, numTimelineRequests, 0)
+
+	// With the timeline, issue 2 is found through its cross-reference, which is cached between items.
+	matcher = NewMatchinator().WithReferencesIssue(100, true)
+	assert.DeepEqual(t, populate(matcher, 1).ReferencedIssues, []int{100})
+	assert.Equal(t, numTimelineRequests, 0)
+
+	item := populate(matcher, 2)
+	assert.DeepEqual(t, item.ReferencedIssues, []int{5, 100})
+
+	matches, reason := matcher.Matches(item)
+	assert.Assert(t, matches, reason)
+
+	item = populate(matcher, 3)
+	assert.DeepEqual(t, item.ReferencedIssues, []int{5})
+
+	matches, _ = matcher.Matches(item)
+	assert.Assert(t, !matches)
+	assert.Equal(t, numTimelineRequests, 1)
+}
+
+func TestGitHubinatorListPullRequests(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": {"repository": {"pullRequests": {
+			"nodes": [
+				{"author": {"login": "actor"}, "id": "merged", "number": 2, "title": "b", "state": "MERGED",
+				 "updatedAt": "2023-01-01T00:00:00Z", "viewerSubscription": "UNSUBSCRIBED", "isDraft": false,
+				 "merged": true, "body": "fixes **#1**", "bodyText": "fixes #1",
+				 "labels": {"nodes": [{"name": "bug"}]}},
+				{"author": null, "id": "draft", "number": 1, "title": "a", "state": "OPEN",
+				 "updatedAt": "2023-01-01T00:00:00Z", "viewerSubscription": "UNSUBSCRIBED", "isDraft": true,
+				 "merged": false, "body": "", "bodyText": "", "labels": {"nodes": [{"name": "bug"}]}},
+				{"author": null, "id": "unlabeled", "number": 3, "title": "c", "state": "OPEN",
+				 "updatedAt": "2023-01-01T00:00:00Z", "viewerSubscription": "UNSUBSCRIBED", "isDraft": false,
+				 "merged": false, "body": "", "bodyText": "", "labels": {"nodes": []}}
+			],
+			"pageInfo": {"endCursor": "", "hasNextPage": false}
+		}}}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	gh := &gitHubinator{
+		client:    githubv4.NewEnterpriseClient(server.URL, server.Client()),
+		logger:    NewLogger(),
+		repoCache: newTTLCache[string, GitHubRepository](time.Minute),
+	}
+	repo := GitHubRepository{Owner: "owner", Name: "repo"}
+	filter := &GitHubIssueFilter{States: []string{"OPEN", "MERGED"}}
+
+	items, err := gh.ListPullRequests(
+		context.Background(), repo, filter, NewMatchinator().WithRequiredLabels("bug"),
+	)
+	assert.NilError(t, err)
+
+	// Labels and bodies are fetched along with the pull requests, so no other queries are needed.
+	assert.Equal(t, requests, 1)
+	assert.Equal(t, len(items), 2)
+
+	assert.Equal(t, items[0].Number, 1)
+	assert.Equal(t, items[0].Type, GitHubItemPullRequest)
+	assert.Equal(t, items[0].IsDraft(), true)
+	assert.Equal(t, items[0].Author.Login, GitHubGhostLogin)
+
+	assert.Equal(t, items[1].Number, 2)
+	assert.Equal(t, items[1].State, githubv4.IssueState("MERGED"))
+	assert.Equal(t, items[1].Body, "fixes #1")
+	assert.Equal(t, items[1].BodyMarkdown, "fixes **#1**")
+
+	set := GitHubItemAsLabelSet(items[1])
+	assert.Equal(t, set.Get("merged"), "true")
+	assert.Equal(t, set.Get("draft"), "false")
+	assert.Equal(t, set.Get("type"), string(GitHubItemPullRequest))
+}
+
+func TestGitHubinatorListDiscussions(t *testing.T) {
+	server := newTestGraphQLServer(t, `{"data": {"repository": {"discussions": {
+		"nodes": [
+			{"author": {"login": "actor"}, "id": "question", "number": 7, "title": "how?", "closed": true,
+			 "category": {"name": "Q&A"}, "isAnswered": true, "updatedAt": "2023-01-01T00:00:00Z",
+			 "viewerSubscription": "UNSUBSCRIBED", "body": "**how?**", "bodyText": "how?",
+			 "labels": {"nodes": [{"name": "help"}]}},
+			{"author": {"login": "actor"}, "id": "idea", "number": 8, "title": "idea", "closed": false,
+			 "category": {"name": "Ideas"}, "isAnswered": false, "updatedAt": "2023-01-01T00:00:00Z",
+			 "viewerSubscription": "UNSUBSCRIBED", "body": "", "bodyText": "", "labels": {"nodes": []}}
+		],
+		"pageInfo": {"endCursor": "", "hasNextPage": false}
+	}}}}`)
+
+	gh := &gitHubinator{
+		client:    githubv4.NewEnterpriseClient(server.URL, server.Client()),
+		logger:    NewLogger(),
+		repoCache: newTTLCache[string, GitHubRepository](time.Minute),
+	}
+	repo := GitHubRepository{Owner: "owner", Name: "repo"}
+
+	// Search labels are applied after listing, as GitHub can't filter discussions by label.
+	items, err := gh.ListDiscussions(
+		context.Background(), repo, &GitHubIssueFilter{Labels: []string{"help"}}, NewMatchinator(),
+	)
+	assert.NilError(t, err)
+	assert.Equal(t, len(items), 1)
+
+	item := items[0]
+	assert.Equal(t, item.Type, GitHubItemDiscussion)
+	assert.Equal(t, item.State, githubv4.IssueStateClosed)
+	assert.Equal(t, item.Body, "how?")
+	assert.Equal(t, item.Discussion.IsAnswered, true)
+	assert.Equal(t, gitHubItemURL(*item), "https://github.com/owner/repo/discussions/7")
+	assert.Equal(t, GitHubItemAsLabelSet(item).Get("category"), "q-a")
+
+	items, err = gh.ListDiscussions(context.Background(), repo, &GitHubIssueFilter{}, NewMatchinator())
+	assert.NilError(t, err)
+	assert.Equal(t, len(items), 2)
+}
+
+func TestGitHubGraphQLURL(t *testing.T) {
+	assert.Equal(t, GitHubGraphQLURL("https://github.example.com"), "https://github.example.com/api/graphql")
+	assert.Equal(t, GitHubGraphQLURL("https://github.example.com/"), "https://github.example.com/api/graphql")
+	assert.Equal(
+		t, GitHubGraphQLURL("https://github.example.com/api/graphql"), "https://github.example.com/api/graphql",
+	)
+}
+
+func TestGitHubinatorWithBaseURLUsesEnterpriseEndpoint(t *testing.T) {
+	paths := []string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": {"viewer": {"login": "user", "isViewer": true}}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	gh := NewGitHubinator(NewLogger()).WithBaseURL(server.URL).WithRetries(1).WithToken("1234")
+
+	user, err := gh.WhoAmI(context.Background())
+	assert.NilError(t, err)
+	assert.Equal(t, user, "user")
+	assert.DeepEqual(t, paths, []string{"/api/graphql"})
+}
+
+func TestGitHubinatorGetLabelIDsCachesLabels(t *testing.T) {
+	labelQueries := []string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := struct {
+			Variables map[string]any `json:"variables"`
+		}{}
+		assert.NilError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		name, _ := req.Variables["labelName"].(string)
+		labelQueries = append(labelQueries, name)
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if name == "missing" {
+			_, _ = w.Write([]byte(`{"data": {"repository": {"label": null}}}`))
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"data": {"repository": {"label": {"id": "id-` + name + `"}}}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	ctx := context.Background()
+	gh := NewGitHubinator(NewLogger()).WithBaseURL(server.URL).WithRetries(1).WithToken("1234")
+	repo := GitHubRepository{Owner: "owner", Name: "repo"}
+
+	ids, err := gh.GetLabelIDs(ctx, repo, []string{"bug", "triage"})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, ids, []githubv4.ID{"id-bug", "id-triage"})
+
+	ids, err = gh.GetLabelIDs(ctx, repo, []string{"triage"})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, ids, []githubv4.ID{"id-triage"})
+	assert.DeepEqual(t, labelQueries, []string{"bug", "triage"})
+
+	_, err = gh.GetLabelIDs(ctx, repo, []string{"missing"})
+	assert.ErrorContains(t, err, "label 'missing' does not exist in repository owner/repo")
+}
+
+func TestGitHubCommentQueryOnlyMatchesViewerComments(t *testing.T) {
+	server := newTestGraphQLServer(t, `{"data": {"node": {"comments": {"nodes": [
+		{"body": "someone quoting <!-- marker -->", "viewerDidAuthor": false},
+		{"body": "hello", "viewerDidAuthor": true}
+	]}}}}`)
+	client := githubv4.NewEnterpriseClient(server.URL, server.Client())
+
+	q := &gitHubCommentQuery{}
+	assert.NilError(t, client.Query(context.Background(), q, map[string]any{"id": githubv4.ID("id")}))
+	assert.Assert(t, !q.ViewerCommented("<!-- marker -->"))
+	assert.Assert(t, q.ViewerCommented("hello"))
+}
+
+func TestGitHubinatorListIssuesFetchesLabelsAndBodyInline(t *testing.T) {
+	labelQueries := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NilError(t, err)
+
+		w.Header().Set("Content-Type", "application/json")
+
+		// Only the issue with more labels than were fetched inline falls back to the labels query.
+		if strings.Contains(string(body), "labelsCursor") {
+			labelQueries++
+
+			_, _ = w.Write([]byte(`{"data": {"repository": {"issue": {"labels": {
+				"nodes": [{"name": "help"}, {"name": "bug"}],
+				"pageInfo": {"endCursor": "", "hasNextPage": false}
+			}}}}}`))
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"data": {"repository": {"issues": {
+			"nodes": [
+				{"author": null, "id": "inline", "number": 1, "title": "a", "state": "OPEN",
+				 "updatedAt": "2023-01-01T00:00:00Z", "viewerSubscription": "UNSUBSCRIBED",
+				 "body": "**body**", "bodyText": "body",
+				 "labels": {"nodes": [{"name": "bug"}], "pageInfo": {"hasNextPage": false}}},
+				{"author": null, "id": "truncated", "number": 2, "title": "b", "state": "OPEN",
+				 "updatedAt": "2023-01-01T00:00:00Z", "viewerSubscription": "UNSUBSCRIBED",
+				 "body": "", "bodyText": "",
+				 "labels": {"nodes": [{"name": "help"}], "pageInfo": {"hasNextPage": true}}}
+			],
+			"pageInfo": {"endCursor": "", "hasNextPage": false}
+		}}}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	gh := &gitHubinator{
+		client:    githubv4.NewEnterpriseClient(server.URL, server.Client()),
+		logger:    NewLogger(),
+		repoCache: newTTLCache[string, GitHubRepository](time.Minute),
+	}
+	repo := GitHubRepository{Owner: "owner", Name: "repo"}
+	bodyQueriesBefore := CounterValue(MetricIssueBodyQueryTotal)
+	labelQueriesBefore := CounterValue(MetricIssueLabelQueryTotal)
+
+	items, err := gh.ListIssues(
+		context.Background(), repo, &GitHubIssueFilter{},
+		NewMatchinator().WithRequiredLabels("bug").WithBodyRegexes(false, regexp.MustCompile(".*")),
+	)
+	assert.NilError(t, err)
+	assert.Equal(t, len(items), 2)
+
+	assert.Equal(t, items[0].Body, "body")
+	assert.Equal(t, items[0].BodyMarkdown, "**body**")
+	assert.DeepEqual(t, items[0].Labels, []string{"bug"})
+	assert.DeepEqual(t, items[1].Labels, []string{"help", "bug"})
+
+	assert.Equal(t, labelQueries, 1)
+	assert.Equal(t, CounterValue(MetricIssueLabelQueryTotal)-labelQueriesBefore, float64(1))
+	assert.Equal(t, CounterValue(MetricIssueBodyQueryTotal)-bodyQueriesBefore, float64(0))
+}
+
+func TestGitHubinatorWithPageSize(t *testing.T) {
+	gh := NewGitHubinator(NewLogger()).(*gitHubinator)
+	assert.Equal(t, gh.getPageSize(), githubv4.Int(GitHubMaxPageSize))
+	assert.Equal(t, gh.WithPageSize(10).(*gitHubinator).getPageSize(), githubv4.Int(10))
+	assert.Equal(t, gh.WithPageSize(500).(*gitHubinator).getPageSize(), githubv4.Int(GitHubMaxPageSize))
+
+	// The page size is kept by the other builders.
+	assert.Equal(t, gh.WithPageSize(10).WithToken("1234").(*gitHubinator).getPageSize(), githubv4.Int(10))
+}
+
+func TestGitHubinatorListIssueLabelsPaginatesWithPageSize(t *testing.T) {
+	requests := []string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NilError(t, err)
+
+		requests = append(requests, string(body))
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if len(requests) == 1 {
+			_, _ = w.Write([]byte(`{"data": {"repository": {"issue": {"labels": {
+				"nodes": [{"name": "bug"}],
+				"pageInfo": {"endCursor": "cursor", "hasNextPage": true}
+			}}}}}`))
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"data": {"repository": {"issue": {"labels": {
+			"nodes": [{"name": "help"}],
+			"pageInfo": {"endCursor": "", "hasNextPage": false}
+		}}}}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	gh := &gitHubinator{
+		client:   githubv4.NewEnterpriseClient(server.URL, server.Client()),
+		logger:   NewLogger(),
+		pageSize: 1,
+	}
+
+	labels, err := gh.listIssueLabels(context.Background(), GitHubRepository{Owner: "owner", Name: "repo"}, 1)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, labels, []string{"bug", "help"})
+
+	assert.Equal(t, len(requests), 2)
+	assert.Assert(t, strings.Contains(requests[0], `"n":1`), requests[0])
+	assert.Assert(t, strings.Contains(requests[1], `"labelsCursor":"cursor"`), requests[1])
+}
+
+func TestGitHubinatorPopulatesPaginatedAssignees(t *testing.T) {
+	requests := []string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NilError(t, err)
+
+		requests = append(requests, string(body))
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if len(requests) == 1 {
+			_, _ = w.Write([]byte(`{"data": {"repository": {"issue": {"assignees": {
+				"nodes": [{"login": "alice"}],
+				"pageInfo": {"endCursor": "cursor", "hasNextPage": true}
+			}}}}}`))
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"data": {"repository": {"issue": {"assignees": {
+			"nodes": [{"login": "bob"}],
+			"pageInfo": {"endCursor": "", "hasNextPage": false}
+		}}}}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	gh := &gitHubinator{
+		client:   githubv4.NewEnterpriseClient(server.URL, server.Client()),
+		logger:   NewLogger(),
+		pageSize: 1,
+	}
+
+	item := NewTestGitHubItem()
+	matcher := NewMatchinator().WithAssignees("bob")
+
+	assert.NilError(t, gh.populateForMatcher(context.Background(), item, matcher, gh.logger))
+	assert.DeepEqual(t, item.Assignees, []string{"alice", "bob"})
+	assert.Equal(t, item.AssigneesFetched, true)
+
+	matches, _ := matcher.Matches(item)
+	assert.Equal(t, matches, true)
+
+	assert.Equal(t, len(requests), 2)
+	assert.Assert(t, strings.Contains(requests[1], `"assigneesCursor":"cursor"`), requests[1])
+
+	// Assignees which were already fetched aren't fetched again.
+	assert.NilError(t, gh.populateForMatcher(context.Background(), item, matcher, gh.logger))
+	assert.Equal(t, len(requests), 2)
+}
+
+func TestGitHubinatorPopulatesPaginatedComments(t *testing.T) {
+	requests := []string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NilError(t, err)
+
+		requests = append(requests, string(body))
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if len(requests) == 1 {
+			_, _ = w.Write([]byte(`{"data": {"repository": {"issue": {"comments": {
+				"nodes": [{"bodyText": "first comment"}],
+				"pageInfo": {"endCursor": "cursor", "hasNextPage": true}
+			}}}}}`))
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"data": {"repository": {"issue": {"comments": {
+			"nodes": [{"bodyText": "Please backport this"}],
+			"pageInfo": {"endCursor": "", "hasNextPage": false}
+		}}}}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	gh := &gitHubinator{
+		client:   githubv4.NewEnterpriseClient(server.URL, server.Client()),
+		logger:   NewLogger(),
+		pageSize: 1,
+	}
+
+	item := NewTestGitHubItem()
+	matcher := NewMatchinator().WithCommentRegexes(false, regexp.MustCompile("please backport"))
+
+	assert.NilError(t, gh.populateForMatcher(context.Background(), item, matcher, gh.logger))
+	assert.DeepEqual(t, item.Comments, []string{"first comment", "Please backport this"})
+
+	matches, _ := matcher.Matches(item)
+	assert.Equal(t, matches, true)
+
+	assert.Equal(t, len(requests), 2)
+	assert.Assert(t, strings.Contains(requests[1], `"commentsCursor":"cursor"`), requests[1])
+
+	// Comments aren't fetched for matchers which don't need them.
+	assert.NilError(t, gh.populateForMatcher(context.Background(), NewTestGitHubItem(), NewMatchinator(), gh.logger))
+	assert.Equal(t, len(requests), 2)
+}
+
+func TestGitHubinatorGetIssueBodyCountsBodyQueryErrors(t *testing.T) {
+	server := newTestGraphQLServer(t, `{"errors": [{"message": "something went wrong"}]}`)
+	gh := &gitHubinator{
+		client: githubv4.NewEnterpriseClient(server.URL, server.Client()),
+		logger: NewLogger(),
+	}
+
+	bodyErrorsBefore := CounterValue(MetricIssueBodyQueryErrorTotal)
+	labelErrorsBefore := CounterValue(MetricIssueLabelQueryErrorTotal)
+
+	_, _, err := gh.getIssueBody(context.Background(), GitHubRepository{Owner: "owner", Name: "repo"}, 1)
+	assert.ErrorContains(t, err, "something went wrong")
+
+	assert.Equal(t, CounterValue(MetricIssueBodyQueryErrorTotal)-bodyErrorsBefore, float64(1))
+	assert.Equal(t, CounterValue(MetricIssueLabelQueryErrorTotal)-labelErrorsBefore, float64(0))
+}
+
+func TestGitHubinatorSearchIssuesFetchesBodyOnce(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NilError(t, err)
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if strings.Contains(string(body), "search(") {
+			_, _ = w.Write([]byte(`{"data": {"search": {
+				"nodes": [{"id": "issue", "number": 1, "title": "a", "state": "OPEN",
+					"updatedAt": "2023-01-01T00:00:00Z", "viewerSubscription": "UNSUBSCRIBED",
+					"repository": {"name": "repo", "owner": {"login": "owner"}}}],
+				"pageInfo": {"endCursor": "", "hasNextPage": false}
+			}}}`))
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"data": {"repository": {"issue": {"body": "needle", "bodyText": "needle"}}}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	gh := &gitHubinator{
+		client: githubv4.NewEnterpriseClient(server.URL, server.Client()),
+		logger: NewLogger(),
+	}
+
+	bodyQueriesBefore := CounterValue(MetricIssueBodyQueryTotal)
+
+	items, err := gh.SearchIssues(
+		context.Background(), "is:issue", NewMatchinator().WithBodyRegexes(false, regexp.MustCompile("needle")),
+	)
+	assert.NilError(t, err)
+	assert.Equal(t, len(items), 1)
+	assert.Equal(t, items[0].Body, "needle")
+
+	// The body fetched for the body regex is reused once the issue matches, rather than being fetched again.
+	assert.Equal(t, CounterValue(MetricIssueBodyQueryTotal)-bodyQueriesBefore, float64(1))
+}
+
+func TestGitHubinatorGetIssueCachesBodyUntilUpdated(t *testing.T) {
+	bodyQueries := 0
+	updatedAt := "2023-01-01T00:00:00Z"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if strings.Contains(string(body), "bodyText") {
+			bodyQueries++
+
+			_, _ = w.Write([]byte(`{"data": {"repository": {"issue": {"body": "**body**", "bodyText": "body"}}}}`))
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"data": {"repository": {"issue": {
+			"author": null, "id": "an-id", "number": 42, "title": "a", "state": "OPEN",
+			"updatedAt": "` + updatedAt + `", "viewerSubscription": "UNSUBSCRIBED"
+		}}}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	gh := &gitHubinator{
+		client:     githubv4.NewEnterpriseClient(server.URL, server.Client()),
+		logger:     NewLogger(),
+		repoCache:  newTTLCache[string, GitHubRepository](time.Minute),
+		issueCache: newIssueCache(time.Minute),
+	}
+	ctx := context.Background()
+	repo := GitHubRepository{Owner: "owner", Name: "repo"}
+
+	for i := 0; i < 2; i++ {
+		item, err := gh.GetIssue(ctx, repo, 42, NewMatchinator())
+		assert.NilError(t, err)
+		assert.Equal(t, item.Body, "body")
+		assert.Equal(t, item.BodyMarkdown, "**body**")
+	}
+
+	// The issue wasn't updated, so its body was only fetched once.
+	assert.Equal(t, bodyQueries, 1)
+
+	updatedAt = "2023-01-02T00:00:00Z"
+
+	_, err := gh.GetIssue(ctx, repo, 42, NewMatchinator())
+	assert.NilError(t, err)
+	assert.Equal(t, bodyQueries, 2)
+
+	// Changing the token invalidates the cache.
+	assert.Assert(t, gh.WithToken("token").(*gitHubinator).issueCache != gh.issueCache)
+	assert.Assert(t, gh.WithRetries(1).(*gitHubinator).issueCache == gh.issueCache)
+}
+
+func TestIssueCacheMergesFetchedFields(t *testing.T) {
+	c := newIssueCache(time.Minute)
+	updatedAt := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	withBody := NewTestGitHubItem()
+	withBody.ID = "an-id"
+	withBody.UpdatedAt = updatedAt
+	withBody.BodyFetched = true
+	c.add(withBody)
+
+	withLabels := NewTestGitHubItem()
+	withLabels.ID = "an-id"
+	withLabels.UpdatedAt = updatedAt
+	withLabels.Labels = []string{"bug"}
+	withLabels.LabelsFetched = true
+	c.add(withLabels)
+
+	item := NewTestGitHubItem()
+	item.ID = "an-id"
+	item.UpdatedAt = updatedAt
+	item.Body, item.Labels = "", []string{}
+	c.restore(item)
+	assert.Assert(t, item.BodyFetched && item.LabelsFetched)
+	assert.Equal(t, item.Body, withBody.Body)
+	assert.DeepEqual(t, item.Labels, []string{"bug"})
+
+	updated := NewTestGitHubItem()
+	updated.ID = "an-id"
+	updated.UpdatedAt = updatedAt.Add(time.Hour)
+	c.restore(updated)
+	assert.Assert(t, !updated.BodyFetched && !updated.LabelsFetched)
+}
+
+func TestIssueCacheExpiresEntries(t *testing.T) {
+	c := newIssueCache(time.Hour)
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(now)
+	c.cache.clock = clock
+	updatedAt := now
+
+	newItem := func(id string) *GitHubItem {
+		item := NewTestGitHubItem()
+		item.ID = id
+		item.UpdatedAt = updatedAt
+		item.BodyFetched = true
+
+		return item
+	}
+
+	c.add(newItem("stale"))
+
+	clock.Advance(30 * time.Minute)
+	c.add(newItem("fresh"))
+
+	// Entries aren't used once they expire.
+	clock.Advance(45 * time.Minute)
+
+	stale := newItem("stale")
+	stale.BodyFetched = false
+	c.restore(stale)
+	assert.Assert(t, !stale.BodyFetched)
+
+	fresh := newItem("fresh")
+	fresh.BodyFetched = false
+	c.restore(fresh)
+	assert.Assert(t, fresh.BodyFetched)
+
+	// Expired entries are dropped when the next item is added, so the cache doesn't grow forever.
+	c.add(newItem("new"))
+	assert.Equal(t, len(c.cache.entries), 2)
+	_, ok := c.cache.entries["stale"]
+	assert.Assert(t, !ok)
+}
+
+func TestGitHubinatorQueryRecordsRateLimit(t *testing.T) {
+	server := newTestGraphQLServer(t, `{"data": {
+		"rateLimit": {"cost": 3, "remaining": 4321, "resetAt": "2023-01-01T01:00:00Z"},
+		"repository": {"name": "repo"}
+	}}`)
+
+	gh := &gitHubinator{
+		client:    githubv4.NewEnterpriseClient(server.URL, server.Client()),
+		logger:    NewLogger(),
+		repoCache: newTTLCache[string, GitHubRepository](time.Minute),
+	}
+	cost := MetricGitHubQueryCostTotal.WithLabelValues("repository")
+	costBefore := CounterValue(cost)
+
+	assert.NilError(t, gh.CheckRepository(context.Background(), GitHubRepository{Owner: "owner", Name: "repo"}))
+	assert.Equal(t, GaugeValue(MetricGitHubRateLimitRemaining), float64(4321))
+	assert.Equal(t, CounterValue(cost)-costBefore, float64(3))
+
+	// Responses without a rate limit leave the metrics as is.
+	noRateLimit := newTestGraphQLServer(t, `{"data": {"repository": {"name": "repo"}}}`)
+	gh.client = githubv4.NewEnterpriseClient(noRateLimit.URL, noRateLimit.Client())
+
+	assert.NilError(t, gh.CheckRepository(context.Background(), GitHubRepository{Owner: "owner", Name: "other"}))
+	assert.Equal(t, GaugeValue(MetricGitHubRateLimitRemaining), float64(4321))
+	assert.Equal(t, CounterValue(cost)-costBefore, float64(3))
+}
+
+func TestGitHubinatorQueryWarnsOnLowRateLimit(t *testing.T) {
+	remaining := GitHubRateLimitWarningThreshold
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"data": {
+			"rateLimit": {"cost": 7, "remaining": %d, "resetAt": "2023-01-01T01:00:00Z"},
+			"repository": {"name": "repo"}
+		}}`, remaining)
+	}))
+	t.Cleanup(server.Close)
+
+	buf := &bytes.Buffer{}
+	gh := &gitHubinator{
+		client:    githubv4.NewEnterpriseClient(server.URL, server.Client()),
+		logger:    slog.New(slog.NewTextHandler(buf, nil)),
+		repoCache: newTTLCache[string, GitHubRepository](time.Minute),
+	}
+	cost := MetricGitHubQueryCostTotal.WithLabelValues("repository")
+	costBefore := CounterValue(cost)
+
+	// Nothing is logged at the threshold.
+	assert.NilError(t, gh.CheckRepository(context.Background(), GitHubRepository{Owner: "owner", Name: "repo"}))
+	assert.Assert(t, !strings.Contains(buf.String(), "rate limit is running low"), buf.String())
+
+	remaining = GitHubRateLimitWarningThreshold - 1
+
+	assert.NilError(t, gh.CheckRepository(context.Background(), GitHubRepository{Owner: "owner", Name: "other"}))
+	assert.Equal(t, GaugeValue(MetricGitHubRateLimitRemaining), float64(GitHubRateLimitWarningThreshold-1))
+	assert.Equal(t, CounterValue(cost)-costBefore, float64(14))
+	assert.Assert(
+		t, strings.Contains(buf.String(), "level=WARN msg=\"GitHub rate limit is running low\""), buf.String(),
+	)
+	assert.Assert(
+		t, strings.Contains(buf.String(), fmt.Sprintf("remaining=%d cost=7", GitHubRateLimitWarningThreshold-1)),
+		buf.String(),
+	)
+}
+
+func TestGitHubRateLimitWait(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	newResponse := func(headers map[string]string) *http.Response {
+		resp := &http.Response{StatusCode: http.StatusForbidden, Header: http.Header{}, Body: http.NoBody}
+		for k, v := range headers {
+			resp.Header.Set(k, v)
+		}
+
+		return resp
+	}
+
+	assert.Equal(t, gitHubRateLimitWait(newResponse(map[string]string{"Retry-After": "30"}), now), 30*time.Second)
+	assert.Equal(t, gitHubRateLimitWait(newResponse(map[string]string{
+		"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": strconv.FormatInt(now.Add(time.Hour).Unix(), 10),
+	}), now), time.Hour)
+	assert.Equal(t, gitHubRateLimitWait(newResponse(nil), now), GitHubSecondaryRateLimitWait)
+
+	// Waits are bounded by the max.
+	resp := newResponse(map[string]string{"Retry-After": "3600"})
+	retry, err := gitHubCheckRetry(context.Background(), resp, nil)
+	assert.NilError(t, err)
+	assert.Assert(t, retry)
+	assert.Equal(t, gitHubBackoff(time.Second, time.Minute, 1, resp), time.Minute)
+}
+
+func TestGitHubBackoffWaitsForSecondaryRateLimitWithoutHeaders(t *testing.T) {
+	resp := &http.Response{
+		StatusCode: http.StatusForbidden,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(`{"message": "You have exceeded a secondary rate limit."}`)),
+	}
+
+	retry, err := gitHubCheckRetry(context.Background(), resp, nil)
+	assert.NilError(t, err)
+	assert.Assert(t, retry)
+
+	// Like retryablehttp, drain and close the body before asking how long to wait.
+	_, _ = io.Copy(io.Discard, resp.Body)
+	assert.NilError(t, resp.Body.Close())
+
+	assert.Equal(t, gitHubBackoff(time.Millisecond, time.Hour, 1, resp), GitHubSecondaryRateLimitWait)
+
+	// Other failures fall back to the default backoff.
+	failed := &http.Response{StatusCode: http.StatusBadGateway, Header: http.Header{}, Body: http.NoBody}
+	assert.Equal(t, gitHubBackoff(time.Millisecond, time.Hour, 1, failed), 2*time.Millisecond)
+}
+
+func TestIsGitHubRateLimitedReadsSecondaryRateLimitMessage(t *testing.T) {
+	message := `{"message": "You have exceeded a secondary rate limit. ` +
+		`Please wait a few minutes before you try again."}`
+	resp := &http.Response{
+		StatusCode: http.StatusForbidden,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(message)),
+	}
+
+	assert.Assert(t, isGitHubRateLimited(resp))
+
+	// The body can still be read.
+	body, err := io.ReadAll(resp.Body)
+	assert.NilError(t, err)
+	assert.Equal(t, string(body), message)
+
+	resp.Body = io.NopCloser(strings.NewReader(`{"message": "Resource not accessible by integration"}`))
+	assert.Assert(t, !isGitHubRateLimited(resp))
+	assert.Assert(t, !isGitHubRateLimited(&http.Response{StatusCode: http.StatusOK, Header: http.Header{}}))
+}
+
+func TestGitHubinatorRetriesAfterRateLimit(t *testing.T) {
+	requests := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		if requests == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"message": "You have exceeded a secondary rate limit."}`))
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": {"repository": {"name": "repo"}}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	backoffsBefore := CounterValue(MetricRateLimitBackoffTotal)
+	gh := NewGitHubinator(NewLogger()).WithBaseURL(server.URL).WithRetries(1).WithToken("1234")
+
+	assert.NilError(t, gh.CheckRepository(context.Background(), GitHubRepository{Owner: "owner", Name: "repo"}))
+	assert.Equal(t, requests, 2)
+	assert.Equal(t, CounterValue(MetricRateLimitBackoffTotal)-backoffsBefore, float64(1))
+}
+
+func TestGitHubinatorRetriesFailedRequests(t *testing.T) {
+	requests := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		if requests <= 2 {
+			w.WriteHeader(http.StatusBadGateway)
+
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": {"repository": {"name": "repo"}}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	ctx := context.Background()
+	repo := GitHubRepository{Owner: "owner", Name: "repo"}
+
+	// The timeout bounds the wait between retries, keeping the test fast.
+	gh := NewGitHubinator(NewLogger()).WithBaseURL(server.URL).WithTimeout(10 * time.Millisecond).WithToken("1234")
+
+	assert.ErrorContains(t, gh.WithRetries(1).CheckRepository(ctx, repo), "502")
+	assert.Equal(t, requests, 2)
+
+	requests = 0
+
+	assert.NilError(t, gh.WithRetries(2).CheckRepository(ctx, repo))
+	assert.Equal(t, requests, 3)
+}
+
+func TestGitHubinatorWithTokensRotatesTokensPerRequest(t *testing.T) {
+	auth := []string{}
+	logins := map[string]string{"Bearer a": "user", "Bearer b": "user", "Bearer c": "someone-else"}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = append(auth, r.Header.Get("Authorization"))
+
+		w.Header().Set("Content-Type", "application/json")
+
+		body, err := io.ReadAll(r.Body)
+		assert.NilError(t, err)
+
+		if strings.Contains(string(body), "viewer") {
+			login := logins[r.Header.Get("Authorization")]
+			_, _ = w.Write([]byte(`{"data": {"viewer": {"login": "` + login + `", "isViewer": true}}}`))
+
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"data": {"repository": {"name": "repo"}}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	ctx := context.Background()
+	gh := NewGitHubinator(NewLogger()).WithBaseURL(server.URL).WithTokens("a", "b")
+
+	user, err := gh.WhoAmI(ctx)
+	assert.NilError(t, err)
+	assert.Equal(t, user, "user")
+	assert.DeepEqual(t, auth, []string{"Bearer a", "Bearer b"})
+
+	auth = []string{}
+
+	for _, name := range []string{"one", "two", "three"} {
+		assert.NilError(t, gh.CheckRepository(ctx, GitHubRepository{Owner: "owner", Name: name}))
+	}
+
+	assert.DeepEqual(t, auth, []string{"Bearer a", "Bearer b", "Bearer a"})
+
+	_, err = NewGitHubinator(NewLogger()).WithBaseURL(server.URL).WithTokens("a", "c").WhoAmI(ctx)
+	assert.ErrorContains(t, err, "token 2 belongs to 'someone-else', but token 1 belongs to 'user'")
+}
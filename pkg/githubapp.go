@@ -0,0 +1,253 @@
+package pkg
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+const (
+	// GitHubRESTURL is the URL of the REST API of github.com, used to mint GitHub App installation tokens.
+	GitHubRESTURL = "https://api.github.com"
+	// gitHubAppJWTLifetime is how long the JWTs used to authenticate as a GitHub App are valid for. GitHub allows at
+	// most ten minutes.
+	gitHubAppJWTLifetime = 9 * time.Minute
+	// gitHubAppJWTClockSkew is subtracted from the issue time of JWTs, to allow for clock drift between us and GitHub.
+	gitHubAppJWTClockSkew = time.Minute
+)
+
+// gitHubAppAuth holds the credentials used to authenticate as an installation of a GitHub App.
+type gitHubAppAuth struct {
+	appID          int64
+	installationID int64
+	privateKeyPEM  []byte
+}
+
+// GitHubRESTURLFor returns the URL of the REST API of the GitHub Enterprise Server instance at the given base URL,
+// such as 'https://github.example.com/api/v3' for 'https://github.example.com'. If the base URL is empty, the
+// REST API of github.com is returned.
+func GitHubRESTURLFor(baseURL string) string {
+	if baseURL == "" {
+		return GitHubRESTURL
+	}
+
+	baseURL = strings.TrimSuffix(strings.TrimSuffix(baseURL, "/"), "/api/graphql")
+
+	return baseURL + "/api/v3"
+}
+
+// ParseGitHubAppPrivateKey parses the given PEM-encoded private key of a GitHub App. Both PKCS #1 keys, which
+// GitHub generates, and PKCS #8 keys are supported.
+func ParseGitHubAppPrivateKey(privateKeyPEM []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(privateKeyPEM)
+	if block == nil {
+		return nil, errors.New("unable to decode private key, expected a PEM-encoded RSA key")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse private key: %w", err)
+	}
+
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("expected an RSA private key, got %T", key)
+	}
+
+	return rsaKey, nil
+}
+
+// newGitHubAppJWT returns a JWT, signed with the given key, which authenticates as the GitHub App with the given ID
+// until gitHubAppJWTLifetime after now.
+func newGitHubAppJWT(appID int64, key *rsa.PrivateKey, now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+
+	claims, err := json.Marshal(map[string]int64{
+		"iat": now.Add(-gitHubAppJWTClockSkew).Unix(),
+		"exp": now.Add(gitHubAppJWTLifetime).Unix(),
+		"iss": appID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("unable to sign jwt: %w", err)
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// gitHubAppTokenSource is an oauth2.TokenSource which mints installation tokens for a GitHub App. It should be
+// wrapped with oauth2.ReuseTokenSource, so a new token is only minted once the previous one expires.
+type gitHubAppTokenSource struct {
+	auth    *gitHubAppAuth
+	restURL string
+	client  *http.Client
+	now     func() time.Time
+
+	keyOnce sync.Once
+	key     *rsa.PrivateKey
+	keyErr  error
+}
+
+func newGitHubAppTokenSource(auth *gitHubAppAuth, restURL string, client *http.Client) *gitHubAppTokenSource {
+	return &gitHubAppTokenSource{
+		auth:    auth,
+		restURL: strings.TrimSuffix(restURL, "/"),
+		client:  client,
+		now:     time.Now,
+	}
+}
+
+// do sends a request with the given method to the given path of the REST API, authenticated as the GitHub App, and
+// decodes the JSON response into out.
+func (s *gitHubAppTokenSource) do(ctx context.Context, method string, path string, out any) error {
+	s.keyOnce.Do(func() {
+		s.key, s.keyErr = ParseGitHubAppPrivateKey(s.auth.privateKeyPEM)
+	})
+
+	if s.keyErr != nil {
+		return s.keyErr
+	}
+
+	jwt, err := newGitHubAppJWT(s.auth.appID, s.key, s.now())
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, s.restURL+path, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("unable to read response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("unable to decode response: %w", err)
+	}
+
+	return nil
+}
+
+// Token mints a new installation token.
+func (s *gitHubAppTokenSource) Token() (*oauth2.Token, error) {
+	resp := struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}{}
+
+	path := fmt.Sprintf("/app/installations/%d/access_tokens", s.auth.installationID)
+
+	if err := s.do(context.Background(), http.MethodPost, path, &resp); err != nil {
+		return nil, fmt.Errorf("unable to create installation token for app %d: %w", s.auth.appID, err)
+	}
+
+	return &oauth2.Token{AccessToken: resp.Token, Expiry: resp.ExpiresAt}, nil
+}
+
+// Login returns the login of the GitHub App's bot user, such as 'my-app[bot]'.
+func (s *gitHubAppTokenSource) Login(ctx context.Context) (string, error) {
+	resp := struct {
+		Slug string `json:"slug"`
+	}{}
+
+	if err := s.do(ctx, http.MethodGet, "/app", &resp); err != nil {
+		return "", fmt.Errorf("unable to get app %d: %w", s.auth.appID, err)
+	}
+
+	if resp.Slug == "" {
+		return "", fmt.Errorf("unexpected result, app %d has no slug", s.auth.appID)
+	}
+
+	return resp.Slug + "[bot]", nil
+}
+
+// GitHubAppAuthConfig configures authentication as the installation of a GitHub App, rather than with a PAT.
+type GitHubAppAuthConfig struct {
+	// AppID is the ID of the GitHub App.
+	AppID int64 `yaml:"appID"`
+	// InstallationID is the ID of the app's installation to authenticate as.
+	InstallationID int64 `yaml:"installationID"`
+	// PrivateKeyFile is the file containing the app's PEM-encoded private key.
+	PrivateKeyFile string `yaml:"privateKeyFile"`
+	// PrivateKey is the private key contained in the PrivateKeyFile.
+	PrivateKey []byte `yaml:"-"`
+}
+
+// Load ensures the IDs are set and reads the PrivateKeyFile into the PrivateKey field, checking that it holds a
+// valid key.
+func (a *GitHubAppAuthConfig) Load() error {
+	if a.AppID <= 0 {
+		return fmt.Errorf("appID must be greater than zero, got %d", a.AppID)
+	}
+
+	if a.InstallationID <= 0 {
+		return fmt.Errorf("installationID must be greater than zero, got %d", a.InstallationID)
+	}
+
+	if len(a.PrivateKeyFile) == 0 {
+		return errors.New("privateKeyFile cannot be empty")
+	}
+
+	path, err := GetAbsolutePath(a.PrivateKeyFile)
+	if err != nil {
+		return err
+	}
+
+	key, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read private key file %s: %w", a.PrivateKeyFile, err)
+	}
+
+	if _, err := ParseGitHubAppPrivateKey(key); err != nil {
+		return fmt.Errorf("invalid private key in %s: %w", a.PrivateKeyFile, err)
+	}
+
+	a.PrivateKey = key
+
+	return nil
+}
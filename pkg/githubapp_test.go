@@ -0,0 +1,155 @@
+package pkg
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+// newTestGitHubAppKey returns a new RSA key and its PKCS #1 PEM encoding.
+func newTestGitHubAppKey(t *testing.T) (*rsa.PrivateKey, []byte) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NilError(t, err)
+
+	return key, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+}
+
+func TestParseGitHubAppPrivateKey(t *testing.T) {
+	key, pkcs1 := newTestGitHubAppKey(t)
+
+	parsed, err := ParseGitHubAppPrivateKey(pkcs1)
+	assert.NilError(t, err)
+	assert.Assert(t, parsed.Equal(key))
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NilError(t, err)
+
+	parsed, err = ParseGitHubAppPrivateKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	assert.NilError(t, err)
+	assert.Assert(t, parsed.Equal(key))
+
+	_, err = ParseGitHubAppPrivateKey([]byte("not a key"))
+	assert.ErrorContains(t, err, "expected a PEM-encoded RSA key")
+}
+
+func TestNewGitHubAppJWT(t *testing.T) {
+	key, _ := newTestGitHubAppKey(t)
+	now := time.Unix(1700000000, 0)
+
+	jwt, err := newGitHubAppJWT(1234, key, now)
+	assert.NilError(t, err)
+
+	parts := strings.Split(jwt, ".")
+	assert.Equal(t, len(parts), 3)
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	assert.NilError(t, err)
+
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	assert.NilError(t, rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature))
+
+	rawClaims, err := base64.RawURLEncoding.DecodeString(parts[1])
+	assert.NilError(t, err)
+
+	claims := map[string]int64{}
+	assert.NilError(t, json.Unmarshal(rawClaims, &claims))
+	assert.DeepEqual(t, claims, map[string]int64{
+		"iss": 1234,
+		"iat": now.Add(-gitHubAppJWTClockSkew).Unix(),
+		"exp": now.Add(gitHubAppJWTLifetime).Unix(),
+	})
+}
+
+func TestGitHubRESTURLFor(t *testing.T) {
+	assert.Equal(t, GitHubRESTURLFor(""), "https://api.github.com")
+	assert.Equal(t, GitHubRESTURLFor("https://github.example.com/"), "https://github.example.com/api/v3")
+	assert.Equal(t, GitHubRESTURLFor("https://github.example.com/api/graphql"), "https://github.example.com/api/v3")
+}
+
+func TestGitHubinatorWithAppAuthUsesInstallationTokens(t *testing.T) {
+	_, keyPEM := newTestGitHubAppKey(t)
+	tokensMinted := 0
+	graphQLAuth := []string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/api/v3/app/installations/5678/access_tokens":
+			assert.Equal(t, r.Method, http.MethodPost)
+			assert.Assert(t, strings.HasPrefix(r.Header.Get("Authorization"), "Bearer "))
+
+			tokensMinted++
+
+			expiresAt := time.Now().Add(time.Hour).Format(time.RFC3339)
+			_, _ = w.Write([]byte(`{"token": "installation-token", "expires_at": "` + expiresAt + `"}`))
+		case "/api/v3/app":
+			_, _ = w.Write([]byte(`{"slug": "my-app"}`))
+		case "/api/graphql":
+			graphQLAuth = append(graphQLAuth, r.Header.Get("Authorization"))
+			_, _ = w.Write([]byte(`{"data": {"repository": {"name": "repo"}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	ctx := context.Background()
+	gh := NewGitHubinator(NewLogger()).WithBaseURL(server.URL).WithAppAuth(1234, 5678, keyPEM)
+
+	user, err := gh.WhoAmI(ctx)
+	assert.NilError(t, err)
+	assert.Equal(t, user, "my-app[bot]")
+
+	assert.NilError(t, gh.CheckRepository(ctx, GitHubRepository{Owner: "owner", Name: "repo"}))
+	assert.NilError(t, gh.CheckRepository(ctx, GitHubRepository{Owner: "owner", Name: "other"}))
+
+	// The installation token is reused until it expires.
+	assert.Equal(t, tokensMinted, 1)
+	assert.DeepEqual(t, graphQLAuth, []string{"Bearer installation-token", "Bearer installation-token"})
+}
+
+func TestConfigValidateChecksAppAuth(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	e := NewMockEmailinator()
+	c, cleanup, err := NewTestConfig()
+
+	assert.NilError(t, err)
+
+	defer cleanup()
+
+	_, keyPEM := newTestGitHubAppKey(t)
+	keyFile := t.TempDir() + "/key.pem"
+	assert.NilError(t, os.WriteFile(keyFile, keyPEM, 0o600))
+
+	c.AppAuth = &GitHubAppAuthConfig{AppID: 1234, InstallationID: 5678, PrivateKeyFile: keyFile}
+	assert.ErrorContains(t, c.Validate(ctx, gh, e), "patFile and appAuth are mutually exclusive")
+
+	c.PATFile = ""
+	assert.NilError(t, c.Validate(ctx, gh, e))
+	assert.DeepEqual(t, c.AppAuth.PrivateKey, keyPEM)
+
+	c.AppAuth.InstallationID = 0
+	assert.ErrorContains(t, c.Validate(ctx, gh, e), "installationID must be greater than zero")
+
+	c.AppAuth.InstallationID = 5678
+	assert.NilError(t, os.WriteFile(keyFile, []byte("not a key"), 0o600))
+	assert.ErrorContains(t, c.Validate(ctx, gh, e), "invalid private key")
+}
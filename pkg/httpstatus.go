@@ -0,0 +1,146 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"golang.org/x/exp/slog"
+)
+
+var (
+	// DefaultRetryStatuses retries server errors.
+	DefaultRetryStatuses = []string{"5xx"}
+	// DefaultSuccessStatuses treats any 2xx response as a success.
+	DefaultSuccessStatuses = []string{"2xx"}
+)
+
+// HTTPStatusPolicy configures how the HTTP status codes returned by a receiver, such as a webhook, are handled.
+// Statuses are given either as a code, such as "409", or as a class, such as "5xx". A status which is neither a
+// success nor retryable is treated as a permanent failure.
+type HTTPStatusPolicy struct {
+	// RetryStatuses are the statuses which are retried. If empty, DefaultRetryStatuses is used.
+	RetryStatuses []string `yaml:"retryStatuses"`
+	// SuccessStatuses are the statuses which are treated as a success. If empty, DefaultSuccessStatuses is used.
+	SuccessStatuses []string `yaml:"successStatuses"`
+}
+
+func (p *HTTPStatusPolicy) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Any("retryStatuses", p.getRetryStatuses()),
+		slog.Any("successStatuses", p.getSuccessStatuses()),
+	)
+}
+
+// getRetryStatuses returns the RetryStatuses, or DefaultRetryStatuses if none are set.
+func (p *HTTPStatusPolicy) getRetryStatuses() []string {
+	if len(p.RetryStatuses) == 0 {
+		return DefaultRetryStatuses
+	}
+
+	return p.RetryStatuses
+}
+
+// getSuccessStatuses returns the SuccessStatuses, or DefaultSuccessStatuses if none are set.
+func (p *HTTPStatusPolicy) getSuccessStatuses() []string {
+	if len(p.SuccessStatuses) == 0 {
+		return DefaultSuccessStatuses
+	}
+
+	return p.SuccessStatuses
+}
+
+// Validate ensures each status is a valid code or class, and that no code is both a success and retryable.
+func (p *HTTPStatusPolicy) Validate(_ context.Context) error {
+	for _, s := range append(p.getRetryStatuses(), p.getSuccessStatuses()...) {
+		if _, err := parseHTTPStatus(s); err != nil {
+			return err
+		}
+	}
+
+	for code := 100; code < 600; code++ {
+		if p.IsSuccess(code) && p.IsRetryable(code) {
+			return fmt.Errorf("status %d cannot be both a success and retryable", code)
+		}
+	}
+
+	return nil
+}
+
+// IsSuccess returns if the given status code is a success.
+func (p *HTTPStatusPolicy) IsSuccess(code int) bool {
+	return matchesHTTPStatus(p.getSuccessStatuses(), code)
+}
+
+// IsRetryable returns if the given status code should be retried.
+func (p *HTTPStatusPolicy) IsRetryable(code int) bool {
+	return matchesHTTPStatus(p.getRetryStatuses(), code)
+}
+
+// CheckRetry implements retryablehttp.CheckRetry using the policy. Connection errors are retried following
+// retryablehttp.DefaultRetryPolicy. Retries are counted in MetricHTTPRetryTotal and permanent failures in
+// MetricHTTPPermanentFailureTotal, labeled by status class.
+func (p *HTTPStatusPolicy) CheckRetry(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+
+	if err != nil || resp == nil {
+		return retryablehttp.DefaultRetryPolicy(ctx, resp, err)
+	}
+
+	class := httpStatusClass(resp.StatusCode)
+
+	switch {
+	case p.IsSuccess(resp.StatusCode):
+		return false, nil
+	case p.IsRetryable(resp.StatusCode):
+		MetricHTTPRetryTotal.WithLabelValues(class).Inc()
+
+		return true, nil
+	default:
+		MetricHTTPPermanentFailureTotal.WithLabelValues(class).Inc()
+
+		return false, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+}
+
+// httpStatusClass returns the class of the given status code, such as "5xx" for 503.
+func httpStatusClass(code int) string {
+	return fmt.Sprintf("%dxx", code/100)
+}
+
+// parseHTTPStatus validates the given status code or class, returning it in lowercase.
+func parseHTTPStatus(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+
+	if len(s) == 3 && strings.HasSuffix(s, "xx") && s[0] >= '1' && s[0] <= '5' {
+		return s, nil
+	}
+
+	code, err := strconv.Atoi(s)
+	if err != nil || code < 100 || code > 599 {
+		return "", fmt.Errorf("invalid status '%s', expected a code such as '409' or a class such as '5xx'", s)
+	}
+
+	return s, nil
+}
+
+// matchesHTTPStatus returns if the given status code matches any of the given statuses.
+func matchesHTTPStatus(statuses []string, code int) bool {
+	for _, s := range statuses {
+		parsed, err := parseHTTPStatus(s)
+		if err != nil {
+			continue
+		}
+
+		if parsed == strconv.Itoa(code) || parsed == httpStatusClass(code) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,84 @@
+package pkg
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"gotest.tools/v3/assert"
+)
+
+func TestHTTPStatusPolicyDefaults(t *testing.T) {
+	p := &HTTPStatusPolicy{}
+	assert.NilError(t, p.Validate(context.Background()))
+
+	assert.Equal(t, p.IsSuccess(200), true)
+	assert.Equal(t, p.IsSuccess(202), true)
+	assert.Equal(t, p.IsRetryable(503), true)
+	assert.Equal(t, p.IsRetryable(409), false)
+	assert.Equal(t, p.IsSuccess(409), false)
+}
+
+func TestHTTPStatusPolicyValidate(t *testing.T) {
+	ctx := context.Background()
+
+	p := &HTTPStatusPolicy{RetryStatuses: []string{"5xx", "429"}, SuccessStatuses: []string{"200", "409"}}
+	assert.NilError(t, p.Validate(ctx))
+
+	p.RetryStatuses = []string{"6xx"}
+	assert.ErrorContains(t, p.Validate(ctx), "invalid status '6xx'")
+
+	p.RetryStatuses = []string{"abc"}
+	assert.ErrorContains(t, p.Validate(ctx), "invalid status 'abc'")
+
+	p.RetryStatuses = []string{"2xx"}
+	assert.ErrorContains(t, p.Validate(ctx), "status 200 cannot be both a success and retryable")
+}
+
+func TestHTTPStatusPolicyCheckRetryWithRetryableClient(t *testing.T) {
+	var numRequests atomic.Int32
+
+	statuses := []int{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(statuses[numRequests.Add(1)-1])
+	}))
+	defer server.Close()
+
+	p := &HTTPStatusPolicy{SuccessStatuses: []string{"200", "202"}}
+
+	client := retryablehttp.NewClient()
+	client.RetryMax = 3
+	client.RetryWaitMin = time.Millisecond
+	client.RetryWaitMax = time.Millisecond
+	client.CheckRetry = p.CheckRetry
+	client.Logger = nil
+
+	retries := MetricHTTPRetryTotal.WithLabelValues("5xx")
+	retriesBefore := CounterValue(retries)
+	failures := MetricHTTPPermanentFailureTotal.WithLabelValues("4xx")
+	failuresBefore := CounterValue(failures)
+
+	// Server errors are retried until a success.
+	statuses = []int{http.StatusServiceUnavailable, http.StatusAccepted}
+
+	resp, err := client.Get(server.URL)
+	assert.NilError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, resp.StatusCode, http.StatusAccepted)
+	assert.Equal(t, numRequests.Load(), int32(2))
+	assert.Equal(t, CounterValue(retries)-retriesBefore, float64(1))
+
+	// Other statuses are permanent failures and are not retried.
+	numRequests.Store(0)
+	statuses = []int{http.StatusConflict}
+
+	_, err = client.Get(server.URL)
+	assert.ErrorContains(t, err, "unexpected status 409")
+	assert.Equal(t, numRequests.Load(), int32(1))
+	assert.Equal(t, CounterValue(failures)-failuresBefore, float64(1))
+}
@@ -1,6 +1,9 @@
 package pkg
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"os"
 
 	"golang.org/x/exp/slog"
@@ -26,8 +29,46 @@ var (
 	}
 	// LogKeyError is used to set the standard key that should be used when providing an error in a log.
 	LogKeyError = "err"
+	// LogKeyTickID is used to set the standard key that should be used when providing a poll tick's ID in a log.
+	LogKeyTickID = "tickID"
 )
 
+// tickIDContextKey is the context key used to carry a poll tick's ID.
+type tickIDContextKey struct{}
+
+// NewTickID generates a short, random ID which can be used to correlate the logs of a single poll tick.
+func NewTickID() string {
+	b := make([]byte, 4)
+
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// ContextWithTickID returns a copy of the given context carrying the given tick ID.
+func ContextWithTickID(ctx context.Context, tickID string) context.Context {
+	return context.WithValue(ctx, tickIDContextKey{}, tickID)
+}
+
+// TickIDFromContext returns the tick ID carried by the given context, if present.
+func TickIDFromContext(ctx context.Context) (string, bool) {
+	tickID, ok := ctx.Value(tickIDContextKey{}).(string)
+
+	return tickID, ok
+}
+
+// LoggerFromContext returns the given logger with any log attributes carried by the given context, such as the
+// tick ID, attached.
+func LoggerFromContext(ctx context.Context, logger *slog.Logger) *slog.Logger {
+	if tickID, ok := TickIDFromContext(ctx); ok {
+		return logger.With(LogKeyTickID, tickID)
+	}
+
+	return logger
+}
+
 // NewLogger creates a new logger. It should be an inexpensive call. If no LogOptions are provided, then
 // DefaultLogOptions are used. Only the first LogOptions provided to the function will be recognized, the rest
 // will be ignored.
@@ -0,0 +1,31 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"golang.org/x/exp/slog"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+)
+
+func TestLoggerFromContextAddsTickID(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(buf, nil))
+
+	LoggerFromContext(context.Background(), logger).Info("no tick")
+	assert.Assert(t, !bytes.Contains(buf.Bytes(), []byte(LogKeyTickID)))
+
+	tickID := NewTickID()
+	assert.Equal(t, len(tickID), 8)
+
+	ctx := ContextWithTickID(context.Background(), tickID)
+
+	got, ok := TickIDFromContext(ctx)
+	assert.Assert(t, ok)
+	assert.Equal(t, got, tickID)
+
+	LoggerFromContext(ctx, logger).Info("with tick")
+	assert.Assert(t, cmp.Contains(buf.String(), LogKeyTickID+"="+tickID))
+}
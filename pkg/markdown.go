@@ -0,0 +1,234 @@
+package pkg
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	markdownScriptRegex  = regexp.MustCompile(`(?is)<script\b.*?</script\s*>`)
+	markdownStyleRegex   = regexp.MustCompile(`(?is)<style\b.*?</style\s*>`)
+	markdownTagRegex     = regexp.MustCompile(`(?s)</?[a-zA-Z!][^>]*>`)
+	markdownHeadingRegex = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	markdownULRegex      = regexp.MustCompile(`^\s*[-*+]\s+(.*)$`)
+	markdownOLRegex      = regexp.MustCompile(`^\s*\d+[.)]\s+(.*)$`)
+	markdownLinkRegex    = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	markdownBoldRegex    = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	markdownItalicRegex  = regexp.MustCompile(`\*([^*]+)\*|\b_([^_]+)_\b`)
+)
+
+// markdownAllowedSchemes are the URL schemes which may be used in rendered links.
+var markdownAllowedSchemes = map[string]bool{"http": true, "https": true, "mailto": true}
+
+// isMarkdownFence returns if the given line opens or closes a fenced code block.
+func isMarkdownFence(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), "```")
+}
+
+// stripMarkdownScripts removes script and style elements, along with their contents, from the given Markdown.
+// Fenced code blocks are left untouched.
+func stripMarkdownScripts(md string) string {
+	b := strings.Builder{}
+	text := strings.Builder{}
+	inCode := false
+
+	flushText := func() {
+		stripped := markdownScriptRegex.ReplaceAllString(text.String(), "")
+		b.WriteString(markdownStyleRegex.ReplaceAllString(stripped, ""))
+		text.Reset()
+	}
+
+	for _, line := range strings.SplitAfter(md, "\n") {
+		if isMarkdownFence(line) {
+			if !inCode {
+				flushText()
+			}
+
+			inCode = !inCode
+		}
+
+		if inCode || isMarkdownFence(line) {
+			b.WriteString(line)
+		} else {
+			text.WriteString(line)
+		}
+	}
+
+	flushText()
+
+	return b.String()
+}
+
+// renderMarkdownEmphasis renders the bold and italic text in the given escaped text.
+func renderMarkdownEmphasis(text string) string {
+	text = markdownBoldRegex.ReplaceAllString(text, "<strong>$1$2</strong>")
+
+	return markdownItalicRegex.ReplaceAllString(text, "<em>$1$2</em>")
+}
+
+// renderMarkdownLinks renders the links, bold and italic text in the given escaped text. Emphasis is only rendered
+// outside of link URLs, so URLs holding '_' or '*' are kept as is.
+func renderMarkdownLinks(text string) string {
+	b := strings.Builder{}
+	last := 0
+
+	for _, loc := range markdownLinkRegex.FindAllStringIndex(text, -1) {
+		b.WriteString(renderMarkdownEmphasis(text[last:loc[0]]))
+		b.WriteString(renderMarkdownLink(text[loc[0]:loc[1]]))
+		last = loc[1]
+	}
+
+	b.WriteString(renderMarkdownEmphasis(text[last:]))
+
+	return b.String()
+}
+
+// renderMarkdownLink renders a Markdown link whose text and URL have already been escaped, along with the bold and
+// italic text in the link's text. Links with a scheme that isn't allowed are rendered as their text alone.
+func renderMarkdownLink(match string) string {
+	groups := markdownLinkRegex.FindStringSubmatch(match)
+	text, rawURL := renderMarkdownEmphasis(groups[1]), html.UnescapeString(groups[2])
+
+	u, err := url.Parse(rawURL)
+	if err != nil || !markdownAllowedSchemes[strings.ToLower(u.Scheme)] {
+		return text
+	}
+
+	return `<a href="` + html.EscapeString(u.String()) + `">` + text + `</a>`
+}
+
+// renderMarkdownInline strips raw HTML tags from the given line of Markdown, escapes it and renders its code spans,
+// links, bold and italic text.
+func renderMarkdownInline(line string) string {
+	b := strings.Builder{}
+
+	// Odd segments are within backticks.
+	for i, segment := range strings.Split(line, "`") {
+		if i%2 == 1 {
+			b.WriteString("<code>" + html.EscapeString(segment) + "</code>")
+
+			continue
+		}
+
+		escaped := html.EscapeString(markdownTagRegex.ReplaceAllString(segment, ""))
+		b.WriteString(renderMarkdownLinks(escaped))
+	}
+
+	return b.String()
+}
+
+// RenderMarkdownHTML renders the given Markdown as sanitized HTML. A common subset of Markdown is supported: headings,
+// paragraphs, fenced code blocks, block quotes, lists, code spans, links, bold and italic text. Raw HTML is never
+// passed through: script and style elements are removed, other tags are stripped and the remaining text is escaped,
+// so the only elements in the result are the ones generated for the Markdown. Code is escaped and kept as is. Links
+// are only rendered for http, https and mailto URLs.
+//
+// A Markdown library and an HTML sanitizer aren't used, as emails only need this subset and generating every element
+// here means there is no raw HTML to sanitize, without adding two dependencies for it.
+func RenderMarkdownHTML(md string) string {
+	md = stripMarkdownScripts(strings.ReplaceAll(md, "\r\n", "\n"))
+
+	b := strings.Builder{}
+	paragraph := []string{}
+	list := ""
+	inCode := false
+
+	flushParagraph := func() {
+		if len(paragraph) > 0 {
+			b.WriteString("<p>" + strings.Join(paragraph, "<br>\n") + "</p>\n")
+			paragraph = []string{}
+		}
+	}
+
+	closeList := func() {
+		if list != "" {
+			b.WriteString("</" + list + ">\n")
+			list = ""
+		}
+	}
+
+	openList := func(tag string) {
+		if list != tag {
+			closeList()
+			b.WriteString("<" + tag + ">\n")
+			list = tag
+		}
+	}
+
+	for _, line := range strings.Split(md, "\n") {
+		if isMarkdownFence(line) {
+			if inCode {
+				b.WriteString("</code></pre>\n")
+			} else {
+				flushParagraph()
+				closeList()
+				b.WriteString("<pre><code>")
+			}
+
+			inCode = !inCode
+
+			continue
+		}
+
+		if inCode {
+			b.WriteString(html.EscapeString(line) + "\n")
+
+			continue
+		}
+
+		if strings.TrimSpace(line) == "" {
+			flushParagraph()
+			closeList()
+
+			continue
+		}
+
+		if m := markdownHeadingRegex.FindStringSubmatch(line); m != nil {
+			flushParagraph()
+			closeList()
+
+			tag := "h" + string(rune('0'+len(m[1])))
+			b.WriteString("<" + tag + ">" + renderMarkdownInline(m[2]) + "</" + tag + ">\n")
+
+			continue
+		}
+
+		if m := markdownULRegex.FindStringSubmatch(line); m != nil {
+			flushParagraph()
+			openList("ul")
+			b.WriteString("<li>" + renderMarkdownInline(m[1]) + "</li>\n")
+
+			continue
+		}
+
+		if m := markdownOLRegex.FindStringSubmatch(line); m != nil {
+			flushParagraph()
+			openList("ol")
+			b.WriteString("<li>" + renderMarkdownInline(m[1]) + "</li>\n")
+
+			continue
+		}
+
+		closeList()
+
+		if quote, ok := strings.CutPrefix(strings.TrimSpace(line), ">"); ok {
+			flushParagraph()
+			b.WriteString("<blockquote>" + renderMarkdownInline(strings.TrimSpace(quote)) + "</blockquote>\n")
+
+			continue
+		}
+
+		paragraph = append(paragraph, renderMarkdownInline(strings.TrimSpace(line)))
+	}
+
+	if inCode {
+		b.WriteString("</code></pre>\n")
+	}
+
+	flushParagraph()
+	closeList()
+
+	return b.String()
+}
@@ -0,0 +1,78 @@
+package pkg
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+)
+
+func TestRenderMarkdownHTML(t *testing.T) {
+	md := "# Title\n\n" +
+		"Some **bold**, *italic* and `<code>` with a [link](https://example.com/?a=1&b=2).\n" +
+		"A second line.\n\n" +
+		"- one\n- two\n\n" +
+		"1. first\n\n" +
+		"> quoted\n\n" +
+		"```\n<script>if a < b {}</script>\n```\n"
+
+	assert.Equal(
+		t,
+		RenderMarkdownHTML(md),
+		"<h1>Title</h1>\n"+
+			"<p>Some <strong>bold</strong>, <em>italic</em> and <code>&lt;code&gt;</code> with a "+
+			"<a href=\"https://example.com/?a=1&amp;b=2\">link</a>.<br>\n"+
+			"A second line.</p>\n"+
+			"<ul>\n<li>one</li>\n<li>two</li>\n</ul>\n"+
+			"<ol>\n<li>first</li>\n</ol>\n"+
+			"<blockquote>quoted</blockquote>\n"+
+			"<pre><code>&lt;script&gt;if a &lt; b {}&lt;/script&gt;\n</code></pre>\n",
+	)
+}
+
+func TestRenderMarkdownHTMLSanitizes(t *testing.T) {
+	for md, expected := range map[string]string{
+		"<script>alert('x')</script>hello":          "<p>hello</p>\n",
+		"<style>body {display: none}</style>hello":  "<p>hello</p>\n",
+		"<img src=x onerror=\"alert('x')\">hello":   "<p>hello</p>\n",
+		"<a href=\"https://example.com\">hello</a>": "<p>hello</p>\n",
+		"[hello](javascript:evil)":                  "<p>hello</p>\n",
+		"[hello](https://example.com\"onclick=\"alert)": "<p><a href=\"https://example.com&#34;onclick=&#34;alert\">" +
+			"hello</a></p>\n",
+		"a < b & c > d": "<p>a &lt; b &amp; c &gt; d</p>\n",
+	} {
+		assert.Equal(t, RenderMarkdownHTML(md), expected, md)
+	}
+}
+
+func TestRenderMarkdownHTMLKeepsLinkURLs(t *testing.T) {
+	for md, expected := range map[string]string{
+		"[docs](https://example.com/a_b_c)":       "<p><a href=\"https://example.com/a_b_c\">docs</a></p>\n",
+		"[docs](https://example.com/__init__.py)": "<p><a href=\"https://example.com/__init__.py\">docs</a></p>\n",
+		"[docs](https://example.com/*a*/**b**)":   "<p><a href=\"https://example.com/*a*/**b**\">docs</a></p>\n",
+		"see _this_ [**docs**](https://example.com/a_b) and *that*": "<p>see <em>this</em> " +
+			"<a href=\"https://example.com/a_b\"><strong>docs</strong></a> and <em>that</em></p>\n",
+	} {
+		assert.Equal(t, RenderMarkdownHTML(md), expected, md)
+	}
+}
+
+func TestRenderEmailHTML(t *testing.T) {
+	item := *NewTestGitHubItem()
+	item.Title = "<b>title</b>"
+
+	rendered := renderEmailHTML(item)
+	assert.Assert(
+		t, cmp.Contains(rendered, "<a href=\"https://github.com/owner/repo/issues/1\">&lt;b&gt;title&lt;/b&gt;</a>"),
+	)
+	assert.Assert(t, cmp.Contains(rendered, "<p>issue <strong>body</strong></p>"))
+	assert.Assert(t, cmp.Contains(rendered, "<a href=\"https://github.com/owner/repo/issues/1\">owner/repo#1</a> by "))
+	assert.Assert(t, cmp.Contains(rendered, ">a/test/label</span>"))
+
+	item.Labels = []string{"<script>"}
+	assert.Assert(t, cmp.Contains(renderEmailHTML(item), ">&lt;script&gt;</span>"))
+
+	item.Labels = []string{}
+	assert.Assert(t, !strings.Contains(renderEmailHTML(item), "<span"))
+}
@@ -3,9 +3,12 @@ package pkg
 import (
 	"fmt"
 	"regexp"
+	"slices"
 	"strings"
+	"time"
 
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
 )
 
 // GitHubItemMatcher is used to select GitHubItem structs based on a specific criteria encoded in the Matcher field.
@@ -16,39 +19,280 @@ type GitHubItemMatcher struct {
 	Name string
 }
 
+// matchesRepositoryTopics returns if the given requirement on the 'repo.topic' key matches the given topics. Negative
+// requirements, such as 'repo.topic!=x', must match every topic, while any other requirement must match at least one.
+func matchesRepositoryTopics(r labels.Requirement, topics []string) bool {
+	switch r.Operator() {
+	case selection.NotEquals, selection.NotIn, selection.DoesNotExist:
+		for _, t := range topics {
+			if !r.Matches(labels.Set{"repo.topic": t}) {
+				return false
+			}
+		}
+
+		return true
+	default:
+		for _, t := range topics {
+			if r.Matches(labels.Set{"repo.topic": t}) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
 // SelectorAsGitHubItemMatcher creates a new GitHubItemMatcher from the given k8s.io/apimachinery/pkg/labels.Selector.
 // If the given selector matches the GitHubItem as a label set (see GitHubItemAsLabelSet), then the matcher returns
-// true.
+// true. As a repository can have many topics, requirements on the 'repo.topic' key are matched against each of the
+// repository's topics, see matchesRepositoryTopics.
 func SelectorAsGitHubItemMatcher(s labels.Selector) GitHubItemMatcher {
 	return GitHubItemMatcher{
 		Matcher: func(i *GitHubItem) bool {
 			m := GitHubItemAsLabelSet(i)
 
-			return s.Matches(m)
+			// The internal selector that is created through labels.Parse will always
+			// return 'true' for selectable here, so ignore it.
+			requirements, _ := s.Requirements()
+			for _, r := range requirements {
+				if r.Key() == "repo.topic" {
+					if !matchesRepositoryTopics(r, i.Repo.Topics) {
+						return false
+					}
+				} else if !r.Matches(m) {
+					return false
+				}
+			}
+
+			return true
 		},
 		Name: fmt.Sprintf("selector: '%s'", s.String()),
 	}
 }
 
-// BodyRegexAsGitHubItemMatcher creates a new GitHubItemMatcher from the given bodyRegex. If the given bodyRegex
-// matches on the GitHubItem's Body field, then the matcher returns true.
-func BodyRegexAsGitHubItemMatcher(bodyRegex *regexp.Regexp) GitHubItemMatcher {
+// regexGitHubItemMatcher creates a new GitHubItemMatcher with the given name, which returns true if the given regex
+// matches on the string returned by field for the GitHubItem. Unless caseSensitive is set, the regex is matched
+// case-insensitively, as if it started with '(?i)'. Inline flags in the regex, such as '(?-i)', still apply.
+func regexGitHubItemMatcher(
+	name string, r *regexp.Regexp, caseSensitive bool, field func(i *GitHubItem) string,
+) GitHubItemMatcher {
+	match := r
+	if !caseSensitive {
+		// r was already compiled, so adding a flag to it can't fail.
+		match = regexp.MustCompile("(?i)" + r.String())
+	}
+
+	return GitHubItemMatcher{
+		Matcher: func(i *GitHubItem) bool {
+			return match.MatchString(field(i))
+		},
+		Name: fmt.Sprintf("%s: '%s'", name, r.String()),
+	}
+}
+
+// AnyOfGitHubItemMatcher creates a new GitHubItemMatcher which groups the given matchers under the given name. If any
+// of the given matchers returns true, then the matcher returns true.
+func AnyOfGitHubItemMatcher(name string, matchers ...GitHubItemMatcher) GitHubItemMatcher {
+	names := []string{}
+	for _, m := range matchers {
+		names = append(names, m.Name)
+	}
+
 	return GitHubItemMatcher{
 		Matcher: func(i *GitHubItem) bool {
-			return bodyRegex.Match([]byte(strings.ToLower(i.Body)))
+			for _, m := range matchers {
+				if m.Matcher(i) {
+					return true
+				}
+			}
+
+			return false
 		},
-		Name: fmt.Sprintf("bodyRegex: '%s'", bodyRegex.String()),
+		Name: fmt.Sprintf("anyOf '%s': [%s]", name, strings.Join(names, ", ")),
 	}
 }
 
+// BodyRegexAsGitHubItemMatcher creates a new GitHubItemMatcher from the given bodyRegex. If the given bodyRegex
+// matches on the GitHubItem's Body field, then the matcher returns true. Unless caseSensitive is set, the body is
+// matched case-insensitively.
+func BodyRegexAsGitHubItemMatcher(bodyRegex *regexp.Regexp, caseSensitive bool) GitHubItemMatcher {
+	return regexGitHubItemMatcher("bodyRegex", bodyRegex, caseSensitive, func(i *GitHubItem) string {
+		return i.Body
+	})
+}
+
+// CommentRegexAsGitHubItemMatcher creates a new GitHubItemMatcher from the given commentRegex. If the given
+// commentRegex matches on the GitHubItem's Comments, joined together by newlines, then the matcher returns true.
+// Unless caseSensitive is set, the comments are matched case-insensitively.
+func CommentRegexAsGitHubItemMatcher(commentRegex *regexp.Regexp, caseSensitive bool) GitHubItemMatcher {
+	return regexGitHubItemMatcher("commentRegex", commentRegex, caseSensitive, func(i *GitHubItem) string {
+		return strings.Join(i.Comments, "\n")
+	})
+}
+
 // TitleRegexAsGitHubItemMatcher creates a new GitHubItemMatcher from the given titleRegex. If the given titleRegex
-// matches on the GitHubItem's Title field, then the matcher returns true.
-func TitleRegexAsGitHubItemMatcher(titleRegex *regexp.Regexp) GitHubItemMatcher {
+// matches on the GitHubItem's Title field, then the matcher returns true. Unless caseSensitive is set, the title is
+// matched case-insensitively.
+func TitleRegexAsGitHubItemMatcher(titleRegex *regexp.Regexp, caseSensitive bool) GitHubItemMatcher {
+	return regexGitHubItemMatcher("titleRegex", titleRegex, caseSensitive, func(i *GitHubItem) string {
+		return i.Title
+	})
+}
+
+// titleStringGitHubItemMatcher creates a new GitHubItemMatcher which returns true if the given comparison of the
+// GitHubItem's Title with the given value is true. Unless caseSensitive is set, both are lowercased first.
+func titleStringGitHubItemMatcher(
+	name string, value string, caseSensitive bool, compare func(title string, value string) bool,
+) GitHubItemMatcher {
+	if !caseSensitive {
+		value = strings.ToLower(value)
+	}
+
+	return GitHubItemMatcher{
+		Matcher: func(i *GitHubItem) bool {
+			title := i.Title
+			if !caseSensitive {
+				title = strings.ToLower(title)
+			}
+
+			return compare(title, value)
+		},
+		Name: fmt.Sprintf("%s: '%s'", name, value),
+	}
+}
+
+// TitlePrefixGitHubItemMatcher creates a new GitHubItemMatcher which returns true if the GitHubItem's Title starts
+// with the given prefix. The prefix is matched literally, so characters such as '[' don't need escaping.
+func TitlePrefixGitHubItemMatcher(prefix string, caseSensitive bool) GitHubItemMatcher {
+	return titleStringGitHubItemMatcher("titlePrefix", prefix, caseSensitive, strings.HasPrefix)
+}
+
+// TitleSuffixGitHubItemMatcher creates a new GitHubItemMatcher which returns true if the GitHubItem's Title ends
+// with the given suffix. The suffix is matched literally.
+func TitleSuffixGitHubItemMatcher(suffix string, caseSensitive bool) GitHubItemMatcher {
+	return titleStringGitHubItemMatcher("titleSuffix", suffix, caseSensitive, strings.HasSuffix)
+}
+
+// TitleContainsGitHubItemMatcher creates a new GitHubItemMatcher which returns true if the GitHubItem's Title
+// contains the given substring. The substring is matched literally.
+func TitleContainsGitHubItemMatcher(substr string, caseSensitive bool) GitHubItemMatcher {
+	return titleStringGitHubItemMatcher("titleContains", substr, caseSensitive, strings.Contains)
+}
+
+// LastActivityByGitHubItemMatcher creates a new GitHubItemMatcher from the given logins. If the actor of the
+// GitHubItem's most recent timeline activity is one of the given logins, then the matcher returns true. Logins are
+// compared case-insensitively. Items without any timeline activity are not matched.
+func LastActivityByGitHubItemMatcher(logins []string) GitHubItemMatcher {
+	return GitHubItemMatcher{
+		Matcher: func(i *GitHubItem) bool {
+			if i.LastActivityBy == "" {
+				return false
+			}
+
+			for _, l := range logins {
+				if strings.EqualFold(l, i.LastActivityBy) {
+					return true
+				}
+			}
+
+			return false
+		},
+		Name: fmt.Sprintf("lastActivityBy: '%s'", strings.Join(logins, ",")),
+	}
+}
+
+// AuthorGitHubItemMatcher creates a new GitHubItemMatcher from the given logins. If the GitHubItem was opened by one
+// of the given logins, then the matcher returns true. Logins are compared case-insensitively.
+func AuthorGitHubItemMatcher(logins []string) GitHubItemMatcher {
+	return GitHubItemMatcher{
+		Matcher: func(i *GitHubItem) bool {
+			for _, l := range logins {
+				if strings.EqualFold(l, i.Author.Login) {
+					return true
+				}
+			}
+
+			return false
+		},
+		Name: fmt.Sprintf("authors: '%s'", strings.Join(logins, ",")),
+	}
+}
+
+// ReferencesIssueGitHubItemMatcher creates a new GitHubItemMatcher which matches items referencing the issue with
+// the given number in the same repository, according to the item's ReferencedIssues.
+func ReferencesIssueGitHubItemMatcher(number int) GitHubItemMatcher {
+	return GitHubItemMatcher{
+		Matcher: func(i *GitHubItem) bool {
+			return slices.Contains(i.ReferencedIssues, number)
+		},
+		Name: fmt.Sprintf("references issue: '#%d'", number),
+	}
+}
+
+// ExcludeDraftsGitHubItemMatcher creates a new GitHubItemMatcher which returns false for draft pull requests. Issues
+// are never drafts, so they are always matched.
+func ExcludeDraftsGitHubItemMatcher() GitHubItemMatcher {
+	return GitHubItemMatcher{
+		Matcher: func(i *GitHubItem) bool {
+			return !i.IsDraft()
+		},
+		Name: "excludeDrafts",
+	}
+}
+
+// NumberRangeAsGitHubItemMatcher creates a new GitHubItemMatcher which returns true if the GitHubItem's number is
+// between the given min and max, inclusive. A min or max of zero leaves that end of the range unbounded.
+func NumberRangeAsGitHubItemMatcher(min int, max int) GitHubItemMatcher {
+	return GitHubItemMatcher{
+		Matcher: func(i *GitHubItem) bool {
+			if min > 0 && i.Number < min {
+				return false
+			}
+
+			return max <= 0 || i.Number <= max
+		},
+		Name: fmt.Sprintf("numberRange: [%d, %d]", min, max),
+	}
+}
+
+// AgeAsGitHubItemMatcher creates a new GitHubItemMatcher which returns true if the time returned by field for the
+// GitHubItem is at or after the given cutoff. The cutoff is part of the matcher's name, so it appears in the reason an
+// item didn't match.
+func AgeAsGitHubItemMatcher(name string, cutoff time.Time, field func(i *GitHubItem) time.Time) GitHubItemMatcher {
+	return GitHubItemMatcher{
+		Matcher: func(i *GitHubItem) bool {
+			return !field(i).Before(cutoff)
+		},
+		Name: fmt.Sprintf("%s: since %s", name, cutoff.UTC().Format(time.RFC3339)),
+	}
+}
+
+// UpdatedWithinGitHubItemMatcher creates a new GitHubItemMatcher which returns true if the GitHubItem was updated
+// within the given window before now.
+func UpdatedWithinGitHubItemMatcher(window time.Duration, now time.Time) GitHubItemMatcher {
+	return AgeAsGitHubItemMatcher("updatedWithin", now.Add(-window), func(i *GitHubItem) time.Time {
+		return i.UpdatedAt
+	})
+}
+
+// CreatedWithinGitHubItemMatcher creates a new GitHubItemMatcher which returns true if the GitHubItem was created
+// within the given window before now.
+func CreatedWithinGitHubItemMatcher(window time.Duration, now time.Time) GitHubItemMatcher {
+	return AgeAsGitHubItemMatcher("createdWithin", now.Add(-window), func(i *GitHubItem) time.Time {
+		return i.CreatedAt
+	})
+}
+
+// NotUpdatedWithinGitHubItemMatcher creates a new GitHubItemMatcher which returns true if the GitHubItem wasn't
+// updated within the given window before now, so it's stale.
+func NotUpdatedWithinGitHubItemMatcher(window time.Duration, now time.Time) GitHubItemMatcher {
+	cutoff := now.Add(-window)
+
 	return GitHubItemMatcher{
 		Matcher: func(i *GitHubItem) bool {
-			return titleRegex.Match([]byte(strings.ToLower(i.Title)))
+			return i.UpdatedAt.Before(cutoff)
 		},
-		Name: fmt.Sprintf("titleRegex: '%s'", titleRegex.String()),
+		Name: fmt.Sprintf("notUpdatedWithin: before %s", cutoff.UTC().Format(time.RFC3339)),
 	}
 }
 
@@ -69,6 +313,40 @@ func RequiredLabelAsGitHubItemMatcher(requiredLabel string) GitHubItemMatcher {
 	}
 }
 
+// ExcludedLabelAsGitHubItemMatcher creates a new GitHubItemMatcher from the given excludedLabel. If the given
+// excludedLabel is present in the GitHubItem's labels, then the matcher returns false.
+func ExcludedLabelAsGitHubItemMatcher(excludedLabel string) GitHubItemMatcher {
+	return GitHubItemMatcher{
+		Matcher: func(i *GitHubItem) bool {
+			for _, itemLabel := range i.Labels {
+				if itemLabel == excludedLabel {
+					return false
+				}
+			}
+
+			return true
+		},
+		Name: fmt.Sprintf("excludedLabel: '%s'", excludedLabel),
+	}
+}
+
+// AssigneeAsGitHubItemMatcher creates a new GitHubItemMatcher from the given assignee. If the given login is one of
+// the GitHubItem's assignees, then the matcher returns true. Logins are compared case-insensitively.
+func AssigneeAsGitHubItemMatcher(assignee string) GitHubItemMatcher {
+	return GitHubItemMatcher{
+		Matcher: func(i *GitHubItem) bool {
+			for _, a := range i.Assignees {
+				if strings.EqualFold(a, assignee) {
+					return true
+				}
+			}
+
+			return false
+		},
+		Name: fmt.Sprintf("assignee: '%s'", assignee),
+	}
+}
+
 // Matchinator is used to provide custom criteria for filtering GitHubItems that may not be built in to GitHub's
 // GraphQL API. It specifies a list of critieria which the GitHubItem MUST match in order to be selected. If any
 // of the criteria is not met, then the GitHubItem is not matched.
@@ -80,31 +358,156 @@ type Matchinator interface {
 	// WithSelectors adds the given k8s.io/apimachinery/pkg/labels.Selectors to the match criteria.
 	WithSelectors(selectors ...labels.Selector) Matchinator
 
-	// WithBodyRegexes adds the given bodyRegexes to the match critieria.
-	WithBodyRegexes(bodyRegexes ...*regexp.Regexp) Matchinator
+	// WithAnyOf adds the given matchers to the match criteria as a group with the given name, which matches if any of
+	// them match. The group is still ANDed with the rest of the criteria.
+	WithAnyOf(name string, matchers ...GitHubItemMatcher) Matchinator
+
+	// WithSelectorGroup adds the given selectors and labels to the match criteria as a group with the given name,
+	// which matches if any of the selectors match or the item has any of the labels. See WithAnyOf.
+	WithSelectorGroup(name string, selectors []labels.Selector, labels []string) Matchinator
+
+	// WithBodyRegexes adds the given bodyRegexes to the match critieria. Unless caseSensitive is set, they are
+	// matched case-insensitively.
+	WithBodyRegexes(caseSensitive bool, bodyRegexes ...*regexp.Regexp) Matchinator
 
-	// WithTitleRegexes adds the given titleRegexes to the match critieria.
-	WithTitleRegexes(titleRegexes ...*regexp.Regexp) Matchinator
+	// WithTitleRegexes adds the given titleRegexes to the match critieria. Unless caseSensitive is set, they are
+	// matched case-insensitively.
+	WithTitleRegexes(caseSensitive bool, titleRegexes ...*regexp.Regexp) Matchinator
+
+	// WithTitleStrings adds the given literal title prefix, suffix and substring to the match criteria. Empty
+	// values are ignored. Unless caseSensitive is set, titles are compared case-insensitively.
+	WithTitleStrings(prefix string, suffix string, contains string, caseSensitive bool) Matchinator
 
 	// HasBodyRegex returns if a bodyRegex is part of the match criteria.
 	HasBodyRegex() bool
 
-	// WithRequiredLabels adds the given labels to the match criteria.
+	// WithCommentRegexes adds the given commentRegexes to the match critieria. Unless caseSensitive is set, they are
+	// matched case-insensitively.
+	WithCommentRegexes(caseSensitive bool, commentRegexes ...*regexp.Regexp) Matchinator
+
+	// HasCommentRegex returns if a commentRegex is part of the match criteria.
+	HasCommentRegex() bool
+
+	// WithRequiredLabels adds the given labels to the match criteria, requiring that the item has each of them.
 	WithRequiredLabels(labels ...string) Matchinator
 
-	// HasRequiredLabels returns if a label is part of the match criteria.
-	HasRequiredLabels() bool
+	// WithExcludedLabels adds the given labels to the match criteria, requiring that the item has none of them.
+	WithExcludedLabels(labels ...string) Matchinator
+
+	// HasLabels returns if the item's labels are part of the match criteria, either through WithRequiredLabels,
+	// WithExcludedLabels or a selector on a 'label.' key.
+	HasLabels() bool
+
+	// WithAssignees adds the given logins to the match criteria, requiring that the item is assigned to each of them.
+	WithAssignees(logins ...string) Matchinator
+
+	// HasAssignees returns if the item's assignees are part of the match criteria, either through WithAssignees or a
+	// selector on the 'assignees' key.
+	HasAssignees() bool
+
+	// WithExcludeDrafts adds the exclusion of draft pull requests to the match criteria, if exclude is true.
+	WithExcludeDrafts(exclude bool) Matchinator
+
+	// WithUpdatedWithin adds the given window to the match criteria, requiring that the item was updated within it,
+	// relative to when the item is matched. A zero window is ignored.
+	WithUpdatedWithin(window time.Duration) Matchinator
+
+	// WithCreatedWithin adds the given window to the match criteria, requiring that the item was created within it,
+	// relative to when the item is matched. A zero window is ignored.
+	WithCreatedWithin(window time.Duration) Matchinator
+
+	// WithNotUpdatedWithin adds the given window to the match criteria, requiring that the item wasn't updated within
+	// it, relative to when the item is matched. A zero window is ignored.
+	WithNotUpdatedWithin(window time.Duration) Matchinator
+
+	// WithNumberRange adds the given inclusive range of item numbers to the match criteria. A min or max of zero
+	// leaves that end of the range unbounded, so if both are zero, nothing is added.
+	WithNumberRange(min int, max int) Matchinator
+
+	// HasProjectFields returns if a selector targeting a project field value is part of the match criteria.
+	HasProjectFields() bool
+
+	// HasRepositoryMetadata returns if a selector targeting the repository's language or topics is part of the match
+	// criteria.
+	HasRepositoryMetadata() bool
+
+	// WithAuthors adds the given logins to the match criteria, requiring that the item was opened by one of them.
+	WithAuthors(logins ...string) Matchinator
+
+	// WithLastActivityBy adds the given logins to the match criteria, requiring that the item's most recent timeline
+	// activity was by one of them.
+	WithLastActivityBy(logins ...string) Matchinator
+
+	// HasLastActivityBy returns if the actor of the item's most recent timeline activity is part of the match
+	// criteria.
+	HasLastActivityBy() bool
+
+	// WithReferencesIssue adds the given issue number to the match criteria, requiring that the item references the
+	// issue in the same repository. References are parsed from the item's body. If timeline is set, items which
+	// appear as cross-references in the issue's timeline also match, which catches references made outside the
+	// body, such as in comments. A zero number is ignored.
+	WithReferencesIssue(number int, timeline bool) Matchinator
+
+	// HasReferencesIssue returns if a reference to an issue is part of the match criteria.
+	HasReferencesIssue() bool
+
+	// ReferencesIssueTimeline returns the number of the issue whose timeline is checked for cross-references, or
+	// zero if no timeline is checked.
+	ReferencesIssueTimeline() int
+
+	// WithClock sets the Clock used by time-based match criteria.
+	WithClock(clock Clock) Matchinator
+
+	// WithAgeBuckets sets the buckets used to derive each item's AgeBucket before it is matched. If not set,
+	// DefaultAgeBuckets are used.
+	WithAgeBuckets(buckets ...AgeBucket) Matchinator
+
+	// WithTracer sets a function which is called with the result of each call to Matches, including for items which
+	// are filtered out. It allows the reason each item was or wasn't matched to be audited.
+	WithTracer(tracer func(MatchResult)) Matchinator
 
 	// Matches returns a boolean specifying if the GitHubItem matched the configured criteria. If no criteria is
 	// configured, then this function always returns true.
 	Matches(item *GitHubItem) (bool, string)
 }
 
+// MatchResult is the result of matching a GitHubItem with a Matchinator.
+type MatchResult struct {
+	Item    *GitHubItem
+	Matches bool
+	Reason  string
+}
+
+// MatchItems matches each of the given items with the given Matchinator, returning a MatchResult for each.
+func MatchItems(m Matchinator, items []*GitHubItem) []MatchResult {
+	results := []MatchResult{}
+
+	for _, i := range items {
+		matches, reason := m.Matches(i)
+		results = append(results, MatchResult{Item: i, Matches: matches, Reason: reason})
+	}
+
+	return results
+}
+
 // matchinator is the internal implementation of the Matchinator interface.
 type matchinator struct {
-	matchFuncs        []GitHubItemMatcher
-	hasBodyRegex      bool
-	hasRequiredLabels bool
+	matchFuncs         []GitHubItemMatcher
+	hasBodyRegex       bool
+	hasCommentRegex    bool
+	hasLabels          bool
+	hasAssignees       bool
+	hasProjectFields   bool
+	hasRepoMetadata    bool
+	hasLastActivityBy  bool
+	referencesIssue    int
+	referencesTimeline bool
+	updatedWithin      time.Duration
+	createdWithin      time.Duration
+	notUpdatedWithin   time.Duration
+	clock              Clock
+	ageBuckets         []AgeBucket
+	tracer             func(MatchResult)
 }
 
 func (m *matchinator) WithMatchFunc(match GitHubItemMatcher) Matchinator {
@@ -113,6 +516,28 @@ func (m *matchinator) WithMatchFunc(match GitHubItemMatcher) Matchinator {
 	return m
 }
 
+// trackSelectorKeys records the fields the given selector needs to be fetched before an item can be matched.
+func (m *matchinator) trackSelectorKeys(s labels.Selector) {
+	requirements, _ := s.Requirements()
+	for _, r := range requirements {
+		if isProjectFieldKey(r.Key()) {
+			m.hasProjectFields = true
+		}
+
+		if isRepositoryMetadataKey(r.Key()) {
+			m.hasRepoMetadata = true
+		}
+
+		if r.Key() == "assignees" {
+			m.hasAssignees = true
+		}
+
+		if isLabelKey(r.Key()) {
+			m.hasLabels = true
+		}
+	}
+}
+
 func (m *matchinator) WithSelectors(selectors ...labels.Selector) Matchinator {
 	if len(selectors) == 0 {
 		return m
@@ -120,12 +545,39 @@ func (m *matchinator) WithSelectors(selectors ...labels.Selector) Matchinator {
 
 	for _, s := range selectors {
 		m.matchFuncs = append(m.matchFuncs, SelectorAsGitHubItemMatcher(s))
+		m.trackSelectorKeys(s)
 	}
 
 	return m
 }
 
-func (m *matchinator) WithBodyRegexes(bodyRegexes ...*regexp.Regexp) Matchinator {
+func (m *matchinator) WithAnyOf(name string, matchers ...GitHubItemMatcher) Matchinator {
+	if len(matchers) == 0 {
+		return m
+	}
+
+	m.matchFuncs = append(m.matchFuncs, AnyOfGitHubItemMatcher(name, matchers...))
+
+	return m
+}
+
+func (m *matchinator) WithSelectorGroup(name string, selectors []labels.Selector, labels []string) Matchinator {
+	matchers := []GitHubItemMatcher{}
+
+	for _, s := range selectors {
+		matchers = append(matchers, SelectorAsGitHubItemMatcher(s))
+		m.trackSelectorKeys(s)
+	}
+
+	for _, l := range labels {
+		matchers = append(matchers, RequiredLabelAsGitHubItemMatcher(l))
+		m.hasLabels = true
+	}
+
+	return m.WithAnyOf(name, matchers...)
+}
+
+func (m *matchinator) WithBodyRegexes(caseSensitive bool, bodyRegexes ...*regexp.Regexp) Matchinator {
 	if len(bodyRegexes) == 0 {
 		return m
 	}
@@ -133,19 +585,35 @@ func (m *matchinator) WithBodyRegexes(bodyRegexes ...*regexp.Regexp) Matchinator
 	m.hasBodyRegex = true
 
 	for _, r := range bodyRegexes {
-		m.matchFuncs = append(m.matchFuncs, BodyRegexAsGitHubItemMatcher(r))
+		m.matchFuncs = append(m.matchFuncs, BodyRegexAsGitHubItemMatcher(r, caseSensitive))
 	}
 
 	return m
 }
 
-func (m *matchinator) WithTitleRegexes(titleRegexes ...*regexp.Regexp) Matchinator {
+func (m *matchinator) WithTitleRegexes(caseSensitive bool, titleRegexes ...*regexp.Regexp) Matchinator {
 	if len(titleRegexes) == 0 {
 		return m
 	}
 
 	for _, r := range titleRegexes {
-		m.matchFuncs = append(m.matchFuncs, TitleRegexAsGitHubItemMatcher(r))
+		m.matchFuncs = append(m.matchFuncs, TitleRegexAsGitHubItemMatcher(r, caseSensitive))
+	}
+
+	return m
+}
+
+func (m *matchinator) WithTitleStrings(prefix string, suffix string, contains string, caseSensitive bool) Matchinator {
+	if prefix != "" {
+		m.matchFuncs = append(m.matchFuncs, TitlePrefixGitHubItemMatcher(prefix, caseSensitive))
+	}
+
+	if suffix != "" {
+		m.matchFuncs = append(m.matchFuncs, TitleSuffixGitHubItemMatcher(suffix, caseSensitive))
+	}
+
+	if contains != "" {
+		m.matchFuncs = append(m.matchFuncs, TitleContainsGitHubItemMatcher(contains, caseSensitive))
 	}
 
 	return m
@@ -155,12 +623,30 @@ func (m *matchinator) HasBodyRegex() bool {
 	return m.hasBodyRegex
 }
 
+func (m *matchinator) WithCommentRegexes(caseSensitive bool, commentRegexes ...*regexp.Regexp) Matchinator {
+	if len(commentRegexes) == 0 {
+		return m
+	}
+
+	m.hasCommentRegex = true
+
+	for _, r := range commentRegexes {
+		m.matchFuncs = append(m.matchFuncs, CommentRegexAsGitHubItemMatcher(r, caseSensitive))
+	}
+
+	return m
+}
+
+func (m *matchinator) HasCommentRegex() bool {
+	return m.hasCommentRegex
+}
+
 func (m *matchinator) WithRequiredLabels(labels ...string) Matchinator {
 	if len(labels) == 0 {
 		return m
 	}
 
-	m.hasRequiredLabels = true
+	m.hasLabels = true
 
 	for _, l := range labels {
 		m.matchFuncs = append(m.matchFuncs, RequiredLabelAsGitHubItemMatcher(l))
@@ -169,12 +655,200 @@ func (m *matchinator) WithRequiredLabels(labels ...string) Matchinator {
 	return m
 }
 
-func (m *matchinator) HasRequiredLabels() bool {
-	return m.hasRequiredLabels
+func (m *matchinator) WithExcludedLabels(labels ...string) Matchinator {
+	if len(labels) == 0 {
+		return m
+	}
+
+	m.hasLabels = true
+
+	for _, l := range labels {
+		m.matchFuncs = append(m.matchFuncs, ExcludedLabelAsGitHubItemMatcher(l))
+	}
+
+	return m
+}
+
+func (m *matchinator) HasLabels() bool {
+	return m.hasLabels
+}
+
+func (m *matchinator) WithAssignees(logins ...string) Matchinator {
+	if len(logins) == 0 {
+		return m
+	}
+
+	m.hasAssignees = true
+
+	for _, l := range logins {
+		m.matchFuncs = append(m.matchFuncs, AssigneeAsGitHubItemMatcher(l))
+	}
+
+	return m
+}
+
+func (m *matchinator) HasAssignees() bool {
+	return m.hasAssignees
+}
+
+func (m *matchinator) WithAuthors(logins ...string) Matchinator {
+	if len(logins) == 0 {
+		return m
+	}
+
+	m.matchFuncs = append(m.matchFuncs, AuthorGitHubItemMatcher(logins))
+
+	return m
+}
+
+func (m *matchinator) WithLastActivityBy(logins ...string) Matchinator {
+	if len(logins) == 0 {
+		return m
+	}
+
+	m.hasLastActivityBy = true
+	m.matchFuncs = append(m.matchFuncs, LastActivityByGitHubItemMatcher(logins))
+
+	return m
+}
+
+func (m *matchinator) HasLastActivityBy() bool {
+	return m.hasLastActivityBy
+}
+
+func (m *matchinator) WithReferencesIssue(number int, timeline bool) Matchinator {
+	if number == 0 {
+		return m
+	}
+
+	m.referencesIssue = number
+	m.referencesTimeline = timeline
+	m.matchFuncs = append(m.matchFuncs, ReferencesIssueGitHubItemMatcher(number))
+
+	return m
+}
+
+func (m *matchinator) HasReferencesIssue() bool {
+	return m.referencesIssue != 0
+}
+
+func (m *matchinator) ReferencesIssueTimeline() int {
+	if !m.referencesTimeline {
+		return 0
+	}
+
+	return m.referencesIssue
+}
+
+func (m *matchinator) WithExcludeDrafts(exclude bool) Matchinator {
+	if !exclude {
+		return m
+	}
+
+	m.matchFuncs = append(m.matchFuncs, ExcludeDraftsGitHubItemMatcher())
+
+	return m
+}
+
+func (m *matchinator) WithUpdatedWithin(window time.Duration) Matchinator {
+	m.updatedWithin = window
+
+	return m
+}
+
+func (m *matchinator) WithCreatedWithin(window time.Duration) Matchinator {
+	m.createdWithin = window
+
+	return m
+}
+
+func (m *matchinator) WithNotUpdatedWithin(window time.Duration) Matchinator {
+	m.notUpdatedWithin = window
+
+	return m
+}
+
+// timeMatchers returns the matchers of the criteria which are relative to the given time.
+func (m *matchinator) timeMatchers(now time.Time) []GitHubItemMatcher {
+	matchers := []GitHubItemMatcher{}
+
+	if m.updatedWithin > 0 {
+		matchers = append(matchers, UpdatedWithinGitHubItemMatcher(m.updatedWithin, now))
+	}
+
+	if m.createdWithin > 0 {
+		matchers = append(matchers, CreatedWithinGitHubItemMatcher(m.createdWithin, now))
+	}
+
+	if m.notUpdatedWithin > 0 {
+		matchers = append(matchers, NotUpdatedWithinGitHubItemMatcher(m.notUpdatedWithin, now))
+	}
+
+	return matchers
+}
+
+func (m *matchinator) WithNumberRange(min int, max int) Matchinator {
+	if min <= 0 && max <= 0 {
+		return m
+	}
+
+	m.matchFuncs = append(m.matchFuncs, NumberRangeAsGitHubItemMatcher(min, max))
+
+	return m
+}
+
+func (m *matchinator) HasProjectFields() bool {
+	return m.hasProjectFields
+}
+
+func (m *matchinator) HasRepositoryMetadata() bool {
+	return m.hasRepoMetadata
+}
+
+func (m *matchinator) WithClock(clock Clock) Matchinator {
+	m.clock = clock
+
+	return m
+}
+
+func (m *matchinator) WithAgeBuckets(buckets ...AgeBucket) Matchinator {
+	if len(buckets) == 0 {
+		return m
+	}
+
+	m.ageBuckets = buckets
+
+	return m
+}
+
+func (m *matchinator) WithTracer(tracer func(MatchResult)) Matchinator {
+	m.tracer = tracer
+
+	return m
 }
 
 func (m *matchinator) Matches(item *GitHubItem) (bool, string) {
-	for _, m := range m.matchFuncs {
+	matches, reason := m.match(item)
+
+	if m.tracer != nil {
+		m.tracer(MatchResult{Item: item, Matches: matches, Reason: reason})
+	}
+
+	return matches, reason
+}
+
+// match implements Matches, without calling the tracer.
+func (m *matchinator) match(item *GitHubItem) (bool, string) {
+	// Derive the age bucket on a copy, so the given item isn't modified.
+	now := m.clock.Now()
+	withAgeBucket := *item
+	withAgeBucket.AgeBucket = GetAgeBucket(m.ageBuckets, now.Sub(item.CreatedAt))
+	item = &withAgeBucket
+
+	// Time-based matchers are created for each item, so their cutoffs are relative to when it's matched.
+	matchFuncs := append(m.matchFuncs[:len(m.matchFuncs):len(m.matchFuncs)], m.timeMatchers(now)...)
+
+	for _, m := range matchFuncs {
 		if !m.Matcher(item) {
 			return false, fmt.Sprintf("did not match %s", m.Name)
 		}
@@ -187,5 +861,7 @@ func (m *matchinator) Matches(item *GitHubItem) (bool, string) {
 func NewMatchinator() Matchinator {
 	return &matchinator{
 		matchFuncs: []GitHubItemMatcher{},
+		clock:      NewSystemClock(),
+		ageBuckets: DefaultAgeBuckets,
 	}
 }
@@ -1,10 +1,15 @@
 package pkg
 
 import (
+	"fmt"
 	"regexp"
 	"testing"
+	"time"
 
+	"github.com/goccy/go-json"
+	"github.com/shurcooL/githubv4"
 	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/selection"
 )
@@ -29,13 +34,75 @@ func TestBodyRegexAsGitHubItemMatcherCreatesWorkingMatcher(t *testing.T) {
 	item := NewTestGitHubItem()
 	item.Body = "this is my issue description"
 
-	matcher := BodyRegexAsGitHubItemMatcher(regexp.MustCompile("^this"))
+	matcher := BodyRegexAsGitHubItemMatcher(regexp.MustCompile("^this"), false)
 	assert.Equal(t, matcher.Matcher(item), true)
 
 	item.Body = "a description"
 	assert.Equal(t, matcher.Matcher(item), false)
 }
 
+func TestBodyRegexAsGitHubItemMatcherHandlesCase(t *testing.T) {
+	item := NewTestGitHubItem()
+
+	cases := []struct {
+		regex         string
+		caseSensitive bool
+		matches       map[string]bool
+	}{
+		{
+			regex:   "urgent",
+			matches: map[string]bool{"URGENT": true, "urgent": true, "Urgent": true},
+		},
+		{
+			regex:   "URGENT",
+			matches: map[string]bool{"URGENT": true, "urgent": true, "Urgent": true},
+		},
+		{
+			regex:         "URGENT",
+			caseSensitive: true,
+			matches:       map[string]bool{"URGENT": true, "urgent": false, "Urgent": false},
+		},
+		{
+			regex:         "Urgent",
+			caseSensitive: true,
+			matches:       map[string]bool{"URGENT": false, "urgent": false, "Urgent": true},
+		},
+		{
+			// Inline flags still apply.
+			regex:   "(?-i)URGENT",
+			matches: map[string]bool{"URGENT": true, "urgent": false, "Urgent": false},
+		},
+		{
+			regex:         "(?i)urgent",
+			caseSensitive: true,
+			matches:       map[string]bool{"URGENT": true, "urgent": true, "Urgent": true},
+		},
+	}
+
+	for _, c := range cases {
+		matcher := BodyRegexAsGitHubItemMatcher(regexp.MustCompile(c.regex), c.caseSensitive)
+		assert.Equal(t, matcher.Name, fmt.Sprintf("bodyRegex: '%s'", c.regex))
+
+		for body, matches := range c.matches {
+			item.Body = "this is " + body
+			assert.Equal(t, matcher.Matcher(item), matches, "regex %q, body %q", c.regex, body)
+		}
+	}
+}
+
+func TestTitleAndCommentRegexMatchersHandleCase(t *testing.T) {
+	item := NewTestGitHubItem()
+	item.Title = "Can the watchinator subscribe?"
+	item.Comments = []string{"LGTM", "Please Backport"}
+
+	assert.Equal(t, TitleRegexAsGitHubItemMatcher(regexp.MustCompile("^can"), false).Matcher(item), true)
+	assert.Equal(t, TitleRegexAsGitHubItemMatcher(regexp.MustCompile("^can"), true).Matcher(item), false)
+	assert.Equal(t, TitleRegexAsGitHubItemMatcher(regexp.MustCompile("^Can"), true).Matcher(item), true)
+
+	assert.Equal(t, CommentRegexAsGitHubItemMatcher(regexp.MustCompile("^lgtm"), false).Matcher(item), true)
+	assert.Equal(t, CommentRegexAsGitHubItemMatcher(regexp.MustCompile("^lgtm"), true).Matcher(item), false)
+}
+
 func TestRequiredLabelAsGitHubItemMatcherCreatesWorkingMatcher(t *testing.T) {
 	item := NewTestGitHubItem()
 	item.Labels = []string{"a cool label"}
@@ -47,6 +114,134 @@ func TestRequiredLabelAsGitHubItemMatcherCreatesWorkingMatcher(t *testing.T) {
 	assert.Equal(t, matcher.Matcher(item), false)
 }
 
+func TestExcludedLabelAsGitHubItemMatcherCreatesWorkingMatcher(t *testing.T) {
+	item := NewTestGitHubItem()
+	item.Labels = []string{"duplicate"}
+
+	matcher := ExcludedLabelAsGitHubItemMatcher("duplicate")
+	assert.Equal(t, matcher.Matcher(item), false)
+
+	item.Labels = []string{"bug"}
+	assert.Equal(t, matcher.Matcher(item), true)
+}
+
+func TestMatchinatorHasLabelsWithOnlyExcludedLabels(t *testing.T) {
+	assert.Assert(t, !NewMatchinator().HasLabels())
+	assert.Assert(t, NewMatchinator().WithRequiredLabels("bug").HasLabels())
+
+	m := NewMatchinator().WithExcludedLabels("duplicate")
+	assert.Assert(t, m.HasLabels())
+
+	item := NewTestGitHubItem()
+	item.Labels = []string{"bug", "duplicate"}
+
+	matches, reason := m.Matches(item)
+	assert.Equal(t, matches, false)
+	assert.Equal(t, reason, "did not match excludedLabel: 'duplicate'")
+}
+
+func TestAnyOfGitHubItemMatcherMatchesIfAnyChildMatches(t *testing.T) {
+	item := NewTestGitHubItem()
+	item.Labels = []string{"bar"}
+
+	matcher := AnyOfGitHubItemMatcher(
+		"foo-or-bar", RequiredLabelAsGitHubItemMatcher("foo"), RequiredLabelAsGitHubItemMatcher("bar"),
+	)
+	assert.Equal(t, matcher.Matcher(item), true)
+	assert.Equal(t, matcher.Name, "anyOf 'foo-or-bar': [requiredLabel: 'foo', requiredLabel: 'bar']")
+
+	item.Labels = []string{"baz"}
+	assert.Equal(t, matcher.Matcher(item), false)
+
+	// The group is ANDed with the rest of the criteria, and its name is part of the reason it didn't match.
+	m := NewMatchinator().
+		WithAnyOf("foo-or-bar", RequiredLabelAsGitHubItemMatcher("foo"), RequiredLabelAsGitHubItemMatcher("bar"))
+
+	matches, reason := m.Matches(item)
+	assert.Equal(t, matches, false)
+	assert.Assert(t, cmp.Contains(reason, "anyOf 'foo-or-bar'"))
+}
+
+func TestMatchinatorWithSelectorGroupTracksFields(t *testing.T) {
+	selector := labels.SelectorFromSet(labels.Set{"assignees": "a"})
+
+	m := NewMatchinator().WithSelectorGroup("group", []labels.Selector{selector}, nil)
+	assert.Assert(t, m.HasAssignees())
+	assert.Assert(t, !m.HasLabels())
+
+	m = NewMatchinator().WithSelectorGroup("group", nil, []string{"foo"})
+	assert.Assert(t, m.HasLabels())
+}
+
+func TestNumberRangeAsGitHubItemMatcherIsInclusive(t *testing.T) {
+	item := NewTestGitHubItem()
+
+	cases := []struct {
+		min     int
+		max     int
+		matches map[int]bool
+	}{
+		{min: 1000, matches: map[int]bool{999: false, 1000: true, 20000: true}},
+		{max: 1000, matches: map[int]bool{3: true, 1000: true, 1001: false}},
+		{min: 10, max: 20, matches: map[int]bool{9: false, 10: true, 20: true, 21: false}},
+	}
+
+	for _, c := range cases {
+		matcher := NumberRangeAsGitHubItemMatcher(c.min, c.max)
+
+		for number, matches := range c.matches {
+			item.Number = number
+			assert.Equal(t, matcher.Matcher(item), matches, "range [%d, %d], number %d", c.min, c.max, number)
+		}
+	}
+
+	matches, reason := NewMatchinator().WithNumberRange(1000, 0).Matches(item)
+	assert.Equal(t, matches, false)
+	assert.Equal(t, reason, "did not match numberRange: [1000, 0]")
+}
+
+func TestMatchinatorWithTimeWindows(t *testing.T) {
+	now := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	item := NewTestGitHubItem()
+	item.CreatedAt = now.Add(-10 * 24 * time.Hour)
+	item.UpdatedAt = now.Add(-time.Hour)
+
+	m := NewMatchinator().WithClock(NewFakeClock(now)).WithUpdatedWithin(72 * time.Hour)
+	matches, reason := m.Matches(item)
+	assert.Assert(t, matches, reason)
+
+	m = m.WithCreatedWithin(7 * 24 * time.Hour)
+	matches, reason = m.Matches(item)
+	assert.Equal(t, matches, false)
+	assert.Equal(t, reason, "did not match createdWithin: since 2024-01-03T00:00:00Z")
+
+	// Items created exactly at the cutoff are within the window.
+	item.CreatedAt = now.Add(-7 * 24 * time.Hour)
+	matches, reason = m.Matches(item)
+	assert.Assert(t, matches, reason)
+
+	item.UpdatedAt = now.Add(-96 * time.Hour)
+	matches, reason = m.Matches(item)
+	assert.Equal(t, matches, false)
+	assert.Equal(t, reason, "did not match updatedWithin: since 2024-01-07T00:00:00Z")
+}
+
+func TestMatchinatorWithNotUpdatedWithin(t *testing.T) {
+	now := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	item := NewTestGitHubItem()
+	item.UpdatedAt = now.Add(-45 * 24 * time.Hour)
+
+	m := NewMatchinator().WithClock(NewFakeClock(now)).WithNotUpdatedWithin(30 * 24 * time.Hour)
+	matches, reason := m.Matches(item)
+	assert.Assert(t, matches, reason)
+
+	// Items updated exactly at the cutoff were updated within the window.
+	item.UpdatedAt = now.Add(-30 * 24 * time.Hour)
+	matches, reason = m.Matches(item)
+	assert.Equal(t, matches, false)
+	assert.Equal(t, reason, "did not match notUpdatedWithin: before 2024-01-01T00:00:00Z")
+}
+
 func TestEmptyMatchinatorAlwaysMatches(t *testing.T) {
 	item := NewTestGitHubItem()
 	matchinator := NewMatchinator()
@@ -70,3 +265,303 @@ func TestCanAddMatcherToMatchinator(t *testing.T) {
 	matches, _ := matchinator.Matches(item)
 	assert.Equal(t, matches, false)
 }
+
+func TestSelectorCanTargetProjectFieldValues(t *testing.T) {
+	item := NewTestGitHubItem()
+	item.ProjectFieldValues = []GitHubProjectFieldValue{
+		{ProjectNumber: 3, Field: "Severity", Value: "high"},
+		{ProjectNumber: 4, Field: "Effort Estimate", Value: "small"},
+	}
+
+	set := GitHubItemAsLabelSet(item)
+	assert.Equal(t, set.Get("project.3.field.severity"), "high")
+	assert.Equal(t, set.Get("project.4.field.effort-estimate"), "small")
+
+	selector, err := labels.Parse("project.3.field.severity==high")
+	assert.NilError(t, err)
+
+	matchinator := NewMatchinator().WithSelectors(selector)
+	assert.Equal(t, matchinator.HasProjectFields(), true)
+
+	matches, _ := matchinator.Matches(item)
+	assert.Equal(t, matches, true)
+
+	item.ProjectFieldValues[0].Value = "low"
+	matches, _ = matchinator.Matches(item)
+	assert.Equal(t, matches, false)
+
+	selector, err = labels.Parse("type==issue")
+	assert.NilError(t, err)
+	assert.Equal(t, NewMatchinator().WithSelectors(selector).HasProjectFields(), false)
+}
+
+func TestSelectorCanTargetLabels(t *testing.T) {
+	assert.Equal(t, gitHubLabelKey("kind/bug"), "label.kind/bug")
+	assert.Equal(t, gitHubLabelKey("Good First Issue"), "label.good-first-issue")
+	assert.Equal(t, gitHubLabelKey("a/test/label"), "label.a/test-label")
+
+	item := NewTestGitHubItem()
+	item.Labels = []string{"kind/bug", "Good First Issue"}
+
+	set := GitHubItemAsLabelSet(item)
+	assert.Equal(t, set.Get("label.kind/bug"), "true")
+	assert.Equal(t, set.Get("label.good-first-issue"), "true")
+
+	selector, err := labels.Parse("label.kind/bug=true,!label.wontfix")
+	assert.NilError(t, err)
+
+	matchinator := NewMatchinator().WithSelectors(selector)
+	assert.Equal(t, matchinator.HasLabels(), true)
+
+	matches, _ := matchinator.Matches(item)
+	assert.Equal(t, matches, true)
+
+	item.Labels = []string{"kind/bug", "wontfix"}
+	matches, _ = matchinator.Matches(item)
+	assert.Equal(t, matches, false)
+
+	selector, err = labels.Parse("type==issue")
+	assert.NilError(t, err)
+	assert.Equal(t, NewMatchinator().WithSelectors(selector).HasLabels(), false)
+}
+
+func TestExcludeDraftsGitHubItemMatcherSkipsDraftPullRequests(t *testing.T) {
+	matcher := ExcludeDraftsGitHubItemMatcher()
+
+	issue := NewTestGitHubItem()
+	assert.Equal(t, matcher.Matcher(issue), true)
+	assert.Equal(t, GitHubItemAsLabelSet(issue).Get("draft"), "false")
+
+	pr := NewTestGitHubItem()
+	pr.Type = GitHubItemPullRequest
+	pr.PullRequest = &GitHubPullRequest{IsDraft: false}
+	assert.Equal(t, matcher.Matcher(pr), true)
+	assert.Equal(t, GitHubItemAsLabelSet(pr).Get("draft"), "false")
+
+	pr.PullRequest.IsDraft = true
+	assert.Equal(t, matcher.Matcher(pr), false)
+	assert.Equal(t, GitHubItemAsLabelSet(pr).Get("draft"), "true")
+
+	matches, _ := NewMatchinator().WithExcludeDrafts(false).Matches(pr)
+	assert.Equal(t, matches, true)
+
+	matches, _ = NewMatchinator().WithExcludeDrafts(true).Matches(pr)
+	assert.Equal(t, matches, false)
+}
+
+func TestLastActivityByGitHubItemMatcherCreatesWorkingMatcher(t *testing.T) {
+	item := NewTestGitHubItem()
+	matcher := LastActivityByGitHubItemMatcher([]string{"maintainer", "Another-Maintainer"})
+
+	item.LastActivityBy = "maintainer"
+	assert.Equal(t, matcher.Matcher(item), true)
+
+	item.LastActivityBy = "another-maintainer"
+	assert.Equal(t, matcher.Matcher(item), true)
+
+	item.LastActivityBy = "someone-else"
+	assert.Equal(t, matcher.Matcher(item), false)
+
+	// Items without activity are never matched.
+	item.LastActivityBy = ""
+	assert.Equal(t, matcher.Matcher(item), false)
+
+	assert.Equal(t, NewMatchinator().HasLastActivityBy(), false)
+	assert.Equal(t, NewMatchinator().WithLastActivityBy("maintainer").HasLastActivityBy(), true)
+}
+
+func TestSelectorCanTargetAuthorAssociation(t *testing.T) {
+	item := NewTestGitHubItem()
+	item.AuthorAssociation = githubv4.CommentAuthorAssociationFirstTimeContributor
+
+	assert.Equal(t, GitHubItemAsLabelSet(item).Get("author.association"), "FIRST_TIME_CONTRIBUTOR")
+	assert.Equal(t, isGitHubItemField("author.association"), true)
+
+	selector, err := labels.Parse("author.association==FIRST_TIME_CONTRIBUTOR")
+	assert.NilError(t, err)
+
+	matches, _ := NewMatchinator().WithSelectors(selector).Matches(item)
+	assert.Equal(t, matches, true)
+
+	item.AuthorAssociation = githubv4.CommentAuthorAssociationMember
+	matches, _ = NewMatchinator().WithSelectors(selector).Matches(item)
+	assert.Equal(t, matches, false)
+}
+
+func TestAssigneeAsGitHubItemMatcherCreatesWorkingMatcher(t *testing.T) {
+	item := NewTestGitHubItem()
+	matcher := AssigneeAsGitHubItemMatcher("Alice")
+
+	assert.Equal(t, matcher.Matcher(item), false)
+	_, ok := GitHubItemAsLabelSet(item)["assignees"]
+	assert.Equal(t, ok, false)
+
+	item.Assignees = []string{"bob", "alice"}
+	assert.Equal(t, matcher.Matcher(item), true)
+	assert.Equal(t, GitHubItemAsLabelSet(item).Get("assignees"), "bob,alice")
+
+	assert.Equal(t, NewMatchinator().HasAssignees(), false)
+	assert.Equal(t, NewMatchinator().WithAssignees("alice").HasAssignees(), true)
+
+	selector, err := labels.Parse("!assignees")
+	assert.NilError(t, err)
+
+	m := NewMatchinator().WithSelectors(selector)
+	assert.Equal(t, m.HasAssignees(), true)
+
+	matches, _ := m.Matches(item)
+	assert.Equal(t, matches, false)
+
+	matches, _ = m.Matches(NewTestGitHubItem())
+	assert.Equal(t, matches, true)
+}
+
+func TestMatchItemsFromJSON(t *testing.T) {
+	w := NewTestWatch()
+	w.Selectors = []string{"number>1"}
+	w.RequiredLabels = []string{"bug"}
+	w.BodyRegex = []string{}
+	w.TitleRegex = []string{"^fix"}
+	assert.NilError(t, w.PopulateMatchers())
+
+	items := []*GitHubItem{}
+	assert.NilError(t, json.Unmarshal([]byte(`[
+		{"number": 1, "title": "fix one", "labels": ["bug"], "type": "issue", "repo": {"owner": "o", "name": "r"}},
+		{"number": 2, "title": "fix two", "labels": ["bug"], "type": "issue", "repo": {"owner": "o", "name": "r"}},
+		{"number": 3, "title": "fix three", "labels": [], "type": "issue", "repo": {"owner": "o", "name": "r"}}
+	]`), &items))
+
+	results := MatchItems(w.GetMatchinator(), items)
+	assert.Equal(t, len(results), 3)
+	assert.Equal(t, results[0].Matches, false)
+	assert.Assert(t, cmp.Contains(results[0].Reason, "selector"))
+	assert.Equal(t, results[1].Matches, true)
+	assert.Equal(t, results[2].Matches, false)
+	assert.Assert(t, cmp.Contains(results[2].Reason, "bug"))
+}
+
+func TestSelectorMatchesRepositoryLanguageAndTopics(t *testing.T) {
+	item := NewTestGitHubItem()
+	item.Repo.Language = "Go"
+	item.Repo.Topics = []string{"kubernetes", "cli"}
+
+	for selector, expected := range map[string]bool{
+		"repo.language==Go":              true,
+		"repo.language==Rust":            false,
+		"repo.topic==cli":                true,
+		"repo.topic in (web,kubernetes)": true,
+		"repo.topic==web":                false,
+		"repo.topic!=web":                true,
+		"repo.topic!=cli":                false,
+		"repo.topic notin (web,docs)":    true,
+		"repo.topic":                     true,
+		"!repo.topic":                    false,
+		"repo.topic==cli,number==2":      false,
+	} {
+		s, err := labels.Parse(selector)
+		assert.NilError(t, err)
+
+		m := NewMatchinator().WithSelectors(s)
+		assert.Equal(t, m.HasRepositoryMetadata(), true, selector)
+
+		matches, _ := m.Matches(item)
+		assert.Equal(t, matches, expected, selector)
+	}
+
+	// Repositories without a detected language or topics.
+	item.Repo.Language = ""
+	item.Repo.Topics = []string{}
+
+	for selector, expected := range map[string]bool{
+		"repo.language==Go": false,
+		"repo.language==":   true,
+		"repo.topic==cli":   false,
+		"repo.topic!=cli":   true,
+		"!repo.topic":       true,
+	} {
+		s, err := labels.Parse(selector)
+		assert.NilError(t, err)
+
+		matches, _ := NewMatchinator().WithSelectors(s).Matches(item)
+		assert.Equal(t, matches, expected, selector)
+	}
+
+	s, err := labels.Parse("number==1")
+	assert.NilError(t, err)
+	assert.Equal(t, NewMatchinator().WithSelectors(s).HasRepositoryMetadata(), false)
+}
+
+func TestSelectorMatchesAgeBucket(t *testing.T) {
+	now := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(now)
+	item := NewTestGitHubItem()
+
+	for age, expected := range map[time.Duration]string{
+		time.Hour:           "new",
+		2 * 24 * time.Hour:  "week",
+		7 * 24 * time.Hour:  "month",
+		30 * 24 * time.Hour: "stale",
+	} {
+		item.CreatedAt = now.Add(-age)
+
+		s, err := labels.Parse("ageBucket==" + expected)
+		assert.NilError(t, err)
+
+		matches, reason := NewMatchinator().WithClock(clock).WithSelectors(s).Matches(item)
+		assert.Assert(t, matches, "expected item of age %s to be in bucket %s: %s", age, expected, reason)
+		assert.Equal(t, item.AgeBucket, "", "expected item not to be modified")
+	}
+
+	// Custom buckets.
+	item.CreatedAt = now.Add(-2 * time.Hour)
+
+	s, err := labels.Parse("ageBucket in (fresh)")
+	assert.NilError(t, err)
+
+	matches, _ := NewMatchinator().
+		WithClock(clock).
+		WithSelectors(s).
+		WithAgeBuckets(AgeBucket{Name: "fresh", MaxAge: 4 * time.Hour}, AgeBucket{Name: "old"}).
+		Matches(item)
+	assert.Assert(t, matches)
+}
+
+func TestTitleStringGitHubItemMatchersCreateWorkingMatchers(t *testing.T) {
+	item := NewTestGitHubItem()
+	item.Title = "[RFC] Add a (WIP) cache.*"
+
+	for _, tc := range []struct {
+		matcher GitHubItemMatcher
+		matches bool
+	}{
+		{TitlePrefixGitHubItemMatcher("[RFC]", false), true},
+		{TitlePrefixGitHubItemMatcher("[rfc]", false), true},
+		{TitlePrefixGitHubItemMatcher("[rfc]", true), false},
+		{TitlePrefixGitHubItemMatcher("RFC", false), false},
+		{TitleSuffixGitHubItemMatcher("cache.*", false), true},
+		{TitleSuffixGitHubItemMatcher("CACHE.*", true), false},
+		{TitleSuffixGitHubItemMatcher("cache", false), false},
+		{TitleContainsGitHubItemMatcher("(wip)", false), true},
+		{TitleContainsGitHubItemMatcher("(WIP)", true), true},
+		{TitleContainsGitHubItemMatcher("(wip)", true), false},
+		{TitleContainsGitHubItemMatcher("wip cache", false), false},
+	} {
+		assert.Equal(t, tc.matcher.Matcher(item), tc.matches, tc.matcher.Name)
+	}
+}
+
+func TestMatchinatorWithTitleStringsRequiresAll(t *testing.T) {
+	item := NewTestGitHubItem()
+	item.Title = "[RFC] Add a cache (WIP)"
+
+	matches, _ := NewMatchinator().WithTitleStrings("[rfc]", "(wip)", "", false).Matches(item)
+	assert.Equal(t, matches, true)
+
+	matches, reason := NewMatchinator().WithTitleStrings("[RFC]", "", "queue", false).Matches(item)
+	assert.Equal(t, matches, false)
+	assert.Equal(t, reason, "did not match titleContains: 'queue'")
+
+	// Empty values are ignored.
+	matches, _ = NewMatchinator().WithTitleStrings("", "", "", true).Matches(item)
+	assert.Equal(t, matches, true)
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
 )
 
 var (
@@ -24,6 +25,30 @@ var (
 			Help: "The total number of errors observed when subscribing to new items on GitHub",
 		},
 	)
+	MetricAddLabelsTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "watchinator_add_labels_total",
+			Help: "The total number of times labels have been added to items on GitHub",
+		},
+	)
+	MetricAddLabelsErrorTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "watchinator_add_labels_error_total",
+			Help: "The total number of errors observed when adding labels to items on GitHub",
+		},
+	)
+	MetricAddCommentTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "watchinator_add_comment_total",
+			Help: "The total number of times comments have been added to items on GitHub",
+		},
+	)
+	MetricAddCommentErrorTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "watchinator_add_comment_error_total",
+			Help: "The total number of errors observed when adding comments to items on GitHub",
+		},
+	)
 	MetricFilteredTotal = promauto.NewCounter(
 		prometheus.CounterOpts{
 			Name: "watchinator_filtered_items_total",
@@ -56,6 +81,40 @@ var (
 		},
 		[]string{"watch"},
 	)
+	MetricReportTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "watchinator_report_total",
+			Help: "The total number of scheduled reports sent",
+		},
+		[]string{"watch"},
+	)
+	MetricReportErrorTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "watchinator_report_error_total",
+			Help: "The total number of errors that have occurred while sending a scheduled report",
+		},
+		[]string{"watch"},
+	)
+	MetricWatchTag = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "watchinator_watch_tag",
+			Help: "Set to 1 for each tag of each watch, allowing per-watch metrics to be joined with their tags",
+		},
+		[]string{"watch", "key", "value"},
+	)
+	MetricPollBackoffSeconds = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "watchinator_poll_backoff_seconds",
+			Help: "The time a poll waits until its next tick after consecutive errors, or 0 if its last tick succeeded",
+		},
+		[]string{"poll"},
+	)
+	MetricPaused = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "watchinator_paused",
+			Help: "Set to 1 while polls are paused, 0 otherwise",
+		},
+	)
 	MetricRepoQueryTotal = promauto.NewCounter(
 		prometheus.CounterOpts{
 			Name: "watchinator_repo_query_total",
@@ -80,6 +139,30 @@ var (
 			Help: "The total number of errors observed during issue queries against GitHub",
 		},
 	)
+	MetricPullRequestQueryTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "watchinator_pull_request_query_total",
+			Help: "The total number of pull request queries that have been made against GitHub",
+		},
+	)
+	MetricPullRequestQueryErrorTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "watchinator_pull_request_query_error_total",
+			Help: "The total number of errors observed during pull request queries against GitHub",
+		},
+	)
+	MetricDiscussionQueryTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "watchinator_discussion_query_total",
+			Help: "The total number of discussion queries that have been made against GitHub",
+		},
+	)
+	MetricDiscussionQueryErrorTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "watchinator_discussion_query_error_total",
+			Help: "The total number of errors observed during discussion queries against GitHub",
+		},
+	)
 	MetricIssueLabelQueryTotal = promauto.NewCounter(
 		prometheus.CounterOpts{
 			Name: "watchinator_issue_label_query_total",
@@ -92,6 +175,30 @@ var (
 			Help: "The total number of errors observed during issue label queries against GitHub",
 		},
 	)
+	MetricIssueAssigneeQueryTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "watchinator_issue_assignee_query_total",
+			Help: "The total number of issue assignee queries that have been made against GitHub",
+		},
+	)
+	MetricIssueAssigneeQueryErrorTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "watchinator_issue_assignee_query_error_total",
+			Help: "The total number of errors observed during issue assignee queries against GitHub",
+		},
+	)
+	MetricIssueCommentQueryTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "watchinator_issue_comment_query_total",
+			Help: "The total number of issue comment queries that have been made against GitHub",
+		},
+	)
+	MetricIssueCommentQueryErrorTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "watchinator_issue_comment_query_error_total",
+			Help: "The total number of errors observed during issue comment queries against GitHub",
+		},
+	)
 	MetricIssueBodyQueryTotal = promauto.NewCounter(
 		prometheus.CounterOpts{
 			Name: "watchinator_issue_body_query_total",
@@ -104,6 +211,67 @@ var (
 			Help: "The total number of errors observed during issue body queries against GitHub",
 		},
 	)
+	MetricGitHubQueryDurationSeconds = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "watchinator_github_query_duration_seconds",
+			Help:    "The wall-clock duration of queries made against GitHub, labeled by query type",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"query"},
+	)
+	MetricGitHubRateLimitRemaining = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "watchinator_github_rate_limit_remaining",
+			Help: "The number of points remaining in the GitHub GraphQL API rate limit, as of the last query",
+		},
+	)
+	MetricGitHubQueryCostTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "watchinator_github_query_cost_total",
+			Help: "The total rate limit cost of queries made against GitHub, labeled by query type",
+		}, []string{"query"},
+	)
+	MetricRateLimitBackoffTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "watchinator_github_rate_limit_backoff_total",
+			Help: "The total number of requests to GitHub which were rate limited and retried after backing off",
+		},
+	)
+	MetricIssueProjectFieldQueryTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "watchinator_issue_project_field_query_total",
+			Help: "The total number of issue project field queries that have been made against GitHub",
+		},
+	)
+	MetricIssueProjectFieldQueryErrorTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "watchinator_issue_project_field_query_error_total",
+			Help: "The total number of errors observed during issue project field queries against GitHub",
+		},
+	)
+	MetricIssueTimelineQueryTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "watchinator_issue_timeline_query_total",
+			Help: "The total number of issue timeline queries that have been made against GitHub",
+		},
+	)
+	MetricIssueTimelineQueryErrorTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "watchinator_issue_timeline_query_error_total",
+			Help: "The total number of errors observed during issue timeline queries against GitHub",
+		},
+	)
+	MetricHTTPRetryTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "watchinator_http_retry_total",
+			Help: "The total number of HTTP requests retried due to their response status, labeled by status class",
+		}, []string{"class"},
+	)
+	MetricHTTPPermanentFailureTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "watchinator_http_permanent_failure_total",
+			Help: "The total number of HTTP requests which failed with a non-retryable status, labeled by status class",
+		}, []string{"class"},
+	)
 	MetricActionHandleTotal = promauto.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "watchinator_action_handle_total",
@@ -116,8 +284,57 @@ var (
 			Help: "The total number of times an error occurred during an action handler execution",
 		}, []string{"action"},
 	)
+	MetricNotificationsDropped = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "watchinator_notifications_dropped_total",
+			Help: "The total number of items dropped from a full notification buffer, labeled by action name and " +
+				"drop policy",
+		}, []string{"action", "policy"},
+	)
+	MetricActionSkippedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "watchinator_action_skipped_total",
+			Help: "The total number of times an action was not performed on a matched item, labeled by action " +
+				"and reason",
+		}, []string{"action", "reason"},
+	)
+	MetricActionRetryTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "watchinator_action_retry_total",
+			Help: "The total number of times a failed action was retried, labeled by action name",
+		}, []string{"action"},
+	)
+	MetricActionDryRunTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "watchinator_action_dryrun_total",
+			Help: "The total number of times an action would have been performed in dry-run mode, labeled by " +
+				"action name",
+		}, []string{"action"},
+	)
 )
 
+// CounterValue returns the current value of the given counter. If the value cannot be read, zero is returned.
+func CounterValue(c prometheus.Counter) float64 {
+	m := &dto.Metric{}
+
+	if err := c.Write(m); err != nil {
+		return 0
+	}
+
+	return m.GetCounter().GetValue()
+}
+
+// GaugeValue returns the current value of the given gauge. If the value cannot be read, zero is returned.
+func GaugeValue(g prometheus.Gauge) float64 {
+	m := &dto.Metric{}
+
+	if err := g.Write(m); err != nil {
+		return 0
+	}
+
+	return m.GetGauge().GetValue()
+}
+
 // ServePromEndpoint creates a new http server which serves prometheus metrics at :2112/metrics.
 func ServePromEndpoint(ctx context.Context) {
 	http.Handle("/metrics", promhttp.Handler())
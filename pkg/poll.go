@@ -2,18 +2,34 @@ package pkg
 
 import (
 	"context"
+	"math/rand"
+	"sync"
 	"time"
 
 	"golang.org/x/exp/slog"
 )
 
+// DefaultMaxPollBackoff is the longest a poll waits between callbacks after consecutive errors, unless its interval
+// is longer.
+const DefaultMaxPollBackoff = time.Hour
+
 // Pollinator handles executing functions on a ticker.
 type Pollinator interface {
 	// Add creates a new poll, whose callback will be executed on the given interval.
 	// If the given poll already exists, then it is updated with the given interval and callback.
 	// The argument doInitialCallback can be used to toggle if the poll is executed for the first time after the
 	// call to Add, or if the poll is executed for the first time after the given interval.
-	Add(name string, interval time.Duration, callback func(t time.Time), doInitialCallback bool)
+	// If the callback returns an error, the poll backs off: after n consecutive errors, the next callback is executed
+	// after 2^n intervals, up to DefaultMaxPollBackoff. The interval is restored once the callback succeeds.
+	Add(name string, interval time.Duration, callback func(t time.Time) error, doInitialCallback bool)
+
+	// AddWithOffset is like Add, but the poll starts after the given offset: the initial callback, if enabled, is
+	// executed after the offset, and ticks follow every interval from then on. Giving polls on the same interval
+	// different offsets spreads their callbacks across the interval, see SpreadOffsets.
+	AddWithOffset(
+		name string, interval time.Duration, offset time.Duration, callback func(t time.Time) error,
+		doInitialCallback bool,
+	)
 
 	// Delete removes the poll by the given name. If it doesn't exist, then this is a no-op.
 	Delete(name string)
@@ -23,10 +39,31 @@ type Pollinator interface {
 
 	// StopAll stops all the added polls, blocking until all exit. Use this as a cleanup.
 	StopAll()
+
+	// WithClock sets the Clock used to create tickers for polls added afterwards.
+	WithClock(clock Clock) Pollinator
+
+	// WithJitter delays the start of polls added afterwards by a random amount of up to the given fraction of their
+	// interval, on top of any offset. This keeps polls on the same interval from firing in lockstep. A jitter of
+	// zero, the default, disables it.
+	WithJitter(jitter float64) Pollinator
+
+	// Pause stops the ticker of every poll without deleting them. Polls added while paused are not started until
+	// Resume is called. If already paused, this is a no-op.
+	Pause()
+
+	// Resume restarts the ticker of every poll stopped by Pause. The first callback of each poll is executed after
+	// its offset and interval. If not paused, this is a no-op.
+	Resume()
+
+	// Paused returns if the polls are currently paused.
+	Paused() bool
 }
 
 // poll holds information necessary for running a new ticker in a separate go-routine.
 type poll struct {
+	// name is the name the poll was added with, used to label its metrics.
+	name string
 	// cancelChan can be closed to cancel the poll.
 	cancelChan chan bool
 	// doneChan signals that the poll has been cleaned up.
@@ -36,44 +73,123 @@ type poll struct {
 	// callbackOnStart determines if the callback should be executed as soon as the poll starts or if the
 	// callback should only be executed after an initial interval.
 	callbackOnStart bool
-	logger          *slog.Logger
-	ticker          *time.Ticker
-	callback        func(t time.Time)
+	interval        time.Duration
+	// offset is the amount of time to wait before the poll starts.
+	offset time.Duration
+	logger *slog.Logger
+	clock  Clock
+	// ticker fires on the poll's interval. If the poll has an offset, it first fires once after the offset, and is
+	// then replaced by runPoll, which also replaces it while backing off. It is only stopped by runPoll.
+	ticker   Ticker
+	callback func(t time.Time) error
+	// maxBackoff is the longest the poll waits between callbacks after consecutive errors. If it is shorter than the
+	// interval, the poll doesn't back off.
+	maxBackoff time.Duration
+}
+
+// getBackoff returns how long the poll waits until its next callback after the given number of consecutive errors.
+// The wait doubles with each error, up to the poll's maxBackoff.
+func (p *poll) getBackoff(errors int) time.Duration {
+	backoff := p.interval
+
+	for i := 0; i < errors && backoff < p.maxBackoff; i++ {
+		backoff *= 2
+	}
+
+	return min(backoff, max(p.maxBackoff, p.interval))
+}
+
+// resetTicker replaces the poll's ticker with one which ticks on the given duration.
+func (p *poll) resetTicker(d time.Duration) {
+	p.ticker.Stop()
+	p.ticker = p.clock.NewTicker(d)
 }
 
 // runPoll is meant to be started within a go-routine. On every tick, the poll's callback is executed. If the
-// poll's context is cancelled or cancelChan is closed, the function returns.
+// callback returns an error, the ticker is slowed down according to getBackoff until the callback succeeds again. If
+// the poll's context is cancelled or cancelChan is closed, the function returns.
 func runPoll(p *poll) {
+	defer close(p.doneChan)
+	defer func() { p.ticker.Stop() }()
+
+	backoffMetric := MetricPollBackoffSeconds.WithLabelValues(p.name)
+	backoffMetric.Set(0)
+
+	defer MetricPollBackoffSeconds.DeleteLabelValues(p.name)
+
 	p.logger.Debug("starting poller")
 
+	if p.offset > 0 {
+		p.logger.Debug("waiting for offset", "offset", p.offset)
+
+		select {
+		case <-p.ctx.Done():
+			p.logger.Debug("closing poll, context closed", LogKeyError, p.ctx.Err())
+
+			return
+		case <-p.cancelChan:
+			p.logger.Debug("closing poll, cancel chan closed")
+
+			return
+		case <-p.ticker.C():
+		}
+
+		p.resetTicker(p.interval)
+	}
+
+	errors := 0
+	current := p.interval
+
+	// run executes the callback, replacing the ticker whenever the time until the next callback changes.
+	run := func(t time.Time) {
+		next := p.interval
+
+		if err := p.callback(t); err != nil {
+			errors++
+			next = p.getBackoff(errors)
+
+			p.logger.Warn("poll failed, backing off", LogKeyError, err, "errors", errors, "backoff", next)
+			backoffMetric.Set(next.Seconds())
+		} else if errors > 0 {
+			p.logger.Info("poll recovered after errors, restoring interval", "errors", errors)
+
+			errors = 0
+
+			backoffMetric.Set(0)
+		}
+
+		if next != current {
+			p.resetTicker(next)
+			current = next
+		}
+	}
+
 	if p.callbackOnStart {
 		p.logger.Debug("running initial callback on start")
-		p.callback(time.Now())
+		run(p.clock.Now())
 	}
 
 	for {
 		select {
 		case <-p.ctx.Done():
 			p.logger.Debug("closing poll, context closed", LogKeyError, p.ctx.Err())
-			close(p.doneChan)
 
 			return
 		case <-p.cancelChan:
 			p.logger.Debug("closing poll, cancel chan closed")
-			close(p.doneChan)
 
 			return
-		case t := <-p.ticker.C:
+		case t := <-p.ticker.C():
 			p.logger.Debug("new tick", "time", t)
-			p.callback(t)
+			run(t)
 		}
 	}
 }
 
-// stopPoll stops the given poll by closing its cancelChan. It blocks until the poll's doneChan is closed.
+// stopPoll stops the given poll by closing its cancelChan. It blocks until the poll's doneChan is closed, by which
+// point its ticker has been stopped.
 func stopPoll(p *poll) {
 	p.logger.Debug("stopping poller")
-	p.ticker.Stop()
 	close(p.cancelChan)
 
 	p.logger.Debug("waiting for done")
@@ -92,30 +208,91 @@ type pollinator struct {
 	polls map[string]*poll
 	// logger is the base logger passed to all polls.
 	logger *slog.Logger
+	// clock is used to create the ticker for each poll.
+	clock Clock
+	// maxBackoff is the longest each poll waits between callbacks after consecutive errors.
+	maxBackoff time.Duration
+	// jitter is the fraction of a poll's interval its start is randomly delayed by, see WithJitter.
+	jitter float64
+	// random returns a random number in [0.0,1.0), used to pick each poll's jitter.
+	random func() float64
+	// paused is true if the polls have been stopped with Pause.
+	paused bool
+	// lock guards polls, paused and jitter.
+	lock *sync.Mutex
 }
 
-func (p *pollinator) Add(name string, interval time.Duration, callback func(t time.Time), doInitialCallback bool) {
-	_, ok := p.polls[name]
-	if ok {
-		p.Delete(name)
-	}
-
+// newPoll creates a new poll struct with the given parameters. If the pollinator is paused, the poll's ticker is
+// not created and the poll is marked as done, so it can be started later by Resume.
+func (p *pollinator) newPoll(
+	name string, interval time.Duration, offset time.Duration, callback func(t time.Time) error, doInitialCallback bool,
+) *poll {
 	newPoll := &poll{
+		name:            name,
 		cancelChan:      make(chan bool),
 		doneChan:        make(chan bool),
 		ctx:             p.ctx,
 		logger:          p.logger.With("name", name),
-		ticker:          time.NewTicker(interval),
+		clock:           p.clock,
+		interval:        interval,
+		offset:          offset,
 		callbackOnStart: doInitialCallback,
 		callback:        callback,
+		maxBackoff:      p.maxBackoff,
+	}
+
+	if p.paused {
+		close(newPoll.cancelChan)
+		close(newPoll.doneChan)
+
+		return newPoll
 	}
 
-	p.polls[name] = newPoll
+	if offset > 0 {
+		newPoll.ticker = p.clock.NewTicker(offset)
+	} else {
+		newPoll.ticker = p.clock.NewTicker(interval)
+	}
 
 	go runPoll(newPoll)
+
+	return newPoll
+}
+
+func (p *pollinator) Add(
+	name string, interval time.Duration, callback func(t time.Time) error, doInitialCallback bool,
+) {
+	p.AddWithOffset(name, interval, 0, callback, doInitialCallback)
+}
+
+func (p *pollinator) AddWithOffset(
+	name string, interval time.Duration, offset time.Duration, callback func(t time.Time) error, doInitialCallback bool,
+) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	_, ok := p.polls[name]
+	if ok {
+		p.delete(name)
+	}
+
+	// The jitter is kept as part of the offset, so the poll stays out of step with others when resumed.
+	if p.jitter > 0 {
+		offset += time.Duration(float64(interval) * p.jitter * p.random())
+	}
+
+	p.polls[name] = p.newPoll(name, interval, offset, callback, doInitialCallback)
 }
 
 func (p *pollinator) Delete(name string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.delete(name)
+}
+
+// delete stops and removes the poll by the given name. The caller must hold the pollinator's lock.
+func (p *pollinator) delete(name string) {
 	oldPoll, ok := p.polls[name]
 	if !ok {
 		p.logger.Warn("got delete on non-existent poll", "name", name)
@@ -123,11 +300,17 @@ func (p *pollinator) Delete(name string) {
 		return
 	}
 
-	stopPoll(oldPoll)
+	if !p.paused {
+		stopPoll(oldPoll)
+	}
+
 	delete(p.polls, name)
 }
 
 func (p *pollinator) List() []string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
 	polls := []string{}
 
 	for k := range p.polls {
@@ -138,6 +321,9 @@ func (p *pollinator) List() []string {
 }
 
 func (p *pollinator) StopAll() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
 	p.cancelCtx()
 
 	for n, poll := range p.polls {
@@ -146,15 +332,91 @@ func (p *pollinator) StopAll() {
 	}
 }
 
+func (p *pollinator) Pause() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.paused {
+		return
+	}
+
+	p.logger.Info("pausing polls")
+
+	for _, poll := range p.polls {
+		stopPoll(poll)
+	}
+
+	p.paused = true
+
+	MetricPaused.Set(1)
+}
+
+func (p *pollinator) Resume() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if !p.paused {
+		return
+	}
+
+	p.logger.Info("resuming polls")
+
+	p.paused = false
+
+	for name, poll := range p.polls {
+		p.polls[name] = p.newPoll(name, poll.interval, poll.offset, poll.callback, false)
+	}
+
+	MetricPaused.Set(0)
+}
+
+func (p *pollinator) Paused() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.paused
+}
+
+func (p *pollinator) WithClock(clock Clock) Pollinator {
+	p.clock = clock
+
+	return p
+}
+
+func (p *pollinator) WithJitter(jitter float64) Pollinator {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.jitter = jitter
+
+	return p
+}
+
 // NewPollinator creates a new pollinator. The given baseLogger and context will be used as the parent logger and
 // context for all poll's created.
 func NewPollinator(ctx context.Context, baseLogger *slog.Logger) Pollinator {
 	pollCtx, cancelPollCtx := context.WithCancel(ctx)
 
 	return &pollinator{
-		ctx:       pollCtx,
-		cancelCtx: cancelPollCtx,
-		polls:     map[string]*poll{},
-		logger:    baseLogger,
+		ctx:        pollCtx,
+		cancelCtx:  cancelPollCtx,
+		polls:      map[string]*poll{},
+		logger:     baseLogger,
+		clock:      NewSystemClock(),
+		maxBackoff: DefaultMaxPollBackoff,
+		random:     rand.Float64,
+		lock:       &sync.Mutex{},
+	}
+}
+
+// SpreadOffsets returns offsets which spread n polls on the given interval evenly across it, such that the i-th poll
+// starts i/n of the way through the interval. For instance, 12 polls on an hourly interval start 5 minutes apart.
+func SpreadOffsets(interval time.Duration, n int) []time.Duration {
+	offsets := make([]time.Duration, n)
+
+	for i := range offsets {
+		offsets[i] = interval * time.Duration(i) / time.Duration(n)
 	}
+
+	return offsets
 }
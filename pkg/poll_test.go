@@ -3,14 +3,12 @@ package pkg
 import (
 	"context"
 	"errors"
-	"fmt"
 	"os"
 	"testing"
 	"time"
 
 	"golang.org/x/exp/slog"
 	"gotest.tools/v3/assert"
-	"gotest.tools/v3/assert/cmp"
 )
 
 var debugLogger = slog.New(
@@ -38,38 +36,35 @@ func TestRunPollCreatesPollThatExecsCallbackOnInterval(t *testing.T) {
 
 	go haveTestTimeout(t, time.Millisecond*100, testDoneChan)
 
+	startTime := time.Now()
+	clock := NewFakeClock(startTime)
 	cancelChan := make(chan bool)
-
-	var startTime time.Time
-
-	seenInitialTick := false
+	callTimes := make(chan time.Time, 2)
 
 	p := poll{
 		cancelChan:      cancelChan,
 		doneChan:        make(chan bool),
 		ctx:             context.Background(),
 		logger:          debugLogger,
-		ticker:          time.NewTicker(50 * time.Millisecond),
+		clock:           clock,
+		ticker:          clock.NewTicker(50 * time.Millisecond),
 		callbackOnStart: true,
-		callback: func(callTime time.Time) {
-			interval := callTime.Sub(startTime)
-
-			if !seenInitialTick {
-				assert.Assert(t, cmp.Equal(true, interval < time.Millisecond*5))
-				seenInitialTick = true
-
-				return
-			}
+		callback: func(callTime time.Time) error {
+			callTimes <- callTime
 
-			assert.Assert(t, cmp.Equal(true, interval < time.Millisecond*60))
-			assert.Assert(t, cmp.Equal(true, interval > time.Millisecond*40))
-			close(cancelChan)
+			return nil
 		},
 	}
 
-	startTime = time.Now()
+	go runPoll(&p)
+
+	assert.Equal(t, <-callTimes, startTime)
 
-	runPoll(&p)
+	clock.Advance(50 * time.Millisecond)
+	assert.Equal(t, <-callTimes, startTime.Add(50*time.Millisecond))
+
+	close(cancelChan)
+	<-p.doneChan
 
 	close(testDoneChan)
 }
@@ -79,6 +74,7 @@ func TestPollClosesDoneChanAfterCancelled(t *testing.T) {
 
 	go haveTestTimeout(t, time.Millisecond*100, testDoneChan)
 
+	clock := NewFakeClock(time.Now())
 	gotTickChan := make(chan bool)
 	doneChan := make(chan bool)
 	cancelChan := make(chan bool)
@@ -88,13 +84,17 @@ func TestPollClosesDoneChanAfterCancelled(t *testing.T) {
 		doneChan:   doneChan,
 		ctx:        context.Background(),
 		logger:     debugLogger,
-		ticker:     time.NewTicker(50 * time.Millisecond),
-		callback: func(callTime time.Time) {
+		clock:      clock,
+		ticker:     clock.NewTicker(50 * time.Millisecond),
+		callback: func(callTime time.Time) error {
 			close(gotTickChan)
+
+			return nil
 		},
 	}
 
 	go runPoll(&p)
+	clock.Advance(50 * time.Millisecond)
 	<-gotTickChan
 	close(cancelChan)
 	<-doneChan
@@ -107,6 +107,7 @@ func TestPollCanBeClosedUsingContext(t *testing.T) {
 
 	go haveTestTimeout(t, time.Millisecond*100, testDoneChan)
 
+	clock := NewFakeClock(time.Now())
 	gotTick := make(chan bool)
 	ctx, cancel := context.WithCancel(context.Background())
 	doneChan := make(chan bool)
@@ -116,13 +117,17 @@ func TestPollCanBeClosedUsingContext(t *testing.T) {
 		doneChan:   doneChan,
 		ctx:        ctx,
 		logger:     slog.Default(),
-		ticker:     time.NewTicker(50 * time.Millisecond),
-		callback: func(callTime time.Time) {
+		clock:      clock,
+		ticker:     clock.NewTicker(50 * time.Millisecond),
+		callback: func(callTime time.Time) error {
 			close(gotTick)
+
+			return nil
 		},
 	}
 
 	go runPoll(&p)
+	clock.Advance(50 * time.Millisecond)
 	<-gotTick
 	cancel()
 	<-doneChan
@@ -135,34 +140,38 @@ func TestPollinatorCanStartAndStopNewPolls(t *testing.T) {
 
 	go haveTestTimeout(t, time.Millisecond*300, testDoneChan)
 
-	p := NewPollinator(context.Background(), debugLogger)
+	clock := NewFakeClock(time.Now())
+	p := NewPollinator(context.Background(), debugLogger).WithClock(clock)
 
 	numTickOne := 0
 	numTickTwo := 0
 
 	p.Add(
 		"test-1", time.Millisecond*50,
-		func(_ time.Time) {
+		func(_ time.Time) error {
 			numTickOne += 1
+
+			return nil
 		},
 		false,
 	)
 	p.Add(
 		"test-2", time.Millisecond*100,
-		func(_ time.Time) {
+		func(_ time.Time) error {
 			numTickTwo += 1
+
+			return nil
 		},
 		false,
 	)
 
-	time.Sleep(time.Millisecond * 150)
+	clock.Advance(time.Millisecond * 150)
 
 	p.Delete("test-1")
 	p.Delete("test-2")
 
-	fmt.Println(numTickOne)
-	assert.Equal(t, numTickOne == 3, true)
-	assert.Equal(t, numTickTwo == 1, true)
+	assert.Equal(t, numTickOne, 3)
+	assert.Equal(t, numTickTwo, 1)
 
 	close(testDoneChan)
 }
@@ -172,30 +181,35 @@ func TestPollinatorCanUpdateExistingTicker(t *testing.T) {
 
 	go haveTestTimeout(t, time.Millisecond*300, testDoneChan)
 
-	p := NewPollinator(context.Background(), debugLogger)
+	clock := NewFakeClock(time.Now())
+	p := NewPollinator(context.Background(), debugLogger).WithClock(clock)
 	numTickOne := 0
 
 	p.Add(
 		"test-1", time.Millisecond*50,
-		func(_ time.Time) {
-			t.Error(errors.New("ticker was not updated within 50 ms"))
+		func(_ time.Time) error {
+			t.Error(errors.New("ticker was not updated"))
 			t.FailNow()
+
+			return nil
 		},
 		false,
 	)
 	p.Add(
 		"test-1", time.Millisecond*50,
-		func(t time.Time) {
+		func(t time.Time) error {
 			numTickOne += 1
+
+			return nil
 		},
 		false,
 	)
 
-	time.Sleep(time.Millisecond * 105)
+	clock.Advance(time.Millisecond * 100)
 
 	p.Delete("test-1")
 
-	assert.Equal(t, numTickOne == 2, true)
+	assert.Equal(t, numTickOne, 2)
 
 	close(testDoneChan)
 }
@@ -205,32 +219,38 @@ func TestPollinatorCanDeleteExistingTicker(t *testing.T) {
 
 	go haveTestTimeout(t, time.Millisecond*300, testDoneChan)
 
-	p := NewPollinator(context.Background(), debugLogger)
+	clock := NewFakeClock(time.Now())
+	p := NewPollinator(context.Background(), debugLogger).WithClock(clock)
 
 	numTickOne := 0
 	numTickTwo := 0
 
 	p.Add(
 		"test-1", time.Millisecond*50,
-		func(t time.Time) {
+		func(t time.Time) error {
 			numTickOne += 1
+
+			return nil
 		},
 		false,
 	)
 	p.Add(
 		"test-2", time.Millisecond*50,
-		func(_ time.Time) {
+		func(_ time.Time) error {
 			numTickTwo += 1
+
+			return nil
 		},
 		false,
 	)
 
-	time.Sleep(time.Millisecond * 55)
+	clock.Advance(time.Millisecond * 50)
 	p.Delete("test-1")
-	time.Sleep(time.Millisecond * 55)
+	clock.Advance(time.Millisecond * 50)
+	p.Delete("test-2")
 
-	assert.Equal(t, numTickOne == 1, true)
-	assert.Equal(t, numTickTwo == 2, true)
+	assert.Equal(t, numTickOne, 1)
+	assert.Equal(t, numTickTwo, 2)
 
 	close(testDoneChan)
 }
@@ -242,10 +262,12 @@ func TestPollinatorCanStopAllTickers(t *testing.T) {
 
 	p := NewPollinator(context.Background(), debugLogger)
 
-	failNow := func(_ time.Time) {
+	failNow := func(_ time.Time) error {
 		t.Error(errors.New("poll was not stopped"))
 		t.FailNow()
 		os.Exit(1)
+
+		return nil
 	}
 
 	p.Add("test-1", time.Millisecond*100, failNow, false)
@@ -256,3 +278,245 @@ func TestPollinatorCanStopAllTickers(t *testing.T) {
 
 	close(testDoneChan)
 }
+
+func TestPollinatorCanPauseAndResume(t *testing.T) {
+	testDoneChan := make(chan bool)
+
+	go haveTestTimeout(t, time.Millisecond*300, testDoneChan)
+
+	clock := NewFakeClock(time.Now())
+	p := NewPollinator(context.Background(), debugLogger).WithClock(clock)
+
+	numTickOne := 0
+	numTickTwo := 0
+
+	p.Add(
+		"test-1", time.Millisecond*50,
+		func(_ time.Time) error {
+			numTickOne += 1
+
+			return nil
+		},
+		false,
+	)
+
+	clock.Advance(time.Millisecond * 50)
+	p.Pause()
+	assert.Equal(t, p.Paused(), true)
+
+	// Polls added while paused are not started.
+	p.Add(
+		"test-2", time.Millisecond*50,
+		func(_ time.Time) error {
+			numTickTwo += 1
+
+			return nil
+		},
+		true,
+	)
+
+	clock.Advance(time.Millisecond * 100)
+	assert.Equal(t, numTickOne, 1)
+	assert.Equal(t, numTickTwo, 0)
+	assert.Equal(t, len(p.List()), 2)
+
+	p.Resume()
+	assert.Equal(t, p.Paused(), false)
+
+	clock.Advance(time.Millisecond * 50)
+	p.StopAll()
+
+	assert.Equal(t, numTickOne, 2)
+	assert.Equal(t, numTickTwo, 1)
+
+	close(testDoneChan)
+}
+
+func TestPollinatorAddWithOffsetDelaysPoll(t *testing.T) {
+	testDoneChan := make(chan bool)
+
+	go haveTestTimeout(t, time.Millisecond*300, testDoneChan)
+
+	startTime := time.Now()
+	clock := NewFakeClock(startTime)
+	p := NewPollinator(context.Background(), debugLogger).WithClock(clock)
+	callTimes := make(chan time.Time, 3)
+
+	p.AddWithOffset(
+		"test", time.Millisecond*50, time.Millisecond*20,
+		func(callTime time.Time) error {
+			callTimes <- callTime
+
+			return nil
+		},
+		true,
+	)
+
+	clock.Advance(time.Millisecond * 10)
+	assert.Equal(t, len(callTimes), 0)
+
+	// The initial callback is executed after the offset, and ticks follow every interval from then on.
+	clock.Advance(time.Millisecond * 10)
+	assert.Equal(t, <-callTimes, startTime.Add(time.Millisecond*20))
+
+	clock.Advance(time.Millisecond * 50)
+	assert.Equal(t, <-callTimes, startTime.Add(time.Millisecond*70))
+
+	p.StopAll()
+
+	close(testDoneChan)
+}
+
+func TestPollinatorWithJitterKeepsPollsOutOfLockstep(t *testing.T) {
+	testDoneChan := make(chan bool)
+
+	go haveTestTimeout(t, time.Millisecond*300, testDoneChan)
+
+	startTime := time.Now()
+	clock := NewFakeClock(startTime)
+	p := NewPollinator(context.Background(), debugLogger).WithClock(clock).WithJitter(0.5)
+	callTimesOne := make(chan time.Time, 3)
+	callTimesTwo := make(chan time.Time, 3)
+
+	randoms := []float64{0.25, 0.75}
+	p.(*pollinator).random = func() float64 {
+		r := randoms[0]
+		randoms = randoms[1:]
+
+		return r
+	}
+
+	record := func(callTimes chan time.Time) func(time.Time) error {
+		return func(callTime time.Time) error {
+			callTimes <- callTime
+
+			return nil
+		}
+	}
+
+	p.Add("one", time.Millisecond*160, record(callTimesOne), true)
+	p.Add("two", time.Millisecond*160, record(callTimesTwo), true)
+
+	// Both polls have the same interval, but each starts after its own jitter, of up to half the interval.
+	clock.Advance(time.Millisecond * 10)
+	assert.Equal(t, len(callTimesOne), 0)
+	assert.Equal(t, len(callTimesTwo), 0)
+
+	clock.Advance(time.Millisecond * 10)
+	assert.Equal(t, <-callTimesOne, startTime.Add(time.Millisecond*20))
+	assert.Equal(t, len(callTimesTwo), 0)
+
+	clock.Advance(time.Millisecond * 40)
+	assert.Equal(t, <-callTimesTwo, startTime.Add(time.Millisecond*60))
+
+	clock.Advance(time.Millisecond * 120)
+	assert.Equal(t, <-callTimesOne, startTime.Add(time.Millisecond*180))
+	assert.Equal(t, len(callTimesTwo), 0)
+
+	clock.Advance(time.Millisecond * 40)
+	assert.Equal(t, <-callTimesTwo, startTime.Add(time.Millisecond*220))
+
+	p.StopAll()
+
+	close(testDoneChan)
+}
+
+func TestPollGetBackoffDoublesUpToMax(t *testing.T) {
+	p := poll{interval: time.Minute, maxBackoff: 10 * time.Minute}
+
+	assert.Equal(t, p.getBackoff(0), time.Minute)
+	assert.Equal(t, p.getBackoff(1), 2*time.Minute)
+	assert.Equal(t, p.getBackoff(2), 4*time.Minute)
+	assert.Equal(t, p.getBackoff(3), 8*time.Minute)
+	assert.Equal(t, p.getBackoff(4), 10*time.Minute)
+	assert.Equal(t, p.getBackoff(100), 10*time.Minute)
+
+	// Polls on intervals longer than the max don't back off.
+	p.interval = time.Hour
+	assert.Equal(t, p.getBackoff(3), time.Hour)
+}
+
+// tickerRecordingClock is a FakeClock which sends the interval of each new ticker on newTickers.
+type tickerRecordingClock struct {
+	*FakeClock
+	newTickers chan time.Duration
+}
+
+func (c tickerRecordingClock) NewTicker(d time.Duration) Ticker {
+	t := c.FakeClock.NewTicker(d)
+	c.newTickers <- d
+
+	return t
+}
+
+func TestPollinatorBacksOffFailingPolls(t *testing.T) {
+	testDoneChan := make(chan bool)
+
+	go haveTestTimeout(t, time.Millisecond*300, testDoneChan)
+
+	startTime := time.Now()
+	clock := tickerRecordingClock{FakeClock: NewFakeClock(startTime), newTickers: make(chan time.Duration, 5)}
+	p := NewPollinator(context.Background(), debugLogger).WithClock(clock)
+	p.(*pollinator).maxBackoff = time.Millisecond * 40
+	callTimes := make(chan time.Time, 3)
+	failing := true
+
+	p.Add(
+		"backoff-test", time.Millisecond*10,
+		func(callTime time.Time) error {
+			var err error
+			if failing {
+				err = errors.New("my test error")
+			}
+
+			callTimes <- callTime
+
+			return err
+		},
+		true,
+	)
+
+	backoff := MetricPollBackoffSeconds.WithLabelValues("backoff-test")
+
+	assert.Equal(t, <-clock.newTickers, time.Millisecond*10)
+
+	// Each error doubles the time until the next callback, up to the max.
+	assert.Equal(t, <-callTimes, startTime)
+	assert.Equal(t, <-clock.newTickers, time.Millisecond*20)
+	assert.Equal(t, GaugeValue(backoff), (time.Millisecond * 20).Seconds())
+
+	clock.Advance(time.Millisecond * 20)
+	assert.Equal(t, <-callTimes, startTime.Add(time.Millisecond*20))
+	assert.Equal(t, <-clock.newTickers, time.Millisecond*40)
+	assert.Equal(t, GaugeValue(backoff), (time.Millisecond * 40).Seconds())
+
+	clock.Advance(time.Millisecond * 40)
+	assert.Equal(t, <-callTimes, startTime.Add(time.Millisecond*60))
+	assert.Equal(t, len(clock.newTickers), 0)
+
+	// Once the callback succeeds, the interval is restored.
+	failing = false
+
+	clock.Advance(time.Millisecond * 40)
+	assert.Equal(t, <-callTimes, startTime.Add(time.Millisecond*100))
+	assert.Equal(t, <-clock.newTickers, time.Millisecond*10)
+	assert.Equal(t, GaugeValue(backoff), float64(0))
+
+	clock.Advance(time.Millisecond * 10)
+	assert.Equal(t, <-callTimes, startTime.Add(time.Millisecond*110))
+
+	p.StopAll()
+
+	close(testDoneChan)
+}
+
+func TestSpreadOffsetsSpreadsPollsAcrossInterval(t *testing.T) {
+	offsets := SpreadOffsets(time.Hour, 12)
+	assert.Equal(t, len(offsets), 12)
+	assert.Equal(t, offsets[0], time.Duration(0))
+	assert.Equal(t, offsets[1], 5*time.Minute)
+	assert.Equal(t, offsets[11], 55*time.Minute)
+
+	assert.DeepEqual(t, SpreadOffsets(time.Minute, 3), []time.Duration{0, 20 * time.Second, 40 * time.Second})
+	assert.Equal(t, len(SpreadOffsets(time.Minute, 0)), 0)
+}
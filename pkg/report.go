@@ -0,0 +1,121 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/wneessen/go-mail"
+	"golang.org/x/exp/slog"
+)
+
+// ReportConfig configures a scheduled report, which emails a summary of every item currently matching a Watch. Unlike
+// the email action, which sends each item as it's found, a report lists the full match set on each run.
+type ReportConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Schedule is the interval between reports, such as '24h'. It is independent of the poll interval.
+	Schedule time.Duration `yaml:"schedule"`
+	// To is the address the report is sent to.
+	To string `yaml:"to"`
+	// MaxBodySize is the maximum size of the report's body in bytes. Larger reports are attached as 'report.txt',
+	// with a short summary as the body. Zero disables the limit.
+	MaxBodySize int `yaml:"maxBodySize"`
+}
+
+func (r *ReportConfig) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Bool("enabled", r.Enabled),
+		slog.Duration("schedule", r.Schedule),
+		slog.String("to", r.To),
+		slog.Int("maxBodySize", r.MaxBodySize),
+	)
+}
+
+func (r *ReportConfig) Validate(_ context.Context) error {
+	if !r.Enabled {
+		return nil
+	}
+
+	if r.Schedule <= 0 {
+		return fmt.Errorf("schedule must be greater than zero '%s'", r.Schedule)
+	}
+
+	if r.To == "" {
+		return fmt.Errorf("to cannot be empty if report is enabled")
+	}
+
+	if r.MaxBodySize < 0 {
+		return fmt.Errorf("maxBodySize cannot be negative, got %d", r.MaxBodySize)
+	}
+
+	return nil
+}
+
+// reportPollSuffix is appended to a Watch's name to create the name of the poll which sends its report.
+const reportPollSuffix = "/report"
+
+// ReportPollName returns the name of the poll which sends the report of the Watch with the given name.
+func ReportPollName(watch string) string {
+	return watch + reportPollSuffix
+}
+
+// FormatReport formats a plain text summary of the given items, which matched the Watch with the given name at the
+// given time. Items are listed in the order given.
+func FormatReport(watch string, items []*GitHubItem, t time.Time) string {
+	b := strings.Builder{}
+
+	b.WriteString(fmt.Sprintf(
+		"%d item(s) matched watch '%s' as of %s.\n", len(items), watch, t.UTC().Format(time.RFC1123),
+	))
+
+	writeItemList(&b, items)
+
+	return b.String()
+}
+
+// writeItemList writes a plain text entry for each of the given items to the given builder, holding the item's
+// number, title, state, last update and URL.
+func writeItemList(b *strings.Builder, items []*GitHubItem) {
+	for _, i := range items {
+		b.WriteString(fmt.Sprintf(
+			"\n%s/%s#%d: %s\n  %s, updated %s\n  %s\n",
+			i.Repo.Owner, i.Repo.Name, i.Number, i.Title,
+			i.State, i.UpdatedAt.UTC().Format(time.DateOnly), gitHubItemURL(*i),
+		))
+	}
+}
+
+// SendReport emails a report of the given items, which matched the given Watch at the given time, to the address
+// in the Watch's ReportConfig. If the report exceeds the ReportConfig's MaxBodySize, it is attached instead. The
+// Watch's tags are set in the EmailTagsHeader.
+func SendReport(ctx context.Context, emailinator Emailinator, watch *Watch, items []*GitHubItem, t time.Time) error {
+	m, err := emailinator.NewMsg()
+	if err != nil {
+		return fmt.Errorf("unable to create new message: %w", err)
+	}
+
+	if err := m.To(watch.Report.To); err != nil {
+		return fmt.Errorf("unable to set To address: %w", err)
+	}
+
+	m.Subject(fmt.Sprintf("watchinator report: %s: %d item(s)", watch.Name, len(items)))
+
+	report := FormatReport(watch.Name, items, t)
+	summary, _, _ := strings.Cut(report, "\n")
+
+	_, err = setEmailBody(m, report, watch.Report.MaxBodySize, "report.txt", mail.TypeTextPlain, summary)
+	if err != nil {
+		return err
+	}
+
+	if len(watch.Tags) > 0 {
+		m.SetGenHeader(EmailTagsHeader, FormatTags(watch.Tags))
+	}
+
+	if err := emailinator.Send(ctx, m); err != nil {
+		return fmt.Errorf("unable to send message: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,141 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestReportConfigValidate(t *testing.T) {
+	ctx := context.Background()
+
+	assert.NilError(t, (&ReportConfig{}).Validate(ctx))
+	assert.NilError(t, (&ReportConfig{Enabled: true, Schedule: 24 * time.Hour, To: "test@example.com"}).Validate(ctx))
+	assert.ErrorContains(
+		t, (&ReportConfig{Enabled: true, To: "test@example.com"}).Validate(ctx), "schedule must be greater than zero",
+	)
+	assert.ErrorContains(
+		t, (&ReportConfig{Enabled: true, Schedule: time.Hour}).Validate(ctx), "to cannot be empty",
+	)
+}
+
+func TestFormatReport(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	item := NewTestGitHubItem()
+	item.UpdatedAt = now
+
+	report := FormatReport("foo", []*GitHubItem{item}, now)
+	assert.Equal(
+		t, report,
+		"1 item(s) matched watch 'foo' as of Tue, 02 Jan 2024 03:04:05 UTC.\n\n"+
+			"owner/repo#1: a test issue\n  OPEN, updated 2024-01-02\n  https://github.com/owner/repo/issues/1\n",
+	)
+
+	assert.Equal(
+		t, FormatReport("foo", []*GitHubItem{}, now),
+		"0 item(s) matched watch 'foo' as of Tue, 02 Jan 2024 03:04:05 UTC.\n",
+	)
+}
+
+func TestWatchinatorReportCallbackSendsReport(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	e := &capturingEmailinator{MockEmailinator: *NewMockEmailinator()}
+
+	for _, n := range []int{2, 1} {
+		i := NewTestGitHubItem()
+		i.Number = n
+		gh.ListIssuesReturn = append(gh.ListIssuesReturn, i)
+	}
+
+	watch := NewTestWatch()
+	watch.Report = ReportConfig{Enabled: true, Schedule: 24 * time.Hour, To: "report@example.com"}
+	watch.Tags = map[string]string{"team": "platform"}
+	assert.NilError(t, watch.ValidateAndPopulate(ctx, gh))
+
+	sent := MetricReportTotal.WithLabelValues(watch.Name)
+	sentBefore := CounterValue(sent)
+
+	w := NewWatchinator(NewLogger(), gh, nil, nil, e).(*watchinator)
+	w.getReportCallback(ctx, gh, e, watch)(time.Now())
+
+	assert.Equal(t, len(e.sent), 1)
+	assert.Equal(t, CounterValue(sent)-sentBefore, float64(1))
+	assert.DeepEqual(t, e.sent[0].GetGenHeader(EmailTagsHeader), []string{"team=platform"})
+
+	buf := &bytes.Buffer{}
+	_, err := e.sent[0].WriteTo(buf)
+	assert.NilError(t, err)
+
+	msg := buf.String()
+	assert.Assert(t, bytes.Contains(buf.Bytes(), []byte("Subject: watchinator report: name: 2 item(s)")), msg)
+
+	// Items are listed in order.
+	first := bytes.Index(buf.Bytes(), []byte("owner/repo#1:"))
+	second := bytes.Index(buf.Bytes(), []byte("owner/repo#2:"))
+	assert.Assert(t, first >= 0 && first < second, msg)
+}
+
+func TestWatchinatorReportCallbackRespectsDryRun(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	gh.ListIssuesReturn = []*GitHubItem{NewTestGitHubItem()}
+	e := &capturingEmailinator{MockEmailinator: *NewMockEmailinator()}
+
+	watch := NewTestWatch()
+	watch.Report = ReportConfig{Enabled: true, Schedule: 24 * time.Hour, To: "report@example.com"}
+	assert.NilError(t, watch.ValidateAndPopulate(ctx, gh))
+
+	sent := MetricReportTotal.WithLabelValues(watch.Name)
+	sentBefore := CounterValue(sent)
+	dryRun := MetricActionDryRunTotal.WithLabelValues("report")
+	dryRunBefore := CounterValue(dryRun)
+
+	w := NewWatchinator(NewLogger(), gh, nil, nil, e).WithDryRun(true).(*watchinator)
+	assert.NilError(t, w.getReportCallback(ctx, gh, e, watch)(time.Now()))
+
+	assert.Equal(t, len(e.sent), 0)
+	assert.Equal(t, CounterValue(sent)-sentBefore, float64(0))
+	assert.Equal(t, CounterValue(dryRun)-dryRunBefore, float64(1))
+}
+
+func TestWatchinatorConfigCallbackAddsReportPolls(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	p := NewPollinator(ctx, NewLogger()).WithClock(NewFakeClock(time.Now()))
+
+	defer p.StopAll()
+
+	w := NewWatchinator(NewLogger(), gh, p, nil, NewMockEmailinator()).(*watchinator)
+
+	watch := NewTestWatch()
+	watch.Report = ReportConfig{Enabled: true, Schedule: 24 * time.Hour, To: "report@example.com"}
+	c := &Config{Interval: time.Hour, Watches: []*Watch{watch}}
+
+	w.getConfigCallback(ctx)(c)
+
+	polls := p.List()
+	sort.Strings(polls)
+	assert.DeepEqual(t, polls, []string{"name", "name/report"})
+
+	watch.Report.Enabled = false
+	w.getConfigCallback(ctx)(c)
+	assert.DeepEqual(t, p.List(), []string{"name"})
+}
+
+func TestSendReportAttachesLargeReports(t *testing.T) {
+	e := &capturingEmailinator{MockEmailinator: *NewMockEmailinator()}
+	watch := NewTestWatch()
+	watch.Report = ReportConfig{Enabled: true, Schedule: time.Hour, To: "report@example.com", MaxBodySize: 100}
+
+	items := []*GitHubItem{NewTestGitHubItem(), NewTestGitHubItem()}
+	assert.NilError(t, SendReport(context.Background(), e, watch, items, time.Now()))
+
+	attachments := e.sent[0].GetAttachments()
+	assert.Equal(t, len(attachments), 1)
+	assert.Equal(t, attachments[0].Name, "report.txt")
+}
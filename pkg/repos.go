@@ -0,0 +1,147 @@
+package pkg
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ReposCommandTimeout is the maximum amount of time a Watch's ReposCommand may take to list repositories.
+const ReposCommandTimeout = 30 * time.Second
+
+// ParseRepositoryList parses a list of repositories in the form 'owner/name', one per line. Blank lines and lines
+// starting with '#' are skipped, as are duplicate repositories.
+func ParseRepositoryList(r io.Reader) ([]GitHubRepository, error) {
+	repos := []GitHubRepository{}
+	seen := map[string]bool{}
+	scanner := bufio.NewScanner(r)
+
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		repo, err := ParseGitHubRepository(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+
+		if seen[repo.fullName()] {
+			continue
+		}
+
+		seen[repo.fullName()] = true
+		repos = append(repos, repo)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return repos, nil
+}
+
+// readReposFile reads the list of repositories in the given file, see ParseRepositoryList.
+func readReposFile(path string) ([]GitHubRepository, error) {
+	absPath, err := GetAbsolutePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(absPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ParseRepositoryList(file)
+}
+
+// runReposCommand runs the given command, without a shell, and parses the list of repositories it prints to stdout,
+// see ParseRepositoryList. The command is killed if it takes longer than ReposCommandTimeout.
+func runReposCommand(ctx context.Context, command []string) ([]GitHubRepository, error) {
+	ctx, cancel := context.WithTimeout(ctx, ReposCommandTimeout)
+	defer cancel()
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+
+		return nil, err
+	}
+
+	return ParseRepositoryList(stdout)
+}
+
+// reposCommandSkipped returns if the Watch's ReposCommand isn't run, which is the case when validating offline or
+// for disabled watches. Its Repositories aren't known then, so checks against them are skipped.
+func (w *Watch) reposCommandSkipped() bool {
+	return w.offline && len(w.ReposCommand) > 0
+}
+
+// hasRepositories returns if the Watch watches a list of repositories, even if its ReposCommand was skipped.
+func (w *Watch) hasRepositories() bool {
+	return len(w.Repositories) > 0 || w.reposCommandSkipped()
+}
+
+// resolveRepositories sets the Watch's Repositories from its ReposFile or ReposCommand. It is called each time the
+// Watch is validated, so the list is re-read whenever the config is reloaded. The ReposCommand isn't run if it's
+// skipped, see reposCommandSkipped.
+func (w *Watch) resolveRepositories(ctx context.Context) error {
+	if w.ReposFile == "" && len(w.ReposCommand) == 0 {
+		return nil
+	}
+
+	if w.ReposFile != "" && len(w.ReposCommand) > 0 {
+		return fmt.Errorf("reposFile and reposCommand cannot both be set")
+	}
+
+	if len(w.Repositories) > 0 && !w.repositoriesResolved {
+		return fmt.Errorf("repos cannot be set with reposFile or reposCommand")
+	}
+
+	if w.reposCommandSkipped() {
+		return nil
+	}
+
+	var (
+		repos  []GitHubRepository
+		err    error
+		source string
+	)
+
+	if w.ReposFile != "" {
+		source = fmt.Sprintf("reposFile '%s'", w.ReposFile)
+		repos, err = readReposFile(w.ReposFile)
+	} else {
+		source = fmt.Sprintf("reposCommand '%s'", strings.Join(w.ReposCommand, " "))
+		repos, err = runReposCommand(ctx, w.ReposCommand)
+	}
+
+	if err != nil {
+		return fmt.Errorf("unable to list repositories from %s: %w", source, err)
+	}
+
+	if len(repos) == 0 {
+		return fmt.Errorf("%s did not list any repositories", source)
+	}
+
+	w.Repositories = repos
+	w.repositoriesResolved = true
+
+	return nil
+}
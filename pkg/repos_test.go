@@ -0,0 +1,113 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestParseRepositoryList(t *testing.T) {
+	repos, err := ParseRepositoryList(strings.NewReader("# catalog\nowner/a\n\n  owner/b  \nowner/a\n"))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, repos, []GitHubRepository{{Owner: "owner", Name: "a"}, {Owner: "owner", Name: "b"}})
+
+	_, err = ParseRepositoryList(strings.NewReader("owner/a\nnotarepo\n"))
+	assert.ErrorContains(t, err, "line 2: invalid repository 'notarepo', expected 'owner/name'")
+}
+
+func TestWatchValidateResolvesReposFile(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	path := filepath.Join(t.TempDir(), "repos.txt")
+	assert.NilError(t, os.WriteFile(path, []byte("owner/a\nowner/b\n"), 0600))
+
+	w := NewTestWatch()
+	w.Repositories = nil
+	w.ReposFile = path
+	assert.NilError(t, w.ValidateAndPopulate(ctx, gh))
+	assert.DeepEqual(t, w.Repositories, []GitHubRepository{{Owner: "owner", Name: "a"}, {Owner: "owner", Name: "b"}})
+	assert.Equal(t, len(gh.CheckRepositoryRequests), 2)
+
+	// The file is re-read each time the Watch is validated.
+	assert.NilError(t, os.WriteFile(path, []byte("owner/c\n"), 0600))
+	assert.NilError(t, w.ValidateAndPopulate(ctx, gh))
+	assert.DeepEqual(t, w.Repositories, []GitHubRepository{{Owner: "owner", Name: "c"}})
+
+	gh.CheckRepositoryError = errors.New("not found")
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "unable to validate repository")
+	gh.CheckRepositoryError = nil
+
+	assert.NilError(t, os.WriteFile(path, []byte("# nothing yet\n"), 0600))
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "did not list any repositories")
+
+	w.ReposFile = filepath.Join(t.TempDir(), "missing.txt")
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "unable to list repositories from reposFile")
+}
+
+func TestWatchValidateResolvesReposCommand(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+
+	w := NewTestWatch()
+	w.Repositories = nil
+	w.ReposCommand = []string{"echo", "owner/a"}
+	assert.NilError(t, w.ValidateAndPopulate(ctx, gh))
+	assert.DeepEqual(t, w.Repositories, []GitHubRepository{{Owner: "owner", Name: "a"}})
+
+	w.ReposCommand = []string{"sh", "-c", "echo catalog unavailable >&2; exit 1"}
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "exit status 1: catalog unavailable")
+}
+
+func TestWatchValidateSkipsReposCommandOffline(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	marker := filepath.Join(t.TempDir(), "ran")
+
+	w := NewTestWatch()
+	w.Repositories = nil
+	w.ReposCommand = []string{"sh", "-c", "touch " + marker + "; echo owner/a"}
+	w.Actions.Subscribe.Repos = []GitHubRepository{{Owner: "owner", Name: "a"}}
+	w.offline = true
+	assert.NilError(t, w.ValidateAndPopulate(ctx, gh))
+	assert.Equal(t, len(w.Repositories), 0)
+
+	_, statErr := os.Stat(marker)
+	assert.Assert(t, os.IsNotExist(statErr))
+
+	// Disabled watches are validated offline, so their command isn't run either.
+	c, cleanup, err := NewTestConfig()
+	assert.NilError(t, err)
+
+	defer cleanup()
+
+	enabled := false
+	w.Enabled = &enabled
+	w.offline = false
+	c.Watches = []*Watch{w}
+	assert.NilError(t, c.Validate(ctx, gh, NewMockEmailinator()))
+
+	_, err = os.Stat(marker)
+	assert.Assert(t, os.IsNotExist(err))
+
+	w.Self = true
+	w.offline = true
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "self and repos cannot both be set")
+}
+
+func TestWatchValidateChecksReposSourcesAreExclusive(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+
+	w := NewTestWatch()
+	w.ReposFile = "repos.txt"
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "repos cannot be set with reposFile or reposCommand")
+
+	w.Repositories = nil
+	w.ReposCommand = []string{"echo", "owner/a"}
+	assert.ErrorContains(t, w.ValidateAndPopulate(ctx, gh), "reposFile and reposCommand cannot both be set")
+}
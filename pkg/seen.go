@@ -0,0 +1,238 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shurcooL/githubv4"
+)
+
+// SeenStore records when an action was last performed on a GitHubItem for a Watch.
+type SeenStore interface {
+	// LastSeen returns the last time the given action was performed on the item with the given ID for the given
+	// watch. If the action has never been performed, the returned boolean is false.
+	LastSeen(watch string, id githubv4.ID, action string) (time.Time, bool)
+
+	// MarkSeen records that the given action was performed on the item with the given ID for the given watch at
+	// the given time.
+	MarkSeen(watch string, id githubv4.ID, action string, t time.Time) error
+
+	// Prune drops the records of the given watch for items other than those with the given IDs, such as items which
+	// no longer match the watch.
+	Prune(watch string, keep []githubv4.ID)
+
+	// Flush persists the records added or dropped since the last call to Flush, if the store is persistent.
+	Flush() error
+}
+
+// seenStoreKey creates the key used to identify a (watch, item, action) tuple in a SeenStore.
+func seenStoreKey(watch string, id githubv4.ID, action string) string {
+	return fmt.Sprintf("%s/%v/%s", watch, id, action)
+}
+
+// isPrunedSeenStoreKey returns if the given key, created by seenStoreKey, belongs to the given watch and an item
+// other than those in keep. Neither item IDs nor action names contain a '/', which tells apart watches whose names
+// share a prefix.
+func isPrunedSeenStoreKey(key string, watch string, keep map[string]bool) bool {
+	rest, ok := strings.CutPrefix(key, watch+"/")
+	if !ok {
+		return false
+	}
+
+	id, action, ok := strings.Cut(rest, "/")
+	if !ok || strings.Contains(action, "/") {
+		return false
+	}
+
+	return !keep[id]
+}
+
+// pruneSeen drops the records of the given watch in the given map for items other than those with the given IDs,
+// returning if any were dropped.
+func pruneSeen(seen map[string]time.Time, watch string, keep []githubv4.ID) bool {
+	keepIDs := map[string]bool{}
+	for _, id := range keep {
+		keepIDs[fmt.Sprintf("%v", id)] = true
+	}
+
+	pruned := false
+
+	for key := range seen {
+		if isPrunedSeenStoreKey(key, watch, keepIDs) {
+			delete(seen, key)
+
+			pruned = true
+		}
+	}
+
+	return pruned
+}
+
+// memorySeenStore is an in-memory implementation of the SeenStore interface. Its contents are lost on restart.
+type memorySeenStore struct {
+	lock *sync.Mutex
+	seen map[string]time.Time
+}
+
+func (m *memorySeenStore) LastSeen(watch string, id githubv4.ID, action string) (time.Time, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	t, ok := m.seen[seenStoreKey(watch, id, action)]
+
+	return t, ok
+}
+
+func (m *memorySeenStore) MarkSeen(watch string, id githubv4.ID, action string, t time.Time) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.seen[seenStoreKey(watch, id, action)] = t
+
+	return nil
+}
+
+func (m *memorySeenStore) Prune(watch string, keep []githubv4.ID) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	pruneSeen(m.seen, watch, keep)
+}
+
+func (m *memorySeenStore) Flush() error {
+	return nil
+}
+
+// NewMemorySeenStore creates a new SeenStore which holds its records in memory.
+func NewMemorySeenStore() SeenStore {
+	return &memorySeenStore{
+		lock: &sync.Mutex{},
+		seen: map[string]time.Time{},
+	}
+}
+
+// fileSeenStore is an implementation of the SeenStore interface which persists its records to a JSON file, so they
+// survive restarts. Records are held in memory until Flush rewrites the whole file, so a batch of actions only costs a
+// single write.
+type fileSeenStore struct {
+	lock *sync.Mutex
+	// writeLock is held while the file is written, so a stale snapshot never replaces a newer one, without blocking
+	// calls to LastSeen and MarkSeen on disk writes.
+	writeLock *sync.Mutex
+	path      string
+	seen      map[string]time.Time
+	// dirty is set if records were added or dropped since the file was last written.
+	dirty bool
+}
+
+func (f *fileSeenStore) LastSeen(watch string, id githubv4.ID, action string) (time.Time, bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	t, ok := f.seen[seenStoreKey(watch, id, action)]
+
+	return t, ok
+}
+
+func (f *fileSeenStore) MarkSeen(watch string, id githubv4.ID, action string, t time.Time) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.seen[seenStoreKey(watch, id, action)] = t
+	f.dirty = true
+
+	return nil
+}
+
+func (f *fileSeenStore) Prune(watch string, keep []githubv4.ID) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if pruneSeen(f.seen, watch, keep) {
+		f.dirty = true
+	}
+}
+
+func (f *fileSeenStore) Flush() error {
+	f.writeLock.Lock()
+	defer f.writeLock.Unlock()
+
+	f.lock.Lock()
+
+	if !f.dirty {
+		f.lock.Unlock()
+
+		return nil
+	}
+
+	marshalled, err := json.Marshal(f.seen)
+	if err != nil {
+		f.lock.Unlock()
+
+		return fmt.Errorf("unable to marshal seen store: %w", err)
+	}
+
+	f.dirty = false
+	f.lock.Unlock()
+
+	if err := f.write(marshalled); err != nil {
+		// The records are written again on the next flush.
+		f.lock.Lock()
+		f.dirty = true
+		f.lock.Unlock()
+
+		return err
+	}
+
+	return nil
+}
+
+// write replaces the file with the given contents. A temporary file is renamed over it, so it is never left half
+// written.
+func (f *fileSeenStore) write(contents []byte) error {
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, contents, 0o600); err != nil {
+		return fmt.Errorf("unable to write seen store: %w", err)
+	}
+
+	if err := os.Rename(tmp, f.path); err != nil {
+		return fmt.Errorf("unable to write seen store: %w", err)
+	}
+
+	return nil
+}
+
+// NewFileSeenStore creates a new SeenStore which persists its records to the file at the given path. Records already
+// in the file are loaded. If the file doesn't exist, it is created on the first call to Flush.
+func NewFileSeenStore(path string) (SeenStore, error) {
+	f := &fileSeenStore{
+		lock:      &sync.Mutex{},
+		writeLock: &sync.Mutex{},
+		path:      path,
+		seen:      map[string]time.Time{},
+	}
+
+	contents, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to read seen store: %w", err)
+	}
+
+	if err := json.Unmarshal(contents, &f.seen); err != nil {
+		return nil, fmt.Errorf("unable to parse seen store %s: %w", path, err)
+	}
+
+	if f.seen == nil {
+		f.seen = map[string]time.Time{}
+	}
+
+	return f, nil
+}
@@ -0,0 +1,83 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shurcooL/githubv4"
+	"gotest.tools/v3/assert"
+)
+
+func TestFileSeenStorePersistsRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	seenAt := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	store, err := NewFileSeenStore(path)
+	assert.NilError(t, err)
+
+	_, ok := store.LastSeen("watch", "an-id", "email")
+	assert.Assert(t, !ok)
+
+	assert.NilError(t, store.MarkSeen("watch", "an-id", "email", seenAt))
+
+	// Records are only written once they're flushed.
+	_, err = os.Stat(path)
+	assert.Assert(t, os.IsNotExist(err))
+	assert.NilError(t, store.Flush())
+
+	// Records are loaded by new stores, such as after a restart.
+	reloaded, err := NewFileSeenStore(path)
+	assert.NilError(t, err)
+
+	last, ok := reloaded.LastSeen("watch", "an-id", "email")
+	assert.Assert(t, ok)
+	assert.Assert(t, last.Equal(seenAt))
+
+	_, ok = reloaded.LastSeen("another-watch", "an-id", "email")
+	assert.Assert(t, !ok)
+
+	assert.NilError(t, os.WriteFile(path, []byte("not json"), 0o600))
+
+	_, err = NewFileSeenStore(path)
+	assert.ErrorContains(t, err, "unable to parse seen store")
+}
+
+func TestFileSeenStorePrunesRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	seenAt := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	store, err := NewFileSeenStore(path)
+	assert.NilError(t, err)
+
+	assert.NilError(t, store.MarkSeen("watch", "kept", "email", seenAt))
+	assert.NilError(t, store.MarkSeen("watch", "kept", "subscribe", seenAt))
+	assert.NilError(t, store.MarkSeen("watch", "dropped", "email", seenAt))
+	assert.NilError(t, store.MarkSeen("watch/other", "dropped", "email", seenAt))
+	assert.NilError(t, store.MarkSeen("another-watch", "dropped", "email", seenAt))
+	assert.NilError(t, store.Flush())
+
+	store.Prune("watch", []githubv4.ID{"kept"})
+	assert.NilError(t, store.Flush())
+
+	reloaded, err := NewFileSeenStore(path)
+	assert.NilError(t, err)
+
+	for _, record := range []struct {
+		watch  string
+		id     string
+		action string
+		kept   bool
+	}{
+		{"watch", "kept", "email", true},
+		{"watch", "kept", "subscribe", true},
+		{"watch", "dropped", "email", false},
+		// Other watches are left as is, even if their name starts with the pruned watch's.
+		{"watch/other", "dropped", "email", true},
+		{"another-watch", "dropped", "email", true},
+	} {
+		_, ok := reloaded.LastSeen(record.watch, record.id, record.action)
+		assert.Equal(t, ok, record.kept, "%+v", record)
+	}
+}
@@ -0,0 +1,55 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+)
+
+// MatchTrace records whether an item scanned by a Watch was matched, why, and which actions would be performed on it.
+type MatchTrace struct {
+	Watch   string `json:"watch"`
+	Repo    string `json:"repo"`
+	Number  int    `json:"number"`
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Matches bool   `json:"matches"`
+	// Reason is the filter the item did not match, if Matches is false.
+	Reason string `json:"reason,omitempty"`
+	// Actions are the decisions for each of the Watch's actions, if Matches is true.
+	Actions []ActionDecision `json:"actions,omitempty"`
+}
+
+// TraceItems lists the Watch's items like ListItems, returning a MatchTrace for every item the Watch's filters were
+// applied to, including those which were filtered out. Items filtered out by GitHub, such as by searchLabels or
+// states, are never scanned so aren't included. No actions are performed, and the given Emailinator is only used to
+// construct the email action.
+func (w *Watch) TraceItems(ctx context.Context, gh GitHubinator, e Emailinator) ([]MatchTrace, error) {
+	actioninator := w.GetActioninator(gh, e)
+	traces := []MatchTrace{}
+	clock := NewSystemClock()
+
+	matcher := w.GetMatchinator().WithTracer(func(r MatchResult) {
+		i := r.Item
+		trace := MatchTrace{
+			Watch:   w.Name,
+			Repo:    i.Repo.fullName(),
+			Number:  i.Number,
+			Title:   i.Title,
+			URL:     gitHubItemURL(*i),
+			Matches: r.Matches,
+			Reason:  r.Reason,
+		}
+
+		if r.Matches {
+			trace.Actions = actioninator.Plan(*i)
+		}
+
+		traces = append(traces, trace)
+	})
+
+	if _, err := w.listItems(ctx, gh, matcher.WithClock(clock), clock.Now()); err != nil {
+		return nil, fmt.Errorf("unable to trace items: %w", err)
+	}
+
+	return traces, nil
+}
@@ -0,0 +1,91 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shurcooL/githubv4"
+	"gotest.tools/v3/assert"
+)
+
+// matchingGitHubinator is a MockGitHubinator which applies the given Matchinator to the items it lists, like the
+// gitHubinator does.
+type matchingGitHubinator struct {
+	*MockGitHubinator
+}
+
+func (m matchingGitHubinator) ListIssues(
+	ctx context.Context, ghr GitHubRepository, filter *GitHubIssueFilter, matcher Matchinator,
+) ([]*GitHubItem, error) {
+	items, err := m.MockGitHubinator.ListIssues(ctx, ghr, filter, matcher)
+	matched := []*GitHubItem{}
+
+	for _, i := range items {
+		if ok, _ := matcher.Matches(i); ok {
+			matched = append(matched, i)
+		}
+	}
+
+	return matched, err
+}
+
+func TestWatchTraceItemsRecordsEveryScannedItem(t *testing.T) {
+	ctx := context.Background()
+	gh := matchingGitHubinator{NewMockGitHubinator()}
+
+	subscribed := NewTestGitHubItem()
+	subscribed.Number = 1
+	subscribed.Subscription = githubv4.SubscriptionStateSubscribed
+
+	unsubscribed := NewTestGitHubItem()
+	unsubscribed.Number = 2
+	unsubscribed.URL = "https://github.com/owner/repo/issues/2"
+	unsubscribed.Subscription = githubv4.SubscriptionStateUnsubscribed
+
+	filtered := NewTestGitHubItem()
+	filtered.Number = 3
+	filtered.Title = "not a bug"
+
+	gh.ListIssuesReturn = []*GitHubItem{subscribed, unsubscribed, filtered}
+
+	w := NewTestWatch()
+	w.TitleRegex = []string{"^a test"}
+	w.BodyRegex = nil
+	w.RequiredLabels = nil
+	assert.NilError(t, w.ValidateAndPopulate(ctx, gh))
+
+	traces, err := w.TraceItems(ctx, gh, NewMockEmailinator())
+	assert.NilError(t, err)
+	assert.Equal(t, len(traces), 3)
+
+	assert.Equal(t, traces[0].Matches, true)
+	assert.DeepEqual(t, traces[0].Actions, []ActionDecision{
+		{Action: "subscribe", Reason: ActionSkipReasonAlreadySubscribed},
+		{Action: "email", Reason: ActionSkipReasonAlreadySubscribed},
+	})
+
+	assert.Equal(t, traces[1].Repo, "owner/repo")
+	assert.Equal(t, traces[1].URL, "https://github.com/owner/repo/issues/2")
+	assert.DeepEqual(t, traces[1].Actions, []ActionDecision{
+		{Action: "subscribe", Fire: true},
+		{Action: "email", Fire: true},
+	})
+
+	assert.Equal(t, traces[2].Number, 3)
+	assert.Equal(t, traces[2].Matches, false)
+	assert.Equal(t, traces[2].Reason, "did not match titleRegex: '^a test'")
+	assert.Assert(t, traces[2].Actions == nil)
+
+	// Nothing is actually done to the items.
+	assert.Equal(t, len(gh.SetSubscriptionRequests), 0)
+}
+
+func TestActioninatorPlanSkipsActionsLimitedToOtherRepos(t *testing.T) {
+	action := GitHubItemAction{Name: "limited", Repos: []GitHubRepository{{Owner: "owner", Name: "other"}}}
+	a := NewActioninator().WithAction(action).WithAction(GitHubItemAction{Name: "any"})
+
+	assert.DeepEqual(t, a.Plan(*NewTestGitHubItem()), []ActionDecision{
+		{Action: "limited", Reason: ActionSkipReasonRepo},
+		{Action: "any", Fire: true},
+	})
+}
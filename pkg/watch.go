@@ -2,9 +2,15 @@ package pkg
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
 	"time"
 
+	"github.com/shurcooL/githubv4"
 	"golang.org/x/exp/slog"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
 )
 
 // Watchinator is used to periodically poll GitHub for new GitHubItems that should be subscribed to. It uses a
@@ -13,6 +19,27 @@ type Watchinator interface {
 	// Watch is the 'main' function of the Watchinator, which sets up both a Pollinator and a Configinator to
 	// watch GitHub for new items and subscribe to them as needed.
 	Watch(ctx context.Context, configFilePath string) error
+
+	// WithClock sets the Clock used by the time-based logic of each watch.
+	WithClock(clock Clock) Watchinator
+
+	// WithDryRun logs the actions each watch would perform on items instead of performing them, if dryRun is set.
+	WithDryRun(dryRun bool) Watchinator
+
+	// Reprocess fetches the given item and, if it matches the given watch, performs the watch's actions on it once.
+	// If force is set, the watch's cooldown is ignored. It uses the config most recently loaded by Watch.
+	Reprocess(
+		ctx context.Context, watchName string, ghr GitHubRepository, number int, force bool,
+	) (*ReprocessResult, error)
+}
+
+// ReprocessResult is the result of reprocessing an item through a watch.
+type ReprocessResult struct {
+	Watch   string `json:"watch"`
+	Item    string `json:"item"`
+	Matched bool   `json:"matched"`
+	// Reason is why the item didn't match the watch.
+	Reason string `json:"reason,omitempty"`
 }
 
 // watchinator is the internal implementation of the Watchinator interface.
@@ -22,39 +49,128 @@ type watchinator struct {
 	pollinator   Pollinator
 	configinator Configinator
 	emailinator  Emailinator
+	clock        Clock
+	dryRun       bool
+
+	// lock guards the fields below, which are set each time a config is loaded.
+	lock   *sync.Mutex
+	config *Config
+	gh     GitHubinator
+	e      Emailinator
+	// seenStore records the actions performed on items. It is persisted to the stateFile, if one is configured, in
+	// which case actions aren't repeated on items which weren't updated.
+	seenStore SeenStore
+	stateFile string
+	// actionSem limits the number of actions performed at once across every watch, to actionConcurrency.
+	actionSem         *semaphore.Weighted
+	actionConcurrency int
+	// reportSchedules holds the schedule each watch's report poll was added with, so the poll is kept across config
+	// reloads which don't change it.
+	reportSchedules map[string]time.Duration
+}
+
+// getSeenStore returns the current SeenStore, and if actions should be deduplicated using it.
+func (w *watchinator) getSeenStore() (SeenStore, bool) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return w.seenStore, w.stateFile != ""
+}
+
+// getActionSemaphore returns the semaphore limiting the number of actions performed at once across every watch.
+func (w *watchinator) getActionSemaphore() *semaphore.Weighted {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return w.actionSem
+}
+
+// useActionConcurrency limits the number of actions performed at once across every watch to the given limit. The
+// semaphore is only replaced if the limit changed, so actions in progress aren't counted against the new one.
+func (w *watchinator) useActionConcurrency(limit int) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.actionSem != nil && w.actionConcurrency == limit {
+		return
+	}
+
+	w.actionSem, w.actionConcurrency = semaphore.NewWeighted(int64(limit)), limit
+}
+
+// useStateFile persists the SeenStore to the state file at the given path. The store is only replaced if the path
+// changed, and an empty path switches back to an in-memory store.
+func (w *watchinator) useStateFile(path string) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if path == w.stateFile {
+		return nil
+	}
+
+	if path == "" {
+		w.seenStore, w.stateFile = NewMemorySeenStore(), ""
+
+		return nil
+	}
+
+	absPath, err := GetAbsolutePath(path)
+	if err != nil {
+		return err
+	}
+
+	store, err := NewFileSeenStore(absPath)
+	if err != nil {
+		return err
+	}
+
+	w.seenStore, w.stateFile = store, path
+
+	return nil
 }
 
 // getPollCallback returns a function that executes on each tick in the poller for a Watch. It lists items from GitHub
 // using the given GitHubinator, and subscribes to them if the viewer is not already subscribed. Errors are logged.
+// An error is returned if no items could be listed, such as when the search fails or every repository fails to be
+// listed, so the poll backs off. Errors handling individual items, or listing some of the repositories, aren't.
 func (w *watchinator) getPollCallback(
 	ctx context.Context, gh GitHubinator, e Emailinator, watch *Watch,
-) func(t time.Time) {
-	filter := watch.GetIssueFilter()
-	matchinator := watch.GetMatchinator()
-	actioninator := watch.GetActioninator(gh, e)
+) func(t time.Time) error {
+	matchinator := watch.GetMatchinator().WithClock(w.clock)
+	seenStore, dedup := w.getSeenStore()
+	actioninator := watch.GetActioninator(gh, e).
+		WithCooldown(seenStore, watch.Name, watch.Actions.NotifyCooldown).
+		WithClock(w.clock).
+		WithDryRun(w.dryRun).
+		WithSemaphore(w.getActionSemaphore())
+
+	if dedup {
+		actioninator = actioninator.WithDedup(seenStore, watch.Name)
+	}
 
 	MetricPollTickTotal.WithLabelValues(watch.Name).Inc()
 
 	errorMetric := MetricPollErrorTotal.WithLabelValues(watch.Name)
 
-	return func(t time.Time) {
-		logger := w.logger.With("time", t, "watch", watch.Name)
+	return func(t time.Time) error {
+		tickID := NewTickID()
+		ctx := ContextWithTickID(ctx, tickID)
+		logger := w.logger.With("time", t, "watch", watch.Name, LogKeyTickID, tickID)
 
-		for _, r := range watch.Repositories {
-			repoLogger := logger.With("repo", r)
-			repoLogger.Info("updating repo")
+		if len(watch.Tags) > 0 {
+			logger = logger.With("tags", watch.Tags)
+		}
 
-			issues, err := gh.ListIssues(ctx, r, filter, matchinator)
-			if err != nil {
-				repoLogger.Error("unable to list issues from GitHub", LogKeyError, err)
+		// handled holds the IDs of the items which matched the watch on this tick.
+		handled := []githubv4.ID{}
 
-				errorMetric.Inc()
+		handleItems := func(logger *slog.Logger, items []*GitHubItem) {
+			SortGitHubItems(items)
 
-				continue
-			}
+			for _, i := range items {
+				handled = append(handled, i.ID)
 
-			for _, i := range issues {
-				issueLogger := repoLogger.With(
+				issueLogger := logger.With(
 					"issue",
 					slog.GroupValue(
 						slog.Int("number", i.Number),
@@ -69,36 +185,388 @@ func (w *watchinator) getPollCallback(
 				}
 			}
 		}
+
+		// flush performs the actions batched up on this tick. If every item was listed, the records of items which
+		// no longer match the watch are dropped. The records are then written to the stateFile in one go.
+		flush := func(listedAll bool) {
+			if err := actioninator.Flush(ctx, logger); err != nil {
+				logger.Error("unable to flush actions", LogKeyError, err)
+
+				errorMetric.Inc()
+			}
+
+			// Dry runs leave the stateFile as is.
+			if w.dryRun {
+				return
+			}
+
+			if listedAll {
+				seenStore.Prune(watch.Name, handled)
+			}
+
+			if err := seenStore.Flush(); err != nil {
+				logger.Error("unable to write state file", LogKeyError, err)
+
+				errorMetric.Inc()
+			}
+		}
+
+		if search := watch.GetSearchQuery(); search != "" {
+			searchLogger := logger.With("search", search)
+			searchLogger.Info("searching issues")
+
+			issues, err := gh.SearchIssues(ctx, search, matchinator)
+			if err != nil {
+				searchLogger.Error("unable to search issues on GitHub", LogKeyError, err)
+
+				errorMetric.Inc()
+
+				return fmt.Errorf("unable to search issues: %w", err)
+			}
+
+			handleItems(searchLogger, issues)
+			flush(true)
+
+			return nil
+		}
+
+		repos, err := watch.ListRepositories(ctx, gh)
+		if err != nil {
+			logger.Error("unable to list repositories for watch", LogKeyError, err)
+
+			errorMetric.Inc()
+
+			return fmt.Errorf("unable to list repositories: %w", err)
+		}
+
+		filter := watch.GetIssueFilter(t)
+
+		// Items are listed from several repositories at once, then handled one repository at a time in order. A
+		// repository which fails to be listed doesn't stop the others, so the group's error is always nil.
+		listed := make([][]*GitHubItem, len(repos))
+		listErrors := make([]error, len(repos))
+		repoLoggers := make([]*slog.Logger, len(repos))
+
+		g := errgroup.Group{}
+		g.SetLimit(watch.getRepoConcurrency())
+
+		for i, r := range repos {
+			i, r := i, r
+			repoLoggers[i] = logger.With("repo", r)
+
+			g.Go(func() error {
+				repoLoggers[i].Info("updating repo")
+
+				issues, err := watch.listRepositoryItems(ctx, gh, r, filter, matchinator)
+				if err != nil {
+					repoLoggers[i].Error("unable to list issues from GitHub", LogKeyError, err)
+
+					errorMetric.Inc()
+
+					listErrors[i] = err
+
+					return nil
+				}
+
+				listed[i] = issues
+
+				return nil
+			})
+		}
+
+		_ = g.Wait()
+
+		failed := 0
+
+		for i, issues := range listed {
+			if listErrors[i] != nil {
+				failed++
+
+				continue
+			}
+
+			if issues != nil {
+				handleItems(repoLoggers[i], issues)
+			}
+		}
+
+		flush(failed == 0)
+
+		if failed > 0 && failed == len(repos) {
+			return fmt.Errorf(
+				"unable to list issues from any of %d repositories: %w", failed, errors.Join(listErrors...),
+			)
+		}
+
+		return nil
 	}
 }
 
+// getReportCallback returns a function that executes on each tick of the report poll for a Watch. It lists every item
+// currently matching the Watch and emails a summary of them using the given Emailinator. Errors are logged and
+// returned, so the report poll backs off. In dry-run mode, the report is logged and counted in
+// MetricActionDryRunTotal instead of being sent.
+func (w *watchinator) getReportCallback(
+	ctx context.Context, gh GitHubinator, e Emailinator, watch *Watch,
+) func(t time.Time) error {
+	errorMetric := MetricReportErrorTotal.WithLabelValues(watch.Name)
+	dryRun := w.dryRun
+	clock := w.clock
+
+	return func(t time.Time) error {
+		tickID := NewTickID()
+		ctx := ContextWithTickID(ctx, tickID)
+		logger := w.logger.With("time", t, "watch", watch.Name, LogKeyTickID, tickID, "to", watch.Report.To)
+
+		if len(watch.Tags) > 0 {
+			logger = logger.With("tags", watch.Tags)
+		}
+
+		logger.Info("sending report")
+
+		items, err := watch.ListItems(ctx, gh, clock)
+		if err != nil {
+			logger.Error("unable to list items for report", LogKeyError, err)
+
+			errorMetric.Inc()
+
+			return fmt.Errorf("unable to list items for report: %w", err)
+		}
+
+		SortGitHubItems(items)
+
+		if dryRun {
+			logger.Info("dry-run, would send report", "items", len(items))
+			MetricActionDryRunTotal.WithLabelValues("report").Inc()
+
+			return nil
+		}
+
+		if err := SendReport(ctx, e, watch, items, t); err != nil {
+			logger.Error("unable to send report", LogKeyError, err)
+
+			errorMetric.Inc()
+
+			return fmt.Errorf("unable to send report: %w", err)
+		}
+
+		MetricReportTotal.WithLabelValues(watch.Name).Inc()
+		logger.Info("sent report", "items", len(items))
+
+		return nil
+	}
+}
+
+// getCurrentReportCallback returns a function which sends the report of the watch with the given name, using the
+// config loaded when it is executed. This lets a report poll be kept across config reloads, so reloads don't push
+// back the next report or reset its backoff.
+func (w *watchinator) getCurrentReportCallback(ctx context.Context, watchName string) func(t time.Time) error {
+	return func(t time.Time) error {
+		w.lock.Lock()
+		c, gh, e := w.config, w.gh, w.e
+		w.lock.Unlock()
+
+		watch := c.GetWatch(watchName)
+		if watch == nil || !watch.IsEnabled() || !watch.Report.Enabled {
+			return nil
+		}
+
+		return w.getReportCallback(ctx, gh, e, watch)(t)
+	}
+}
+
+// updateReportSchedule records the schedule of the report poll of the watch with the given name, returning if it
+// changed. The poll must then be added again.
+func (w *watchinator) updateReportSchedule(watchName string, schedule time.Duration) bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	previous, ok := w.reportSchedules[watchName]
+	w.reportSchedules[watchName] = schedule
+
+	return !ok || previous != schedule
+}
+
 // getConfigCallback returns a function that is executed whenever a config change is detected. It ensures the currently
-// running polls in the pollinator match the watches in the config.
+// running polls in the pollinator match the enabled watches in the config.
 func (w *watchinator) getConfigCallback(ctx context.Context) func(c *Config) {
 	return func(c *Config) {
-		gh := w.gitHubinator.WithToken(c.PAT)
+		gh := c.GetGitHubinator(w.gitHubinator)
 		e := w.emailinator.WithConfig(&c.Email)
 
-		for _, p := range w.pollinator.List() {
-			needsToBeDeleted := true
+		w.lock.Lock()
+		w.config, w.gh, w.e = c, gh, e
+		w.lock.Unlock()
 
-			for _, w := range c.Watches {
-				if p == w.Name {
-					needsToBeDeleted = false
+		w.useActionConcurrency(c.GetActionConcurrency())
 
-					break
-				}
+		if err := w.useStateFile(c.StateFile); err != nil {
+			w.logger.Error(
+				"unable to load state file, keeping the previous one", "stateFile", c.StateFile, LogKeyError, err,
+			)
+		}
+
+		polls := map[string]bool{}
+
+		for _, watch := range c.Watches {
+			if !watch.IsEnabled() {
+				continue
+			}
+
+			polls[watch.Name] = true
+
+			if watch.Report.Enabled {
+				polls[ReportPollName(watch.Name)] = true
 			}
+		}
 
-			if needsToBeDeleted {
+		for _, p := range w.pollinator.List() {
+			if !polls[p] {
 				w.pollinator.Delete(p)
 			}
 		}
 
+		w.lock.Lock()
+		for name := range w.reportSchedules {
+			if !polls[ReportPollName(name)] {
+				delete(w.reportSchedules, name)
+			}
+		}
+		w.lock.Unlock()
+
+		MetricWatchTag.Reset()
+
+		w.pollinator.WithJitter(c.Jitter)
+
+		// Only enabled watches are spread across the interval, so disabled ones don't leave gaps.
+		numEnabled, i := 0, 0
+
 		for _, watch := range c.Watches {
-			w.pollinator.Add(watch.Name, c.Interval, w.getPollCallback(ctx, gh, e, watch), true)
+			if watch.IsEnabled() {
+				numEnabled++
+			}
 		}
+
+		for _, watch := range c.Watches {
+			if !watch.IsEnabled() {
+				w.logger.Info("skipping disabled watch", "watch", watch.Name)
+
+				continue
+			}
+
+			for k, v := range watch.Tags {
+				MetricWatchTag.WithLabelValues(watch.Name, k, v).Set(1)
+			}
+
+			interval := c.GetInterval(watch)
+
+			// Each watch is offset by the same fraction of its own interval, so overrides stay spread out.
+			offset := time.Duration(0)
+			if c.SpreadTicks {
+				offset = SpreadOffsets(interval, numEnabled)[i]
+			}
+
+			i++
+
+			w.pollinator.AddWithOffset(
+				watch.Name, interval, offset, w.getPollCallback(ctx, gh, e, watch), c.GetInitialScan(watch),
+			)
+
+			if watch.Report.Enabled && w.updateReportSchedule(watch.Name, watch.Report.Schedule) {
+				w.pollinator.Add(
+					ReportPollName(watch.Name), watch.Report.Schedule,
+					w.getCurrentReportCallback(ctx, watch.Name), false,
+				)
+			}
+		}
+	}
+}
+
+func (w *watchinator) WithClock(clock Clock) Watchinator {
+	w.clock = clock
+
+	return w
+}
+
+func (w *watchinator) WithDryRun(dryRun bool) Watchinator {
+	w.dryRun = dryRun
+
+	return w
+}
+
+func (w *watchinator) Reprocess(
+	ctx context.Context, watchName string, ghr GitHubRepository, number int, force bool,
+) (*ReprocessResult, error) {
+	w.lock.Lock()
+	c, gh, e := w.config, w.gh, w.e
+	w.lock.Unlock()
+
+	if c == nil {
+		return nil, fmt.Errorf("no config has been loaded")
+	}
+
+	watch := c.GetWatch(watchName)
+	if watch == nil {
+		return nil, fmt.Errorf("unknown watch with name '%s'", watchName)
+	}
+
+	if !watch.IsEnabled() {
+		return nil, fmt.Errorf("watch '%s' is disabled", watchName)
+	}
+
+	if watch.Search == "" && !watch.Self && !containsRepository(watch.Repositories, ghr) {
+		return nil, fmt.Errorf("repository %s/%s is not watched by watch '%s'", ghr.Owner, ghr.Name, watchName)
+	}
+
+	result := &ReprocessResult{
+		Watch: watchName,
+		Item:  fmt.Sprintf("%s/%s#%d", ghr.Owner, ghr.Name, number),
+	}
+
+	logger := LoggerFromContext(ctx, w.logger).With("watch", watchName, "item", result.Item, "force", force)
+	logger.Info("reprocessing item")
+
+	matchinator := watch.GetMatchinator().WithClock(w.clock)
+
+	item, err := gh.GetIssue(ctx, ghr, number, matchinator)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get %s: %w", result.Item, err)
+	}
+
+	result.Matched, result.Reason = matchinator.Matches(item)
+	if !result.Matched {
+		logger.Info("item does not match watch", "reason", result.Reason)
+
+		return result, nil
 	}
+
+	actioninator := watch.GetActioninator(gh, e).
+		WithClock(w.clock).
+		WithDryRun(w.dryRun).
+		WithSemaphore(w.getActionSemaphore())
+	if seenStore, dedup := w.getSeenStore(); !force {
+		actioninator = actioninator.WithCooldown(seenStore, watch.Name, watch.Actions.NotifyCooldown)
+
+		if dedup {
+			actioninator = actioninator.WithDedup(seenStore, watch.Name)
+		}
+	}
+
+	if err := actioninator.Handle(ctx, *item, logger); err != nil {
+		return nil, fmt.Errorf("unable to handle %s: %w", result.Item, err)
+	}
+
+	if err := actioninator.Flush(ctx, logger); err != nil {
+		return nil, fmt.Errorf("unable to handle %s: %w", result.Item, err)
+	}
+
+	if seenStore, _ := w.getSeenStore(); !force && !w.dryRun {
+		if err := seenStore.Flush(); err != nil {
+			return nil, fmt.Errorf("unable to write state file: %w", err)
+		}
+	}
+
+	return result, nil
 }
 
 func (w *watchinator) Watch(ctx context.Context, configFilePath string) error {
@@ -116,10 +584,14 @@ func NewWatchinator(
 	emailinator Emailinator,
 ) Watchinator {
 	return &watchinator{
-		logger:       logger,
-		gitHubinator: gitHubinator,
-		pollinator:   pollinator,
-		configinator: configinator,
-		emailinator:  emailinator,
+		logger:          logger,
+		gitHubinator:    gitHubinator,
+		pollinator:      pollinator,
+		configinator:    configinator,
+		emailinator:     emailinator,
+		seenStore:       NewMemorySeenStore(),
+		reportSchedules: map[string]time.Duration{},
+		clock:           NewSystemClock(),
+		lock:            &sync.Mutex{},
 	}
 }
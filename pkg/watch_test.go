@@ -0,0 +1,469 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shurcooL/githubv4"
+	"github.com/wneessen/go-mail"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/assert/cmp"
+)
+
+func TestSortGitHubItems(t *testing.T) {
+	newItem := func(owner string, name string, number int) *GitHubItem {
+		i := NewTestGitHubItem()
+		i.Repo = GitHubRepository{Owner: owner, Name: name}
+		i.Number = number
+
+		return i
+	}
+
+	items := []*GitHubItem{
+		newItem("b", "repo", 1),
+		newItem("a", "repo", 3),
+		newItem("a", "other", 2),
+		newItem("a", "repo", 1),
+	}
+
+	SortGitHubItems(items)
+
+	got := []string{}
+	for _, i := range items {
+		got = append(got, fmt.Sprintf("%s/%s#%d", i.Repo.Owner, i.Repo.Name, i.Number))
+	}
+
+	assert.DeepEqual(t, got, []string{"a/other#2", "a/repo#1", "a/repo#3", "b/repo#1"})
+}
+
+func TestWatchinatorPollCallbackActionsInStableOrder(t *testing.T) {
+	gh := NewMockGitHubinator()
+
+	for _, n := range []int{3, 1, 2} {
+		i := NewTestGitHubItem()
+		i.Number = n
+		i.ID = githubv4.ID(n)
+		i.Subscription = githubv4.SubscriptionStateUnsubscribed
+		gh.ListIssuesReturn = append(gh.ListIssuesReturn, i)
+	}
+
+	watch := NewTestWatch()
+	watch.Actions.Email.Enabled = false
+
+	w := NewWatchinator(NewLogger(), gh, nil, nil, NewMockEmailinator()).(*watchinator)
+
+	w.getPollCallback(context.Background(), gh, NewMockEmailinator(), watch)(time.Now())
+
+	assert.DeepEqual(t, gh.SetSubscriptionRequests, []githubv4.ID{githubv4.ID(1), githubv4.ID(2), githubv4.ID(3)})
+}
+
+func TestWatchinatorPollCallbackHandlesListedIssues(t *testing.T) {
+	newItem := func(number int, state githubv4.SubscriptionState) *GitHubItem {
+		i := NewTestGitHubItem()
+		i.Number = number
+		i.ID = githubv4.ID(number)
+		i.Subscription = state
+
+		return i
+	}
+
+	cases := []struct {
+		name       string
+		items      []*GitHubItem
+		err        error
+		subscribed []githubv4.ID
+	}{
+		{
+			name:       "no issues",
+			items:      []*GitHubItem{},
+			subscribed: []githubv4.ID{},
+		},
+		{
+			name: "unsubscribed issues are subscribed to",
+			items: []*GitHubItem{
+				newItem(1, githubv4.SubscriptionStateUnsubscribed),
+				newItem(2, githubv4.SubscriptionStateSubscribed),
+			},
+			subscribed: []githubv4.ID{githubv4.ID(1)},
+		},
+		{
+			name:       "error listing issues",
+			items:      []*GitHubItem{newItem(1, githubv4.SubscriptionStateUnsubscribed)},
+			err:        errors.New("my test error"),
+			subscribed: []githubv4.ID{},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			gh := NewMockGitHubinator()
+			gh.ListIssuesReturn = c.items
+			gh.ListIssuesError = c.err
+
+			watch := NewTestWatch()
+			watch.Actions.Email.Enabled = false
+
+			w := NewWatchinator(NewLogger(), gh, nil, nil, NewMockEmailinator()).(*watchinator)
+			w.getPollCallback(context.Background(), gh, NewMockEmailinator(), watch)(time.Now())
+
+			assert.DeepEqual(t, gh.ListIssuesRequests, watch.Repositories)
+			assert.Equal(t, len(gh.ListIssuesFilters), 1)
+			assert.DeepEqual(t, gh.ListIssuesFilters[0].Labels, watch.SearchLabels)
+			assert.DeepEqual(t, gh.SetSubscriptionRequests, c.subscribed)
+		})
+	}
+}
+
+func TestWatchinatorPollCallbackSendsDigest(t *testing.T) {
+	gh := NewMockGitHubinator()
+	e := &capturingEmailinator{MockEmailinator: *NewMockEmailinator()}
+
+	first := NewTestGitHubItem()
+	second := NewTestGitHubItem()
+	second.Number = 2
+	second.ID = githubv4.ID(2)
+	gh.ListIssuesReturn = []*GitHubItem{first, second}
+
+	watch := NewTestWatch()
+	watch.Actions.Email.Digest = true
+
+	w := NewWatchinator(NewLogger(), gh, nil, nil, e).(*watchinator)
+	assert.NilError(t, w.getPollCallback(context.Background(), gh, e, watch)(time.Now()))
+
+	assert.Equal(t, len(e.sent), 1)
+	assert.DeepEqual(
+		t, e.sent[0].GetGenHeader(mail.HeaderSubject),
+		[]string{fmt.Sprintf("watchinator digest: %s: 2 new item(s)", watch.Name)},
+	)
+}
+
+// concurrentListGitHubinator is a MockGitHubinator which records the highest number of concurrent calls to ListIssues
+// and fails to list issues from the repository named 'broken'.
+type concurrentListGitHubinator struct {
+	*MockGitHubinator
+
+	lock     sync.Mutex
+	inFlight int
+	maxSeen  int
+}
+
+func (c *concurrentListGitHubinator) ListIssues(
+	ctx context.Context, ghr GitHubRepository, filter *GitHubIssueFilter, matcher Matchinator,
+) ([]*GitHubItem, error) {
+	c.lock.Lock()
+	c.inFlight++
+	c.maxSeen = max(c.maxSeen, c.inFlight)
+	c.lock.Unlock()
+
+	// Give the other calls time to start.
+	time.Sleep(10 * time.Millisecond)
+
+	c.lock.Lock()
+	c.inFlight--
+	c.lock.Unlock()
+
+	if ghr.Name == "broken" {
+		return nil, errors.New("my test error")
+	}
+
+	item := NewTestGitHubItem()
+	item.Repo = ghr
+	item.ID = githubv4.ID(ghr.Name)
+	item.Subscription = githubv4.SubscriptionStateUnsubscribed
+
+	return []*GitHubItem{item}, nil
+}
+
+func TestWatchinatorPollCallbackListsRepositoriesConcurrently(t *testing.T) {
+	for _, limit := range []int{1, 2} {
+		t.Run(fmt.Sprintf("limit %d", limit), func(t *testing.T) {
+			gh := &concurrentListGitHubinator{MockGitHubinator: NewMockGitHubinator()}
+
+			watch := NewTestWatch()
+			watch.Actions.Email.Enabled = false
+			watch.repoConcurrency = limit
+			watch.Repositories = []GitHubRepository{}
+
+			for _, name := range []string{"a", "broken", "c", "d", "e"} {
+				watch.Repositories = append(watch.Repositories, GitHubRepository{Owner: "owner", Name: name})
+			}
+
+			errors := MetricPollErrorTotal.WithLabelValues(watch.Name)
+			errorsBefore := CounterValue(errors)
+
+			w := NewWatchinator(NewLogger(), gh, nil, nil, NewMockEmailinator()).(*watchinator)
+			assert.NilError(t, w.getPollCallback(context.Background(), gh, NewMockEmailinator(), watch)(time.Now()))
+
+			assert.Equal(t, gh.maxSeen, limit)
+
+			// The broken repository doesn't stop the others, which are handled in order.
+			assert.Equal(t, CounterValue(errors)-errorsBefore, float64(1))
+			assert.DeepEqual(
+				t, gh.SetSubscriptionRequests,
+				[]githubv4.ID{githubv4.ID("a"), githubv4.ID("c"), githubv4.ID("d"), githubv4.ID("e")},
+			)
+		})
+	}
+}
+
+func TestWatchinatorPollCallbackReturnsErrorWhenNoRepositoryIsListed(t *testing.T) {
+	gh := &concurrentListGitHubinator{MockGitHubinator: NewMockGitHubinator()}
+
+	watch := NewTestWatch()
+	watch.Actions.Email.Enabled = false
+	watch.Repositories = []GitHubRepository{{Owner: "owner", Name: "broken"}}
+
+	w := NewWatchinator(NewLogger(), gh, nil, nil, NewMockEmailinator()).(*watchinator)
+	err := w.getPollCallback(context.Background(), gh, NewMockEmailinator(), watch)(time.Now())
+	assert.ErrorContains(t, err, "unable to list issues from any of 1 repositories: my test error")
+}
+
+func TestWatchinatorReprocess(t *testing.T) {
+	ctx := context.Background()
+	gh := NewMockGitHubinator()
+	e := NewMockEmailinator()
+	repo := GitHubRepository{Owner: "owner", Name: "repo"}
+
+	watch := NewTestWatch()
+	watch.Actions.Email.Enabled = false
+	watch.Actions.NotifyCooldown = time.Hour
+	assert.NilError(t, watch.ValidateAndPopulate(ctx, gh))
+
+	w := NewWatchinator(NewLogger(), gh, nil, nil, e).(*watchinator)
+
+	_, err := w.Reprocess(ctx, watch.Name, repo, 1, false)
+	assert.ErrorContains(t, err, "no config has been loaded")
+
+	w.config, w.gh, w.e = &Config{Watches: []*Watch{watch}}, gh, e
+
+	_, err = w.Reprocess(ctx, "unknown", repo, 1, false)
+	assert.ErrorContains(t, err, "unknown watch with name 'unknown'")
+
+	// Disabled watches don't perform actions.
+	off := false
+	watch.Enabled = &off
+	_, err = w.Reprocess(ctx, watch.Name, repo, 1, false)
+	assert.ErrorContains(t, err, "watch 'name' is disabled")
+	assert.Equal(t, len(gh.GetIssueRequests), 0)
+
+	watch.Enabled = nil
+
+	_, err = w.Reprocess(ctx, watch.Name, GitHubRepository{Owner: "owner", Name: "other"}, 1, false)
+	assert.ErrorContains(t, err, "repository owner/other is not watched by watch 'name'")
+
+	// The item doesn't have the watch's required label.
+	result, err := w.Reprocess(ctx, watch.Name, repo, 1, false)
+	assert.NilError(t, err)
+	assert.Equal(t, result.Matched, false)
+	assert.Assert(t, cmp.Contains(result.Reason, "a/requiredLabel"))
+	assert.DeepEqual(t, gh.GetIssueRequests, []string{"owner/repo#1"})
+	assert.Equal(t, len(gh.SetSubscriptionRequests), 0)
+
+	gh.GetIssueReturn.Labels = []string{"a/requiredLabel"}
+
+	result, err = w.Reprocess(ctx, watch.Name, repo, 1, false)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, result, &ReprocessResult{Watch: "name", Item: "owner/repo#1", Matched: true})
+	assert.Equal(t, len(gh.SetSubscriptionRequests), 1)
+
+	// The item was just actioned, so it is within the watch's cooldown unless forced.
+	_, err = w.Reprocess(ctx, watch.Name, repo, 1, false)
+	assert.NilError(t, err)
+	assert.Equal(t, len(gh.SetSubscriptionRequests), 1)
+
+	_, err = w.Reprocess(ctx, watch.Name, repo, 1, true)
+	assert.NilError(t, err)
+	assert.Equal(t, len(gh.SetSubscriptionRequests), 2)
+
+	gh.SetSubscriptionError = errors.New("my test error")
+	_, err = w.Reprocess(ctx, watch.Name, repo, 1, true)
+	assert.ErrorContains(t, err, "my test error")
+}
+
+func TestWatchinatorPollCallbackDryRun(t *testing.T) {
+	gh := NewMockGitHubinator()
+	gh.ListIssuesReturn = []*GitHubItem{NewTestGitHubItem()}
+	e := &capturingEmailinator{MockEmailinator: *NewMockEmailinator()}
+
+	watch := NewTestWatch()
+	watch.Actions.Subscribe.Enabled = true
+
+	w := NewWatchinator(NewLogger(), gh, nil, nil, e).WithDryRun(true).(*watchinator)
+	assert.NilError(t, w.getPollCallback(context.Background(), gh, e, watch)(time.Now()))
+
+	assert.Equal(t, len(gh.ListIssuesRequests), len(watch.Repositories))
+	assert.Equal(t, len(gh.SetSubscriptionRequests), 0)
+	assert.Equal(t, len(e.sent), 0)
+}
+
+func TestWatchinatorStateFileDedupsActionsAcrossRestarts(t *testing.T) {
+	ctx := context.Background()
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	gh := NewMockGitHubinator()
+	gh.ListIssuesReturn = []*GitHubItem{NewTestGitHubItem()}
+	watch := NewTestWatch()
+
+	poll := func() int {
+		e := &capturingEmailinator{MockEmailinator: *NewMockEmailinator()}
+		w := NewWatchinator(NewLogger(), gh, nil, nil, e).(*watchinator)
+		assert.NilError(t, w.useStateFile(stateFile))
+
+		callback := w.getPollCallback(ctx, gh, e, watch)
+		assert.NilError(t, callback(time.Now()))
+		assert.NilError(t, callback(time.Now()))
+
+		return len(e.sent)
+	}
+
+	// The item is emailed once, and isn't emailed again after a restart.
+	assert.Equal(t, poll(), 1)
+	assert.Equal(t, poll(), 0)
+
+	// Once it's updated, it is emailed again.
+	gh.ListIssuesReturn[0].UpdatedAt = time.Now()
+	assert.Equal(t, poll(), 1)
+}
+
+func TestWatchinatorStateFileDropsItemsWhichNoLongerMatch(t *testing.T) {
+	ctx := context.Background()
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	gh := NewMockGitHubinator()
+	gh.ListIssuesReturn = []*GitHubItem{NewTestGitHubItem()}
+	watch := NewTestWatch()
+
+	e := &capturingEmailinator{MockEmailinator: *NewMockEmailinator()}
+	w := NewWatchinator(NewLogger(), gh, nil, nil, e).(*watchinator)
+	assert.NilError(t, w.useStateFile(stateFile))
+
+	callback := w.getPollCallback(ctx, gh, e, watch)
+	assert.NilError(t, callback(time.Now()))
+
+	// The records of a poll are written once it's done.
+	contents, err := os.ReadFile(stateFile)
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(string(contents), watch.Name+"/"), string(contents))
+
+	// The item no longer matches, so its records are dropped, and it's emailed again once it matches.
+	items := gh.ListIssuesReturn
+	gh.ListIssuesReturn = []*GitHubItem{}
+	assert.NilError(t, callback(time.Now()))
+
+	contents, err = os.ReadFile(stateFile)
+	assert.NilError(t, err)
+	assert.Equal(t, string(contents), "{}")
+
+	gh.ListIssuesReturn = items
+	assert.NilError(t, callback(time.Now()))
+	assert.Equal(t, len(e.sent), 2)
+}
+
+func TestWatchinatorConfigCallbackUsesWatchInterval(t *testing.T) {
+	ctx := context.Background()
+	p := NewPollinator(ctx, NewLogger()).WithClock(NewFakeClock(time.Now()))
+
+	defer p.StopAll()
+
+	w := NewWatchinator(NewLogger(), NewMockGitHubinator(), p, nil, NewMockEmailinator()).(*watchinator)
+
+	quiet := NewTestWatch()
+	quiet.Name = "quiet"
+	busy := NewTestWatch()
+	busy.Name = "busy"
+	busy.Interval = 5 * time.Minute
+
+	c := &Config{Interval: time.Hour, SpreadTicks: true, Watches: []*Watch{quiet, busy}}
+	w.getConfigCallback(ctx)(c)
+
+	polls := p.(*pollinator)
+	polls.lock.Lock()
+	defer polls.lock.Unlock()
+
+	assert.Equal(t, polls.polls["quiet"].interval, time.Hour)
+	assert.Equal(t, polls.polls["quiet"].offset, time.Duration(0))
+	assert.Equal(t, polls.polls["busy"].interval, 5*time.Minute)
+	assert.Equal(t, polls.polls["busy"].offset, 150*time.Second)
+}
+
+func TestWatchinatorConfigCallbackSkipsDisabledWatches(t *testing.T) {
+	ctx := context.Background()
+	p := NewPollinator(ctx, NewLogger()).WithClock(NewFakeClock(time.Now()))
+
+	defer p.StopAll()
+
+	w := NewWatchinator(NewLogger(), NewMockGitHubinator(), p, nil, NewMockEmailinator()).(*watchinator)
+
+	enabled := NewTestWatch()
+	enabled.Name = "enabled"
+	disabled := NewTestWatch()
+	disabled.Name = "disabled"
+	disabled.Report = ReportConfig{Enabled: true, Schedule: time.Hour, To: "report@example.com"}
+
+	c := &Config{Interval: time.Hour, Watches: []*Watch{enabled, disabled}}
+	w.getConfigCallback(ctx)(c)
+	assert.Equal(t, len(p.List()), 3)
+
+	// Disabled watches aren't counted when spreading the enabled ones across the interval.
+	off := false
+	first := NewTestWatch()
+	first.Name = "first"
+	skipped := NewTestWatch()
+	skipped.Name = "skipped"
+	skipped.Enabled = &off
+	last := NewTestWatch()
+	last.Name = "last"
+	w.getConfigCallback(ctx)(&Config{Interval: time.Hour, SpreadTicks: true, Watches: []*Watch{first, skipped, last}})
+
+	polls := p.(*pollinator)
+	polls.lock.Lock()
+	assert.Equal(t, polls.polls["first"].offset, time.Duration(0))
+	assert.Equal(t, polls.polls["last"].offset, 30*time.Minute)
+	polls.lock.Unlock()
+
+	w.getConfigCallback(ctx)(c)
+
+	// Disabling a running watch stops its polls.
+	disabled.Enabled = &off
+	w.getConfigCallback(ctx)(c)
+	assert.DeepEqual(t, p.List(), []string{"enabled"})
+}
+
+func TestWatchinatorConfigCallbackKeepsUnchangedReportPolls(t *testing.T) {
+	ctx := context.Background()
+	p := NewPollinator(ctx, NewLogger()).WithClock(NewFakeClock(time.Now()))
+
+	defer p.StopAll()
+
+	w := NewWatchinator(NewLogger(), NewMockGitHubinator(), p, nil, NewMockEmailinator()).(*watchinator)
+	polls := p.(*pollinator)
+	getReportPoll := func() *poll {
+		polls.lock.Lock()
+		defer polls.lock.Unlock()
+
+		return polls.polls[ReportPollName("name")]
+	}
+
+	watch := NewTestWatch()
+	watch.Report = ReportConfig{Enabled: true, Schedule: time.Hour, To: "report@example.com"}
+
+	w.getConfigCallback(ctx)(&Config{Interval: time.Hour, Watches: []*Watch{watch}})
+	first := getReportPoll()
+
+	// Reloading the config doesn't push back the report.
+	reloaded := NewTestWatch()
+	reloaded.Report = ReportConfig{Enabled: true, Schedule: time.Hour, To: "other@example.com"}
+	w.getConfigCallback(ctx)(&Config{Interval: time.Hour, Watches: []*Watch{reloaded}})
+	assert.Equal(t, getReportPoll(), first)
+
+	// Changing the schedule restarts the poll.
+	reloaded.Report.Schedule = 2 * time.Hour
+	w.getConfigCallback(ctx)(&Config{Interval: time.Hour, Watches: []*Watch{reloaded}})
+	assert.Assert(t, getReportPoll() != first)
+	assert.Equal(t, getReportPoll().interval, 2*time.Hour)
+}